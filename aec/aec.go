@@ -0,0 +1,456 @@
+package aec
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/cyberxnomad/wasapi/audioclient"
+	"github.com/cyberxnomad/wasapi/fft"
+	"github.com/cyberxnomad/wasapi/internal/dsp"
+	"github.com/cyberxnomad/wasapi/resample"
+)
+
+const (
+	DefaultTail     = 150 * time.Millisecond // 默认的回声尾长
+	DefaultStepSize = 0.5                    // 默认的归一化步长
+	DefaultGeigel   = 0.5                    // 默认的 Geigel 双讲检测门限
+	dtHangover      = 50 * time.Millisecond  // 双讲检测的保持时间
+	blockDuration   = 8 * time.Millisecond   // 块长的下限
+)
+
+// Stats 为回声消除的状态。
+type Stats struct {
+	ERLE       float64 // 回声损耗增强 (dB)，近端能量与输出能量之比
+	DoubleTalk bool    // 当前是否检测到双讲，此时暂停自适应
+	Diverged   uint64  // 背景滤波器发散而从前景滤波器恢复的次数
+}
+
+// Canceller 是分块频域自适应滤波 (PBFDAF) 的回声消除器。
+//
+// 以环回流作为远端参考，麦克风作为近端，二者各自带有 QPC 时间戳 (GetBuffer 返回的 QPCPosition)，
+// 据此对齐；采样率不同时参考信号会被重采样。多声道输入先混合为单声道，输出为单声道，
+// 相对输入延迟一个块。
+//
+// 采用前景/背景双滤波器结构: 背景滤波器在非双讲期间自适应，误差更小时复制到前景滤波器，
+// 输出由前景滤波器给出，双讲漏检导致的发散不会直接影响输出。
+type Canceller struct {
+	mu sync.Mutex
+
+	format      audioclient.WAVEFORMATEXTENSIBLE
+	refFormat   audioclient.WAVEFORMATEXTENSIBLE
+	rate        float64
+	block       int
+	size        int
+	partitions  int
+	transform   *fft.FFT
+	resampler   *resample.Resampler
+	stepSize    float64
+	geigel      float64
+	delayFrames int
+
+	// 远端参考环形缓冲，按绝对采样序号寻址
+	ref          []float32
+	refWritten   int64
+	refAnchor    int64
+	refAnchorQPC uint64
+	haveRef      bool
+
+	// 近端
+	near          []float32
+	nearCount     int64
+	nearAnchor    int64
+	nearAnchorQPC uint64
+	out           []float32
+
+	// 自适应滤波器
+	prevRef    []float64
+	refBlock   []float64      // 当前参考块
+	errBlock   []float64      // 背景滤波器的误差
+	spectra    [][]complex128 // 最近 partitions 个参考块的频谱，spectra[0] 为最新
+	weights    [][]complex128 // 背景滤波器
+	foreground [][]complex128 // 前景滤波器
+	power      []float64
+	blockMax   []float64
+	constrain  int
+	scratch    []complex128
+	errBuf     []complex128
+	foreBuf    []complex128
+
+	dtCount  int
+	pathGain float64
+	hangover int
+	nearPow  float64
+	forePow  float64
+	backPow  float64
+	stats    Stats
+
+	buf []float32
+}
+
+// NewCanceller 创建回声消除器，format 为麦克风格式，refFormat 为环回流格式，tail 为回声尾长。
+func NewCanceller(format, refFormat *audioclient.WAVEFORMATEXTENSIBLE, tail time.Duration) *Canceller {
+	rate := float64(format.Format.SamplesPerSec)
+	block := fft.NextPow2(int(blockDuration.Seconds() * rate))
+	partitions := max(int(math.Ceil(tail.Seconds()*rate/float64(block))), 1)
+	size := 2 * block
+	transform, _ := fft.New(size)
+
+	c := &Canceller{
+		format:     *format,
+		refFormat:  *refFormat,
+		rate:       rate,
+		block:      block,
+		size:       size,
+		partitions: partitions,
+		transform:  transform,
+		stepSize:   DefaultStepSize,
+		geigel:     DefaultGeigel,
+		ref:        make([]float32, fft.NextPow2(int(rate)*2)),
+		prevRef:    make([]float64, block),
+		refBlock:   make([]float64, block),
+		errBlock:   make([]float64, block),
+		spectra:    make([][]complex128, partitions),
+		weights:    make([][]complex128, partitions),
+		foreground: make([][]complex128, partitions),
+		power:      make([]float64, size),
+		blockMax:   make([]float64, partitions),
+		scratch:    make([]complex128, size),
+		errBuf:     make([]complex128, size),
+		foreBuf:    make([]complex128, size),
+		hangover:   int(dtHangover.Seconds() * rate / float64(block)),
+	}
+
+	// 输出队列预置一个块的静音，使输出帧数与输入相同
+	c.out = make([]float32, block)
+
+	for p := range c.spectra {
+		c.spectra[p] = make([]complex128, size)
+		c.weights[p] = make([]complex128, size)
+		c.foreground[p] = make([]complex128, size)
+	}
+
+	if refFormat.Format.SamplesPerSec != format.Format.SamplesPerSec {
+		c.resampler = resample.New(1, refFormat.Format.SamplesPerSec, format.Format.SamplesPerSec)
+	}
+
+	return c
+}
+
+// BlockFrames 方法返回处理的块长 (帧)，也是输出相对输入的延迟。
+func (c *Canceller) BlockFrames() int {
+	return c.block
+}
+
+// SetStepSize 方法设置自适应的归一化步长，范围为 (0, 1]。
+func (c *Canceller) SetStepSize(mu float64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.stepSize = mu
+}
+
+// SetGeigelThreshold 方法设置 Geigel 双讲检测门限。
+//
+// 近端峰值超过远端在尾长内峰值的该倍数时判定为双讲，
+// 实际门限不低于估计的回声路径峰值增益的 2 倍。
+func (c *Canceller) SetGeigelThreshold(threshold float64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.geigel = threshold
+}
+
+// SetDelay 方法设置已知的整体延迟 (例如呈现缓冲区延迟)，使自适应滤波器的尾长集中于房间响应。
+func (c *Canceller) SetDelay(delay time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.delayFrames = int(delay.Seconds() * c.rate)
+}
+
+// Stats 方法返回当前状态。
+func (c *Canceller) Stats() Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.stats
+}
+
+// WriteReference 方法写入环回流捕获的交错 float32 采样，qpc 为首帧的 QPC 时间 (100 纳秒单位)。
+func (c *Canceller) WriteReference(samples []float32, qpc uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	mono := downmix(samples, int(c.refFormat.Format.Channels))
+	if c.resampler != nil {
+		// 重采样器引入的延迟折算到时间戳
+		qpc -= uint64(float64(c.resampler.Latency()) * 1e7 / float64(c.refFormat.Format.SamplesPerSec))
+		mono = c.resampler.Process(nil, mono)
+	}
+
+	c.refAnchor = c.refWritten
+	c.refAnchorQPC = qpc
+	c.haveRef = true
+
+	mask := int64(len(c.ref) - 1)
+	for _, v := range mono {
+		c.ref[c.refWritten&mask] = v
+		c.refWritten++
+	}
+}
+
+// Process 方法处理麦克风捕获的交错 float32 采样，qpc 为首帧的 QPC 时间，
+// 返回同样帧数的单声道输出，返回的切片在下次调用前有效。
+func (c *Canceller) Process(samples []float32, qpc uint64) (out []float32) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.process(samples, qpc)
+}
+
+// ProcessBytes 方法按格式解码麦克风数据并处理，参见 Process。
+func (c *Canceller) ProcessBytes(data []byte, qpc uint64) (out []float32, err error) {
+	err = dsp.ProcessBytes(&c.mu, &c.format, &c.buf, data, false, func(samples []float32) {
+		out = c.process(samples, qpc)
+	})
+
+	return
+}
+
+func (c *Canceller) process(samples []float32, qpc uint64) (out []float32) {
+	c.nearAnchor = c.nearCount + int64(len(c.near))
+	c.nearAnchorQPC = qpc
+
+	mono := downmix(samples, int(c.format.Format.Channels))
+	c.near = append(c.near, mono...)
+
+	for len(c.near) >= c.block {
+		c.processBlock(c.near[:c.block])
+		c.near = c.near[c.block:]
+		c.nearCount += int64(c.block)
+	}
+
+	out = append([]float32(nil), c.out[:len(mono)]...)
+	c.out = c.out[len(mono):]
+
+	return
+}
+
+// Reset 方法清除滤波器及所有缓冲。
+func (c *Canceller) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.resetFilter()
+	c.near = c.near[:0]
+	c.out = make([]float32, c.block)
+	c.haveRef = false
+	c.stats = Stats{}
+}
+
+func (c *Canceller) resetFilter() {
+	for p := range c.weights {
+		clear(c.weights[p])
+		clear(c.foreground[p])
+		clear(c.spectra[p])
+	}
+
+	clear(c.power)
+	clear(c.blockMax)
+	clear(c.prevRef)
+	c.nearPow = 0
+	c.forePow = 0
+	c.backPow = 0
+	c.pathGain = 0
+}
+
+// 近端块对应的参考信号起始序号
+func (c *Canceller) refStart() int64 {
+	// 近端块首帧的 QPC 时间
+	t := float64(c.nearAnchorQPC) + float64(c.nearCount-c.nearAnchor)*1e7/c.rate
+
+	return c.refAnchor + int64(math.Round((t-float64(c.refAnchorQPC))*c.rate/1e7)) - int64(c.delayFrames)
+}
+
+func (c *Canceller) processBlock(near []float32) {
+	b, n := c.block, c.size
+
+	// 取对齐的参考块，缺失的部分视为静音
+	x := c.refBlock
+	clear(x)
+	if c.haveRef {
+		start := c.refStart()
+		mask := int64(len(c.ref) - 1)
+		for i := range x {
+			if idx := start + int64(i); idx >= 0 && idx < c.refWritten && idx > c.refWritten-int64(len(c.ref)) {
+				x[i] = float64(c.ref[idx&mask])
+			}
+		}
+	}
+
+	// 参考频谱: [上一块, 当前块]
+	copy(c.spectra[1:], c.spectra[:c.partitions-1])
+	c.spectra[0] = c.scratch
+	c.scratch = c.spectra[c.partitions-1]
+	if c.partitions == 1 {
+		c.scratch = make([]complex128, n)
+	}
+
+	for i := 0; i < b; i++ {
+		c.spectra[0][i] = complex(c.prevRef[i], 0)
+		c.spectra[0][b+i] = complex(x[i], 0)
+	}
+	copy(c.prevRef, x)
+	c.transform.Transform(c.spectra[0])
+
+	var xmax float64
+	for _, v := range x {
+		xmax = max(xmax, math.Abs(v))
+	}
+	copy(c.blockMax[1:], c.blockMax[:c.partitions-1])
+	c.blockMax[0] = xmax
+
+	// 前景滤波器给出输出，背景滤波器持续自适应
+	yf := c.estimate(c.foreground, c.foreBuf)
+	yb := c.estimate(c.weights, c.errBuf)
+
+	e := c.errBlock
+	var nearEnergy, foreEnergy, backEnergy, nearMax, echoMax float64
+	for i := 0; i < b; i++ {
+		d := float64(near[i])
+		ef := d - real(yf[b+i])
+		e[i] = d - real(yb[b+i])
+		echoMax = max(echoMax, math.Abs(real(yf[b+i])))
+		nearEnergy += d * d
+		foreEnergy += ef * ef
+		backEnergy += e[i] * e[i]
+		nearMax = max(nearMax, math.Abs(d))
+		c.out = append(c.out, float32(ef))
+	}
+
+	c.nearPow += (nearEnergy - c.nearPow) * 0.1
+	c.forePow += (foreEnergy - c.forePow) * 0.3
+	c.backPow += (backEnergy - c.backPow) * 0.3
+	if c.nearPow > 1e-10 {
+		c.stats.ERLE = 10 * math.Log10(c.nearPow/max(c.forePow, 1e-12))
+	}
+
+	// Geigel 双讲检测
+	var farMax float64
+	for _, v := range c.blockMax {
+		farMax = max(farMax, v)
+	}
+
+	// 门限随估计的回声路径增益提高，避免强耦合时误判
+	threshold := max(c.geigel, 2*c.pathGain)
+	if nearMax > threshold*farMax {
+		c.dtCount = c.hangover
+	} else if c.dtCount > 0 {
+		c.dtCount--
+	}
+
+	c.stats.DoubleTalk = c.dtCount > 0 && farMax > 0
+
+	switch {
+	case !c.stats.DoubleTalk && c.backPow < 0.8*c.forePow:
+		// 背景滤波器明显更优，更新前景滤波器
+		for p := range c.weights {
+			copy(c.foreground[p], c.weights[p])
+		}
+		c.forePow = c.backPow
+
+	case c.backPow > 4*c.forePow && c.backPow > 1e-8:
+		// 背景滤波器发散 (通常由漏检的双讲引起)，从前景滤波器恢复
+		for p := range c.weights {
+			copy(c.weights[p], c.foreground[p])
+		}
+		c.backPow = c.forePow
+		c.stats.Diverged++
+	}
+
+	if c.stats.DoubleTalk || farMax == 0 {
+		return
+	}
+
+	// 峰值跟踪: 快速上升，缓慢回落；取前景滤波器的回声估计，背景滤波器发散时不抬高门限
+	if ratio := echoMax / farMax; ratio > c.pathGain {
+		c.pathGain += (ratio - c.pathGain) * 0.5
+	} else {
+		c.pathGain += (ratio - c.pathGain) * 0.01
+	}
+
+	c.adapt(e)
+}
+
+// 用给定的滤波器估计回声，结果的后半部分为当前块
+func (c *Canceller) estimate(weights [][]complex128, y []complex128) []complex128 {
+	clear(y)
+	for p := range weights {
+		w, s := weights[p], c.spectra[p]
+		for k := range y {
+			y[k] += w[k] * s[k]
+		}
+	}
+	c.transform.Inverse(y)
+
+	return y
+}
+
+// 归一化频域 LMS 更新，并轮流对一个分区施加梯度约束
+func (c *Canceller) adapt(e []float64) {
+	b, n := c.block, c.size
+
+	grad := c.errBuf
+	clear(grad)
+	for i := 0; i < b; i++ {
+		grad[b+i] = complex(e[i], 0)
+	}
+	c.transform.Transform(grad)
+
+	delta := 1e-4 * float64(n)
+	// 归一化功率取尾长内各分区参考功率之和，参考电平起伏时也与滤波器实际看到的输入一致
+	for k := range c.power {
+		var sum float64
+		for p := range c.spectra {
+			s := c.spectra[p][k]
+			sum += real(s)*real(s) + imag(s)*imag(s)
+		}
+		c.power[k] += (sum - c.power[k]) * 0.5
+	}
+
+	for p := range c.weights {
+		w, s := c.weights[p], c.spectra[p]
+		for k := range w {
+			norm := c.stepSize / (c.power[k] + delta)
+			conj := complex(real(s[k]), -imag(s[k]))
+			w[k] += complex(norm, 0) * conj * grad[k]
+		}
+	}
+
+	// 梯度约束: 时域滤波器的后半部分置零
+	w := c.weights[c.constrain]
+	c.transform.Inverse(w)
+	for i := b; i < n; i++ {
+		w[i] = 0
+	}
+	c.transform.Transform(w)
+	c.constrain = (c.constrain + 1) % c.partitions
+}
+
+func downmix(samples []float32, channels int) (mono []float32) {
+	if channels <= 1 {
+		return samples
+	}
+
+	mono = make([]float32, len(samples)/channels)
+	for i := range mono {
+		var sum float32
+		for c := 0; c < channels; c++ {
+			sum += samples[i*channels+c]
+		}
+		mono[i] = sum / float32(channels)
+	}
+
+	return
+}
@@ -0,0 +1,254 @@
+package aec
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/cyberxnomad/wasapi/audioclient"
+	"github.com/cyberxnomad/wasapi/generator"
+	"github.com/cyberxnomad/wasapi/pcm"
+	"github.com/cyberxnomad/wasapi/resample"
+)
+
+// 离线模拟: 48 kHz 立体声环回流作为远端参考，经合成房间到达 16 kHz 单声道麦克风，
+// 6 至 8 秒之间近端讲话形成双讲。
+const (
+	refRate  = 48000
+	micRate  = 16000
+	packet   = 10 * time.Millisecond
+	duration = 10 * time.Second
+)
+
+var rooms = []struct {
+	name  string
+	delay time.Duration
+	rt60  time.Duration
+	gain  float64
+}{
+	{"small room", 3 * time.Millisecond, 80 * time.Millisecond, 0.2},
+	{"office", 10 * time.Millisecond, 120 * time.Millisecond, 0.3},
+}
+
+type result struct {
+	echoReduction float64 // 单讲稳态时的回声衰减 (dB)
+	nearSNR       float64 // 双讲期间近端语音与失真之比 (dB)
+	detected      float64 // 双讲期间检测到双讲的块所占比例
+	falseAlarm    float64 // 单讲稳态时误判双讲的块所占比例
+	stats         Stats
+}
+
+// 参考流的时间偏移: pipeline 为参考信号时间戳之外额外的回放延迟，delay 为 SetDelay 的补偿值，
+// lead 为参考流先于麦克风数据送达的包数
+type offset struct {
+	pipeline time.Duration
+	delay    time.Duration
+	lead     int
+}
+
+func simulate(t *testing.T, delay, rt60 time.Duration, gain float64, o offset) (r result) {
+	t.Helper()
+
+	refFormat := pcm.NewFormat(pcm.SampleTypeFloat, refRate, 2, 32, audioclient.KSAUDIO_SPEAKER_STEREO)
+	micFormat := pcm.NewFormat(pcm.SampleTypeFloat, micRate, 1, 32, audioclient.KSAUDIO_SPEAKER_MONO)
+
+	room := NewRoom(micRate, delay, rt60, gain, 1)
+	canceller := NewCanceller(&micFormat, &refFormat, DefaultTail)
+	canceller.SetDelay(o.delay)
+	toMic := resample.New(1, refRate, micRate)
+
+	far := generator.NewNoise(generator.NoisePink, 0.3, 2)
+	near := generator.NewMultiTone(micRate, []float64{220, 440, 660, 880, 1320}, 0.3)
+
+	refFrames := int(packet.Seconds() * refRate)
+	micFrames := int(packet.Seconds() * micRate)
+	latency := canceller.BlockFrames()
+
+	// 先生成全部数据包，再按到达顺序送入
+	type packetData struct {
+		ts       time.Duration
+		ref, mic []float32
+		talk     bool
+	}
+	var packets []packetData
+	var history []float32 // 近端讲话，用于评估双讲时的失真
+	pipeline := make([]float32, int(o.pipeline.Seconds()*micRate))
+
+	for ts := time.Duration(0); ts < duration; ts += packet {
+		// 远端: 音节包络调制的粉红噪声
+		ref := make([]float32, refFrames*2)
+		mono := make([]float32, refFrames)
+		for i := range mono {
+			s := ts.Seconds() + float64(i)/refRate
+			env := 0.5 + 0.5*math.Sin(2*math.Pi*3*s)
+			mono[i] = far.Next() * float32(env)
+			ref[2*i], ref[2*i+1] = mono[i], mono[i]
+		}
+
+		pipeline = append(pipeline, room.Process(toMic.Process(nil, mono))...)
+		echo := pipeline[:micFrames]
+		pipeline = pipeline[micFrames:]
+
+		mic := make([]float32, len(echo))
+		talk := ts >= 6*time.Second && ts < 8*time.Second
+		for i := range mic {
+			var v float32
+			if talk {
+				v = near.Next()
+			}
+			history = append(history, v)
+			mic[i] = echo[i] + v
+		}
+
+		packets = append(packets, packetData{ts, ref, mic, talk})
+	}
+
+	var echoPow, outPow, dtNearPow, dtErrPow float64
+	var talkPackets, talkDetected, quietPackets, quietDetected int
+
+	// 参考流提前 lead 个包送达，时间戳不变
+	for _, p := range packets[:min(o.lead, len(packets))] {
+		canceller.WriteReference(p.ref, uint64(p.ts/100))
+	}
+
+	for k, p := range packets {
+		if k+o.lead < len(packets) {
+			next := packets[k+o.lead]
+			canceller.WriteReference(next.ref, uint64(next.ts/100))
+		}
+
+		ts, mic, talk := p.ts, p.mic, p.talk
+		out := canceller.Process(mic, uint64(ts/100))
+
+		for i, v := range out {
+			idx := k*micFrames + i - latency
+			if idx < 0 {
+				continue
+			}
+
+			switch {
+			case ts >= 4*time.Second && ts < 6*time.Second:
+				echoPow += float64(mic[i] * mic[i])
+				outPow += float64(v * v)
+			case talk && ts >= 6*time.Second+100*time.Millisecond:
+				d := float64(v - history[idx])
+				dtNearPow += float64(history[idx] * history[idx])
+				dtErrPow += d * d
+			}
+		}
+
+		switch dt := canceller.Stats().DoubleTalk; {
+		case talk && ts >= 6*time.Second+100*time.Millisecond:
+			talkPackets++
+			if dt {
+				talkDetected++
+			}
+		case ts >= 3*time.Second && ts < 6*time.Second, ts >= 8*time.Second+200*time.Millisecond:
+			quietPackets++
+			if dt {
+				quietDetected++
+			}
+		}
+	}
+
+	r.echoReduction = 10 * math.Log10(echoPow/outPow)
+	r.nearSNR = 10 * math.Log10(dtNearPow/dtErrPow)
+	r.detected = float64(talkDetected) / float64(talkPackets)
+	r.falseAlarm = float64(quietDetected) / float64(quietPackets)
+	r.stats = canceller.Stats()
+
+	return
+}
+
+func TestCancellerRooms(t *testing.T) {
+	for _, room := range rooms {
+		r := simulate(t, room.delay, room.rt60, room.gain, offset{})
+		t.Logf("%-10s echo reduction %.1f dB, near-end SNR %.1f dB, detected %.0f%%, false alarm %.0f%%, resets %d",
+			room.name, r.echoReduction, r.nearSNR, r.detected*100, r.falseAlarm*100, r.stats.Diverged)
+
+		if r.echoReduction < 18 {
+			t.Errorf("%s: echo reduction %.1f dB, want >= 18 dB", room.name, r.echoReduction)
+		}
+
+		// 近端讲话应被检测为双讲，且不被滤波器消除
+		if r.detected < 0.9 {
+			t.Errorf("%s: double-talk detected in %.0f%% of the talk window, want >= 90%%", room.name, r.detected*100)
+		}
+		if r.nearSNR < 25 {
+			t.Errorf("%s: near-end SNR during double-talk %.1f dB, want >= 25 dB", room.name, r.nearSNR)
+		}
+
+		if r.falseAlarm > 0.1 {
+			t.Errorf("%s: double-talk falsely flagged in %.0f%% of single-talk", room.name, r.falseAlarm*100)
+		}
+		if r.stats.Diverged > 3 {
+			t.Errorf("%s: %d divergence resets, want <= 3", room.name, r.stats.Diverged)
+		}
+	}
+}
+
+func TestCancellerOffset(t *testing.T) {
+	room := rooms[0]
+
+	for _, tt := range []struct {
+		name string
+		o    offset
+	}{
+		{"reference ahead", offset{lead: 8}},
+		{"pipeline delay", offset{pipeline: 200 * time.Millisecond, delay: 200 * time.Millisecond}},
+		{"both", offset{pipeline: 120 * time.Millisecond, delay: 120 * time.Millisecond, lead: 3}},
+	} {
+		r := simulate(t, room.delay, room.rt60, room.gain, tt.o)
+		t.Logf("%-15s echo reduction %.1f dB, near-end SNR %.1f dB", tt.name, r.echoReduction, r.nearSNR)
+
+		if r.echoReduction < 18 {
+			t.Errorf("%s: echo reduction %.1f dB, want >= 18 dB", tt.name, r.echoReduction)
+		}
+		if r.nearSNR < 25 {
+			t.Errorf("%s: near-end SNR during double-talk %.1f dB, want >= 25 dB", tt.name, r.nearSNR)
+		}
+	}
+
+	// 未补偿的回放延迟超出尾长，回声无法消除
+	r := simulate(t, room.delay, room.rt60, room.gain, offset{pipeline: 200 * time.Millisecond})
+	if r.echoReduction > 6 {
+		t.Errorf("uncompensated delay: echo reduction %.1f dB, want < 6 dB", r.echoReduction)
+	}
+}
+
+func TestCancellerWithoutReference(t *testing.T) {
+	format := pcm.NewFormat(pcm.SampleTypeFloat, micRate, 2, 32, audioclient.KSAUDIO_SPEAKER_STEREO)
+	canceller := NewCanceller(&format, &format, DefaultTail)
+	block := canceller.BlockFrames()
+
+	// 无参考信号时输出为混合后的输入，延迟一个块
+	var in, out []float32
+	for k := 0; k < 10; k++ {
+		mic := make([]float32, 2*160)
+		for i := 0; i < 160; i++ {
+			v := float32(math.Sin(float64(k*160+i) / 7))
+			mic[2*i], mic[2*i+1] = v, v/2
+			in = append(in, v*3/4)
+		}
+
+		got := canceller.Process(mic, uint64(k)*100000)
+		if len(got) != 160 {
+			t.Fatalf("Process returned %d frames, want 160", len(got))
+		}
+		out = append(out, got...)
+	}
+
+	for i := range out {
+		var want float32
+		if i >= block {
+			want = in[i-block]
+		}
+		if math.Abs(float64(out[i]-want)) > 1e-6 {
+			t.Fatalf("sample %d = %v, want %v", i, out[i], want)
+		}
+	}
+
+	if stats := canceller.Stats(); stats.DoubleTalk || stats.Diverged != 0 {
+		t.Fatalf("stats = %+v", stats)
+	}
+}
@@ -0,0 +1,74 @@
+package aec
+
+import (
+	"math"
+	"math/rand"
+	"time"
+)
+
+// Room 是合成的房间脉冲响应，用于离线模拟扬声器到麦克风的回声路径。
+type Room struct {
+	response []float64
+	history  []float64
+	pos      int
+}
+
+// NewRoom 创建合成房间，delay 为直达声延迟，rt60 为混响衰减 60 dB 的时间，
+// gain 为回声路径的总能量增益 (线性)，seed 为随机数种子。
+func NewRoom(samplesPerSec uint32, delay, rt60 time.Duration, gain float64, seed int64) *Room {
+	rate := float64(samplesPerSec)
+	start := int(delay.Seconds() * rate)
+	length := start + int(rt60.Seconds()*rate)
+	r := rand.New(rand.NewSource(seed))
+
+	response := make([]float64, max(length, start+1))
+	response[start] = 1
+
+	// 指数衰减的噪声尾部，60 dB 衰减对应幅度衰减 1000 倍
+	decay := math.Log(1000) / (rt60.Seconds() * rate)
+	for i := start + 1; i < len(response); i++ {
+		response[i] = r.NormFloat64() * 0.3 * math.Exp(-decay*float64(i-start))
+	}
+
+	var energy float64
+	for _, v := range response {
+		energy += v * v
+	}
+
+	scale := math.Sqrt(gain / energy)
+	for i := range response {
+		response[i] *= scale
+	}
+
+	return &Room{
+		response: response,
+		history:  make([]float64, len(response)),
+	}
+}
+
+// Response 方法返回脉冲响应。
+func (r *Room) Response() []float64 {
+	return r.response
+}
+
+// Process 方法将单声道信号通过房间，返回回声信号。
+func (r *Room) Process(src []float32) (echo []float32) {
+	n := len(r.response)
+	echo = make([]float32, len(src))
+
+	for i, v := range src {
+		r.history[r.pos] = float64(v)
+
+		var acc float64
+		for k, h := range r.response {
+			if h != 0 {
+				acc += h * r.history[(r.pos-k+n)%n]
+			}
+		}
+
+		echo[i] = float32(acc)
+		r.pos = (r.pos + 1) % n
+	}
+
+	return
+}
@@ -0,0 +1,223 @@
+package aes67
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cyberxnomad/wasapi/audioclient"
+	"github.com/cyberxnomad/wasapi/pcm"
+	"github.com/cyberxnomad/wasapi/rtp"
+)
+
+func newTestSender(t *testing.T) *Sender {
+	t.Helper()
+
+	conn, err := net.Dial("udp4", "127.0.0.1:5004")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	format := pcm.NewFormat(pcm.SampleTypeFloat, 48000, 6, 32, audioclient.KSAUDIO_SPEAKER_5POINT1_SURROUND)
+	s, err := NewSender(conn, nil, &format, audioclient.SPEAKER_FRONT_LEFT|audioclient.SPEAKER_FRONT_RIGHT)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return s
+}
+
+func TestSDPRoundTrip(t *testing.T) {
+	s := newTestSender(t)
+	s.SetReferenceClock(PTPReferenceClock([8]byte{0x00, 0x1D, 0xC1, 0xFF, 0xFE, 0x12, 0x34, 0x56}, 0))
+
+	desc := s.Description("192.168.1.10", "239.69.1.1", 5004, 32)
+	text := desc.String()
+
+	for _, line := range []string{
+		"c=IN IP4 239.69.1.1/32",
+		"m=audio 5004 RTP/AVP 98",
+		"a=rtpmap:98 L24/48000/2",
+		"a=ptime:1",
+		"a=ts-refclk:ptp=IEEE1588-2008:00-1D-C1-FF-FE-12-34-56:0",
+		fmt.Sprintf("a=mediaclk:direct=%d", s.RTP().TimestampAt(0)),
+	} {
+		if !strings.Contains(text, line+"\r\n") {
+			t.Errorf("sdp missing %q:\n%s", line, text)
+		}
+	}
+
+	got, err := rtp.ParseDescription(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got.SessionName != desc.SessionName || got.SessionID != desc.SessionID || got.Origin != desc.Origin ||
+		got.Address != desc.Address || got.TTL != desc.TTL || got.Port != desc.Port ||
+		got.PayloadType != desc.PayloadType || got.PayloadFormat != desc.PayloadFormat ||
+		got.SamplesPerSec != desc.SamplesPerSec || got.Channels != desc.Channels ||
+		got.PacketTime != desc.PacketTime || !slices.Equal(got.Attributes, desc.Attributes) {
+		t.Fatalf("ParseDescription = %+v, want %+v", got, desc)
+	}
+
+	if got.String() != text {
+		t.Fatalf("regenerated sdp differs:\n%s\nwant:\n%s", got.String(), text)
+	}
+}
+
+func TestParseDescriptionErrors(t *testing.T) {
+	for _, text := range []string{
+		"",
+		"v=1\r\n",
+		"v=0\r\ns=x\r\n",                     // 无媒体描述
+		"v=0\r\nm=audio 5004 RTP/AVP 98\r\n", // 无 rtpmap
+		"v=0\r\nm=audio 5004 RTP/AVP 98\r\na=rtpmap:98 OPUS/48000/2\r\n",
+		"v=0\r\nm=video 5004 RTP/AVP 98\r\n",
+	} {
+		if _, err := rtp.ParseDescription(text); err == nil {
+			t.Errorf("ParseDescription(%q) succeeded", text)
+		}
+	}
+}
+
+func TestNewSenderErrors(t *testing.T) {
+	format := pcm.NewFormat(pcm.SampleTypeFloat, 44100, 2, 32, audioclient.KSAUDIO_SPEAKER_STEREO)
+	if _, err := NewSender(nil, nil, &format, 0); !errors.Is(err, ErrSampleRate) {
+		t.Fatalf("44100 Hz: err = %v", err)
+	}
+
+	format = pcm.NewFormat(pcm.SampleTypeFloat, 48000, 2, 32, audioclient.KSAUDIO_SPEAKER_STEREO)
+	if _, err := NewSender(nil, nil, &format, audioclient.SPEAKER_BACK_LEFT); err == nil {
+		t.Fatal("selecting absent channel succeeded")
+	}
+}
+
+func TestSAPPacketRoundTrip(t *testing.T) {
+	p := SAPPacket{Delete: true, MessageID: 0x1234, Origin: net.IPv4(10, 0, 0, 1), SDP: "v=0\r\n"}
+
+	buf, err := p.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := "\x24\x00\x12\x34\x0A\x00\x00\x01application/sdp\x00v=0\r\n"
+	if string(buf) != want {
+		t.Fatalf("Marshal = %q, want %q", buf, want)
+	}
+
+	var got SAPPacket
+	if err = got.Unmarshal(buf); err != nil {
+		t.Fatal(err)
+	}
+	if got.Delete != p.Delete || got.MessageID != p.MessageID || !got.Origin.Equal(p.Origin) || got.SDP != p.SDP {
+		t.Fatalf("Unmarshal = %+v, want %+v", got, p)
+	}
+
+	// 负载类型字段可省略
+	if err = got.Unmarshal([]byte("\x20\x00\x00\x01\x0A\x00\x00\x01v=0\r\n")); err != nil || got.SDP != "v=0\r\n" || got.Delete {
+		t.Fatalf("Unmarshal without payload type = %+v, %v", got, err)
+	}
+
+	for _, buf := range [][]byte{
+		nil,
+		[]byte("\x40\x00\x00\x01\x0A\x00\x00\x01v=0"),            // 版本 2
+		[]byte("\x21\x00\x00\x01\x0A\x00\x00\x01v=0"),            // 加密
+		[]byte("\x20\x00\x00\x01\x0A\x00\x00\x01text/plain\x00"), // 非 SDP 负载
+	} {
+		if err = got.Unmarshal(buf); !errors.Is(err, ErrInvalidSAP) {
+			t.Errorf("Unmarshal(%q): err = %v", buf, err)
+		}
+	}
+}
+
+func TestAnnouncerMulticast(t *testing.T) {
+	group, err := net.ResolveUDPAddr("udp4", SAPAddress)
+	if err != nil {
+		t.Fatal(err)
+	}
+	group.Port = 0
+
+	listener, err := net.ListenMulticastUDP("udp4", nil, group)
+	if err != nil {
+		t.Skipf("multicast unavailable: %v", err)
+	}
+	defer listener.Close()
+
+	group.Port = listener.LocalAddr().(*net.UDPAddr).Port
+	conn, err := net.DialUDP("udp4", nil, group)
+	if err != nil {
+		t.Skipf("multicast unavailable: %v", err)
+	}
+	defer conn.Close()
+
+	s := newTestSender(t)
+	desc := s.Description("127.0.0.1", "239.69.1.1", 5004, 32)
+	sdp := desc.String()
+
+	announcer := NewAnnouncer(conn, net.IPv4(127, 0, 0, 1), sdp)
+	announcer.SetInterval(20 * time.Millisecond)
+
+	quit := make(chan struct{})
+	done := make(chan error, 1)
+	go func() { done <- announcer.Run(quit) }()
+
+	read := func() (p SAPPacket) {
+		t.Helper()
+
+		buf := make([]byte, 2048)
+		listener.SetReadDeadline(time.Now().Add(2 * time.Second))
+		n, _, err := listener.ReadFrom(buf)
+		if err != nil {
+			t.Fatalf("no sap packet on loopback multicast: %v", err)
+		}
+
+		if err = p.Unmarshal(buf[:n]); err != nil {
+			t.Fatal(err)
+		}
+
+		return
+	}
+
+	// 至少收到两次周期性通告
+	var id uint16
+	for i := 0; i < 2; i++ {
+		p := read()
+		if p.Delete || p.SDP != sdp || !p.Origin.Equal(net.IPv4(127, 0, 0, 1)) {
+			t.Fatalf("announcement %d = %+v", i, p)
+		}
+
+		if i > 0 && p.MessageID != id {
+			t.Fatalf("message id changed from %04X to %04X", id, p.MessageID)
+		}
+		id = p.MessageID
+	}
+
+	close(quit)
+	if err = <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	// 跳过关闭前已发出的通告，最后一个包为撤销通告
+	for {
+		p := read()
+		if !p.Delete {
+			continue
+		}
+
+		if p.MessageID != id || p.SDP != sdp {
+			t.Fatalf("deletion = %+v", p)
+		}
+
+		parsed, err := rtp.ParseDescription(p.SDP)
+		if err != nil || parsed.Address != "239.69.1.1" {
+			t.Fatalf("deleted session %+v, %v", parsed, err)
+		}
+
+		break
+	}
+}
@@ -0,0 +1,167 @@
+package aes67
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"net"
+	"time"
+)
+
+// SAP 默认组播地址及端口 (RFC 2974，管理范围 239.255.0.0/16)
+const SAPAddress = "239.255.255.255:9875"
+
+const sapPayloadType = "application/sdp"
+
+var ErrInvalidSAP = errors.New("invalid sap packet")
+
+// SAPPacket 为会话通告协议 (RFC 2974) 数据包，仅支持 IPv4 源且不含认证数据。
+type SAPPacket struct {
+	Delete    bool // 为 true 时表示撤销通告
+	MessageID uint16
+	Origin    net.IP
+	SDP       string
+}
+
+// Marshal 方法编码 SAP 数据包。
+func (p *SAPPacket) Marshal() (buf []byte, err error) {
+	origin := p.Origin.To4()
+	if origin == nil {
+		err = ErrInvalidSAP
+		return
+	}
+
+	buf = make([]byte, 8, 8+len(sapPayloadType)+1+len(p.SDP))
+
+	// V=1, A=0 (IPv4), R=0, T, E=0, C=0
+	buf[0] = 1 << 5
+	if p.Delete {
+		buf[0] |= 1 << 2
+	}
+
+	buf[1] = 0
+	binary.BigEndian.PutUint16(buf[2:4], p.MessageID)
+	copy(buf[4:8], origin)
+
+	buf = append(buf, sapPayloadType...)
+	buf = append(buf, 0)
+	buf = append(buf, p.SDP...)
+
+	return
+}
+
+// Unmarshal 方法解码 SAP 数据包。
+func (p *SAPPacket) Unmarshal(buf []byte) (err error) {
+	if len(buf) < 4 || buf[0]>>5 != 1 {
+		err = ErrInvalidSAP
+		return
+	}
+
+	addrLen := 4
+	if buf[0]&0x10 != 0 {
+		addrLen = 16
+	}
+
+	// 认证数据长度以 32 位字为单位
+	offset := 4 + addrLen + 4*int(buf[1])
+	if len(buf) < offset || buf[0]&0x03 != 0 {
+		err = ErrInvalidSAP
+		return
+	}
+
+	p.Delete = buf[0]&0x04 != 0
+	p.MessageID = binary.BigEndian.Uint16(buf[2:4])
+	p.Origin = net.IP(append([]byte(nil), buf[4:4+addrLen]...))
+
+	payload := buf[offset:]
+
+	// 可选的负载类型字段，以 NUL 结尾
+	if !bytes.HasPrefix(payload, []byte("v=0")) {
+		i := bytes.IndexByte(payload, 0)
+		if i < 0 {
+			err = ErrInvalidSAP
+			return
+		}
+
+		if string(payload[:i]) != sapPayloadType {
+			err = ErrInvalidSAP
+			return
+		}
+
+		payload = payload[i+1:]
+	}
+
+	p.SDP = string(payload)
+
+	return
+}
+
+// Announcer 周期性地通过 SAP 通告会话描述。
+type Announcer struct {
+	conn     net.Conn
+	packet   SAPPacket
+	interval time.Duration
+}
+
+// NewAnnouncer 创建 SAP 通告者，conn 一般连接到 SAPAddress，origin 为发送端的 IPv4 地址。
+//
+// 默认通告间隔为 30 秒。
+func NewAnnouncer(conn net.Conn, origin net.IP, sdp string) *Announcer {
+	return &Announcer{
+		conn: conn,
+		packet: SAPPacket{
+			MessageID: uint16(crc32.ChecksumIEEE([]byte(sdp))),
+			Origin:    origin,
+			SDP:       sdp,
+		},
+		interval: 30 * time.Second,
+	}
+}
+
+// SetInterval 方法设置通告间隔。
+func (a *Announcer) SetInterval(interval time.Duration) {
+	a.interval = interval
+}
+
+// Announce 方法发送一次通告。
+func (a *Announcer) Announce() (err error) {
+	return a.send(false)
+}
+
+// Delete 方法发送撤销通告。
+func (a *Announcer) Delete() (err error) {
+	return a.send(true)
+}
+
+// Run 方法立即发送通告并按间隔重复，quit 关闭后发送撤销通告并返回。
+func (a *Announcer) Run(quit <-chan struct{}) (err error) {
+	ticker := time.NewTicker(a.interval)
+	defer ticker.Stop()
+
+	for {
+		if err = a.Announce(); err != nil {
+			return
+		}
+
+		select {
+		case <-quit:
+			return a.Delete()
+		case <-ticker.C:
+		}
+	}
+}
+
+func (a *Announcer) send(del bool) (err error) {
+	packet := a.packet
+	packet.Delete = del
+
+	var buf []byte
+	if buf, err = packet.Marshal(); err != nil {
+		return
+	}
+
+	_, err = a.conn.Write(buf)
+
+	return
+}
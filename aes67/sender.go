@@ -0,0 +1,187 @@
+package aes67
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/cyberxnomad/wasapi/audioclient"
+	"github.com/cyberxnomad/wasapi/pcm"
+	"github.com/cyberxnomad/wasapi/rtp"
+)
+
+// AES67 媒体配置
+const (
+	SamplesPerSec      = 48000
+	PacketTime         = time.Millisecond
+	MaxChannels        = 8
+	DefaultPayloadType = 98
+)
+
+var ErrSampleRate = errors.New("aes67 requires 48000 Hz")
+
+// PTPReferenceClock 返回 IEEE 1588-2008 (PTPv2) 主时钟的 ts-refclk 值 (RFC 7273)。
+func PTPReferenceClock(grandmaster [8]byte, domain uint8) string {
+	return fmt.Sprintf("ptp=IEEE1588-2008:%02X-%02X-%02X-%02X-%02X-%02X-%02X-%02X:%d",
+		grandmaster[0], grandmaster[1], grandmaster[2], grandmaster[3],
+		grandmaster[4], grandmaster[5], grandmaster[6], grandmaster[7], domain)
+}
+
+// Sender 按 AES67 配置 (48 kHz、L24、1 毫秒包时长) 发送音频。
+//
+// 本包不实现 PTP 同步，RTP 时间戳由设备位置推导，
+// 参考时钟需由调用方通过 SetReferenceClock 声明。
+type Sender struct {
+	rtp *rtp.Sender
+
+	format   audioclient.WAVEFORMATEXTENSIBLE
+	output   audioclient.WAVEFORMATEXTENSIBLE
+	selected []int
+	refClock string
+
+	samples []float32
+	frame   []float32
+	buf     []byte
+}
+
+// NewSender 创建 AES67 发送端。
+//
+// format 为捕获流的格式，采样率必须为 48000 Hz；channelMask 选择要发送的声道，
+// 发送的声道数为其中置位的个数，为 0 时发送全部声道。
+func NewSender(conn, ctrl net.Conn, format *audioclient.WAVEFORMATEXTENSIBLE, channelMask uint32) (s *Sender, err error) {
+	if format.Format.SamplesPerSec != SamplesPerSec {
+		err = ErrSampleRate
+		return
+	}
+
+	selected, mask := selectChannels(format, channelMask)
+	if len(selected) == 0 || len(selected) > MaxChannels {
+		err = fmt.Errorf("aes67: unsupported channel count %d", len(selected))
+		return
+	}
+
+	s = &Sender{
+		format:   *format,
+		output:   pcm.NewFormat(pcm.SampleTypeFloat, SamplesPerSec, uint16(len(selected)), 32, mask),
+		selected: selected,
+		refClock: "local",
+	}
+
+	if s.rtp, err = rtp.NewSender(conn, ctrl, &s.output, rtp.L24); err != nil {
+		return nil, err
+	}
+	s.rtp.SetPayloadType(DefaultPayloadType)
+	s.rtp.SetPacketTime(PacketTime)
+
+	return
+}
+
+// RTP 方法返回底层的 RTP 发送端。
+func (s *Sender) RTP() *rtp.Sender {
+	return s.rtp
+}
+
+// Channels 方法返回发送的声道数。
+func (s *Sender) Channels() int {
+	return len(s.selected)
+}
+
+// SetReferenceClock 方法设置 SDP 中 ts-refclk 属性的值，默认为 "local"。
+func (s *Sender) SetReferenceClock(refClock string) {
+	s.refClock = refClock
+}
+
+// Send 方法发送一个捕获数据包，参数同 rtp.Sender.Send。
+func (s *Sender) Send(data []byte, devicePosition uint64, QPCPosition uint64) (err error) {
+	frames := pcm.Frames(&s.format, len(data))
+	channels := int(s.format.Format.Channels)
+
+	if len(s.samples) < frames*channels {
+		s.samples = make([]float32, frames*channels)
+	}
+	samples := s.samples[:frames*channels]
+
+	if _, err = pcm.Decode(&s.format, data, samples); err != nil {
+		return
+	}
+
+	// 按声道选择重新交错
+	out := len(s.selected)
+	if len(s.frame) < frames*out {
+		s.frame = make([]float32, frames*out)
+		s.buf = make([]byte, frames*out*4)
+	}
+
+	for i := 0; i < frames; i++ {
+		for j, ch := range s.selected {
+			s.frame[i*out+j] = samples[i*channels+ch]
+		}
+	}
+
+	buf := s.buf[:frames*out*4]
+	if _, err = pcm.Encode(&s.output, s.frame[:frames*out], buf); err != nil {
+		return
+	}
+
+	return s.rtp.Send(buf, devicePosition, QPCPosition)
+}
+
+// Description 方法生成 AES67 的 SDP 描述，包括 ts-refclk 及 mediaclk 属性。
+func (s *Sender) Description(origin, group string, port int, ttl uint8) rtp.Description {
+	desc := rtp.NewDescription(&s.output, rtp.L24, s.rtp.PayloadType())
+	desc.Origin = origin
+	desc.Address = group
+	desc.Port = port
+	desc.TTL = ttl
+	desc.PacketTime = PacketTime
+	desc.Attributes = []string{
+		"recvonly",
+		"ts-refclk:" + s.refClock,
+		fmt.Sprintf("mediaclk:direct=%d", s.rtp.TimestampAt(0)),
+	}
+
+	return desc
+}
+
+// 返回要发送的声道索引及其声道掩码
+func selectChannels(format *audioclient.WAVEFORMATEXTENSIBLE, channelMask uint32) (selected []int, mask uint32) {
+	channels := int(format.Format.Channels)
+
+	if channelMask == 0 {
+		for i := 0; i < channels; i++ {
+			selected = append(selected, i)
+		}
+
+		mask = format.ChannelMask
+		return
+	}
+
+	// 未指定声道位置时按顺序取前 n 个声道
+	if format.ChannelMask == 0 {
+		for bit := uint32(1); bit != 0 && len(selected) < channels; bit <<= 1 {
+			if channelMask&bit != 0 {
+				selected = append(selected, len(selected))
+				mask |= bit
+			}
+		}
+
+		return
+	}
+
+	index := 0
+	for bit := uint32(1); bit != 0 && index < channels; bit <<= 1 {
+		if format.ChannelMask&bit == 0 {
+			continue
+		}
+
+		if channelMask&bit != 0 {
+			selected = append(selected, index)
+			mask |= bit
+		}
+
+		index++
+	}
+
+	return
+}
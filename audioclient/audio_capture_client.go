@@ -1,7 +1,8 @@
+//go:build windows
+
 package audioclient
 
 import (
-	"fmt"
 	"syscall"
 	"unsafe"
 
@@ -31,7 +32,7 @@ func (client *IAudioCaptureClient) Release() (err error) {
 	r, _, _ := syscall.SyscallN(client.vtbl.Release, uintptr(unsafe.Pointer(client)))
 
 	if com.HRESULT(r) != com.HRESULT(windows.S_OK) {
-		err = fmt.Errorf("IAudioClient::Release failed with code: 0x%08X", com.HRESULT(r))
+		err = &Error{Method: "IAudioClient::Release", Code: uint32(r)}
 		return
 	}
 
@@ -54,7 +55,7 @@ func (client *IAudioCaptureClient) GetBuffer() (data []byte, numFramesToRead uin
 	)
 
 	if com.HRESULT(r) != com.HRESULT(windows.S_OK) {
-		err = fmt.Errorf("IAudioCaptureClient::GetBuffer failed with code: 0x%08X", com.HRESULT(r))
+		err = &Error{Method: "IAudioCaptureClient::GetBuffer", Code: uint32(r)}
 		return
 	}
 
@@ -70,7 +71,7 @@ func (client *IAudioCaptureClient) ReleaseBuffer(numFramesToRead uint32) (err er
 	)
 
 	if com.HRESULT(r) != com.HRESULT(windows.S_OK) {
-		err = fmt.Errorf("IAudioCaptureClient::ReleaseBuffer failed with code: 0x%08X", com.HRESULT(r))
+		err = &Error{Method: "IAudioCaptureClient::ReleaseBuffer", Code: uint32(r)}
 		return
 	}
 
@@ -84,7 +85,7 @@ func (client *IAudioCaptureClient) GetNextPacketSize() (numFramesInNextPacket ui
 	)
 
 	if com.HRESULT(r) != com.HRESULT(windows.S_OK) {
-		err = fmt.Errorf("IAudioCaptureClient::GetNextPacketSize failed with code: 0x%08X", com.HRESULT(r))
+		err = &Error{Method: "IAudioCaptureClient::GetNextPacketSize", Code: uint32(r)}
 		return
 	}
 
@@ -1,7 +1,8 @@
+//go:build windows
+
 package audioclient
 
 import (
-	"fmt"
 	"syscall"
 	"unsafe"
 
@@ -40,7 +41,7 @@ func (client *IAudioClient) Release() (err error) {
 	r, _, _ := syscall.SyscallN(client.vtbl.Release, uintptr(unsafe.Pointer(client)))
 
 	if com.HRESULT(r) != com.HRESULT(windows.S_OK) {
-		err = fmt.Errorf("IAudioClient::Release failed with code: 0x%08X", com.HRESULT(r))
+		err = &Error{Method: "IAudioClient::Release", Code: uint32(r)}
 		return
 	}
 
@@ -67,7 +68,7 @@ func (client *IAudioClient) Initialize(
 	)
 
 	if com.HRESULT(r) != com.HRESULT(windows.S_OK) {
-		err = fmt.Errorf("IAudioClient::Initialize failed with code: 0x%08X", com.HRESULT(r))
+		err = &Error{Method: "IAudioClient::Initialize", Code: uint32(r)}
 		return
 	}
 
@@ -81,7 +82,7 @@ func (client *IAudioClient) GetBufferSize() (numBufferFrames uint32, err error)
 	)
 
 	if com.HRESULT(r) != com.HRESULT(windows.S_OK) {
-		err = fmt.Errorf("IAudioClient::GetBufferSize failed with code: 0x%08X", com.HRESULT(r))
+		err = &Error{Method: "IAudioClient::GetBufferSize", Code: uint32(r)}
 		return
 	}
 
@@ -95,7 +96,7 @@ func (client *IAudioClient) GetStreamLatency() (hnsLatency uint64, err error) {
 	)
 
 	if com.HRESULT(r) != com.HRESULT(windows.S_OK) {
-		err = fmt.Errorf("IAudioClient::GetStreamLatency failed with code: 0x%08X", com.HRESULT(r))
+		err = &Error{Method: "IAudioClient::GetStreamLatency", Code: uint32(r)}
 		return
 	}
 
@@ -109,7 +110,7 @@ func (client *IAudioClient) GetCurrentPadding() (numPaddingFrames uint32, err er
 	)
 
 	if com.HRESULT(r) != com.HRESULT(windows.S_OK) {
-		err = fmt.Errorf("IAudioClient::GetCurrentPadding failed with code: 0x%08X", com.HRESULT(r))
+		err = &Error{Method: "IAudioClient::GetCurrentPadding", Code: uint32(r)}
 		return
 	}
 
@@ -132,7 +133,7 @@ func (client *IAudioClient) IsFormatSupported(shareMode AUDCLNT_SHAREMODE, forma
 	)
 
 	if com.HRESULT(r) != com.HRESULT(windows.S_OK) {
-		err = fmt.Errorf("IAudioClient::IsFormatSupported failed with code: 0x%08X", com.HRESULT(r))
+		err = &Error{Method: "IAudioClient::IsFormatSupported", Code: uint32(r)}
 		return
 	}
 
@@ -155,7 +156,7 @@ func (client *IAudioClient) GetMixFormat() (deviceFormat WAVEFORMATEXTENSIBLE, e
 	)
 
 	if com.HRESULT(r) != com.HRESULT(windows.S_OK) {
-		err = fmt.Errorf("IAudioClient::GetMixFormat failed with code: 0x%08X", com.HRESULT(r))
+		err = &Error{Method: "IAudioClient::GetMixFormat", Code: uint32(r)}
 		return
 	}
 
@@ -176,7 +177,7 @@ func (client *IAudioClient) GetDevicePeriod() (hnsDefaultDevicePeriod, hnsMinimu
 	)
 
 	if com.HRESULT(r) != com.HRESULT(windows.S_OK) {
-		err = fmt.Errorf("IAudioClient::GetDevicePeriod failed with code: 0x%08X", com.HRESULT(r))
+		err = &Error{Method: "IAudioClient::GetDevicePeriod", Code: uint32(r)}
 		return
 	}
 
@@ -188,7 +189,7 @@ func (client *IAudioClient) Start() (err error) {
 	r, _, _ := syscall.SyscallN(client.vtbl.Start, uintptr(unsafe.Pointer(client)))
 
 	if com.HRESULT(r) != com.HRESULT(windows.S_OK) {
-		err = fmt.Errorf("IAudioClient::Start failed with code: 0x%08X", com.HRESULT(r))
+		err = &Error{Method: "IAudioClient::Start", Code: uint32(r)}
 		return
 	}
 
@@ -200,7 +201,7 @@ func (client *IAudioClient) Stop() (err error) {
 	r, _, _ := syscall.SyscallN(client.vtbl.Stop, uintptr(unsafe.Pointer(client)))
 
 	if com.HRESULT(r) != com.HRESULT(windows.S_OK) {
-		err = fmt.Errorf("IAudioClient::Stop failed with code: 0x%08X", com.HRESULT(r))
+		err = &Error{Method: "IAudioClient::Stop", Code: uint32(r)}
 		return
 	}
 
@@ -212,7 +213,7 @@ func (client *IAudioClient) Reset() (err error) {
 	r, _, _ := syscall.SyscallN(client.vtbl.Reset, uintptr(unsafe.Pointer(client)))
 
 	if com.HRESULT(r) != com.HRESULT(windows.S_OK) {
-		err = fmt.Errorf("IAudioClient::Reset failed with code: 0x%08X", com.HRESULT(r))
+		err = &Error{Method: "IAudioClient::Reset", Code: uint32(r)}
 		return
 	}
 
@@ -226,7 +227,7 @@ func (client *IAudioClient) SetEventHandle(eventHandle windows.Handle) (err erro
 	)
 
 	if com.HRESULT(r) != com.HRESULT(windows.S_OK) {
-		err = fmt.Errorf("IAudioClient::SetEventHandle failed with code: 0x%08X", com.HRESULT(r))
+		err = &Error{Method: "IAudioClient::SetEventHandle", Code: uint32(r)}
 		return
 	}
 
@@ -241,7 +242,7 @@ func (client *IAudioClient) GetService(iid *windows.GUID) (v unsafe.Pointer, err
 	)
 
 	if com.HRESULT(r) != com.HRESULT(windows.S_OK) {
-		err = fmt.Errorf("IAudioClient::GetService failed with code: 0x%08X", com.HRESULT(r))
+		err = &Error{Method: "IAudioClient::GetService", Code: uint32(r)}
 		return
 	}
 
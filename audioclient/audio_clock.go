@@ -1,7 +1,8 @@
+//go:build windows
+
 package audioclient
 
 import (
-	"fmt"
 	"syscall"
 	"unsafe"
 
@@ -31,7 +32,7 @@ func (clock *IAudioClock) Release() (err error) {
 	r, _, _ := syscall.SyscallN(clock.vtbl.Release, uintptr(unsafe.Pointer(clock)))
 
 	if com.HRESULT(r) != com.HRESULT(windows.S_OK) {
-		err = fmt.Errorf("IAudioClock::Release failed with code: 0x%08X", com.HRESULT(r))
+		err = &Error{Method: "IAudioClock::Release", Code: uint32(r)}
 		return
 	}
 
@@ -45,7 +46,7 @@ func (clock *IAudioClock) GetFrequency() (frequency uint64, err error) {
 	)
 
 	if com.HRESULT(r) != com.HRESULT(windows.S_OK) {
-		err = fmt.Errorf("IAudioClock::GetFrequency failed with code: 0x%08X", com.HRESULT(r))
+		err = &Error{Method: "IAudioClock::GetFrequency", Code: uint32(r)}
 		return
 	}
 
@@ -60,7 +61,7 @@ func (clock *IAudioClock) GetPosition() (position uint64, QPCPosition uint64, er
 	)
 
 	if com.HRESULT(r) != com.HRESULT(windows.S_OK) {
-		err = fmt.Errorf("IAudioClock::GetPosition failed with code: 0x%08X", com.HRESULT(r))
+		err = &Error{Method: "IAudioClock::GetPosition", Code: uint32(r)}
 		return
 	}
 
@@ -74,7 +75,7 @@ func (clock *IAudioClock) GetCharacteristics() (characteristics uint32, err erro
 	)
 
 	if com.HRESULT(r) != com.HRESULT(windows.S_OK) {
-		err = fmt.Errorf("IAudioClock::GetCharacteristics failed with code: 0x%08X", com.HRESULT(r))
+		err = &Error{Method: "IAudioClock::GetCharacteristics", Code: uint32(r)}
 		return
 	}
 
@@ -0,0 +1,55 @@
+//go:build windows
+
+package audioclient
+
+import (
+	"math"
+	"syscall"
+	"unsafe"
+
+	"github.com/cyberxnomad/wasapi/com"
+	"golang.org/x/sys/windows"
+)
+
+// MIDL_INTERFACE("f6e4c0a0-46d9-4fb8-be21-57a3ef2b626c")
+var _IID_IAudioClockAdjustment = windows.GUID{Data1: 0xF6E4C0A0, Data2: 0x46D9, Data3: 0x4FB8, Data4: [8]byte{0xBE, 0x21, 0x57, 0xA3, 0xEF, 0x2B, 0x62, 0x6C}}
+
+func IID_IAudioClockAdjustment() windows.GUID {
+	return _IID_IAudioClockAdjustment
+}
+
+// IAudioClockAdjustment 仅在音频流以 AUDCLNT_STREAMFLAGS_RATEADJUST 标志初始化时可用。
+type IAudioClockAdjustment struct {
+	vtbl *_IAudioClockAdjustmentVtbl
+}
+
+type _IAudioClockAdjustmentVtbl struct {
+	com.IUnknownVtbl
+	SetSampleRate uintptr
+}
+
+func (adjustment *IAudioClockAdjustment) Release() (err error) {
+	r, _, _ := syscall.SyscallN(adjustment.vtbl.Release, uintptr(unsafe.Pointer(adjustment)))
+
+	if com.HRESULT(r) != com.HRESULT(windows.S_OK) {
+		err = &Error{Method: "IAudioClockAdjustment::Release", Code: uint32(r)}
+		return
+	}
+
+	return
+}
+
+// SetSampleRate 方法设置音频流的采样率 (帧每秒)。
+func (adjustment *IAudioClockAdjustment) SetSampleRate(samplesPerSec float32) (err error) {
+	// x64 调用约定下浮点参数通过 XMM 寄存器传递，SyscallN 会同时设置整数及 XMM 寄存器
+	r, _, _ := syscall.SyscallN(adjustment.vtbl.SetSampleRate, uintptr(unsafe.Pointer(adjustment)),
+		uintptr(math.Float32bits(samplesPerSec)),
+	)
+
+	if com.HRESULT(r) != com.HRESULT(windows.S_OK) {
+		err = &Error{Method: "IAudioClockAdjustment::SetSampleRate", Code: uint32(r)}
+		return
+	}
+
+	return
+}
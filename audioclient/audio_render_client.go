@@ -1,7 +1,8 @@
+//go:build windows
+
 package audioclient
 
 import (
-	"fmt"
 	"syscall"
 	"unsafe"
 
@@ -31,7 +32,7 @@ func (client *IAudioRenderClient) Release() (err error) {
 	r, _, _ := syscall.SyscallN(client.vtbl.Release, uintptr(unsafe.Pointer(client)))
 
 	if com.HRESULT(r) != com.HRESULT(windows.S_OK) {
-		err = fmt.Errorf("IAudioRenderClient::Release failed with code: 0x%08X", com.HRESULT(r))
+		err = &Error{Method: "IAudioRenderClient::Release", Code: uint32(r)}
 		return
 	}
 
@@ -47,7 +48,7 @@ func (client *IAudioRenderClient) GetBuffer(numFramesRequested uint32) (data []b
 	)
 
 	if com.HRESULT(r) != com.HRESULT(windows.S_OK) {
-		err = fmt.Errorf("IAudioRenderClient::GetBuffer failed with code: 0x%08X", com.HRESULT(r))
+		err = &Error{Method: "IAudioRenderClient::GetBuffer", Code: uint32(r)}
 		return
 	}
 
@@ -64,7 +65,7 @@ func (client *IAudioRenderClient) ReleaseBuffer(numFramesWritten uint32, flags u
 	)
 
 	if com.HRESULT(r) != com.HRESULT(windows.S_OK) {
-		err = fmt.Errorf("IAudioRenderClient::ReleaseBuffer failed with code: 0x%08X", com.HRESULT(r))
+		err = &Error{Method: "IAudioRenderClient::ReleaseBuffer", Code: uint32(r)}
 		return
 	}
 
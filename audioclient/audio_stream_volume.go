@@ -1,7 +1,9 @@
+//go:build windows
+
 package audioclient
 
 import (
-	"fmt"
+	"math"
 	"syscall"
 	"unsafe"
 
@@ -33,7 +35,7 @@ func (volume *IAudioStreamVolume) Release() (err error) {
 	r, _, _ := syscall.SyscallN(volume.vtbl.Release, uintptr(unsafe.Pointer(volume)))
 
 	if com.HRESULT(r) != com.HRESULT(windows.S_OK) {
-		err = fmt.Errorf("IAudioStreamVolume::Release failed with code: 0x%08X", com.HRESULT(r))
+		err = &Error{Method: "IAudioStreamVolume::Release", Code: uint32(r)}
 		return
 	}
 
@@ -47,7 +49,7 @@ func (volume *IAudioStreamVolume) GetChannelCount() (count uint32, err error) {
 	)
 
 	if com.HRESULT(r) != com.HRESULT(windows.S_OK) {
-		err = fmt.Errorf("IAudioStreamVolume::GetChannelCount failed with code: 0x%08X", com.HRESULT(r))
+		err = &Error{Method: "IAudioStreamVolume::GetChannelCount", Code: uint32(r)}
 		return
 	}
 
@@ -58,11 +60,11 @@ func (volume *IAudioStreamVolume) GetChannelCount() (count uint32, err error) {
 func (volume *IAudioStreamVolume) SetChannelVolume(index uint32, level float32) (err error) {
 	r, _, _ := syscall.SyscallN(volume.vtbl.SetChannelVolume, uintptr(unsafe.Pointer(volume)),
 		uintptr(index),
-		uintptr(level),
+		uintptr(math.Float32bits(level)),
 	)
 
 	if com.HRESULT(r) != com.HRESULT(windows.S_OK) {
-		err = fmt.Errorf("IAudioStreamVolume::SetChannelVolume failed with code: 0x%08X", com.HRESULT(r))
+		err = &Error{Method: "IAudioStreamVolume::SetChannelVolume", Code: uint32(r)}
 		return
 	}
 
@@ -77,37 +79,48 @@ func (volume *IAudioStreamVolume) GetChannelVolume(index uint32) (level float32,
 	)
 
 	if com.HRESULT(r) != com.HRESULT(windows.S_OK) {
-		err = fmt.Errorf("IAudioStreamVolume::GetChannelVolume failed with code: 0x%08X", com.HRESULT(r))
+		err = &Error{Method: "IAudioStreamVolume::GetChannelVolume", Code: uint32(r)}
 		return
 	}
 
 	return
 }
 
-// SetAllVolumes 方法设置音频流中所有声道的单个音量级别。
-func (volume *IAudioStreamVolume) SetAllVolumes(count uint32, level float32) (err error) {
+// SetAllVolumes 方法设置音频流中所有声道的音量级别，levels 的长度至少为 count，否则返回 E_INVALIDARG 错误。
+func (volume *IAudioStreamVolume) SetAllVolumes(count uint32, levels []float32) (err error) {
+	if uint32(len(levels)) < count || count == 0 {
+		err = &Error{Method: "IAudioStreamVolume::SetAllVolumes", Code: E_INVALIDARG}
+		return
+	}
+
 	r, _, _ := syscall.SyscallN(volume.vtbl.SetAllVolumes, uintptr(unsafe.Pointer(volume)),
 		uintptr(count),
-		uintptr(level),
+		uintptr(unsafe.Pointer(&levels[0])),
 	)
 
 	if com.HRESULT(r) != com.HRESULT(windows.S_OK) {
-		err = fmt.Errorf("IAudioStreamVolume::SetAllVolumes failed with code: 0x%08X", com.HRESULT(r))
+		err = &Error{Method: "IAudioStreamVolume::SetAllVolumes", Code: uint32(r)}
 		return
 	}
 
 	return
 }
 
-// GetAllVolumes 方法检索音频流中所有声道的音量级别。
-func (volume *IAudioStreamVolume) GetAllVolumes(count uint32) (level float32, err error) {
+// GetAllVolumes 方法检索音频流中所有声道的音量级别，count 为 0 时返回 E_INVALIDARG 错误。
+func (volume *IAudioStreamVolume) GetAllVolumes(count uint32) (levels []float32, err error) {
+	if count == 0 {
+		err = &Error{Method: "IAudioStreamVolume::GetAllVolumes", Code: E_INVALIDARG}
+		return
+	}
+
+	levels = make([]float32, count)
 	r, _, _ := syscall.SyscallN(volume.vtbl.GetAllVolumes, uintptr(unsafe.Pointer(volume)),
 		uintptr(count),
-		uintptr(unsafe.Pointer(&level)),
+		uintptr(unsafe.Pointer(&levels[0])),
 	)
 
 	if com.HRESULT(r) != com.HRESULT(windows.S_OK) {
-		err = fmt.Errorf("IAudioStreamVolume::GetAllVolumes failed with code: 0x%08X", com.HRESULT(r))
+		err = &Error{Method: "IAudioStreamVolume::GetAllVolumes", Code: uint32(r)}
 		return
 	}
 
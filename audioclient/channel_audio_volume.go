@@ -1,7 +1,9 @@
+//go:build windows
+
 package audioclient
 
 import (
-	"fmt"
+	"math"
 	"syscall"
 	"unsafe"
 
@@ -33,7 +35,7 @@ func (volume *IChannelAudioVolume) Release() (err error) {
 	r, _, _ := syscall.SyscallN(volume.vtbl.Release, uintptr(unsafe.Pointer(volume)))
 
 	if com.HRESULT(r) != com.HRESULT(windows.S_OK) {
-		err = fmt.Errorf("IChannelAudioVolume::Release failed with code: 0x%08X", com.HRESULT(r))
+		err = &Error{Method: "IChannelAudioVolume::Release", Code: uint32(r)}
 		return
 	}
 
@@ -47,7 +49,7 @@ func (volume *IChannelAudioVolume) GetChannelCount() (count uint32, err error) {
 	)
 
 	if com.HRESULT(r) != com.HRESULT(windows.S_OK) {
-		err = fmt.Errorf("IChannelAudioVolume::GetChannelCount failed with code: 0x%08X", com.HRESULT(r))
+		err = &Error{Method: "IChannelAudioVolume::GetChannelCount", Code: uint32(r)}
 		return
 	}
 
@@ -58,12 +60,12 @@ func (volume *IChannelAudioVolume) GetChannelCount() (count uint32, err error) {
 func (volume *IChannelAudioVolume) SetChannelVolume(index uint32, level float32, eventContext *windows.GUID) (err error) {
 	r, _, _ := syscall.SyscallN(volume.vtbl.SetChannelVolume, uintptr(unsafe.Pointer(volume)),
 		uintptr(index),
-		uintptr(level),
+		uintptr(math.Float32bits(level)),
 		uintptr(unsafe.Pointer(eventContext)),
 	)
 
 	if com.HRESULT(r) != com.HRESULT(windows.S_OK) {
-		err = fmt.Errorf("IChannelAudioVolume::SetChannelVolume failed with code: 0x%08X", com.HRESULT(r))
+		err = &Error{Method: "IChannelAudioVolume::SetChannelVolume", Code: uint32(r)}
 		return
 	}
 
@@ -78,38 +80,49 @@ func (volume *IChannelAudioVolume) GetChannelVolume(index uint32) (level float32
 	)
 
 	if com.HRESULT(r) != com.HRESULT(windows.S_OK) {
-		err = fmt.Errorf("IChannelAudioVolume::GetChannelVolume failed with code: 0x%08X", com.HRESULT(r))
+		err = &Error{Method: "IChannelAudioVolume::GetChannelVolume", Code: uint32(r)}
 		return
 	}
 
 	return
 }
 
-// SetAllVolumes 方法设置音频会话中所有声道的单个音量级别。
-func (volume *IChannelAudioVolume) SetAllVolumes(count uint32, level []float32, eventContext *windows.GUID) (err error) {
+// SetAllVolumes 方法设置音频会话中所有声道的音量级别，levels 的长度至少为 count，否则返回 E_INVALIDARG 错误。
+func (volume *IChannelAudioVolume) SetAllVolumes(count uint32, levels []float32, eventContext *windows.GUID) (err error) {
+	if uint32(len(levels)) < count || count == 0 {
+		err = &Error{Method: "IChannelAudioVolume::SetAllVolumes", Code: E_INVALIDARG}
+		return
+	}
+
 	r, _, _ := syscall.SyscallN(volume.vtbl.SetAllVolumes, uintptr(unsafe.Pointer(volume)),
 		uintptr(count),
-		uintptr(unsafe.Pointer(&level)),
+		uintptr(unsafe.Pointer(&levels[0])),
 		uintptr(unsafe.Pointer(eventContext)),
 	)
 
 	if com.HRESULT(r) != com.HRESULT(windows.S_OK) {
-		err = fmt.Errorf("IChannelAudioVolume::SetAllVolumes failed with code: 0x%08X", com.HRESULT(r))
+		err = &Error{Method: "IChannelAudioVolume::SetAllVolumes", Code: uint32(r)}
 		return
 	}
 
 	return
 }
 
-// GetAllVolumes 方法检索音频会话中所有通道的音量级别。
-func (volume *IChannelAudioVolume) GetAllVolumes(count uint32) (level []float32, err error) {
+// GetAllVolumes 方法检索音频会话中所有通道的音量级别，count 为 0 时返回 E_INVALIDARG 错误。
+func (volume *IChannelAudioVolume) GetAllVolumes(count uint32) (levels []float32, err error) {
+	if count == 0 {
+		err = &Error{Method: "IChannelAudioVolume::GetAllVolumes", Code: E_INVALIDARG}
+		return
+	}
+
+	levels = make([]float32, count)
 	r, _, _ := syscall.SyscallN(volume.vtbl.GetAllVolumes, uintptr(unsafe.Pointer(volume)),
 		uintptr(count),
-		uintptr(unsafe.Pointer(&level)),
+		uintptr(unsafe.Pointer(&levels[0])),
 	)
 
 	if com.HRESULT(r) != com.HRESULT(windows.S_OK) {
-		err = fmt.Errorf("IChannelAudioVolume::GetAllVolumes failed with code: 0x%08X", com.HRESULT(r))
+		err = &Error{Method: "IChannelAudioVolume::GetAllVolumes", Code: uint32(r)}
 		return
 	}
 
@@ -3,9 +3,6 @@ package audioclient
 import (
 	"encoding/binary"
 	"errors"
-	"unsafe"
-
-	"golang.org/x/sys/windows"
 )
 
 type AUDCLNT_SHAREMODE uint32
@@ -71,7 +68,7 @@ type WAVEFORMATEXTENSIBLE struct {
 	Format      WAVEFORMATEX
 	Samples     uint16
 	ChannelMask uint32
-	SubFormat   windows.GUID
+	SubFormat   GUID
 }
 
 // encode WAVEFORMATEXTENSIBLE to bytes
@@ -396,6 +393,50 @@ const (
 	WAVE_FORMAT_DEVELOPMENT                uint16 = 0xFFFF
 )
 
+// DEFINE_GUIDSTRUCT("00000001-0000-0010-8000-00aa00389b71", KSDATAFORMAT_SUBTYPE_PCM)
+var _KSDATAFORMAT_SUBTYPE_PCM = GUID{Data1: 0x00000001, Data2: 0x0000, Data3: 0x0010, Data4: [8]byte{0x80, 0x00, 0x00, 0xAA, 0x00, 0x38, 0x9B, 0x71}}
+
+// DEFINE_GUIDSTRUCT("00000003-0000-0010-8000-00aa00389b71", KSDATAFORMAT_SUBTYPE_IEEE_FLOAT)
+var _KSDATAFORMAT_SUBTYPE_IEEE_FLOAT = GUID{Data1: 0x00000003, Data2: 0x0000, Data3: 0x0010, Data4: [8]byte{0x80, 0x00, 0x00, 0xAA, 0x00, 0x38, 0x9B, 0x71}}
+
+func KSDATAFORMAT_SUBTYPE_PCM() GUID {
+	return _KSDATAFORMAT_SUBTYPE_PCM
+}
+
+func KSDATAFORMAT_SUBTYPE_IEEE_FLOAT() GUID {
+	return _KSDATAFORMAT_SUBTYPE_IEEE_FLOAT
+}
+
+// 声道掩码中的扬声器位置 (ksmedia.h)
+const (
+	SPEAKER_FRONT_LEFT            uint32 = 0x1
+	SPEAKER_FRONT_RIGHT           uint32 = 0x2
+	SPEAKER_FRONT_CENTER          uint32 = 0x4
+	SPEAKER_LOW_FREQUENCY         uint32 = 0x8
+	SPEAKER_BACK_LEFT             uint32 = 0x10
+	SPEAKER_BACK_RIGHT            uint32 = 0x20
+	SPEAKER_FRONT_LEFT_OF_CENTER  uint32 = 0x40
+	SPEAKER_FRONT_RIGHT_OF_CENTER uint32 = 0x80
+	SPEAKER_BACK_CENTER           uint32 = 0x100
+	SPEAKER_SIDE_LEFT             uint32 = 0x200
+	SPEAKER_SIDE_RIGHT            uint32 = 0x400
+	SPEAKER_TOP_CENTER            uint32 = 0x800
+	SPEAKER_TOP_FRONT_LEFT        uint32 = 0x1000
+	SPEAKER_TOP_FRONT_CENTER      uint32 = 0x2000
+	SPEAKER_TOP_FRONT_RIGHT       uint32 = 0x4000
+	SPEAKER_TOP_BACK_LEFT         uint32 = 0x8000
+	SPEAKER_TOP_BACK_CENTER       uint32 = 0x10000
+	SPEAKER_TOP_BACK_RIGHT        uint32 = 0x20000
+)
+
+const (
+	KSAUDIO_SPEAKER_MONO             = SPEAKER_FRONT_CENTER
+	KSAUDIO_SPEAKER_STEREO           = SPEAKER_FRONT_LEFT | SPEAKER_FRONT_RIGHT
+	KSAUDIO_SPEAKER_QUAD             = SPEAKER_FRONT_LEFT | SPEAKER_FRONT_RIGHT | SPEAKER_BACK_LEFT | SPEAKER_BACK_RIGHT
+	KSAUDIO_SPEAKER_5POINT1_SURROUND = SPEAKER_FRONT_LEFT | SPEAKER_FRONT_RIGHT | SPEAKER_FRONT_CENTER | SPEAKER_LOW_FREQUENCY | SPEAKER_SIDE_LEFT | SPEAKER_SIDE_RIGHT
+	KSAUDIO_SPEAKER_7POINT1_SURROUND = KSAUDIO_SPEAKER_5POINT1_SURROUND | SPEAKER_BACK_LEFT | SPEAKER_BACK_RIGHT
+)
+
 const (
 	AUDCLNT_STREAMFLAGS_CROSSPROCESS        = 0x00010000
 	AUDCLNT_STREAMFLAGS_LOOPBACK            = 0x00020000
@@ -413,7 +454,7 @@ const (
 )
 
 const (
-	AUDCLNT_BUFFERFLAGS_DATA_DISCONTINUITY uint32 = iota
+	AUDCLNT_BUFFERFLAGS_DATA_DISCONTINUITY uint32 = 1 << iota
 	AUDCLNT_BUFFERFLAGS_SILENT
 	AUDCLNT_BUFFERFLAGS_TIMESTAMP_ERROR
 )
@@ -437,7 +478,3 @@ const (
 	AudioCategory_UniformSpeech
 	AudioCategory_VoiceTyping
 )
-
-func ToType[T IAudioCaptureClient | IAudioClient | IAudioClock | IAudioRenderClient | IAudioStreamVolume | IChannelAudioVolume | ISimpleAudioVolume](v unsafe.Pointer) *T {
-	return (*T)(v)
-}
@@ -0,0 +1,11 @@
+//go:build !windows
+
+package audioclient
+
+// GUID 与 windows.GUID 的内存布局相同，使格式等类型可以在其他平台上使用 (例如 fake 后端及离线处理)。
+type GUID struct {
+	Data1 uint32
+	Data2 uint16
+	Data3 uint16
+	Data4 [8]byte
+}
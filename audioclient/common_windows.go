@@ -0,0 +1,14 @@
+package audioclient
+
+import (
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// GUID 在 Windows 上即 windows.GUID。
+type GUID = windows.GUID
+
+func ToType[T IAudioCaptureClient | IAudioClient | IAudioClock | IAudioRenderClient | IAudioStreamVolume | IChannelAudioVolume | ISimpleAudioVolume](v unsafe.Pointer) *T {
+	return (*T)(v)
+}
@@ -0,0 +1,77 @@
+package audioclient
+
+import "fmt"
+
+// 音频客户端返回的 HRESULT (audioclient.h)
+const (
+	S_FALSE                                uint32 = 0x00000001
+	E_INVALIDARG                           uint32 = 0x80070057
+	AUDCLNT_E_NOT_INITIALIZED              uint32 = 0x88890001
+	AUDCLNT_E_ALREADY_INITIALIZED          uint32 = 0x88890002
+	AUDCLNT_E_WRONG_ENDPOINT_TYPE          uint32 = 0x88890003
+	AUDCLNT_E_DEVICE_INVALIDATED           uint32 = 0x88890004
+	AUDCLNT_E_NOT_STOPPED                  uint32 = 0x88890005
+	AUDCLNT_E_BUFFER_TOO_LARGE             uint32 = 0x88890006
+	AUDCLNT_E_OUT_OF_ORDER                 uint32 = 0x88890007
+	AUDCLNT_E_UNSUPPORTED_FORMAT           uint32 = 0x88890008
+	AUDCLNT_E_INVALID_SIZE                 uint32 = 0x88890009
+	AUDCLNT_E_DEVICE_IN_USE                uint32 = 0x8889000A
+	AUDCLNT_E_BUFFER_OPERATION_PENDING     uint32 = 0x8889000B
+	AUDCLNT_E_THREAD_NOT_REGISTERED        uint32 = 0x8889000C
+	AUDCLNT_E_EXCLUSIVE_MODE_NOT_ALLOWED   uint32 = 0x8889000E
+	AUDCLNT_E_ENDPOINT_CREATE_FAILED       uint32 = 0x8889000F
+	AUDCLNT_E_SERVICE_NOT_RUNNING          uint32 = 0x88890010
+	AUDCLNT_E_EVENTHANDLE_NOT_EXPECTED     uint32 = 0x88890011
+	AUDCLNT_E_EXCLUSIVE_MODE_ONLY          uint32 = 0x88890012
+	AUDCLNT_E_BUFDURATION_PERIOD_NOT_EQUAL uint32 = 0x88890013
+	AUDCLNT_E_EVENTHANDLE_NOT_SET          uint32 = 0x88890014
+	AUDCLNT_E_INCORRECT_BUFFER_SIZE        uint32 = 0x88890015
+	AUDCLNT_E_BUFFER_SIZE_ERROR            uint32 = 0x88890016
+	AUDCLNT_E_CPUUSAGE_EXCEEDED            uint32 = 0x88890017
+	AUDCLNT_E_BUFFER_ERROR                 uint32 = 0x88890018
+	AUDCLNT_E_BUFFER_SIZE_NOT_ALIGNED      uint32 = 0x88890019
+	AUDCLNT_E_INVALID_DEVICE_PERIOD        uint32 = 0x88890020
+	AUDCLNT_E_RESOURCES_INVALIDATED        uint32 = 0x88890026
+	AUDCLNT_S_BUFFER_EMPTY                 uint32 = 0x08890001
+)
+
+// 常用的错误，可以用 errors.Is 与任意方法返回的 *Error 比较。
+var (
+	ErrNotInitialized     = &Error{Code: AUDCLNT_E_NOT_INITIALIZED}
+	ErrAlreadyInitialized = &Error{Code: AUDCLNT_E_ALREADY_INITIALIZED}
+	ErrWrongEndpointType  = &Error{Code: AUDCLNT_E_WRONG_ENDPOINT_TYPE}
+	ErrDeviceInvalidated  = &Error{Code: AUDCLNT_E_DEVICE_INVALIDATED}
+	ErrNotStopped         = &Error{Code: AUDCLNT_E_NOT_STOPPED}
+	ErrBufferTooLarge     = &Error{Code: AUDCLNT_E_BUFFER_TOO_LARGE}
+	ErrOutOfOrder         = &Error{Code: AUDCLNT_E_OUT_OF_ORDER}
+	ErrUnsupportedFormat  = &Error{Code: AUDCLNT_E_UNSUPPORTED_FORMAT}
+	ErrInvalidSize        = &Error{Code: AUDCLNT_E_INVALID_SIZE}
+	ErrDeviceInUse        = &Error{Code: AUDCLNT_E_DEVICE_IN_USE}
+	ErrBufferError        = &Error{Code: AUDCLNT_E_BUFFER_ERROR}
+	ErrServiceNotRunning  = &Error{Code: AUDCLNT_E_SERVICE_NOT_RUNNING}
+	ErrBufferEmpty        = &Error{Code: AUDCLNT_S_BUFFER_EMPTY}
+	ErrInvalidArg         = &Error{Code: E_INVALIDARG}
+)
+
+// Error 是方法返回的非 S_OK 的 HRESULT。
+//
+// 注意 IAudioCaptureClient::GetBuffer 在没有数据时返回的 AUDCLNT_S_BUFFER_EMPTY 及
+// IsFormatSupported 返回的 S_FALSE 虽然是成功码，也以 Error 返回。
+type Error struct {
+	Method string // 返回错误的方法，例如 IAudioClient::Start
+	Code   uint32
+}
+
+func (e *Error) Error() string {
+	if e.Method == "" {
+		return fmt.Sprintf("HRESULT 0x%08X", e.Code)
+	}
+
+	return fmt.Sprintf("%s failed with code: 0x%08X", e.Method, e.Code)
+}
+
+// Is 方法按 HRESULT 比较，忽略方法名。
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
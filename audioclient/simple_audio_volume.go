@@ -1,7 +1,9 @@
+//go:build windows
+
 package audioclient
 
 import (
-	"fmt"
+	"math"
 	"syscall"
 	"unsafe"
 
@@ -32,7 +34,7 @@ func (volume *ISimpleAudioVolume) Release() (err error) {
 	r, _, _ := syscall.SyscallN(volume.vtbl.Release, uintptr(unsafe.Pointer(volume)))
 
 	if com.HRESULT(r) != com.HRESULT(windows.S_OK) {
-		err = fmt.Errorf("ISimpleAudioVolume::Release failed with code: 0x%08X", com.HRESULT(r))
+		err = &Error{Method: "ISimpleAudioVolume::Release", Code: uint32(r)}
 		return
 	}
 
@@ -42,12 +44,12 @@ func (volume *ISimpleAudioVolume) Release() (err error) {
 // SetMasterVolume 方法设置音频会话的主音量级别。
 func (volume *ISimpleAudioVolume) SetMasterVolume(level float32, eventContext *windows.GUID) (count uint32, err error) {
 	r, _, _ := syscall.SyscallN(volume.vtbl.SetMasterVolume, uintptr(unsafe.Pointer(volume)),
-		uintptr(level),
+		uintptr(math.Float32bits(level)),
 		uintptr(unsafe.Pointer(eventContext)),
 	)
 
 	if com.HRESULT(r) != com.HRESULT(windows.S_OK) {
-		err = fmt.Errorf("ISimpleAudioVolume::SetMasterVolume failed with code: 0x%08X", com.HRESULT(r))
+		err = &Error{Method: "ISimpleAudioVolume::SetMasterVolume", Code: uint32(r)}
 		return
 	}
 
@@ -61,7 +63,7 @@ func (volume *ISimpleAudioVolume) GetMasterVolume() (level float32, err error) {
 	)
 
 	if com.HRESULT(r) != com.HRESULT(windows.S_OK) {
-		err = fmt.Errorf("ISimpleAudioVolume::GetMasterVolume failed with code: 0x%08X", com.HRESULT(r))
+		err = &Error{Method: "ISimpleAudioVolume::GetMasterVolume", Code: uint32(r)}
 		return
 	}
 
@@ -70,13 +72,19 @@ func (volume *ISimpleAudioVolume) GetMasterVolume() (level float32, err error) {
 
 // SetMute 方法设置音频会话的静音状态。
 func (volume *ISimpleAudioVolume) SetMute(mute bool, eventContext *windows.GUID) (err error) {
+	// BOOL 按值传递
+	var value uintptr
+	if mute {
+		value = 1
+	}
+
 	r, _, _ := syscall.SyscallN(volume.vtbl.SetMute, uintptr(unsafe.Pointer(volume)),
-		uintptr(unsafe.Pointer(&mute)),
+		value,
 		uintptr(unsafe.Pointer(eventContext)),
 	)
 
 	if com.HRESULT(r) != com.HRESULT(windows.S_OK) {
-		err = fmt.Errorf("ISimpleAudioVolume::SetMute failed with code: 0x%08X", com.HRESULT(r))
+		err = &Error{Method: "ISimpleAudioVolume::SetMute", Code: uint32(r)}
 		return
 	}
 
@@ -85,14 +93,18 @@ func (volume *ISimpleAudioVolume) SetMute(mute bool, eventContext *windows.GUID)
 
 // GetMute 方法检索音频会话的当前静音状态。
 func (volume *ISimpleAudioVolume) GetMute() (mute bool, err error) {
+	// BOOL 为 4 字节
+	var value int32
 	r, _, _ := syscall.SyscallN(volume.vtbl.GetMute, uintptr(unsafe.Pointer(volume)),
-		uintptr(unsafe.Pointer(&mute)),
+		uintptr(unsafe.Pointer(&value)),
 	)
 
 	if com.HRESULT(r) != com.HRESULT(windows.S_OK) {
-		err = fmt.Errorf("ISimpleAudioVolume::GetMute failed with code: 0x%08X", com.HRESULT(r))
+		err = &Error{Method: "ISimpleAudioVolume::GetMute", Code: uint32(r)}
 		return
 	}
 
+	mute = value != 0
+
 	return
 }
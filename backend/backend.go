@@ -0,0 +1,123 @@
+package backend
+
+import (
+	"errors"
+
+	"github.com/cyberxnomad/wasapi/audioclient"
+	"github.com/cyberxnomad/wasapi/mmdevice"
+)
+
+var ErrUnsupported = errors.New("object does not belong to a supported backend")
+
+// Enumerator 对应 IMMDeviceEnumerator。
+//
+// EnumAudioEndpoints 直接返回设备列表，不需要时需对每个设备调用 Release 方法。
+type Enumerator interface {
+	EnumAudioEndpoints(dataFlow mmdevice.EDataFlow, stateMask uint32) (devices []Device, err error)
+	GetDefaultAudioEndpoint(dataFlow mmdevice.EDataFlow, role mmdevice.ERole) (endpoint Device, err error)
+	GetDevice(id string) (device Device, err error)
+	Release() (err error)
+}
+
+// Device 对应 IMMDevice，*mmdevice.IMMDevice 实现了该接口，音频客户端由 Activate 函数创建。
+type Device interface {
+	GetId() (id string, err error)
+	GetState() (state uint32, err error)
+	Release() (err error)
+}
+
+// AudioClient 对应 IAudioClient，在 Windows 上 Activate 返回包装 *audioclient.IAudioClient 的实现。
+//
+// 不包括 SetEventHandle 及 GetService，服务由 GetCaptureClient、GetRenderClient 及 GetClock 函数获取。
+type AudioClient interface {
+	Initialize(shareMode audioclient.AUDCLNT_SHAREMODE, streamFlags uint32, hnsBufferDuration uint64, hnsPeriodicity uint64, format *audioclient.WAVEFORMATEXTENSIBLE, audioSessionGuid *audioclient.GUID) (err error)
+	GetBufferSize() (numBufferFrames uint32, err error)
+	GetStreamLatency() (hnsLatency uint64, err error)
+	GetCurrentPadding() (numPaddingFrames uint32, err error)
+	IsFormatSupported(shareMode audioclient.AUDCLNT_SHAREMODE, format *audioclient.WAVEFORMATEXTENSIBLE) (closestMatch audioclient.WAVEFORMATEXTENSIBLE, err error)
+	GetMixFormat() (deviceFormat audioclient.WAVEFORMATEXTENSIBLE, err error)
+	GetDevicePeriod() (hnsDefaultDevicePeriod, hnsMinimumDevicePeriod uint64, err error)
+	Start() (err error)
+	Stop() (err error)
+	Reset() (err error)
+	Release() (err error)
+}
+
+// CaptureClient 对应 IAudioCaptureClient，在 Windows 上包装 *audioclient.IAudioCaptureClient。
+//
+// 与绑定返回帧数长度的切片不同，GetBuffer 返回的 data 长度为字节数，即帧数乘以帧大小。
+type CaptureClient interface {
+	GetBuffer() (data []byte, numFramesToRead uint32, flags uint32, devicePosition uint64, QPCPosition uint64, err error)
+	ReleaseBuffer(numFramesToRead uint32) (err error)
+	GetNextPacketSize() (numFramesInNextPacket uint32, err error)
+	Release() (err error)
+}
+
+// RenderClient 对应 IAudioRenderClient，在 Windows 上包装 *audioclient.IAudioRenderClient。
+//
+// 与绑定返回帧数长度的切片不同，GetBuffer 返回的 data 长度为字节数，即请求的帧数乘以帧大小。
+type RenderClient interface {
+	GetBuffer(numFramesRequested uint32) (data []byte, err error)
+	ReleaseBuffer(numFramesWritten uint32, flags uint32) (err error)
+	Release() (err error)
+}
+
+// Clock 对应 IAudioClock，*audioclient.IAudioClock 实现了该接口。
+type Clock interface {
+	GetFrequency() (frequency uint64, err error)
+	GetPosition() (position uint64, QPCPosition uint64, err error)
+	GetCharacteristics() (characteristics uint32, err error)
+	Release() (err error)
+}
+
+// Activator 由不基于 COM 的 Device 实现，用于 Activate 函数。
+type Activator interface {
+	ActivateAudioClient() (client AudioClient, err error)
+}
+
+// ServiceProvider 由不基于 COM 的 AudioClient 实现，用于 GetCaptureClient 等函数。
+type ServiceProvider interface {
+	CaptureClient() (client CaptureClient, err error)
+	RenderClient() (client RenderClient, err error)
+	Clock() (clock Clock, err error)
+}
+
+// Activate 为设备创建音频客户端，相当于 IMMDevice::Activate(IID_IAudioClient)。
+// 不需要时需主动调用 Release 方法。
+func Activate(device Device) (client AudioClient, err error) {
+	if activator, ok := device.(Activator); ok {
+		return activator.ActivateAudioClient()
+	}
+
+	return activate(device)
+}
+
+// GetCaptureClient 获取音频客户端的捕获服务，相当于 IAudioClient::GetService(IID_IAudioCaptureClient)。
+// 不需要时需主动调用 Release 方法。
+func GetCaptureClient(client AudioClient) (capture CaptureClient, err error) {
+	if provider, ok := client.(ServiceProvider); ok {
+		return provider.CaptureClient()
+	}
+
+	return captureClient(client)
+}
+
+// GetRenderClient 获取音频客户端的呈现服务，相当于 IAudioClient::GetService(IID_IAudioRenderClient)。
+// 不需要时需主动调用 Release 方法。
+func GetRenderClient(client AudioClient) (render RenderClient, err error) {
+	if provider, ok := client.(ServiceProvider); ok {
+		return provider.RenderClient()
+	}
+
+	return renderClient(client)
+}
+
+// GetClock 获取音频客户端的时钟服务，相当于 IAudioClient::GetService(IID_IAudioClock)。
+// 不需要时需主动调用 Release 方法。
+func GetClock(client AudioClient) (clock Clock, err error) {
+	if provider, ok := client.(ServiceProvider); ok {
+		return provider.Clock()
+	}
+
+	return audioClock(client)
+}
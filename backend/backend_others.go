@@ -0,0 +1,19 @@
+//go:build !windows
+
+package backend
+
+func activate(device Device) (client AudioClient, err error) {
+	return nil, ErrUnsupported
+}
+
+func captureClient(client AudioClient) (capture CaptureClient, err error) {
+	return nil, ErrUnsupported
+}
+
+func renderClient(client AudioClient) (render RenderClient, err error) {
+	return nil, ErrUnsupported
+}
+
+func audioClock(client AudioClient) (clock Clock, err error) {
+	return nil, ErrUnsupported
+}
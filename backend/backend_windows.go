@@ -0,0 +1,201 @@
+package backend
+
+import (
+	"unsafe"
+
+	"github.com/cyberxnomad/wasapi/audioclient"
+	"github.com/cyberxnomad/wasapi/com"
+	"github.com/cyberxnomad/wasapi/mmdevice"
+)
+
+var (
+	_ Device        = (*mmdevice.IMMDevice)(nil)
+	_ AudioClient   = (*comAudioClient)(nil)
+	_ CaptureClient = (*comCaptureClient)(nil)
+	_ RenderClient  = (*comRenderClient)(nil)
+	_ Clock         = (*audioclient.IAudioClock)(nil)
+)
+
+// 包装 IAudioClient，记录 Initialize 时格式的帧大小
+type comAudioClient struct {
+	*audioclient.IAudioClient
+	blockAlign int
+}
+
+func (c *comAudioClient) Initialize(shareMode audioclient.AUDCLNT_SHAREMODE, streamFlags uint32, hnsBufferDuration uint64, hnsPeriodicity uint64, format *audioclient.WAVEFORMATEXTENSIBLE, audioSessionGuid *audioclient.GUID) (err error) {
+	if err = c.IAudioClient.Initialize(shareMode, streamFlags, hnsBufferDuration, hnsPeriodicity, format, audioSessionGuid); err != nil {
+		return
+	}
+
+	c.blockAlign = int(format.Format.BlockAlign)
+
+	return
+}
+
+// 包装 IAudioCaptureClient，GetBuffer 返回的切片长度由帧数换算为字节数
+type comCaptureClient struct {
+	*audioclient.IAudioCaptureClient
+	blockAlign int
+}
+
+func (c *comCaptureClient) GetBuffer() (data []byte, numFramesToRead uint32, flags uint32, devicePosition uint64, QPCPosition uint64, err error) {
+	if data, numFramesToRead, flags, devicePosition, QPCPosition, err = c.IAudioCaptureClient.GetBuffer(); err != nil {
+		return
+	}
+
+	data = frameBytes(data, c.blockAlign)
+
+	return
+}
+
+// 包装 IAudioRenderClient，GetBuffer 返回的切片长度由帧数换算为字节数
+type comRenderClient struct {
+	*audioclient.IAudioRenderClient
+	blockAlign int
+}
+
+func (c *comRenderClient) GetBuffer(numFramesRequested uint32) (data []byte, err error) {
+	if data, err = c.IAudioRenderClient.GetBuffer(numFramesRequested); err != nil {
+		return
+	}
+
+	return frameBytes(data, c.blockAlign), nil
+}
+
+// 绑定返回的切片长度为帧数，按帧大小扩展为字节数
+func frameBytes(frames []byte, blockAlign int) []byte {
+	if len(frames) == 0 {
+		return frames
+	}
+
+	return unsafe.Slice(unsafe.SliceData(frames), len(frames)*blockAlign)
+}
+
+// 包装 IMMDeviceEnumerator，设备集合展开为列表
+type comEnumerator struct {
+	enumerator *mmdevice.IMMDeviceEnumerator
+}
+
+// NewEnumerator 创建基于 IMMDeviceEnumerator 的枚举器，调用前需在当前线程初始化 COM 库。
+// 不需要时需主动调用 Release 方法。
+func NewEnumerator() (enumerator Enumerator, err error) {
+	clsid := mmdevice.CLSID_MMDeviceEnumerator()
+	iid := mmdevice.IID_IMMDeviceEnumerator()
+
+	v, err := com.CoCreateInstance(&clsid, nil, mmdevice.CLSCTX_ALL, &iid)
+	if err != nil {
+		return
+	}
+
+	enumerator = &comEnumerator{enumerator: mmdevice.ToType[mmdevice.IMMDeviceEnumerator](v)}
+
+	return
+}
+
+func (e *comEnumerator) EnumAudioEndpoints(dataFlow mmdevice.EDataFlow, stateMask uint32) (devices []Device, err error) {
+	collection, err := e.enumerator.EnumAudioEndpoints(dataFlow, stateMask)
+	if err != nil {
+		return
+	}
+	defer collection.Release()
+
+	count, err := collection.GetCount()
+	if err != nil {
+		return
+	}
+
+	for i := uint32(0); i < uint32(count); i++ {
+		var device *mmdevice.IMMDevice
+		if device, err = collection.Item(i); err != nil {
+			for _, d := range devices {
+				d.Release()
+			}
+			return nil, err
+		}
+
+		devices = append(devices, device)
+	}
+
+	return
+}
+
+func (e *comEnumerator) GetDefaultAudioEndpoint(dataFlow mmdevice.EDataFlow, role mmdevice.ERole) (endpoint Device, err error) {
+	device, err := e.enumerator.GetDefaultAudioEndpoint(dataFlow, role)
+	if err != nil {
+		return
+	}
+
+	return device, nil
+}
+
+func (e *comEnumerator) GetDevice(id string) (device Device, err error) {
+	d, err := e.enumerator.GetDevice(id)
+	if err != nil {
+		return
+	}
+
+	return d, nil
+}
+
+func (e *comEnumerator) Release() (err error) {
+	return e.enumerator.Release()
+}
+
+func activate(device Device) (client AudioClient, err error) {
+	d, ok := device.(*mmdevice.IMMDevice)
+	if !ok {
+		return nil, ErrUnsupported
+	}
+
+	v, err := d.Activate(audioclient.IID_IAudioClient(), mmdevice.CLSCTX_ALL, nil)
+	if err != nil {
+		return
+	}
+
+	return &comAudioClient{IAudioClient: audioclient.ToType[audioclient.IAudioClient](v)}, nil
+}
+
+func captureClient(client AudioClient) (capture CaptureClient, err error) {
+	c, ok := client.(*comAudioClient)
+	if !ok {
+		return nil, ErrUnsupported
+	}
+
+	iid := audioclient.IID_IAudioCaptureClient()
+	v, err := c.GetService(&iid)
+	if err != nil {
+		return
+	}
+
+	return &comCaptureClient{IAudioCaptureClient: audioclient.ToType[audioclient.IAudioCaptureClient](v), blockAlign: c.blockAlign}, nil
+}
+
+func renderClient(client AudioClient) (render RenderClient, err error) {
+	c, ok := client.(*comAudioClient)
+	if !ok {
+		return nil, ErrUnsupported
+	}
+
+	iid := audioclient.IID_IAudioRenderClient()
+	v, err := c.GetService(&iid)
+	if err != nil {
+		return
+	}
+
+	return &comRenderClient{IAudioRenderClient: audioclient.ToType[audioclient.IAudioRenderClient](v), blockAlign: c.blockAlign}, nil
+}
+
+func audioClock(client AudioClient) (clock Clock, err error) {
+	c, ok := client.(*comAudioClient)
+	if !ok {
+		return nil, ErrUnsupported
+	}
+
+	iid := audioclient.IID_IAudioClock()
+	v, err := c.GetService(&iid)
+	if err != nil {
+		return
+	}
+
+	return audioclient.ToType[audioclient.IAudioClock](v), nil
+}
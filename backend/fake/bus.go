@@ -0,0 +1,111 @@
+package fake
+
+import (
+	"time"
+
+	"github.com/cyberxnomad/wasapi/audioclient"
+	"github.com/cyberxnomad/wasapi/pcm"
+)
+
+// 混音总线保留的时长
+const busDuration = 2 * time.Second
+
+// 呈现设备的混音总线，以混音格式保存各呈现流消耗的数据之和，供环回流读取。
+// 位置为虚拟时间 0 起的绝对帧数。
+type bus struct {
+	format   audioclient.WAVEFORMATEXTENSIBLE
+	channels int
+	ring     []float32
+	end      int64 // 已写入或清零的末尾位置
+	buf      []float32
+}
+
+func newBus(format *audioclient.WAVEFORMATEXTENSIBLE) *bus {
+	channels := int(format.Format.Channels)
+
+	return &bus{
+		format:   *format,
+		channels: channels,
+		ring:     make([]float32, durationToFrames(busDuration, format.Format.SamplesPerSec)*channels),
+	}
+}
+
+func (b *bus) frames() int64 {
+	return int64(len(b.ring) / b.channels)
+}
+
+// 将呈现流的数据叠加到 position 处，采样率或声道数与混音格式不同的流被忽略
+func (b *bus) write(format *audioclient.WAVEFORMATEXTENSIBLE, position int64, data []byte) {
+	if len(b.ring) == 0 || format.Format.SamplesPerSec != b.format.Format.SamplesPerSec || int(format.Format.Channels) != b.channels {
+		return
+	}
+
+	samples := len(data) / int(format.Format.BlockAlign) * b.channels
+	if cap(b.buf) < samples {
+		b.buf = make([]float32, samples)
+	}
+	if _, err := pcm.Decode(format, data, b.buf[:samples]); err != nil {
+		return
+	}
+
+	size := b.frames()
+	end := position + int64(samples/b.channels)
+
+	// 清除新写入区域中的旧数据
+	for p := max(b.end, end-size); p < end; p++ {
+		i := int(p%size) * b.channels
+		clear(b.ring[i : i+b.channels])
+	}
+	b.end = max(b.end, end)
+
+	for f := 0; f < samples/b.channels; f++ {
+		p := position + int64(f)
+		if p < b.end-size {
+			continue
+		}
+
+		i := int(p%size) * b.channels
+		for ch := 0; ch < b.channels; ch++ {
+			b.ring[i+ch] += b.buf[f*b.channels+ch]
+		}
+	}
+}
+
+// 从 position 处读取数据并按 format 编码到 data，没有数据的部分为静音，全部为静音时返回 AUDCLNT_BUFFERFLAGS_SILENT
+func (b *bus) read(format *audioclient.WAVEFORMATEXTENSIBLE, position int64, data []byte) (flags uint32) {
+	flags = audioclient.AUDCLNT_BUFFERFLAGS_SILENT
+	if len(b.ring) == 0 || format.Format.SamplesPerSec != b.format.Format.SamplesPerSec || int(format.Format.Channels) != b.channels {
+		return
+	}
+
+	samples := len(data) / int(format.Format.BlockAlign) * b.channels
+	if cap(b.buf) < samples {
+		b.buf = make([]float32, samples)
+	}
+	buf := b.buf[:samples]
+
+	size := b.frames()
+	for f := 0; f < samples/b.channels; f++ {
+		p := position + int64(f)
+		out := buf[f*b.channels : (f+1)*b.channels]
+
+		if p < 0 || p >= b.end || p < b.end-size {
+			clear(out)
+			continue
+		}
+
+		i := int(p%size) * b.channels
+		copy(out, b.ring[i:i+b.channels])
+		for _, v := range out {
+			if v != 0 {
+				flags = 0
+			}
+		}
+	}
+
+	if flags == 0 {
+		pcm.Encode(format, buf, data)
+	}
+
+	return
+}
@@ -0,0 +1,558 @@
+package fake
+
+import (
+	"time"
+
+	"github.com/cyberxnomad/wasapi/audioclient"
+	"github.com/cyberxnomad/wasapi/backend"
+	"github.com/cyberxnomad/wasapi/mmdevice"
+	"github.com/cyberxnomad/wasapi/pcm"
+)
+
+var (
+	_ backend.AudioClient     = (*AudioClient)(nil)
+	_ backend.ServiceProvider = (*AudioClient)(nil)
+)
+
+// 捕获流中的数据包
+type packet struct {
+	data           []byte
+	frames         int
+	flags          uint32
+	devicePosition uint64
+	qpcPosition    uint64
+}
+
+// AudioClient 是内存中的音频客户端，实现 backend.AudioClient 及 backend.ServiceProvider。
+//
+// 与 IAudioClient 一样，共享模式只接受混音格式 (除非指定 AUDCLNT_STREAMFLAGS_AUTOCONVERTPCM)，
+// 未初始化、重复初始化、运行中重置、GetBuffer 与 ReleaseBuffer 顺序错误等情形返回相应的 AUDCLNT_E_* 错误。
+// 呈现设备上以 AUDCLNT_STREAMFLAGS_LOOPBACK 初始化的是环回捕获流，读取该设备上所有呈现流的混音，
+// 延迟一个设备周期，与混音格式的采样率或声道数不同的呈现流不计入混音。
+type AudioClient struct {
+	device *Device
+
+	format       audioclient.WAVEFORMATEXTENSIBLE
+	shareMode    audioclient.AUDCLNT_SHAREMODE
+	streamFlags  uint32
+	capture      bool
+	loopback     bool
+	initialized  bool
+	started      bool
+	invalidated  bool
+	released     bool
+	bufferFrames int
+	period       int
+	blockAlign   int
+
+	run       time.Duration // 运行的总时长
+	last      time.Duration // 上次处理时的虚拟时间
+	processed int64         // 引擎已处理的帧数
+	base      int64         // 设备位置 0 对应的绝对帧数 (虚拟时间 0 起)
+	position  uint64        // 设备位置 (帧)
+	padding   int
+	pending   int // 尚未释放的 GetBuffer 的帧数，-1 表示没有
+
+	queue   []byte // 呈现流中等待消耗的数据
+	packets []packet
+	scratch []byte
+	out     []byte
+
+	discontinuity bool
+	glitches      int
+	event         chan struct{}
+}
+
+// Format 方法返回初始化时的格式。
+func (c *AudioClient) Format() audioclient.WAVEFORMATEXTENSIBLE {
+	c.device.e.mu.Lock()
+	defer c.device.e.mu.Unlock()
+
+	return c.format
+}
+
+// Started 方法返回音频流是否正在运行。
+func (c *AudioClient) Started() bool {
+	c.device.e.mu.Lock()
+	defer c.device.e.mu.Unlock()
+
+	return c.started
+}
+
+// Glitches 方法返回呈现流欠载及捕获流溢出的次数。
+func (c *AudioClient) Glitches() int {
+	c.device.e.mu.Lock()
+	defer c.device.e.mu.Unlock()
+
+	return c.glitches
+}
+
+// Event 方法返回事件通道，以 AUDCLNT_STREAMFLAGS_EVENTCALLBACK 初始化时，每个设备周期发送一次，
+// 代替 SetEventHandle 设置的事件。
+func (c *AudioClient) Event() <-chan struct{} {
+	c.device.e.mu.Lock()
+	defer c.device.e.mu.Unlock()
+
+	if c.event == nil {
+		c.event = make(chan struct{}, 1)
+	}
+
+	return c.event
+}
+
+// Initialize 方法实现 backend.AudioClient 接口。
+func (c *AudioClient) Initialize(shareMode audioclient.AUDCLNT_SHAREMODE, streamFlags uint32, hnsBufferDuration uint64, hnsPeriodicity uint64, format *audioclient.WAVEFORMATEXTENSIBLE, audioSessionGuid *audioclient.GUID) (err error) {
+	const method = "IAudioClient::Initialize"
+
+	c.device.e.mu.Lock()
+	defer c.device.e.mu.Unlock()
+
+	if err = c.check(method, false); err != nil {
+		return
+	}
+
+	config := &c.device.config
+
+	switch {
+	case c.initialized:
+		return c.fail(method, audioclient.AUDCLNT_E_ALREADY_INITIALIZED)
+	case format == nil:
+		return c.fail(method, E_POINTER)
+	case streamFlags&audioclient.AUDCLNT_STREAMFLAGS_LOOPBACK != 0 && config.Flow != mmdevice.ERender:
+		return c.fail(method, audioclient.AUDCLNT_E_WRONG_ENDPOINT_TYPE)
+	case streamFlags&audioclient.AUDCLNT_STREAMFLAGS_LOOPBACK != 0 && shareMode != audioclient.AUDCLNT_SHAREMODE_SHARED:
+		return c.fail(method, E_INVALIDARG)
+	case pcm.SampleTypeOf(format) == pcm.SampleTypeUnknown:
+		return c.fail(method, audioclient.AUDCLNT_E_UNSUPPORTED_FORMAT)
+	}
+
+	period := config.DefaultPeriod
+	buffer := hnsToDuration(hnsBufferDuration)
+
+	switch shareMode {
+	case audioclient.AUDCLNT_SHAREMODE_SHARED:
+		if hnsPeriodicity != 0 {
+			return c.fail(method, E_INVALIDARG)
+		}
+		if streamFlags&audioclient.AUDCLNT_STREAMFLAGS_AUTOCONVERTPCM == 0 && !sameFormat(format, &config.MixFormat) {
+			return c.fail(method, audioclient.AUDCLNT_E_UNSUPPORTED_FORMAT)
+		}
+
+		buffer = max(buffer, 2*period)
+
+	case audioclient.AUDCLNT_SHAREMODE_EXCLUSIVE:
+		if !supported(format, config.Formats) {
+			return c.fail(method, audioclient.AUDCLNT_E_UNSUPPORTED_FORMAT)
+		}
+
+		if hnsPeriodicity != 0 {
+			period = hnsToDuration(hnsPeriodicity)
+		}
+		if period < config.MinimumPeriod {
+			return c.fail(method, audioclient.AUDCLNT_E_INVALID_DEVICE_PERIOD)
+		}
+		if streamFlags&audioclient.AUDCLNT_STREAMFLAGS_EVENTCALLBACK != 0 && buffer != period {
+			return c.fail(method, audioclient.AUDCLNT_E_BUFDURATION_PERIOD_NOT_EQUAL)
+		}
+
+		buffer = max(buffer, period)
+
+	default:
+		return c.fail(method, E_INVALIDARG)
+	}
+
+	c.format = *format
+	c.shareMode = shareMode
+	c.streamFlags = streamFlags
+	c.loopback = streamFlags&audioclient.AUDCLNT_STREAMFLAGS_LOOPBACK != 0
+	c.capture = config.Flow == mmdevice.ECapture || c.loopback
+	c.blockAlign = int(format.Format.BlockAlign)
+	c.period = durationToFrames(period, format.Format.SamplesPerSec)
+	c.bufferFrames = durationToFrames(buffer, format.Format.SamplesPerSec)
+	c.pending = -1
+	c.initialized = true
+
+	return
+}
+
+// GetBufferSize 方法实现 backend.AudioClient 接口。
+func (c *AudioClient) GetBufferSize() (numBufferFrames uint32, err error) {
+	c.device.e.mu.Lock()
+	defer c.device.e.mu.Unlock()
+
+	if err = c.check("IAudioClient::GetBufferSize", true); err != nil {
+		return
+	}
+
+	return uint32(c.bufferFrames), nil
+}
+
+// GetStreamLatency 方法实现 backend.AudioClient 接口。
+func (c *AudioClient) GetStreamLatency() (hnsLatency uint64, err error) {
+	c.device.e.mu.Lock()
+	defer c.device.e.mu.Unlock()
+
+	if err = c.check("IAudioClient::GetStreamLatency", true); err != nil {
+		return
+	}
+
+	return durationToHns(c.device.config.Latency), nil
+}
+
+// GetCurrentPadding 方法实现 backend.AudioClient 接口，呈现流为尚未消耗的帧数，捕获流为尚未读取的帧数。
+func (c *AudioClient) GetCurrentPadding() (numPaddingFrames uint32, err error) {
+	c.device.e.mu.Lock()
+	defer c.device.e.mu.Unlock()
+
+	if err = c.check("IAudioClient::GetCurrentPadding", true); err != nil {
+		return
+	}
+
+	return uint32(c.padding), nil
+}
+
+// IsFormatSupported 方法实现 backend.AudioClient 接口。
+//
+// 与 IAudioClient 的绑定一致，共享模式下格式与混音格式不同时返回 S_FALSE 错误。
+func (c *AudioClient) IsFormatSupported(shareMode audioclient.AUDCLNT_SHAREMODE, format *audioclient.WAVEFORMATEXTENSIBLE) (closestMatch audioclient.WAVEFORMATEXTENSIBLE, err error) {
+	const method = "IAudioClient::IsFormatSupported"
+
+	c.device.e.mu.Lock()
+	defer c.device.e.mu.Unlock()
+
+	if err = c.check(method, false); err != nil {
+		return
+	}
+
+	config := &c.device.config
+
+	switch {
+	case format == nil:
+		err = c.fail(method, E_POINTER)
+	case pcm.SampleTypeOf(format) == pcm.SampleTypeUnknown:
+		err = c.fail(method, audioclient.AUDCLNT_E_UNSUPPORTED_FORMAT)
+	case shareMode == audioclient.AUDCLNT_SHAREMODE_SHARED:
+		if !sameFormat(format, &config.MixFormat) {
+			err = c.fail(method, audioclient.S_FALSE)
+		}
+	case shareMode == audioclient.AUDCLNT_SHAREMODE_EXCLUSIVE:
+		if !supported(format, config.Formats) {
+			err = c.fail(method, audioclient.AUDCLNT_E_UNSUPPORTED_FORMAT)
+		}
+	default:
+		err = c.fail(method, E_INVALIDARG)
+	}
+
+	return
+}
+
+// GetMixFormat 方法实现 backend.AudioClient 接口。
+func (c *AudioClient) GetMixFormat() (deviceFormat audioclient.WAVEFORMATEXTENSIBLE, err error) {
+	c.device.e.mu.Lock()
+	defer c.device.e.mu.Unlock()
+
+	if err = c.check("IAudioClient::GetMixFormat", false); err != nil {
+		return
+	}
+
+	return c.device.config.MixFormat, nil
+}
+
+// GetDevicePeriod 方法实现 backend.AudioClient 接口。
+func (c *AudioClient) GetDevicePeriod() (hnsDefaultDevicePeriod, hnsMinimumDevicePeriod uint64, err error) {
+	c.device.e.mu.Lock()
+	defer c.device.e.mu.Unlock()
+
+	if err = c.check("IAudioClient::GetDevicePeriod", false); err != nil {
+		return
+	}
+
+	return durationToHns(c.device.config.DefaultPeriod), durationToHns(c.device.config.MinimumPeriod), nil
+}
+
+// Start 方法实现 backend.AudioClient 接口，音频流从当前的虚拟时间开始运行。
+func (c *AudioClient) Start() (err error) {
+	const method = "IAudioClient::Start"
+
+	c.device.e.mu.Lock()
+	defer c.device.e.mu.Unlock()
+
+	if err = c.check(method, true); err != nil {
+		return
+	}
+
+	if c.started {
+		return c.fail(method, audioclient.AUDCLNT_E_NOT_STOPPED)
+	}
+
+	now := c.device.e.now
+	c.started = true
+	c.last = now
+	c.base = int64(now)*int64(c.format.Format.SamplesPerSec)/int64(time.Second) - int64(c.position)
+
+	return
+}
+
+// Stop 方法实现 backend.AudioClient 接口，已停止时与 IAudioClient 一样返回 S_FALSE 错误。
+func (c *AudioClient) Stop() (err error) {
+	const method = "IAudioClient::Stop"
+
+	c.device.e.mu.Lock()
+	defer c.device.e.mu.Unlock()
+
+	if err = c.check(method, true); err != nil {
+		return
+	}
+
+	if !c.started {
+		return c.fail(method, audioclient.S_FALSE)
+	}
+
+	c.started = false
+
+	return
+}
+
+// Reset 方法实现 backend.AudioClient 接口，清除缓冲区中的数据并将设备位置归零。
+func (c *AudioClient) Reset() (err error) {
+	const method = "IAudioClient::Reset"
+
+	c.device.e.mu.Lock()
+	defer c.device.e.mu.Unlock()
+
+	if err = c.check(method, true); err != nil {
+		return
+	}
+
+	switch {
+	case c.started:
+		return c.fail(method, audioclient.AUDCLNT_E_NOT_STOPPED)
+	case c.pending >= 0:
+		return c.fail(method, audioclient.AUDCLNT_E_BUFFER_OPERATION_PENDING)
+	}
+
+	c.run, c.processed, c.position, c.padding = 0, 0, 0, 0
+	c.queue = c.queue[:0]
+	c.packets = nil
+	c.discontinuity = false
+
+	return
+}
+
+// Release 方法实现 backend.AudioClient 接口。
+func (c *AudioClient) Release() (err error) {
+	c.device.e.mu.Lock()
+	defer c.device.e.mu.Unlock()
+
+	if !c.released {
+		c.released = true
+		c.started = false
+		c.device.remove(c)
+		c.device.e.objects--
+	}
+
+	return
+}
+
+// CaptureClient 方法实现 backend.ServiceProvider 接口，呈现流返回 AUDCLNT_E_WRONG_ENDPOINT_TYPE。
+func (c *AudioClient) CaptureClient() (client backend.CaptureClient, err error) {
+	if err = c.service("IAudioClient::GetService", true); err != nil {
+		return
+	}
+
+	return &CaptureClient{c: c}, nil
+}
+
+// RenderClient 方法实现 backend.ServiceProvider 接口，捕获流返回 AUDCLNT_E_WRONG_ENDPOINT_TYPE。
+func (c *AudioClient) RenderClient() (client backend.RenderClient, err error) {
+	if err = c.service("IAudioClient::GetService", false); err != nil {
+		return
+	}
+
+	return &RenderClient{c: c}, nil
+}
+
+// Clock 方法实现 backend.ServiceProvider 接口。
+func (c *AudioClient) Clock() (clock backend.Clock, err error) {
+	c.device.e.mu.Lock()
+	defer c.device.e.mu.Unlock()
+
+	if err = c.check("IAudioClient::GetService", true); err != nil {
+		return
+	}
+
+	c.device.e.objects++
+
+	return &Clock{c: c}, nil
+}
+
+func (c *AudioClient) service(method string, capture bool) (err error) {
+	c.device.e.mu.Lock()
+	defer c.device.e.mu.Unlock()
+
+	if err = c.check(method, true); err != nil {
+		return
+	}
+
+	if c.capture != capture {
+		return c.fail(method, audioclient.AUDCLNT_E_WRONG_ENDPOINT_TYPE)
+	}
+
+	c.device.e.objects++
+
+	return
+}
+
+// 检查注入的错误及客户端的状态
+func (c *AudioClient) check(method string, initialized bool) (err error) {
+	if err = c.device.e.fail(method, c.device.faults); err != nil {
+		return
+	}
+
+	switch {
+	case c.invalidated:
+		return c.fail(method, audioclient.AUDCLNT_E_DEVICE_INVALIDATED)
+	case initialized && !c.initialized:
+		return c.fail(method, audioclient.AUDCLNT_E_NOT_INITIALIZED)
+	}
+
+	return
+}
+
+func (c *AudioClient) fail(method string, code uint32) error {
+	return &audioclient.Error{Method: method, Code: code}
+}
+
+// 按经过的虚拟时间处理数据
+func (c *AudioClient) advance(now time.Duration) {
+	if !c.started || c.invalidated {
+		c.last = now
+		return
+	}
+
+	c.run += now - c.last
+	c.last = now
+
+	frames := int64(c.run) * int64(c.format.Format.SamplesPerSec) / int64(time.Second)
+
+	switch {
+	case c.device.config.Padding == PaddingStalled:
+		c.processed = frames
+	case !c.capture && c.device.config.Padding == PaddingContinuous:
+		if n := int(frames - c.processed); n > 0 {
+			c.tick(n)
+		}
+	default:
+		for c.processed+int64(c.period) <= frames {
+			c.tick(c.period)
+		}
+	}
+}
+
+// 引擎处理 n 帧
+func (c *AudioClient) tick(n int) {
+	size := n * c.blockAlign
+	position := c.base + int64(c.position)
+	c.processed += int64(n)
+
+	if c.capture {
+		data := make([]byte, size)
+		flags := audioclient.AUDCLNT_BUFFERFLAGS_SILENT
+
+		switch {
+		case c.loopback:
+			flags = c.device.loopback(&c.format, position, data)
+		case c.device.mirror != nil:
+			flags = c.device.mirror.loopback(&c.format, position, data)
+		case c.device.capture != nil:
+			flags = c.device.capture(&c.format, data)
+		}
+
+		if c.padding+n > c.bufferFrames {
+			// 客户端读取不及时，丢弃数据包
+			c.glitches++
+			c.discontinuity = true
+		} else {
+			if c.discontinuity {
+				flags |= audioclient.AUDCLNT_BUFFERFLAGS_DATA_DISCONTINUITY
+				c.discontinuity = false
+			}
+
+			c.packets = append(c.packets, packet{
+				data:           data,
+				frames:         n,
+				flags:          flags,
+				devicePosition: c.position,
+				qpcPosition:    durationToHns(framesToDuration(position, c.format.Format.SamplesPerSec)),
+			})
+			c.padding += n
+		}
+	} else {
+		if cap(c.out) < size {
+			c.out = make([]byte, size)
+		}
+		out := c.out[:size]
+
+		take := min(n, c.padding)
+		copy(out, c.queue[:take*c.blockAlign])
+		clear(out[take*c.blockAlign:])
+
+		rest := copy(c.queue, c.queue[take*c.blockAlign:])
+		c.queue = c.queue[:rest]
+		c.padding -= take
+
+		if take < n {
+			c.glitches++
+		}
+
+		c.device.bus.write(&c.format, position, out)
+
+		if c.device.render != nil {
+			c.device.render(&c.format, out)
+		}
+	}
+
+	c.position += uint64(n)
+
+	if c.event != nil && c.streamFlags&audioclient.AUDCLNT_STREAMFLAGS_EVENTCALLBACK != 0 {
+		select {
+		case c.event <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func sameFormat(a, b *audioclient.WAVEFORMATEXTENSIBLE) bool {
+	return a.Format.SamplesPerSec == b.Format.SamplesPerSec &&
+		a.Format.Channels == b.Format.Channels &&
+		a.Format.BitsPerSample == b.Format.BitsPerSample &&
+		a.Format.BlockAlign == b.Format.BlockAlign &&
+		pcm.SampleTypeOf(a) == pcm.SampleTypeOf(b)
+}
+
+func supported(format *audioclient.WAVEFORMATEXTENSIBLE, formats []audioclient.WAVEFORMATEXTENSIBLE) bool {
+	for i := range formats {
+		if sameFormat(format, &formats[i]) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func hnsToDuration(hns uint64) time.Duration {
+	return time.Duration(hns) * 100
+}
+
+func durationToHns(d time.Duration) uint64 {
+	return uint64(d / 100)
+}
+
+// 时长对应的帧数，向上取整
+func durationToFrames(d time.Duration, samplesPerSec uint32) int {
+	return int((int64(d)*int64(samplesPerSec) + int64(time.Second) - 1) / int64(time.Second))
+}
+
+func framesToDuration(frames int64, samplesPerSec uint32) time.Duration {
+	return time.Duration(frames * int64(time.Second) / int64(samplesPerSec))
+}
@@ -0,0 +1,205 @@
+package fake
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/cyberxnomad/wasapi/audioclient"
+	"github.com/cyberxnomad/wasapi/backend"
+	"github.com/cyberxnomad/wasapi/mmdevice"
+	"github.com/cyberxnomad/wasapi/pcm"
+)
+
+var stereo = pcm.NewFormat(pcm.SampleTypeInt, 48000, 2, 16, audioclient.KSAUDIO_SPEAKER_STEREO)
+
+// 检查 err 是否为指定 HRESULT 的 audioclient.Error
+func wantCode(t *testing.T, what string, err error, code uint32) {
+	t.Helper()
+
+	var e *audioclient.Error
+	if !errors.As(err, &e) || e.Code != code {
+		t.Fatalf("%s: err = %v, want HRESULT 0x%08X", what, err, code)
+	}
+}
+
+// 激活并以混音格式初始化共享模式的音频客户端
+func open(t *testing.T, device *Device, streamFlags uint32) backend.AudioClient {
+	t.Helper()
+
+	client, err := backend.Activate(device)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { client.Release() })
+
+	format, err := client.GetMixFormat()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = client.Initialize(audioclient.AUDCLNT_SHAREMODE_SHARED, streamFlags, 0, 0, &format, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	return client
+}
+
+func TestNotInitialized(t *testing.T) {
+	e := NewEnumerator()
+	speakers := e.AddDevice(DeviceConfig{ID: "speakers", Flow: mmdevice.ERender, MixFormat: stereo})
+
+	client, err := backend.Activate(speakers)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = client.GetBufferSize()
+	wantCode(t, "GetBufferSize", err, audioclient.AUDCLNT_E_NOT_INITIALIZED)
+	_, err = client.GetCurrentPadding()
+	wantCode(t, "GetCurrentPadding", err, audioclient.AUDCLNT_E_NOT_INITIALIZED)
+	wantCode(t, "Start", client.Start(), audioclient.AUDCLNT_E_NOT_INITIALIZED)
+	_, err = backend.GetRenderClient(client)
+	wantCode(t, "GetService", err, audioclient.AUDCLNT_E_NOT_INITIALIZED)
+
+	// 混音格式在初始化前可用
+	if format, err := client.GetMixFormat(); err != nil || format != stereo {
+		t.Fatalf("GetMixFormat = %+v, %v", format, err)
+	}
+
+	if err = client.Initialize(audioclient.AUDCLNT_SHAREMODE_SHARED, 0, 0, 0, &stereo, nil); err != nil {
+		t.Fatal(err)
+	}
+	err = client.Initialize(audioclient.AUDCLNT_SHAREMODE_SHARED, 0, 0, 0, &stereo, nil)
+	wantCode(t, "second Initialize", err, audioclient.AUDCLNT_E_ALREADY_INITIALIZED)
+
+	if err = client.Release(); err != nil {
+		t.Fatal(err)
+	}
+	if n := e.Outstanding(); n != 0 {
+		t.Fatalf("Outstanding = %d after Release", n)
+	}
+}
+
+func TestRenderBuffer(t *testing.T) {
+	e := NewEnumerator()
+	speakers := e.AddDevice(DeviceConfig{ID: "speakers", Flow: mmdevice.ERender, MixFormat: stereo})
+	client := open(t, speakers, 0)
+
+	render, err := backend.GetRenderClient(client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer render.Release()
+
+	_, err = backend.GetCaptureClient(client)
+	wantCode(t, "capture service on render stream", err, audioclient.AUDCLNT_E_WRONG_ENDPOINT_TYPE)
+
+	size, err := client.GetBufferSize()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = render.GetBuffer(size + 1)
+	wantCode(t, "GetBuffer beyond buffer size", err, audioclient.AUDCLNT_E_BUFFER_TOO_LARGE)
+
+	wantCode(t, "ReleaseBuffer without GetBuffer", render.ReleaseBuffer(0, 0), audioclient.AUDCLNT_E_OUT_OF_ORDER)
+
+	// 与 Windows 上的实现一致，长度为字节数
+	data, err := render.GetBuffer(100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data) != 100*int(stereo.Format.BlockAlign) {
+		t.Fatalf("GetBuffer(100) returned %d bytes, want %d", len(data), 100*int(stereo.Format.BlockAlign))
+	}
+
+	_, err = render.GetBuffer(10)
+	wantCode(t, "second GetBuffer", err, audioclient.AUDCLNT_E_OUT_OF_ORDER)
+	wantCode(t, "Reset with pending buffer", client.Reset(), audioclient.AUDCLNT_E_BUFFER_OPERATION_PENDING)
+	wantCode(t, "ReleaseBuffer too many frames", render.ReleaseBuffer(101, 0), audioclient.AUDCLNT_E_INVALID_SIZE)
+
+	if err = render.ReleaseBuffer(100, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	// 剩余空间之外的请求
+	_, err = render.GetBuffer(size - 99)
+	wantCode(t, "GetBuffer beyond free space", err, audioclient.AUDCLNT_E_BUFFER_TOO_LARGE)
+
+	if padding, err := client.GetCurrentPadding(); err != nil || padding != 100 {
+		t.Fatalf("GetCurrentPadding = %d, %v, want 100", padding, err)
+	}
+}
+
+func TestCaptureBuffer(t *testing.T) {
+	e := NewEnumerator()
+	mic := e.AddDevice(DeviceConfig{ID: "mic", Flow: mmdevice.ECapture, MixFormat: stereo})
+	mic.SetCapture(func(format *audioclient.WAVEFORMATEXTENSIBLE, data []byte) (flags uint32) {
+		for i := range data {
+			data[i] = byte(i)
+		}
+		return
+	})
+
+	client := open(t, mic, 0)
+	capture, err := backend.GetCaptureClient(client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer capture.Release()
+
+	_, _, _, _, _, err = capture.GetBuffer()
+	wantCode(t, "GetBuffer without packets", err, audioclient.AUDCLNT_S_BUFFER_EMPTY)
+	wantCode(t, "ReleaseBuffer without GetBuffer", capture.ReleaseBuffer(0), audioclient.AUDCLNT_E_OUT_OF_ORDER)
+
+	if err = client.Start(); err != nil {
+		t.Fatal(err)
+	}
+	e.Advance(DefaultPeriod)
+
+	data, frames, flags, position, qpc, err := capture.GetBuffer()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 与 Windows 上的实现一致，长度为字节数
+	if frames != 480 || len(data) != 480*int(stereo.Format.BlockAlign) || flags != 0 || position != 0 || qpc != 0 {
+		t.Fatalf("GetBuffer = %d bytes, %d frames, flags %X, position %d, qpc %d", len(data), frames, flags, position, qpc)
+	}
+	for i, v := range data {
+		if v != byte(i) {
+			t.Fatalf("byte %d = %d, want %d", i, v, byte(i))
+		}
+	}
+
+	_, _, _, _, _, err = capture.GetBuffer()
+	wantCode(t, "second GetBuffer", err, audioclient.AUDCLNT_E_OUT_OF_ORDER)
+	wantCode(t, "ReleaseBuffer wrong size", capture.ReleaseBuffer(1), audioclient.AUDCLNT_E_INVALID_SIZE)
+
+	if err = capture.ReleaseBuffer(frames); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := capture.GetNextPacketSize(); err != nil || n != 0 {
+		t.Fatalf("GetNextPacketSize = %d, %v after draining", n, err)
+	}
+}
+
+func TestInjectError(t *testing.T) {
+	e := NewEnumerator()
+	speakers := e.AddDevice(DeviceConfig{ID: "speakers", Flow: mmdevice.ERender, MixFormat: stereo})
+	client := open(t, speakers, 0)
+
+	injected := &audioclient.Error{Method: "IAudioClient::GetCurrentPadding", Code: audioclient.AUDCLNT_E_SERVICE_NOT_RUNNING}
+	speakers.InjectError("IAudioClient::GetCurrentPadding", injected, 1)
+
+	if _, err := client.GetCurrentPadding(); err != injected {
+		t.Fatalf("first call: err = %v, want injected error", err)
+	}
+	if _, err := client.GetCurrentPadding(); err != nil {
+		t.Fatalf("second call: err = %v", err)
+	}
+
+	speakers.Invalidate()
+	_, err := client.GetCurrentPadding()
+	wantCode(t, "after Invalidate", err, audioclient.AUDCLNT_E_DEVICE_INVALIDATED)
+}
@@ -0,0 +1,237 @@
+package fake
+
+import (
+	"time"
+
+	"github.com/cyberxnomad/wasapi/audioclient"
+	"github.com/cyberxnomad/wasapi/backend"
+	"github.com/cyberxnomad/wasapi/mmdevice"
+)
+
+const (
+	DefaultPeriod = 10 * time.Millisecond // 默认的设备周期
+	MinimumPeriod = 3 * time.Millisecond  // 默认的最小设备周期
+)
+
+var (
+	_ backend.Device    = (*Device)(nil)
+	_ backend.Activator = (*Device)(nil)
+)
+
+// Padding 表示呈现流的数据被音频引擎消耗的方式。
+type Padding uint32
+
+const (
+	PaddingPeriodic   Padding = iota // 每个设备周期开始时消耗一个周期的数据，与共享模式的引擎相同
+	PaddingContinuous                // 按经过的时间逐帧消耗
+	PaddingStalled                   // 不消耗数据，模拟停止响应的设备
+)
+
+// CaptureFunc 为捕获流的一个数据包填充数据，返回数据包的标志，例如 AUDCLNT_BUFFERFLAGS_SILENT。
+// data 已清零，格式为音频客户端初始化时的格式。
+type CaptureFunc func(format *audioclient.WAVEFORMATEXTENSIBLE, data []byte) (flags uint32)
+
+// RenderFunc 接收音频引擎从呈现流消耗的数据，欠载的部分为静音。
+type RenderFunc func(format *audioclient.WAVEFORMATEXTENSIBLE, data []byte)
+
+// DeviceConfig 是设备的配置，为零值的字段使用默认值。
+type DeviceConfig struct {
+	ID            string
+	Flow          mmdevice.EDataFlow                 // ERender 或 ECapture
+	State         uint32                             // 为 0 时为 DEVICE_STATE_ACTIVE
+	MixFormat     audioclient.WAVEFORMATEXTENSIBLE   // 共享模式的混音格式
+	Formats       []audioclient.WAVEFORMATEXTENSIBLE // 独占模式支持的格式，为空时只支持混音格式
+	DefaultPeriod time.Duration                      // 为 0 时为 DefaultPeriod
+	MinimumPeriod time.Duration                      // 为 0 时为 MinimumPeriod
+	Latency       time.Duration                      // GetStreamLatency 返回的延迟，为 0 时为一个周期
+	Padding       Padding
+}
+
+// Device 是内存中的音频终结点设备，实现 backend.Device 及 backend.Activator。
+type Device struct {
+	e       *Enumerator
+	config  DeviceConfig
+	state   uint32
+	clients []*AudioClient
+	faults  map[string]*fault
+	capture CaptureFunc
+	render  RenderFunc
+	bus     *bus    // 呈现设备的混音总线
+	mirror  *Device // 环回捕获设备镜像的呈现设备
+}
+
+func newDevice(e *Enumerator, config DeviceConfig) *Device {
+	if config.State == 0 {
+		config.State = mmdevice.DEVICE_STATE_ACTIVE
+	}
+	if config.DefaultPeriod <= 0 {
+		config.DefaultPeriod = DefaultPeriod
+	}
+	if config.MinimumPeriod <= 0 {
+		config.MinimumPeriod = min(MinimumPeriod, config.DefaultPeriod)
+	}
+	if config.Latency <= 0 {
+		config.Latency = config.DefaultPeriod
+	}
+	if len(config.Formats) == 0 {
+		config.Formats = []audioclient.WAVEFORMATEXTENSIBLE{config.MixFormat}
+	}
+
+	d := &Device{
+		e:      e,
+		config: config,
+		state:  config.State,
+		faults: make(map[string]*fault),
+	}
+
+	if config.Flow == mmdevice.ERender {
+		d.bus = newBus(&config.MixFormat)
+	}
+
+	return d
+}
+
+// Config 方法返回设备的配置。
+func (d *Device) Config() DeviceConfig {
+	d.e.mu.Lock()
+	defer d.e.mu.Unlock()
+
+	return d.config
+}
+
+// SetState 方法设置设备状态，设备不再处于活动状态时，其音频客户端的方法均返回 AUDCLNT_E_DEVICE_INVALIDATED，
+// 模拟拔出设备或更改格式等情形。
+func (d *Device) SetState(state uint32) {
+	d.e.mu.Lock()
+	defer d.e.mu.Unlock()
+
+	d.setState(state)
+}
+
+func (d *Device) setState(state uint32) {
+	d.state = state
+	if state != mmdevice.DEVICE_STATE_ACTIVE {
+		d.invalidate()
+	}
+}
+
+// Invalidate 方法使设备现有的音频客户端失效，设备状态不变，模拟更改混音格式等情形。
+func (d *Device) Invalidate() {
+	d.e.mu.Lock()
+	defer d.e.mu.Unlock()
+
+	d.invalidate()
+}
+
+func (d *Device) invalidate() {
+	for _, c := range d.clients {
+		c.invalidated = true
+	}
+}
+
+// SetCapture 方法设置捕获数据的来源，为 nil 时产生带 AUDCLNT_BUFFERFLAGS_SILENT 标志的静音。
+func (d *Device) SetCapture(capture CaptureFunc) {
+	d.e.mu.Lock()
+	defer d.e.mu.Unlock()
+
+	d.capture = capture
+}
+
+// SetRender 方法设置接收呈现数据的函数，为 nil 时丢弃数据。
+func (d *Device) SetRender(render RenderFunc) {
+	d.e.mu.Lock()
+	defer d.e.mu.Unlock()
+
+	d.render = render
+}
+
+// InjectError 方法与 Enumerator.InjectError 相同，但只作用于该设备及其音频客户端和服务，
+// 例如 "IAudioRenderClient::GetBuffer"。
+func (d *Device) InjectError(method string, err error, count int) {
+	d.e.mu.Lock()
+	defer d.e.mu.Unlock()
+
+	inject(d.faults, method, err, count)
+}
+
+// Clients 方法返回设备尚未 Release 的音频客户端。
+func (d *Device) Clients() []*AudioClient {
+	d.e.mu.Lock()
+	defer d.e.mu.Unlock()
+
+	return append([]*AudioClient(nil), d.clients...)
+}
+
+// GetId 方法实现 backend.Device 接口。
+func (d *Device) GetId() (id string, err error) {
+	d.e.mu.Lock()
+	defer d.e.mu.Unlock()
+
+	if err = d.e.fail("IMMDevice::GetId", d.faults); err != nil {
+		return
+	}
+
+	return d.config.ID, nil
+}
+
+// GetState 方法实现 backend.Device 接口。
+func (d *Device) GetState() (state uint32, err error) {
+	d.e.mu.Lock()
+	defer d.e.mu.Unlock()
+
+	if err = d.e.fail("IMMDevice::GetState", d.faults); err != nil {
+		return
+	}
+
+	return d.state, nil
+}
+
+// Release 方法实现 backend.Device 接口，没有实际作用。
+func (d *Device) Release() (err error) {
+	return
+}
+
+// ActivateAudioClient 方法实现 backend.Activator 接口，设备不处于活动状态时返回 AUDCLNT_E_DEVICE_INVALIDATED。
+func (d *Device) ActivateAudioClient() (client backend.AudioClient, err error) {
+	d.e.mu.Lock()
+	defer d.e.mu.Unlock()
+
+	if err = d.e.fail("IMMDevice::Activate", d.faults); err != nil {
+		return
+	}
+
+	if d.state != mmdevice.DEVICE_STATE_ACTIVE {
+		err = &audioclient.Error{Method: "IMMDevice::Activate", Code: audioclient.AUDCLNT_E_DEVICE_INVALIDATED}
+		return
+	}
+
+	c := &AudioClient{device: d}
+	d.clients = append(d.clients, c)
+	d.e.objects++
+
+	return c, nil
+}
+
+// 处理呈现流或捕获流 (包括环回流)
+func (d *Device) advance(now time.Duration, capture bool) {
+	for _, c := range d.clients {
+		if c.capture == capture {
+			c.advance(now)
+		}
+	}
+}
+
+// 为环回流读取混音总线，延迟一个设备周期以确保各呈现流已处理到该位置
+func (d *Device) loopback(format *audioclient.WAVEFORMATEXTENSIBLE, position int64, data []byte) (flags uint32) {
+	delay := int64(durationToFrames(d.config.DefaultPeriod, format.Format.SamplesPerSec))
+	return d.bus.read(format, position-delay, data)
+}
+
+func (d *Device) remove(client *AudioClient) {
+	for i, c := range d.clients {
+		if c == client {
+			d.clients = append(d.clients[:i], d.clients[i+1:]...)
+			return
+		}
+	}
+}
@@ -0,0 +1,319 @@
+package fake
+
+import (
+	"sync"
+	"time"
+
+	"github.com/cyberxnomad/wasapi/audioclient"
+	"github.com/cyberxnomad/wasapi/backend"
+	"github.com/cyberxnomad/wasapi/mmdevice"
+)
+
+// 其他 HRESULT (winerror.h)
+const (
+	E_NOTFOUND    uint32 = 0x80070490
+	E_INVALIDARG  uint32 = 0x80070057
+	E_POINTER     uint32 = 0x80004003
+	E_NOINTERFACE uint32 = 0x80004002
+)
+
+var _ backend.Enumerator = (*Enumerator)(nil)
+
+// 注入的错误
+type fault struct {
+	err   error
+	count int // 剩余次数，小于 0 表示一直有效
+}
+
+// Enumerator 是内存中的设备枚举器，实现 backend.Enumerator，可以在没有音频硬件的平台上使用。
+//
+// 时间是虚拟的，只在调用 Advance 时前进，音频引擎在此时按设备周期消耗呈现数据、产生捕获数据包，
+// 因此测试可以确定地检查填充及缓冲区的行为。所有由其创建的对象共用同一把锁，
+// 设备的回调函数在持有锁时调用，不能再调用这些对象的方法。
+type Enumerator struct {
+	mu sync.Mutex
+
+	devices  []*Device
+	defaults map[[2]uint32]string
+	now      time.Duration
+	faults   map[string]*fault
+	objects  int
+}
+
+// NewEnumerator 创建没有设备的枚举器。
+func NewEnumerator() *Enumerator {
+	return &Enumerator{
+		defaults: make(map[[2]uint32]string),
+		faults:   make(map[string]*fault),
+	}
+}
+
+// AddDevice 方法添加设备，同一方向的第一个设备成为所有角色的默认设备。
+func (e *Enumerator) AddDevice(config DeviceConfig) *Device {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	d := newDevice(e, config)
+	e.devices = append(e.devices, d)
+
+	for role := mmdevice.EConsole; role < mmdevice.ERole_enum_count; role++ {
+		key := [2]uint32{uint32(d.config.Flow), uint32(role)}
+		if _, ok := e.defaults[key]; !ok {
+			e.defaults[key] = d.config.ID
+		}
+	}
+
+	return d
+}
+
+// AddLoopback 方法添加镜像呈现设备 render 的捕获设备 (类似“立体声混音”)，捕获数据为该设备上所有呈现流的混音，
+// 延迟一个设备周期。config 的 Flow 被忽略，MixFormat 为零值时使用呈现设备的混音格式。
+func (e *Enumerator) AddLoopback(config DeviceConfig, render *Device) *Device {
+	if config.MixFormat.Format.SamplesPerSec == 0 {
+		config.MixFormat = render.Config().MixFormat
+	}
+	config.Flow = mmdevice.ECapture
+
+	d := e.AddDevice(config)
+
+	e.mu.Lock()
+	d.mirror = render
+	e.mu.Unlock()
+
+	return d
+}
+
+// RemoveDevice 方法移除设备，设备的音频客户端随之失效，以其为默认设备的角色改用同一方向的第一个活动设备。
+func (e *Enumerator) RemoveDevice(id string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for i, d := range e.devices {
+		if d.config.ID != id {
+			continue
+		}
+
+		d.setState(mmdevice.DEVICE_STATE_NOTPRESENT)
+		e.devices = append(e.devices[:i], e.devices[i+1:]...)
+		break
+	}
+
+	for key, def := range e.defaults {
+		if def != id {
+			continue
+		}
+
+		delete(e.defaults, key)
+		for _, d := range e.devices {
+			if uint32(d.config.Flow) == key[0] && d.state == mmdevice.DEVICE_STATE_ACTIVE {
+				e.defaults[key] = d.config.ID
+				break
+			}
+		}
+	}
+}
+
+// SetDefault 方法设置指定方向及角色的默认设备。
+func (e *Enumerator) SetDefault(dataFlow mmdevice.EDataFlow, role mmdevice.ERole, id string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.defaults[[2]uint32{uint32(dataFlow), uint32(role)}] = id
+}
+
+// InjectError 方法使之后 count 次对名为 method 的方法的调用返回 err，count 小于 0 时一直有效，
+// err 为 nil 时清除。method 为 COM 方法名，例如 "IMMDeviceEnumerator::GetDefaultAudioEndpoint"，
+// 作用于所有设备；只作用于单个设备时使用 Device.InjectError。
+func (e *Enumerator) InjectError(method string, err error, count int) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	inject(e.faults, method, err, count)
+}
+
+// Now 方法返回虚拟时间。
+func (e *Enumerator) Now() time.Duration {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return e.now
+}
+
+// Advance 方法使虚拟时间前进 d，运行中的音频流按经过的时间处理数据。
+func (e *Enumerator) Advance(d time.Duration) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.now += d
+
+	// 先处理呈现流，环回流才能读取到最新的混音
+	for _, capture := range []bool{false, true} {
+		for _, device := range e.devices {
+			device.advance(e.now, capture)
+		}
+	}
+}
+
+// QPCPosition 方法以 100 纳秒为单位返回虚拟时间，对应 clock.QPCPosition，与捕获数据包及时钟服务返回的 QPCPosition 一致。
+func (e *Enumerator) QPCPosition() uint64 {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return durationToHns(e.now)
+}
+
+// Run 方法在后台按实际时间每隔 step 调用一次 Advance，直到调用返回的 stop 函数，
+// 用于让轮询或等待事件的应用代码像在真实设备上一样运行。
+func (e *Enumerator) Run(step time.Duration) (stop func()) {
+	ticker := time.NewTicker(step)
+	done := make(chan struct{})
+	exited := make(chan struct{})
+
+	go func() {
+		defer close(exited)
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				e.Advance(step)
+			}
+		}
+	}()
+
+	var once sync.Once
+
+	return func() {
+		once.Do(func() {
+			ticker.Stop()
+			close(done)
+			<-exited
+		})
+	}
+}
+
+// Outstanding 方法返回已创建但尚未 Release 的音频客户端及服务的个数，用于检查资源泄漏。
+func (e *Enumerator) Outstanding() int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return e.objects
+}
+
+// EnumAudioEndpoints 方法返回符合方向及状态掩码的设备。
+func (e *Enumerator) EnumAudioEndpoints(dataFlow mmdevice.EDataFlow, stateMask uint32) (devices []backend.Device, err error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if err = e.fail("IMMDeviceEnumerator::EnumAudioEndpoints", nil); err != nil {
+		return
+	}
+
+	if dataFlow > mmdevice.EAll || stateMask&^mmdevice.DEVICE_STATEMASK_ALL != 0 {
+		err = &audioclient.Error{Method: "IMMDeviceEnumerator::EnumAudioEndpoints", Code: E_INVALIDARG}
+		return
+	}
+
+	for _, d := range e.devices {
+		if (dataFlow == mmdevice.EAll || d.config.Flow == dataFlow) && d.state&stateMask != 0 {
+			devices = append(devices, d)
+		}
+	}
+
+	return
+}
+
+// GetDefaultAudioEndpoint 方法返回默认设备，没有时返回 E_NOTFOUND。
+func (e *Enumerator) GetDefaultAudioEndpoint(dataFlow mmdevice.EDataFlow, role mmdevice.ERole) (endpoint backend.Device, err error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if err = e.fail("IMMDeviceEnumerator::GetDefaultAudioEndpoint", nil); err != nil {
+		return
+	}
+
+	if dataFlow >= mmdevice.EAll || role >= mmdevice.ERole_enum_count {
+		err = &audioclient.Error{Method: "IMMDeviceEnumerator::GetDefaultAudioEndpoint", Code: E_INVALIDARG}
+		return
+	}
+
+	d := e.find(e.defaults[[2]uint32{uint32(dataFlow), uint32(role)}])
+	if d == nil || d.state != mmdevice.DEVICE_STATE_ACTIVE {
+		err = &audioclient.Error{Method: "IMMDeviceEnumerator::GetDefaultAudioEndpoint", Code: E_NOTFOUND}
+		return
+	}
+
+	return d, nil
+}
+
+// GetDevice 方法按 ID 返回设备，不存在时返回 E_NOTFOUND。
+func (e *Enumerator) GetDevice(id string) (device backend.Device, err error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if err = e.fail("IMMDeviceEnumerator::GetDevice", nil); err != nil {
+		return
+	}
+
+	d := e.find(id)
+	if d == nil {
+		err = &audioclient.Error{Method: "IMMDeviceEnumerator::GetDevice", Code: E_NOTFOUND}
+		return
+	}
+
+	return d, nil
+}
+
+// Release 方法实现 backend.Enumerator 接口，没有实际作用。
+func (e *Enumerator) Release() (err error) {
+	return
+}
+
+// Device 方法按 ID 返回设备，用于配置，不存在时返回 nil。
+func (e *Enumerator) Device(id string) *Device {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return e.find(id)
+}
+
+func (e *Enumerator) find(id string) *Device {
+	for _, d := range e.devices {
+		if d.config.ID == id {
+			return d
+		}
+	}
+
+	return nil
+}
+
+// 检查方法是否有注入的错误，先检查设备的，再检查全局的
+func (e *Enumerator) fail(method string, local map[string]*fault) error {
+	for _, faults := range []map[string]*fault{local, e.faults} {
+		f, ok := faults[method]
+		if !ok {
+			continue
+		}
+
+		err := f.err
+		if f.count > 0 {
+			if f.count--; f.count == 0 {
+				delete(faults, method)
+			}
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+func inject(faults map[string]*fault, method string, err error, count int) {
+	if err == nil || count == 0 {
+		delete(faults, method)
+		return
+	}
+
+	faults[method] = &fault{err: err, count: count}
+}
@@ -0,0 +1,276 @@
+package fake
+
+import (
+	"errors"
+	"io"
+	"math"
+
+	"github.com/cyberxnomad/wasapi/audioclient"
+	"github.com/cyberxnomad/wasapi/generator"
+	"github.com/cyberxnomad/wasapi/mmdevice"
+	"github.com/cyberxnomad/wasapi/pcm"
+	"github.com/cyberxnomad/wasapi/resample"
+	"github.com/cyberxnomad/wasapi/wav"
+)
+
+// 在两种格式的交错 float32 采样之间映射声道并转换采样率，格式改变时才重建混音矩阵及重采样器
+type converter struct {
+	src, dst  audioclient.WAVEFORMATEXTENSIBLE
+	matrix    [][]float32
+	resampler *resample.Resampler
+	remix     []float32
+}
+
+func (c *converter) prepare(src, dst *audioclient.WAVEFORMATEXTENSIBLE) {
+	if c.src == *src && c.dst == *dst {
+		return
+	}
+
+	c.src, c.dst = *src, *dst
+	c.matrix = nil
+	c.resampler = nil
+
+	if src.Format.Channels != dst.Format.Channels {
+		c.matrix = pcm.ChannelMatrix(src, dst)
+	}
+	if src.Format.SamplesPerSec != dst.Format.SamplesPerSec {
+		c.resampler = resample.New(int(dst.Format.Channels), src.Format.SamplesPerSec, dst.Format.SamplesPerSec)
+	}
+}
+
+// 产生 frames 帧输出所需的输入帧数
+func (c *converter) input(frames int) int {
+	if c.resampler == nil {
+		return frames
+	}
+
+	return int(math.Ceil(float64(frames)/c.resampler.Ratio())) + 1
+}
+
+// 转换 src 并追加到 dst
+func (c *converter) process(dst, src []float32) []float32 {
+	if c.matrix != nil {
+		frames := len(src) / int(c.src.Format.Channels)
+		out := grow(&c.remix, frames*int(c.dst.Format.Channels))
+		pcm.Remix(c.matrix, src, out)
+		src = out
+	}
+
+	if c.resampler != nil {
+		return c.resampler.Process(dst, src)
+	}
+
+	return append(dst, src...)
+}
+
+// 返回长度为 n 的 *buf，容量不足时重新分配
+func grow[T any](buf *[]T, n int) []T {
+	if cap(*buf) < n {
+		*buf = make([]T, n)
+	}
+	*buf = (*buf)[:n]
+
+	return *buf
+}
+
+// 捕获设备的音源，以混音格式产生交错的 float32 采样
+type source struct {
+	format  audioclient.WAVEFORMATEXTENSIBLE
+	read    func(dst []float32) (frames int)
+	convert converter
+	buf     []float32
+	pending []float32 // 已转换为客户端格式、尚未取走的采样
+	out     []float32
+}
+
+// 产生一个数据包，按音频客户端的格式编码，声道数或采样率不同时转换，音源结束后为静音
+func (s *source) capture(format *audioclient.WAVEFORMATEXTENSIBLE, data []byte) (flags uint32) {
+	frames := len(data) / int(format.Format.BlockAlign)
+	channels := int(format.Format.Channels)
+	samples := frames * channels
+
+	s.convert.prepare(&s.format, format)
+
+	for len(s.pending) < samples {
+		need := frames - len(s.pending)/channels
+		buf := grow(&s.buf, s.convert.input(need)*int(s.format.Format.Channels))
+
+		n := s.read(buf)
+		if n == 0 {
+			break
+		}
+		s.pending = s.convert.process(s.pending, buf[:n*int(s.format.Format.Channels)])
+	}
+
+	if len(s.pending) == 0 {
+		return audioclient.AUDCLNT_BUFFERFLAGS_SILENT
+	}
+
+	out := grow(&s.out, samples)
+	n := copy(out, s.pending)
+	clear(out[n:])
+	s.pending = s.pending[:copy(s.pending, s.pending[n:])]
+
+	pcm.Encode(format, out, data)
+
+	return
+}
+
+// AddFileCapture 方法添加以 WAV 文件为输入的捕获设备，如同麦克风拾取文件的内容。
+//
+// 文件在添加时全部读入内存，MixFormat 为零值时使用文件的格式；loop 为真时循环播放，否则文件结束后产生静音。
+// 音频客户端的格式与文件不同时自动映射声道及转换采样率。
+func (e *Enumerator) AddFileCapture(config DeviceConfig, r io.Reader, loop bool) (d *Device, err error) {
+	reader, err := wav.NewReader(r)
+	if err != nil {
+		return
+	}
+
+	format := *reader.Format()
+	if pcm.SampleTypeOf(&format) == pcm.SampleTypeUnknown {
+		err = pcm.ErrUnsupportedFormat
+		return
+	}
+
+	data, err := io.ReadAll(reader)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return
+	}
+	err = nil
+
+	frames := pcm.Frames(&format, len(data))
+	samples := make([]float32, frames*int(format.Format.Channels))
+	if _, err = pcm.Decode(&format, data[:frames*int(format.Format.BlockAlign)], samples); err != nil {
+		return
+	}
+
+	var pos int
+	s := &source{format: format}
+	s.read = func(dst []float32) (frames int) {
+		for len(dst) > 0 && len(samples) > 0 {
+			if pos == len(samples) {
+				if !loop {
+					break
+				}
+				pos = 0
+			}
+
+			n := copy(dst, samples[pos:])
+			pos += n
+			dst = dst[n:]
+			frames += n / int(format.Format.Channels)
+		}
+
+		return
+	}
+
+	return e.addSource(config, s), nil
+}
+
+// AddGeneratorCapture 方法添加以信号发生器为输入的捕获设备，信号以 config.MixFormat 产生。
+func (e *Enumerator) AddGeneratorCapture(config DeviceConfig, signal generator.Signal) *Device {
+	g := generator.NewGenerator(&config.MixFormat, signal)
+
+	return e.addSource(config, &source{format: config.MixFormat, read: g.Read})
+}
+
+func (e *Enumerator) addSource(config DeviceConfig, s *source) *Device {
+	if config.MixFormat.Format.SamplesPerSec == 0 {
+		config.MixFormat = s.format
+	}
+	config.Flow = mmdevice.ECapture
+
+	d := e.AddDevice(config)
+	d.SetCapture(s.capture)
+
+	return d
+}
+
+// Recorder 是将呈现数据写入 WAV 文件的呈现设备。
+type Recorder struct {
+	*Device
+
+	format    audioclient.WAVEFORMATEXTENSIBLE
+	writer    *wav.Writer
+	frames    int64
+	err       error
+	convert   converter
+	buf       []float32
+	converted []float32
+	out       []byte
+}
+
+// AddFileRender 方法添加将音频引擎消耗的数据 (包括欠载时的静音) 写入 WAV 文件的呈现设备，
+// 文件的格式为 config.MixFormat。音频客户端的格式与混音格式不同时自动映射声道及转换采样率。
+func (e *Enumerator) AddFileRender(config DeviceConfig, w io.Writer) (r *Recorder, err error) {
+	config.Flow = mmdevice.ERender
+
+	writer, err := wav.NewWriter(w, &config.MixFormat)
+	if err != nil {
+		return
+	}
+
+	r = &Recorder{
+		Device: e.AddDevice(config),
+		format: config.MixFormat,
+		writer: writer,
+	}
+	r.SetRender(r.render)
+
+	return
+}
+
+// Frames 方法返回已写入的帧数。
+func (r *Recorder) Frames() int64 {
+	r.e.mu.Lock()
+	defer r.e.mu.Unlock()
+
+	return r.frames
+}
+
+// Err 方法返回写入文件时的第一个错误。
+func (r *Recorder) Err() error {
+	r.e.mu.Lock()
+	defer r.e.mu.Unlock()
+
+	return r.err
+}
+
+// Close 方法完成文件，可以定位时回写文件头中的长度，之后的呈现数据被丢弃。
+func (r *Recorder) Close() (err error) {
+	r.e.mu.Lock()
+	defer r.e.mu.Unlock()
+
+	if r.writer == nil {
+		return r.err
+	}
+
+	err = r.writer.Close()
+	r.writer = nil
+
+	return
+}
+
+func (r *Recorder) render(format *audioclient.WAVEFORMATEXTENSIBLE, data []byte) {
+	if r.writer == nil || r.err != nil {
+		return
+	}
+
+	frames := len(data) / int(format.Format.BlockAlign)
+
+	if !sameFormat(format, &r.format) {
+		buf := grow(&r.buf, frames*int(format.Format.Channels))
+		pcm.Decode(format, data, buf)
+
+		r.convert.prepare(format, &r.format)
+		r.converted = r.convert.process(r.converted[:0], buf)
+
+		frames = len(r.converted) / int(r.format.Format.Channels)
+		data = grow(&r.out, frames*int(r.format.Format.BlockAlign))
+		pcm.Encode(&r.format, r.converted, data)
+	}
+
+	if _, r.err = r.writer.Write(data); r.err == nil {
+		r.frames += int64(frames)
+	}
+}
@@ -0,0 +1,210 @@
+package fake
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+	"testing"
+
+	"github.com/cyberxnomad/wasapi/audioclient"
+	"github.com/cyberxnomad/wasapi/backend"
+	"github.com/cyberxnomad/wasapi/pcm"
+	"github.com/cyberxnomad/wasapi/wav"
+)
+
+var mono16k = pcm.NewFormat(pcm.SampleTypeFloat, 16000, 1, 32, audioclient.KSAUDIO_SPEAKER_MONO)
+
+// 以 AUTOCONVERTPCM 及 16 位立体声 48 kHz 格式初始化共享模式的音频客户端
+func openConvert(t *testing.T, device *Device) backend.AudioClient {
+	t.Helper()
+
+	client, err := backend.Activate(device)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { client.Release() })
+
+	if err = client.Initialize(audioclient.AUDCLNT_SHAREMODE_SHARED, audioclient.AUDCLNT_STREAMFLAGS_AUTOCONVERTPCM, 0, 0, &stereo, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	return client
+}
+
+// 1 kHz 正弦在 rate 采样率下第 i 帧的值
+func tone(i int, rate float64) float64 {
+	return 0.5 * math.Sin(2*math.Pi*1000*float64(i)/rate)
+}
+
+// 检查 samples 为采样率 rate、峰值 want 的 1 kHz 正弦，跳过开头的重采样延迟
+func checkTone(t *testing.T, what string, samples []float64, rate, want float64) {
+	t.Helper()
+
+	samples = samples[len(samples)/4:]
+
+	var crossings int
+	var peak float64
+	for i := 1; i < len(samples); i++ {
+		if (samples[i-1] < 0) != (samples[i] < 0) {
+			crossings++
+		}
+		peak = max(peak, math.Abs(samples[i]))
+	}
+
+	// 每周期过零两次，采样率错误时频率随之偏移
+	frequency := float64(crossings) / 2 / (float64(len(samples)) / rate)
+	if math.Abs(frequency-1000) > 10 || math.Abs(peak-want) > 0.02 {
+		t.Errorf("%s: %.1f Hz, peak %.3f, want 1000 Hz, peak %.3f", what, frequency, peak, want)
+	}
+}
+
+func TestFileCaptureConvert(t *testing.T) {
+	var file bytes.Buffer
+	w, err := wav.NewWriter(&file, &mono16k)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := make([]byte, 8000*4)
+	for i := 0; i < 8000; i++ {
+		binary.LittleEndian.PutUint32(data[i*4:], math.Float32bits(float32(tone(i, 16000))))
+	}
+	w.Write(data)
+	w.Close()
+
+	e := NewEnumerator()
+	mic, err := e.AddFileCapture(DeviceConfig{ID: "mic"}, bytes.NewReader(file.Bytes()), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := openConvert(t, mic)
+	capture, err := backend.GetCaptureClient(client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer capture.Release()
+
+	if err = client.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	// 以客户端的采样率读取 1 秒，声道数及采样率均被转换
+	var left []float64
+	for i := 0; i < 100; i++ {
+		e.Advance(DefaultPeriod)
+
+		data, frames, flags, _, _, err := capture.GetBuffer()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if frames != 480 || flags != 0 {
+			t.Fatalf("packet %d: %d frames, flags %X", i, frames, flags)
+		}
+
+		for f := 0; f < int(frames); f++ {
+			l := int16(binary.LittleEndian.Uint16(data[f*4:]))
+			r := int16(binary.LittleEndian.Uint16(data[f*4+2:]))
+			if l != r {
+				t.Fatalf("packet %d frame %d: left %d, right %d", i, f, l, r)
+			}
+			left = append(left, float64(l)/32768)
+		}
+
+		if err = capture.ReleaseBuffer(frames); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 单声道以 -3 dB 分配到左右声道
+	checkTone(t, "capture", left, 48000, 0.5*math.Sqrt2/2)
+}
+
+// 可定位的内存文件
+type memFile struct {
+	data []byte
+	pos  int64
+}
+
+func (m *memFile) Write(p []byte) (n int, err error) {
+	if end := m.pos + int64(len(p)); end > int64(len(m.data)) {
+		m.data = append(m.data, make([]byte, end-int64(len(m.data)))...)
+	}
+	n = copy(m.data[m.pos:], p)
+	m.pos += int64(n)
+
+	return
+}
+
+func (m *memFile) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekCurrent:
+		offset += m.pos
+	case io.SeekEnd:
+		offset += int64(len(m.data))
+	}
+	m.pos = offset
+
+	return offset, nil
+}
+
+func TestFileRenderConvert(t *testing.T) {
+	var file memFile
+
+	e := NewEnumerator()
+	recorder, err := e.AddFileRender(DeviceConfig{ID: "speakers", MixFormat: mono16k}, &file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := openConvert(t, recorder.Device)
+	render, err := backend.GetRenderClient(client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer render.Release()
+
+	if err = client.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	// 以 48 kHz 立体声呈现 1 秒，文件中为 16 kHz 单声道
+	var pos int
+	for i := 0; i < 100; i++ {
+		write(t, render, 480, func(frame, ch int) int16 {
+			return int16(tone(pos+frame, 48000) * 32767)
+		})
+		pos += 480
+		e.Advance(DefaultPeriod)
+	}
+
+	if err = recorder.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if frames := recorder.Frames(); math.Abs(float64(frames)-16000) > 32 {
+		t.Errorf("Frames = %d, want about 16000", frames)
+	}
+
+	reader, err := wav.NewReader(bytes.NewReader(file.data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if format := *reader.Format(); format != mono16k {
+		t.Fatalf("file format %+v, want %+v", format, mono16k)
+	}
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	samples := make([]float64, len(data)/4)
+	for i := range samples {
+		samples[i] = float64(math.Float32frombits(binary.LittleEndian.Uint32(data[i*4:])))
+	}
+	if int64(len(samples)) != recorder.Frames() {
+		t.Errorf("file has %d frames, Frames = %d", len(samples), recorder.Frames())
+	}
+
+	// 左右声道各以 -3 dB 并入单声道
+	checkTone(t, "render", samples, 16000, 0.5*math.Sqrt2)
+}
@@ -0,0 +1,188 @@
+package fake
+
+import (
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/cyberxnomad/wasapi/audioclient"
+	"github.com/cyberxnomad/wasapi/backend"
+	"github.com/cyberxnomad/wasapi/mmdevice"
+)
+
+// 写入 frames 帧 16 位立体声数据，样本值由 sample 给出
+func write(t *testing.T, render backend.RenderClient, frames int, sample func(frame, ch int) int16) {
+	t.Helper()
+
+	data, err := render.GetBuffer(uint32(frames))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for f := 0; f < frames; f++ {
+		for ch := 0; ch < 2; ch++ {
+			binary.LittleEndian.PutUint16(data[(f*2+ch)*2:], uint16(sample(f, ch)))
+		}
+	}
+	if err = render.ReleaseBuffer(uint32(frames), 0); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPaddingAdvance(t *testing.T) {
+	tests := []struct {
+		padding Padding
+		want    []uint32 // 依次前进 5 毫秒后的 padding
+	}{
+		{PaddingPeriodic, []uint32{960, 480, 480, 0, 0}},
+		{PaddingContinuous, []uint32{720, 480, 240, 0, 0}},
+		{PaddingStalled, []uint32{960, 960, 960, 960, 960}},
+	}
+
+	for _, tt := range tests {
+		e := NewEnumerator()
+		speakers := e.AddDevice(DeviceConfig{ID: "speakers", Flow: mmdevice.ERender, MixFormat: stereo, Padding: tt.padding})
+		client := open(t, speakers, 0)
+
+		render, err := backend.GetRenderClient(client)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// 共享模式的缓冲区至少为两个设备周期
+		size, err := client.GetBufferSize()
+		if err != nil || size != 960 {
+			t.Fatalf("padding %d: GetBufferSize = %d, %v, want 960", tt.padding, size, err)
+		}
+		write(t, render, int(size), func(frame, ch int) int16 { return 1 })
+
+		// 未启动时不消耗数据
+		e.Advance(DefaultPeriod)
+		if padding, _ := client.GetCurrentPadding(); padding != size {
+			t.Fatalf("padding %d: GetCurrentPadding = %d before Start, want %d", tt.padding, padding, size)
+		}
+
+		if err = client.Start(); err != nil {
+			t.Fatal(err)
+		}
+		for i, want := range tt.want {
+			e.Advance(5 * time.Millisecond)
+			if padding, err := client.GetCurrentPadding(); err != nil || padding != want {
+				t.Fatalf("padding %d: after %v GetCurrentPadding = %d, %v, want %d",
+					tt.padding, time.Duration(i+1)*5*time.Millisecond, padding, err, want)
+			}
+		}
+
+		render.Release()
+	}
+}
+
+func TestLoopbackMirror(t *testing.T) {
+	e := NewEnumerator()
+	speakers := e.AddDevice(DeviceConfig{ID: "speakers", Flow: mmdevice.ERender, MixFormat: stereo})
+	mix := e.AddLoopback(DeviceConfig{ID: "stereo mix"}, speakers)
+
+	if config := mix.Config(); config.Flow != mmdevice.ECapture || config.MixFormat != stereo {
+		t.Fatalf("AddLoopback config = %+v", config)
+	}
+
+	// 两个呈现流的数据在总线上相加
+	for _, sample := range []func(frame, ch int) int16{
+		func(frame, ch int) int16 { return int16(frame*2 + ch) },
+		func(frame, ch int) int16 { return 1000 },
+	} {
+		client := open(t, speakers, 0)
+		render, err := backend.GetRenderClient(client)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer render.Release()
+
+		write(t, render, 960, sample)
+	}
+
+	// 呈现设备上的环回流与镜像设备的捕获流读取同一混音
+	var captures []backend.CaptureClient
+	for _, c := range []struct {
+		device *Device
+		flags  uint32
+	}{
+		{speakers, audioclient.AUDCLNT_STREAMFLAGS_LOOPBACK},
+		{mix, 0},
+	} {
+		client := open(t, c.device, c.flags)
+		capture, err := backend.GetCaptureClient(client)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer capture.Release()
+
+		captures = append(captures, capture)
+	}
+
+	for _, client := range speakers.Clients() {
+		if err := client.Start(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, client := range mix.Clients() {
+		if err := client.Start(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	read := func(capture backend.CaptureClient) (data []byte, flags uint32) {
+		t.Helper()
+
+		data, frames, flags, _, _, err := capture.GetBuffer()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if frames != 480 {
+			t.Fatalf("GetBuffer returned %d frames, want 480", frames)
+		}
+		data = append([]byte(nil), data...)
+		if err = capture.ReleaseBuffer(frames); err != nil {
+			t.Fatal(err)
+		}
+
+		return
+	}
+
+	// 第一个周期读取的是呈现流开始之前的静音
+	e.Advance(DefaultPeriod)
+	for i, capture := range captures {
+		if _, flags := read(capture); flags&audioclient.AUDCLNT_BUFFERFLAGS_SILENT == 0 {
+			t.Fatalf("capture %d: first packet flags %X, want silent", i, flags)
+		}
+	}
+
+	// 之后延迟一个设备周期得到混音
+	for period := 1; period <= 2; period++ {
+		e.Advance(DefaultPeriod)
+
+		for i, capture := range captures {
+			data, flags := read(capture)
+			if flags != 0 {
+				t.Fatalf("capture %d period %d: flags %X", i, period, flags)
+			}
+
+			for f := 0; f < 480; f++ {
+				for ch := 0; ch < 2; ch++ {
+					got := int16(binary.LittleEndian.Uint16(data[(f*2+ch)*2:]))
+					want := int16((period-1)*960 + f*2 + ch + 1000)
+					if got != want {
+						t.Fatalf("capture %d period %d: frame %d ch %d = %d, want %d", i, period, f, ch, got, want)
+					}
+				}
+			}
+		}
+	}
+
+	// 呈现流数据耗尽后恢复静音
+	e.Advance(DefaultPeriod)
+	for i, capture := range captures {
+		if _, flags := read(capture); flags&audioclient.AUDCLNT_BUFFERFLAGS_SILENT == 0 {
+			t.Fatalf("capture %d: packet after underrun flags %X, want silent", i, flags)
+		}
+	}
+}
@@ -0,0 +1,243 @@
+package fake
+
+import (
+	"github.com/cyberxnomad/wasapi/audioclient"
+	"github.com/cyberxnomad/wasapi/backend"
+)
+
+var (
+	_ backend.CaptureClient = (*CaptureClient)(nil)
+	_ backend.RenderClient  = (*RenderClient)(nil)
+	_ backend.Clock         = (*Clock)(nil)
+)
+
+// CaptureClient 是内存中的捕获服务，实现 backend.CaptureClient。
+type CaptureClient struct {
+	c        *AudioClient
+	released bool
+}
+
+// GetBuffer 方法实现 backend.CaptureClient 接口。
+//
+// 与 Windows 上的实现一致，data 的长度为数据包的字节数；
+// 没有数据包时返回 AUDCLNT_S_BUFFER_EMPTY 错误。
+func (cc *CaptureClient) GetBuffer() (data []byte, numFramesToRead uint32, flags uint32, devicePosition uint64, QPCPosition uint64, err error) {
+	const method = "IAudioCaptureClient::GetBuffer"
+	c := cc.c
+
+	c.device.e.mu.Lock()
+	defer c.device.e.mu.Unlock()
+
+	if err = c.check(method, true); err != nil {
+		return
+	}
+
+	switch {
+	case c.pending >= 0:
+		err = c.fail(method, audioclient.AUDCLNT_E_OUT_OF_ORDER)
+		return
+	case len(c.packets) == 0:
+		err = c.fail(method, audioclient.AUDCLNT_S_BUFFER_EMPTY)
+		return
+	}
+
+	p := &c.packets[0]
+	c.pending = p.frames
+
+	return p.data, uint32(p.frames), p.flags, p.devicePosition, p.qpcPosition, nil
+}
+
+// ReleaseBuffer 方法实现 backend.CaptureClient 接口，numFramesToRead 为 0 时保留数据包。
+func (cc *CaptureClient) ReleaseBuffer(numFramesToRead uint32) (err error) {
+	const method = "IAudioCaptureClient::ReleaseBuffer"
+	c := cc.c
+
+	c.device.e.mu.Lock()
+	defer c.device.e.mu.Unlock()
+
+	if err = c.check(method, true); err != nil {
+		return
+	}
+
+	switch {
+	case c.pending < 0:
+		return c.fail(method, audioclient.AUDCLNT_E_OUT_OF_ORDER)
+	case numFramesToRead != 0 && int(numFramesToRead) != c.pending:
+		return c.fail(method, audioclient.AUDCLNT_E_INVALID_SIZE)
+	}
+
+	if numFramesToRead > 0 {
+		c.packets = c.packets[1:]
+		c.padding -= c.pending
+	}
+	c.pending = -1
+
+	return
+}
+
+// GetNextPacketSize 方法实现 backend.CaptureClient 接口。
+func (cc *CaptureClient) GetNextPacketSize() (numFramesInNextPacket uint32, err error) {
+	c := cc.c
+
+	c.device.e.mu.Lock()
+	defer c.device.e.mu.Unlock()
+
+	if err = c.check("IAudioCaptureClient::GetNextPacketSize", true); err != nil {
+		return
+	}
+
+	if len(c.packets) > 0 {
+		numFramesInNextPacket = uint32(c.packets[0].frames)
+	}
+
+	return
+}
+
+// Release 方法实现 backend.CaptureClient 接口。
+func (cc *CaptureClient) Release() (err error) {
+	cc.c.device.e.mu.Lock()
+	defer cc.c.device.e.mu.Unlock()
+
+	if !cc.released {
+		cc.released = true
+		cc.c.device.e.objects--
+	}
+
+	return
+}
+
+// RenderClient 是内存中的呈现服务，实现 backend.RenderClient。
+type RenderClient struct {
+	c        *AudioClient
+	released bool
+}
+
+// GetBuffer 方法实现 backend.RenderClient 接口。
+//
+// 与 Windows 上的实现一致，data 的长度为请求的字节数。
+func (rc *RenderClient) GetBuffer(numFramesRequested uint32) (data []byte, err error) {
+	const method = "IAudioRenderClient::GetBuffer"
+	c := rc.c
+
+	c.device.e.mu.Lock()
+	defer c.device.e.mu.Unlock()
+
+	if err = c.check(method, true); err != nil {
+		return
+	}
+
+	switch {
+	case c.pending >= 0:
+		err = c.fail(method, audioclient.AUDCLNT_E_OUT_OF_ORDER)
+		return
+	case int(numFramesRequested) > c.bufferFrames-c.padding:
+		err = c.fail(method, audioclient.AUDCLNT_E_BUFFER_TOO_LARGE)
+		return
+	}
+
+	size := int(numFramesRequested) * c.blockAlign
+	if cap(c.scratch) < size {
+		c.scratch = make([]byte, size)
+	}
+	c.scratch = c.scratch[:size]
+	clear(c.scratch)
+	c.pending = int(numFramesRequested)
+
+	return c.scratch, nil
+}
+
+// ReleaseBuffer 方法实现 backend.RenderClient 接口。
+func (rc *RenderClient) ReleaseBuffer(numFramesWritten uint32, flags uint32) (err error) {
+	const method = "IAudioRenderClient::ReleaseBuffer"
+	c := rc.c
+
+	c.device.e.mu.Lock()
+	defer c.device.e.mu.Unlock()
+
+	if err = c.check(method, true); err != nil {
+		return
+	}
+
+	switch {
+	case c.pending < 0:
+		return c.fail(method, audioclient.AUDCLNT_E_OUT_OF_ORDER)
+	case int(numFramesWritten) > c.pending:
+		return c.fail(method, audioclient.AUDCLNT_E_INVALID_SIZE)
+	}
+
+	data := c.scratch[:int(numFramesWritten)*c.blockAlign]
+	if flags&audioclient.AUDCLNT_BUFFERFLAGS_SILENT != 0 {
+		clear(data)
+	}
+
+	c.queue = append(c.queue, data...)
+	c.padding += int(numFramesWritten)
+	c.pending = -1
+
+	return
+}
+
+// Release 方法实现 backend.RenderClient 接口。
+func (rc *RenderClient) Release() (err error) {
+	rc.c.device.e.mu.Lock()
+	defer rc.c.device.e.mu.Unlock()
+
+	if !rc.released {
+		rc.released = true
+		rc.c.device.e.objects--
+	}
+
+	return
+}
+
+// Clock 是内存中的时钟服务，实现 backend.Clock，频率为每秒的字节数，位置为引擎已处理的字节数。
+type Clock struct {
+	c        *AudioClient
+	released bool
+}
+
+// GetFrequency 方法实现 backend.Clock 接口。
+func (ck *Clock) GetFrequency() (frequency uint64, err error) {
+	c := ck.c
+
+	c.device.e.mu.Lock()
+	defer c.device.e.mu.Unlock()
+
+	if err = c.check("IAudioClock::GetFrequency", true); err != nil {
+		return
+	}
+
+	return uint64(c.format.Format.SamplesPerSec) * uint64(c.blockAlign), nil
+}
+
+// GetPosition 方法实现 backend.Clock 接口，QPCPosition 为当前的虚拟时间 (100 纳秒单位)。
+func (ck *Clock) GetPosition() (position uint64, QPCPosition uint64, err error) {
+	c := ck.c
+
+	c.device.e.mu.Lock()
+	defer c.device.e.mu.Unlock()
+
+	if err = c.check("IAudioClock::GetPosition", true); err != nil {
+		return
+	}
+
+	return c.position * uint64(c.blockAlign), durationToHns(c.device.e.now), nil
+}
+
+// GetCharacteristics 方法实现 backend.Clock 接口。
+func (ck *Clock) GetCharacteristics() (characteristics uint32, err error) {
+	return
+}
+
+// Release 方法实现 backend.Clock 接口。
+func (ck *Clock) Release() (err error) {
+	ck.c.device.e.mu.Lock()
+	defer ck.c.device.e.mu.Unlock()
+
+	if !ck.released {
+		ck.released = true
+		ck.c.device.e.objects--
+	}
+
+	return
+}
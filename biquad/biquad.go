@@ -0,0 +1,178 @@
+package biquad
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/cmplx"
+)
+
+var ErrInvalidParams = errors.New("invalid filter parameters")
+
+// Type 表示滤波器类型 (RBJ Audio EQ Cookbook)。
+type Type uint32
+
+const (
+	LowPass   Type = iota // 低通
+	HighPass              // 高通
+	BandPass              // 带通 (峰值增益为 0 dB)
+	Notch                 // 陷波
+	AllPass               // 全通
+	Peaking               // 峰值 (钟形) 均衡
+	LowShelf              // 低架
+	HighShelf             // 高架
+)
+
+var typeNames = [...]string{
+	LowPass:   "lowpass",
+	HighPass:  "highpass",
+	BandPass:  "bandpass",
+	Notch:     "notch",
+	AllPass:   "allpass",
+	Peaking:   "peaking",
+	LowShelf:  "lowshelf",
+	HighShelf: "highshelf",
+}
+
+func (t Type) String() string {
+	if int(t) < len(typeNames) {
+		return typeNames[t]
+	}
+
+	return fmt.Sprintf("Type(%d)", uint32(t))
+}
+
+// MarshalText 方法实现 encoding.TextMarshaler，以名称序列化。
+func (t Type) MarshalText() ([]byte, error) {
+	if int(t) >= len(typeNames) {
+		return nil, ErrInvalidParams
+	}
+
+	return []byte(typeNames[t]), nil
+}
+
+// UnmarshalText 方法实现 encoding.TextUnmarshaler。
+func (t *Type) UnmarshalText(text []byte) error {
+	for i, name := range typeNames {
+		if name == string(text) {
+			*t = Type(i)
+			return nil
+		}
+	}
+
+	return fmt.Errorf("unknown filter type %q", text)
+}
+
+// Params 为滤波器参数。
+type Params struct {
+	Type      Type    `json:"type"`
+	Frequency float64 `json:"frequency"`      // 中心或截止频率 (Hz)
+	Q         float64 `json:"q"`              // 品质因数，架式滤波器为斜率参数，0.7071 为 Butterworth
+	Gain      float64 `json:"gain,omitempty"` // 增益 (dB)，仅用于峰值及架式滤波器
+}
+
+// Validate 方法检查参数在采样率 samplesPerSec 下是否有效。
+func (p Params) Validate(samplesPerSec float64) error {
+	if int(p.Type) >= len(typeNames) || !(p.Frequency > 0) || p.Frequency >= samplesPerSec/2 || !(p.Q > 0) || math.IsNaN(p.Gain) {
+		return ErrInvalidParams
+	}
+
+	return nil
+}
+
+// Coefficients 为归一化 (a0 = 1) 的二阶滤波器系数。
+type Coefficients struct {
+	B0, B1, B2 float64
+	A1, A2     float64
+}
+
+// Identity 为直通滤波器的系数。
+var Identity = Coefficients{B0: 1}
+
+// Design 按 RBJ Audio EQ Cookbook 计算滤波器系数，参数无效时返回直通系数。
+func Design(p Params, samplesPerSec float64) Coefficients {
+	if p.Validate(samplesPerSec) != nil {
+		return Identity
+	}
+
+	w := 2 * math.Pi * p.Frequency / samplesPerSec
+	cos, sin := math.Cos(w), math.Sin(w)
+	alpha := sin / (2 * p.Q)
+	a := math.Pow(10, p.Gain/40)
+
+	var b0, b1, b2, a0, a1, a2 float64
+
+	switch p.Type {
+	case LowPass:
+		b0, b1, b2 = (1-cos)/2, 1-cos, (1-cos)/2
+		a0, a1, a2 = 1+alpha, -2*cos, 1-alpha
+	case HighPass:
+		b0, b1, b2 = (1+cos)/2, -(1 + cos), (1+cos)/2
+		a0, a1, a2 = 1+alpha, -2*cos, 1-alpha
+	case BandPass:
+		b0, b1, b2 = alpha, 0, -alpha
+		a0, a1, a2 = 1+alpha, -2*cos, 1-alpha
+	case Notch:
+		b0, b1, b2 = 1, -2*cos, 1
+		a0, a1, a2 = 1+alpha, -2*cos, 1-alpha
+	case AllPass:
+		b0, b1, b2 = 1-alpha, -2*cos, 1+alpha
+		a0, a1, a2 = 1+alpha, -2*cos, 1-alpha
+	case Peaking:
+		b0, b1, b2 = 1+alpha*a, -2*cos, 1-alpha*a
+		a0, a1, a2 = 1+alpha/a, -2*cos, 1-alpha/a
+	case LowShelf:
+		k := 2 * math.Sqrt(a) * alpha
+		b0 = a * ((a + 1) - (a-1)*cos + k)
+		b1 = 2 * a * ((a - 1) - (a+1)*cos)
+		b2 = a * ((a + 1) - (a-1)*cos - k)
+		a0 = (a + 1) + (a-1)*cos + k
+		a1 = -2 * ((a - 1) + (a+1)*cos)
+		a2 = (a + 1) + (a-1)*cos - k
+	case HighShelf:
+		k := 2 * math.Sqrt(a) * alpha
+		b0 = a * ((a + 1) + (a-1)*cos + k)
+		b1 = -2 * a * ((a - 1) + (a+1)*cos)
+		b2 = a * ((a + 1) + (a-1)*cos - k)
+		a0 = (a + 1) - (a-1)*cos + k
+		a1 = 2 * ((a - 1) - (a+1)*cos)
+		a2 = (a + 1) - (a-1)*cos - k
+	}
+
+	return Coefficients{B0: b0 / a0, B1: b1 / a0, B2: b2 / a0, A1: a1 / a0, A2: a2 / a0}
+}
+
+// Response 方法返回频率 frequency (Hz) 处的复数频率响应。
+func (c *Coefficients) Response(frequency, samplesPerSec float64) complex128 {
+	z1 := cmplx.Rect(1, -2*math.Pi*frequency/samplesPerSec)
+	z2 := z1 * z1
+
+	num := complex(c.B0, 0) + complex(c.B1, 0)*z1 + complex(c.B2, 0)*z2
+	den := 1 + complex(c.A1, 0)*z1 + complex(c.A2, 0)*z2
+
+	return num / den
+}
+
+// Magnitude 方法返回频率 frequency (Hz) 处的幅度响应 (dB)。
+func (c *Coefficients) Magnitude(frequency, samplesPerSec float64) float64 {
+	return 20 * math.Log10(cmplx.Abs(c.Response(frequency, samplesPerSec)))
+}
+
+// State 为单个声道的滤波器状态 (直接 II 型转置)。
+type State struct {
+	z1, z2 float64
+}
+
+// Process 方法以系数 c 处理一个采样。
+func (s *State) Process(c *Coefficients, x float64) float64 {
+	y := c.B0*x + s.z1
+	s.z1 = c.B1*x - c.A1*y + s.z2
+	s.z2 = c.B2*x - c.A2*y
+
+	return y
+}
+
+// Reset 方法清除状态。
+func (s *State) Reset() {
+	s.z1, s.z2 = 0, 0
+}
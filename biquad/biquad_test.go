@@ -0,0 +1,152 @@
+package biquad
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"math/cmplx"
+	"testing"
+)
+
+const rate = 48000.0
+
+// RBJ Audio EQ Cookbook 中各类型在 DC、f0 及奈奎斯特频率处的幅度 (dB)，-Inf 表示零点
+func cookbookMagnitudes(p Params) (dc, center, nyquist float64) {
+	inf := math.Inf(-1)
+	q := 20 * math.Log10(p.Q)
+
+	switch p.Type {
+	case LowPass:
+		return 0, q, inf
+	case HighPass:
+		return inf, q, 0
+	case BandPass:
+		return inf, 0, inf
+	case Notch:
+		return 0, inf, 0
+	case AllPass:
+		return 0, 0, 0
+	case Peaking:
+		return 0, p.Gain, 0
+	case LowShelf:
+		return p.Gain, p.Gain / 2, 0
+	case HighShelf:
+		return 0, p.Gain / 2, p.Gain
+	}
+
+	return
+}
+
+func TestMagnitudeResponse(t *testing.T) {
+	for _, p := range []Params{
+		{Type: LowPass, Frequency: 1000, Q: 0.7071},
+		{Type: LowPass, Frequency: 5000, Q: 4},
+		{Type: HighPass, Frequency: 200, Q: 0.7071},
+		{Type: HighPass, Frequency: 3000, Q: 2},
+		{Type: BandPass, Frequency: 1000, Q: 1},
+		{Type: Notch, Frequency: 60, Q: 10},
+		{Type: AllPass, Frequency: 2000, Q: 0.7071},
+		{Type: Peaking, Frequency: 3000, Q: 1, Gain: 6},
+		{Type: Peaking, Frequency: 250, Q: 2, Gain: -9},
+		{Type: LowShelf, Frequency: 150, Q: 0.7071, Gain: 4},
+		{Type: HighShelf, Frequency: 8000, Q: 0.7071, Gain: -5},
+	} {
+		c := Design(p, rate)
+		dc, center, nyquist := cookbookMagnitudes(p)
+
+		for _, point := range []struct {
+			name      string
+			frequency float64
+			want      float64
+		}{
+			{"DC", 0, dc},
+			{"f0", p.Frequency, center},
+			{"nyquist", rate / 2, nyquist},
+		} {
+			got := c.Magnitude(point.frequency, rate)
+			switch {
+			case math.IsInf(point.want, -1):
+				if got > -100 {
+					t.Errorf("%v %v Hz: %s magnitude %.2f dB, want a zero", p.Type, p.Frequency, point.name, got)
+				}
+			case math.Abs(got-point.want) > 1e-6:
+				t.Errorf("%v %v Hz: %s magnitude %.6f dB, want %.6f dB", p.Type, p.Frequency, point.name, got, point.want)
+			}
+		}
+	}
+
+	// 全通滤波器在 f0 处的相移为 -180 度
+	c := Design(Params{Type: AllPass, Frequency: 2000, Q: 0.7071}, rate)
+	if phase := cmplx.Phase(c.Response(2000, rate)); math.Abs(math.Abs(phase)-math.Pi) > 1e-9 {
+		t.Errorf("allpass phase at f0 = %.6f rad, want ±π", phase)
+	}
+}
+
+func TestProcessMatchesResponse(t *testing.T) {
+	p := Params{Type: Peaking, Frequency: 1000, Q: 1.4, Gain: 9}
+
+	for _, frequency := range []float64{100, 700, 1000, 1500, 10000} {
+		f := NewFilter(p, rate, 1)
+
+		samples := make([]float32, rate)
+		for i := range samples {
+			samples[i] = float32(0.25 * math.Sin(2*math.Pi*frequency*float64(i)/rate))
+		}
+		f.Process(samples)
+
+		// 跳过暂态后测量输出幅度
+		var peak float64
+		for _, v := range samples[rate/2:] {
+			peak = max(peak, math.Abs(float64(v)))
+		}
+
+		got := 20 * math.Log10(peak/0.25)
+		if want := f.Magnitude(frequency); math.Abs(got-want) > 0.05 {
+			t.Errorf("%v Hz: measured %.3f dB, response %.3f dB", frequency, got, want)
+		}
+	}
+}
+
+func TestInvalidParams(t *testing.T) {
+	for _, p := range []Params{
+		{Type: LowPass, Frequency: 0, Q: 1},
+		{Type: LowPass, Frequency: rate / 2, Q: 1},
+		{Type: LowPass, Frequency: 1000, Q: 0},
+		{Type: Peaking, Frequency: 1000, Q: 1, Gain: math.NaN()},
+		{Type: Type(99), Frequency: 1000, Q: 1},
+	} {
+		if err := p.Validate(rate); !errors.Is(err, ErrInvalidParams) {
+			t.Errorf("Validate(%+v) = %v, want ErrInvalidParams", p, err)
+		}
+		if c := Design(p, rate); c != Identity {
+			t.Errorf("Design(%+v) = %+v, want Identity", p, c)
+		}
+	}
+
+	f := NewFilter(Params{Type: LowPass, Frequency: 1000, Q: 1}, rate, 1)
+	if err := f.SetParams(Params{Type: LowPass, Frequency: 1000}); !errors.Is(err, ErrInvalidParams) {
+		t.Errorf("SetParams: err = %v", err)
+	}
+	if f.Params().Q != 1 {
+		t.Errorf("invalid SetParams changed the parameters to %+v", f.Params())
+	}
+}
+
+func TestTypeJSON(t *testing.T) {
+	for typ := LowPass; typ <= HighShelf; typ++ {
+		data, err := json.Marshal(typ)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var got Type
+		if err = json.Unmarshal(data, &got); err != nil || got != typ {
+			t.Errorf("%s: round trip = %v, %v", data, got, err)
+		}
+	}
+
+	var typ Type
+	if err := json.Unmarshal([]byte(`"comb"`), &typ); err == nil {
+		t.Error("unknown type name accepted")
+	}
+}
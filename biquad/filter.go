@@ -0,0 +1,120 @@
+package biquad
+
+// Filter 是多声道的二阶滤波器，每个声道有独立的状态。
+type Filter struct {
+	params        Params
+	samplesPerSec float64
+	coefficients  Coefficients
+	states        []State
+}
+
+// NewFilter 创建滤波器，参数无效时为直通。
+func NewFilter(params Params, samplesPerSec uint32, channels int) *Filter {
+	f := &Filter{
+		params:        params,
+		samplesPerSec: float64(samplesPerSec),
+		states:        make([]State, channels),
+	}
+	f.coefficients = Design(params, f.samplesPerSec)
+
+	return f
+}
+
+// Params 方法返回滤波器参数。
+func (f *Filter) Params() Params {
+	return f.params
+}
+
+// SetParams 方法设置滤波器参数并重新计算系数，状态保留以避免咔嗒声。
+func (f *Filter) SetParams(params Params) (err error) {
+	if err = params.Validate(f.samplesPerSec); err != nil {
+		return
+	}
+
+	f.params = params
+	f.coefficients = Design(params, f.samplesPerSec)
+
+	return
+}
+
+// SetSampleRate 方法在采样率 (例如混音格式) 改变时重新计算系数并清除状态。
+func (f *Filter) SetSampleRate(samplesPerSec uint32) {
+	f.samplesPerSec = float64(samplesPerSec)
+	f.coefficients = Design(f.params, f.samplesPerSec)
+	f.Reset()
+}
+
+// Coefficients 方法返回当前的系数。
+func (f *Filter) Coefficients() Coefficients {
+	return f.coefficients
+}
+
+// Magnitude 方法返回频率 frequency (Hz) 处的幅度响应 (dB)。
+func (f *Filter) Magnitude(frequency float64) float64 {
+	return f.coefficients.Magnitude(frequency, f.samplesPerSec)
+}
+
+// Process 方法原地处理交错的 float32 采样。
+func (f *Filter) Process(samples []float32) {
+	channels := len(f.states)
+	for i := 0; i+channels <= len(samples); i += channels {
+		for c := range f.states {
+			samples[i+c] = float32(f.states[c].Process(&f.coefficients, float64(samples[i+c])))
+		}
+	}
+}
+
+// Reset 方法清除所有声道的状态。
+func (f *Filter) Reset() {
+	clear(f.states)
+}
+
+// Cascade 是串联的多个滤波器。
+type Cascade struct {
+	filters []*Filter
+}
+
+// NewCascade 按顺序串联滤波器，各滤波器的声道数及采样率应相同。
+func NewCascade(filters ...*Filter) *Cascade {
+	return &Cascade{filters: filters}
+}
+
+// Add 方法在末尾添加滤波器。
+func (c *Cascade) Add(filter *Filter) {
+	c.filters = append(c.filters, filter)
+}
+
+// Filters 方法返回串联的滤波器。
+func (c *Cascade) Filters() []*Filter {
+	return c.filters
+}
+
+// SetSampleRate 方法为所有滤波器设置采样率。
+func (c *Cascade) SetSampleRate(samplesPerSec uint32) {
+	for _, f := range c.filters {
+		f.SetSampleRate(samplesPerSec)
+	}
+}
+
+// Magnitude 方法返回频率 frequency (Hz) 处的总幅度响应 (dB)。
+func (c *Cascade) Magnitude(frequency float64) (db float64) {
+	for _, f := range c.filters {
+		db += f.Magnitude(frequency)
+	}
+
+	return
+}
+
+// Process 方法依次通过所有滤波器原地处理交错的 float32 采样。
+func (c *Cascade) Process(samples []float32) {
+	for _, f := range c.filters {
+		f.Process(samples)
+	}
+}
+
+// Reset 方法清除所有滤波器的状态。
+func (c *Cascade) Reset() {
+	for _, f := range c.filters {
+		f.Reset()
+	}
+}
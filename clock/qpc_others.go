@@ -0,0 +1,11 @@
+//go:build !windows
+
+package clock
+
+import "errors"
+
+// QPCPosition 在非 Windows 平台上不可用，总是返回错误。
+func QPCPosition() (position uint64, err error) {
+	err = errors.New("QueryPerformanceCounter is only available on Windows")
+	return
+}
@@ -0,0 +1,35 @@
+package clock
+
+import (
+	"fmt"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+var (
+	modkernel32                   = windows.NewLazyDLL("kernel32.dll")
+	procQueryPerformanceCounter   = modkernel32.NewProc("QueryPerformanceCounter")
+	procQueryPerformanceFrequency = modkernel32.NewProc("QueryPerformanceFrequency")
+)
+
+// QPCPosition 返回当前的性能计数器值，换算为 100 纳秒单位，与 GetBuffer 返回的 QPCPosition 一致。
+func QPCPosition() (position uint64, err error) {
+	var counter, frequency int64
+
+	if r, _, e := syscall.SyscallN(procQueryPerformanceFrequency.Addr(), uintptr(unsafe.Pointer(&frequency))); r == 0 {
+		err = fmt.Errorf("kernel32::QueryPerformanceFrequency failed: %w", e)
+		return
+	}
+
+	if r, _, e := syscall.SyscallN(procQueryPerformanceCounter.Addr(), uintptr(unsafe.Pointer(&counter))); r == 0 {
+		err = fmt.Errorf("kernel32::QueryPerformanceCounter failed: %w", e)
+		return
+	}
+
+	// 分开计算整数及余数部分，避免溢出
+	position = uint64(counter/frequency)*10000000 + uint64(counter%frequency)*10000000/uint64(frequency)
+
+	return
+}
@@ -0,0 +1,274 @@
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+// 100 纳秒单位每秒
+const hnsPerSecond = 10000000
+
+type point struct {
+	x float64
+	y float64
+}
+
+// 保存最近 n 个点的窗口，坐标相对第一个点以保持精度
+type window struct {
+	size   int
+	origin point
+	points []point
+}
+
+func (w *window) add(x, y float64) {
+	if len(w.points) == 0 {
+		w.origin = point{x, y}
+	}
+
+	w.points = append(w.points, point{x - w.origin.x, y - w.origin.y})
+	if len(w.points) > w.size {
+		w.points = append(w.points[:0], w.points[len(w.points)-w.size:]...)
+	}
+}
+
+// 最小二乘拟合 y = slope*x + intercept (原始坐标)
+func (w *window) fit() (slope, intercept float64, ok bool) {
+	n := float64(len(w.points))
+	if n < 2 {
+		return
+	}
+
+	var sx, sy float64
+	for _, p := range w.points {
+		sx += p.x
+		sy += p.y
+	}
+	mx, my := sx/n, sy/n
+
+	var sxx, sxy float64
+	for _, p := range w.points {
+		sxx += (p.x - mx) * (p.x - mx)
+		sxy += (p.x - mx) * (p.y - my)
+	}
+
+	if sxx == 0 {
+		return
+	}
+
+	slope = sxy / sxx
+	intercept = my + w.origin.y - slope*(mx+w.origin.x)
+	ok = true
+
+	return
+}
+
+// StreamClock 将 GetBuffer 及 IAudioClock 返回的位置换算为帧、时长及挂钟时间，
+// 并估计设备时钟相对系统时钟 (QPC) 的漂移。
+//
+// GetBuffer 返回的 devicePosition 以帧为单位，QPCPosition 以 100 纳秒为单位；
+// IAudioClock::GetPosition 返回的位置以 GetFrequency 返回的频率为单位。
+type StreamClock struct {
+	mu sync.Mutex
+
+	samplesPerSec uint32
+
+	// 设备帧与 QPC (秒) 的对应关系
+	positions window
+
+	// 挂钟时间 (Unix 纳秒) 与 QPC (纳秒) 的偏移，相对第一个偏移保存以免求和溢出
+	offsetBase  int64
+	offsetSum   int64
+	offsets     []int64
+	offsetLimit int
+}
+
+// NewStreamClock 创建流时钟，samplesPerSec 为流的采样率。
+//
+// 默认使用最近 256 个读数进行拟合。
+func NewStreamClock(samplesPerSec uint32) *StreamClock {
+	return &StreamClock{
+		samplesPerSec: samplesPerSec,
+		positions:     window{size: 256},
+		offsetLimit:   256,
+	}
+}
+
+// SetWindow 方法设置用于拟合的读数个数。
+func (c *StreamClock) SetWindow(n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.positions.size = max(n, 2)
+	c.offsetLimit = max(n, 1)
+}
+
+// Reset 方法清除所有读数，流重新开始或设备位置被重置时调用。
+func (c *StreamClock) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.positions.points = nil
+	c.offsets = nil
+	c.offsetSum = 0
+}
+
+// FramesToDuration 方法将帧数换算为时长，采样率为 0 时返回 0。
+func (c *StreamClock) FramesToDuration(frames uint64) time.Duration {
+	if c.samplesPerSec == 0 {
+		return 0
+	}
+
+	sec := frames / uint64(c.samplesPerSec)
+	rem := frames % uint64(c.samplesPerSec)
+
+	return time.Duration(sec)*time.Second + time.Duration(rem)*time.Second/time.Duration(c.samplesPerSec)
+}
+
+// DurationToFrames 方法将时长换算为帧数，负的时长返回 0。
+func (c *StreamClock) DurationToFrames(d time.Duration) uint64 {
+	if d <= 0 {
+		return 0
+	}
+
+	sec := uint64(d / time.Second)
+	rem := uint64(d % time.Second)
+
+	return sec*uint64(c.samplesPerSec) + rem*uint64(c.samplesPerSec)/uint64(time.Second)
+}
+
+// ClockPositionToFrames 方法将 IAudioClock::GetPosition 返回的位置换算为帧数，
+// frequency 为 IAudioClock::GetFrequency 的返回值。
+func (c *StreamClock) ClockPositionToFrames(position, frequency uint64) uint64 {
+	if frequency == 0 {
+		return 0
+	}
+
+	sec := position / frequency
+	rem := position % frequency
+
+	return sec*uint64(c.samplesPerSec) + rem*uint64(c.samplesPerSec)/frequency
+}
+
+// AddPacket 方法记录 GetBuffer 返回的设备位置及 QPC 位置。
+//
+// 带有 AUDCLNT_BUFFERFLAGS_TIMESTAMP_ERROR 标志的数据包不应记录。
+func (c *StreamClock) AddPacket(devicePosition uint64, QPCPosition uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.positions.add(float64(QPCPosition)/hnsPerSecond, float64(devicePosition))
+}
+
+// AddClockPosition 方法记录 IAudioClock::GetPosition 及 GetFrequency 的返回值。
+func (c *StreamClock) AddClockPosition(position uint64, QPCPosition uint64, frequency uint64) {
+	c.AddPacket(c.ClockPositionToFrames(position, frequency), QPCPosition)
+}
+
+// AddTimeReference 方法记录同一时刻的 QPC 位置 (100 纳秒单位) 与挂钟时间，用于拟合两者的偏移。
+//
+// 在 Windows 上可以使用 QPCPosition 及 time.Now 获取。
+func (c *StreamClock) AddTimeReference(QPCPosition uint64, t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	offset := t.UnixNano() - int64(QPCPosition)*100
+	if len(c.offsets) == 0 {
+		c.offsetBase = offset
+	}
+	offset -= c.offsetBase
+
+	c.offsets = append(c.offsets, offset)
+	c.offsetSum += offset
+
+	// SetWindow 缩小窗口后可能需要丢弃多个
+	for len(c.offsets) > c.offsetLimit {
+		c.offsetSum -= c.offsets[0]
+		c.offsets = c.offsets[1:]
+	}
+}
+
+// Time 方法将 QPC 位置换算为挂钟时间，未记录时间参考时 ok 为 false。
+func (c *StreamClock) Time(QPCPosition uint64) (t time.Time, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.time(float64(QPCPosition) / hnsPerSecond)
+}
+
+func (c *StreamClock) time(qpc float64) (t time.Time, ok bool) {
+	if len(c.offsets) == 0 {
+		return
+	}
+
+	offset := c.offsetBase + c.offsetSum/int64(len(c.offsets))
+	t = time.Unix(0, int64(qpc*1e9)+offset)
+	ok = true
+
+	return
+}
+
+// QPCAt 方法根据拟合结果返回设备位置 (帧) 对应的 QPC 位置。
+func (c *StreamClock) QPCAt(frame uint64) (QPCPosition uint64, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	slope, intercept, fitted := c.positions.fit()
+	if !fitted || slope <= 0 {
+		return
+	}
+
+	QPCPosition = uint64((float64(frame) - intercept) / slope * hnsPerSecond)
+	ok = true
+
+	return
+}
+
+// FrameAt 方法根据拟合结果返回 QPC 位置对应的设备位置 (帧)。
+func (c *StreamClock) FrameAt(QPCPosition uint64) (frame float64, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	slope, intercept, ok := c.positions.fit()
+	if !ok {
+		return
+	}
+
+	frame = slope*float64(QPCPosition)/hnsPerSecond + intercept
+
+	return
+}
+
+// FrameTime 方法返回设备位置 (帧) 对应的挂钟时间。
+func (c *StreamClock) FrameTime(frame uint64) (t time.Time, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	slope, intercept, fitted := c.positions.fit()
+	if !fitted || slope <= 0 {
+		return
+	}
+
+	return c.time((float64(frame) - intercept) / slope)
+}
+
+// Rate 方法返回拟合得到的实际采样率。
+func (c *StreamClock) Rate() (samplesPerSec float64, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	samplesPerSec, _, ok = c.positions.fit()
+
+	return
+}
+
+// Drift 方法返回设备时钟相对系统时钟 (QPC) 的漂移，单位为 ppm，正值表示设备时钟偏快。
+func (c *StreamClock) Drift() (ppm float64, ok bool) {
+	rate, ok := c.Rate()
+	if !ok {
+		return
+	}
+
+	ppm = (rate/float64(c.samplesPerSec) - 1) * 1e6
+
+	return
+}
@@ -0,0 +1,131 @@
+package clock
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestConversions(t *testing.T) {
+	c := NewStreamClock(48000)
+
+	for _, tt := range []struct {
+		frames uint64
+		d      time.Duration
+	}{
+		{0, 0},
+		{48, time.Millisecond},
+		{48000, time.Second},
+		{48000*3600*24*365 + 24, 365*24*time.Hour + 500*time.Microsecond},
+	} {
+		if got := c.FramesToDuration(tt.frames); got != tt.d {
+			t.Errorf("FramesToDuration(%d) = %v, want %v", tt.frames, got, tt.d)
+		}
+		if got := c.DurationToFrames(tt.d); got != tt.frames {
+			t.Errorf("DurationToFrames(%v) = %d, want %d", tt.d, got, tt.frames)
+		}
+	}
+
+	// 不足一帧的部分舍去，负的时长为 0
+	if got := c.DurationToFrames(20 * time.Microsecond); got != 0 {
+		t.Errorf("DurationToFrames(20µs) = %d, want 0", got)
+	}
+	if got := c.DurationToFrames(-time.Second); got != 0 {
+		t.Errorf("DurationToFrames(-1s) = %d, want 0", got)
+	}
+	if got := c.DurationToFrames(math.MinInt64); got != 0 {
+		t.Errorf("DurationToFrames(min) = %d, want 0", got)
+	}
+
+	// IAudioClock 的频率通常为字节率或 QPC 频率
+	if got := c.ClockPositionToFrames(192000*3, 192000); got != 48000*3 {
+		t.Errorf("ClockPositionToFrames in bytes = %d", got)
+	}
+	if got := c.ClockPositionToFrames(10000000, 10000000); got != 48000 {
+		t.Errorf("ClockPositionToFrames in 100ns = %d", got)
+	}
+	if got := c.ClockPositionToFrames(123, 0); got != 0 {
+		t.Errorf("ClockPositionToFrames with zero frequency = %d", got)
+	}
+
+	// 采样率为 0 时不除以 0
+	zero := NewStreamClock(0)
+	if got := zero.FramesToDuration(100); got != 0 {
+		t.Errorf("zero rate: FramesToDuration = %v", got)
+	}
+	if got := zero.DurationToFrames(time.Second); got != 0 {
+		t.Errorf("zero rate: DurationToFrames = %d", got)
+	}
+	if _, ok := zero.Drift(); ok {
+		t.Error("zero rate: Drift ok without packets")
+	}
+}
+
+func TestDrift(t *testing.T) {
+	const ppm = 50
+	c := NewStreamClock(48000)
+
+	if _, ok := c.Rate(); ok {
+		t.Fatal("Rate ok without packets")
+	}
+
+	// 设备时钟偏快 50 ppm，QPC 读数带有 ±20 µs 的抖动
+	rate := 48000 * (1 + ppm/1e6)
+	start := uint64(123456789012)
+	for i := 0; i < 500; i++ {
+		frame := uint64(i) * 480
+		jitter := float64(i%5-2) * 100
+		qpc := start + uint64(float64(frame)/rate*hnsPerSecond+jitter)
+		c.AddPacket(frame, qpc)
+	}
+
+	if drift, ok := c.Drift(); !ok || math.Abs(drift-ppm) > 1 {
+		t.Fatalf("Drift = %.3f ppm, %v, want %d", drift, ok, ppm)
+	}
+
+	// 位置与 QPC 相互换算
+	qpc, ok := c.QPCAt(480 * 499)
+	if want := start + uint64(480*499/rate*hnsPerSecond); !ok || math.Abs(float64(qpc)-float64(want)) > 100 {
+		t.Fatalf("QPCAt = %d, %v, want %d", qpc, ok, want)
+	}
+	if frame, ok := c.FrameAt(qpc); !ok || math.Abs(frame-480*499) > 0.01 {
+		t.Fatalf("FrameAt = %.3f, %v", frame, ok)
+	}
+
+	c.Reset()
+	if _, ok := c.Rate(); ok {
+		t.Fatal("Rate ok after Reset")
+	}
+}
+
+func TestTime(t *testing.T) {
+	c := NewStreamClock(48000)
+
+	if _, ok := c.Time(0); ok {
+		t.Fatal("Time ok without reference")
+	}
+
+	// 挂钟时间参考取平均值
+	ref := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	qpc := uint64(5 * hnsPerSecond)
+	c.AddTimeReference(qpc, ref.Add(-time.Microsecond))
+	c.AddTimeReference(qpc+hnsPerSecond, ref.Add(time.Second+time.Microsecond))
+
+	if got, ok := c.Time(qpc + hnsPerSecond/2); !ok || !got.Equal(ref.Add(500*time.Millisecond)) {
+		t.Fatalf("Time = %v, %v, want %v", got, ok, ref.Add(500*time.Millisecond))
+	}
+
+	// 设备位置经 QPC 换算为挂钟时间
+	c.AddPacket(0, qpc)
+	c.AddPacket(48000, qpc+hnsPerSecond)
+	if got, ok := c.FrameTime(24000); !ok || got.Sub(ref.Add(500*time.Millisecond)).Abs() > time.Microsecond {
+		t.Fatalf("FrameTime = %v, %v", got, ok)
+	}
+
+	// 超出窗口的参考被丢弃
+	c.SetWindow(1)
+	c.AddTimeReference(qpc, ref.Add(time.Hour))
+	if got, _ := c.Time(qpc); !got.Equal(ref.Add(time.Hour)) {
+		t.Fatalf("Time after window = %v", got)
+	}
+}
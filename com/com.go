@@ -1,3 +1,5 @@
+//go:build windows
+
 package com
 
 import (
@@ -14,6 +16,7 @@ var (
 	procCoInitializeEx   = modole32.NewProc("CoInitializeEx")
 	procCoUninitialize   = modole32.NewProc("CoUninitialize")
 	procCoTaskMemFree    = modole32.NewProc("CoTaskMemFree")
+	procPropVariantClear = modole32.NewProc("PropVariantClear")
 )
 
 type HRESULT = uint32
@@ -65,3 +68,15 @@ func CoCreateInstance(
 
 	return
 }
+
+// 释放 PROPVARIANT 中的所有元素并将其类型设置为 VT_EMPTY。
+func PropVariantClear(propVar *PROPVARIANT) (err error) {
+	r, _, _ := syscall.SyscallN(procPropVariantClear.Addr(), uintptr(unsafe.Pointer(propVar)))
+
+	if HRESULT(r) != HRESULT(windows.S_OK) {
+		err = fmt.Errorf("com::PropVariantClear failed with code: 0x%08X", HRESULT(r))
+		return
+	}
+
+	return
+}
@@ -1,3 +1,5 @@
+//go:build windows
+
 package com
 
 import "golang.org/x/sys/windows"
@@ -28,3 +30,14 @@ func PKEY_Device_InstanceId() PROPERTYKEY {
 func PKEY_Device_ContainerId() PROPERTYKEY {
 	return _PKEY_Device_ContainerId
 }
+
+//
+// Audio endpoint properties
+//
+var (
+	_PKEY_AudioEndpoint_FormFactor = PROPERTYKEY{windows.GUID{Data1: 0x1da5d803, Data2: 0xd492, Data3: 0x4edd, Data4: [8]byte{0x8c, 0x23, 0xe0, 0xc0, 0xff, 0xee, 0x7f, 0x0e}}, 0}
+)
+
+func PKEY_AudioEndpoint_FormFactor() PROPERTYKEY {
+	return _PKEY_AudioEndpoint_FormFactor
+}
@@ -1,3 +1,5 @@
+//go:build windows
+
 package com
 
 import (
@@ -6,6 +8,13 @@ import (
 	"golang.org/x/sys/windows"
 )
 
+const (
+	VT_EMPTY  = 0
+	VT_UI4    = 19
+	VT_LPWSTR = 31
+	VT_CLSID  = 72
+)
+
 type PROPVARIANT struct {
 	Vt         uint16 // Value type tag.
 	WReserved1 uint16
@@ -22,3 +31,11 @@ func (propvar *PROPVARIANT) PwszVal() *uint16 {
 func (propvar *PROPVARIANT) PwszValString() string {
 	return windows.UTF16PtrToString(propvar.PwszVal())
 }
+
+func (propvar *PROPVARIANT) UlVal() uint32 {
+	return *(*uint32)(unsafe.Pointer(&propvar.Val))
+}
+
+func (propvar *PROPVARIANT) PuuidVal() *windows.GUID {
+	return *(**windows.GUID)(unsafe.Pointer(&propvar.Val))
+}
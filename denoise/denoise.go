@@ -0,0 +1,271 @@
+package denoise
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/cyberxnomad/wasapi/audioclient"
+	"github.com/cyberxnomad/wasapi/fft"
+	"github.com/cyberxnomad/wasapi/internal/dsp"
+)
+
+const (
+	DefaultStrength = 0.7                   // 默认的降噪强度
+	MaxAttenuation  = 30.0                  // 强度为 1 时的最大衰减 (dB)
+	MaxOverSubtract = 4.0                   // 强度为 1 时的过减因子
+	frameDuration   = 16 * time.Millisecond // 分析帧长的下限
+	priorSmoothing  = 0.9                   // 判决引导法的平滑系数
+	noiseSmoothing  = 0.8                   // 噪声估计的平滑系数
+	speechSNR       = 31.6                  // 语音存在假设下的先验信噪比 (15 dB)
+	warmupDuration  = 50 * time.Millisecond // 初始阶段直接采用平均功率作为噪声估计的时长
+)
+
+// 单个声道的状态
+type channel struct {
+	input  []float64 // 最近一帧的输入
+	acc    []float64 // 重叠相加的累加器
+	output []float64 // 已完成的一跳输出
+	noise  []float64 // 噪声功率估计
+	spp    []float64 // 平滑后的语音存在概率
+	gain   []float64 // 上一帧的增益
+	post   []float64 // 上一帧的后验信噪比
+	frames int
+}
+
+// Suppressor 是基于维纳滤波的降噪器，以语音存在概率 (MMSE-SPP) 跟踪各频点的噪声功率。
+//
+// 每个声道独立处理，采用 50% 重叠、平方根 Hann 窗的短时傅里叶变换，
+// 输出相对输入延迟 Latency 帧。
+type Suppressor struct {
+	mu sync.Mutex
+
+	format    audioclient.WAVEFORMATEXTENSIBLE
+	channels  int
+	size      int
+	hop       int
+	window    []float64
+	transform *fft.FFT
+	spectrum  []complex128
+	strength  float64
+	floor     float64
+	over      float64
+	warmup    int
+	state     []channel
+	fill      int
+
+	buf []float32
+}
+
+// NewSuppressor 创建降噪器，帧长随采样率选取为不小于 16 毫秒的 2 的幂。
+func NewSuppressor(format *audioclient.WAVEFORMATEXTENSIBLE) *Suppressor {
+	rate := float64(format.Format.SamplesPerSec)
+	size := fft.NextPow2(int(frameDuration.Seconds() * rate))
+	hop := size / 2
+	transform, _ := fft.New(size)
+
+	// 平方根周期 Hann 窗，分析与合成各用一次，50% 重叠时恰好恒等
+	window := make([]float64, size)
+	for i := range window {
+		window[i] = math.Sqrt(0.5 - 0.5*math.Cos(2*math.Pi*float64(i)/float64(size)))
+	}
+
+	s := &Suppressor{
+		format:    *format,
+		channels:  int(format.Format.Channels),
+		size:      size,
+		hop:       hop,
+		window:    window,
+		transform: transform,
+		spectrum:  make([]complex128, size),
+		warmup:    int(warmupDuration.Seconds() * rate / float64(hop)),
+		state:     make([]channel, format.Format.Channels),
+	}
+
+	for ch := range s.state {
+		bins := size/2 + 1
+		s.state[ch] = channel{
+			input:  make([]float64, size),
+			acc:    make([]float64, size),
+			output: make([]float64, hop),
+			noise:  make([]float64, bins),
+			spp:    make([]float64, bins),
+			gain:   make([]float64, bins),
+			post:   make([]float64, bins),
+		}
+	}
+
+	s.setStrength(DefaultStrength)
+
+	return s
+}
+
+// Latency 方法返回降噪器引入的延迟 (帧)。
+func (s *Suppressor) Latency() int {
+	return s.size
+}
+
+// SetStrength 方法设置降噪强度，范围为 [0, 1]，0 为不处理；强度同时决定过减因子 (1 至 MaxOverSubtract)
+// 与增益下限，1 时噪声最多衰减 MaxAttenuation。
+func (s *Suppressor) SetStrength(strength float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.setStrength(strength)
+}
+
+func (s *Suppressor) setStrength(strength float64) {
+	s.strength = min(max(strength, 0), 1)
+	s.floor = math.Pow(10, -s.strength*MaxAttenuation/20)
+	s.over = 1 + s.strength*(MaxOverSubtract-1)
+}
+
+// Strength 方法返回降噪强度。
+func (s *Suppressor) Strength() float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.strength
+}
+
+// NoiseFloor 方法返回指定声道当前估计的噪声电平 (dBFS)。
+func (s *Suppressor) NoiseFloor(channel int) float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if channel < 0 || channel >= s.channels {
+		return math.Inf(-1)
+	}
+
+	// 由功率谱还原时域均方值: Parseval 定理，并扣除窗的能量
+	var sum, energy float64
+	for k, v := range s.state[channel].noise {
+		if k == 0 || k == len(s.state[channel].noise)-1 {
+			sum += v
+		} else {
+			sum += 2 * v
+		}
+	}
+	for _, w := range s.window {
+		energy += w * w
+	}
+
+	return 10 * math.Log10(sum/(float64(s.size)*energy)+1e-30)
+}
+
+// Process 方法原地处理交错的 float32 采样，输出延迟 Latency 帧。
+func (s *Suppressor) Process(samples []float32) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.process(samples)
+}
+
+// ProcessBytes 方法按格式解码、处理后原地编码音频数据。
+func (s *Suppressor) ProcessBytes(data []byte) (err error) {
+	return dsp.ProcessBytes(&s.mu, &s.format, &s.buf, data, true, s.process)
+}
+
+func (s *Suppressor) process(samples []float32) {
+	for i := 0; i+s.channels <= len(samples); i += s.channels {
+		frame := samples[i : i+s.channels]
+
+		for ch := range frame {
+			st := &s.state[ch]
+			v := frame[ch]
+			frame[ch] = float32(st.output[s.fill])
+			st.input[s.size-s.hop+s.fill] = float64(v)
+		}
+
+		if s.fill++; s.fill == s.hop {
+			for ch := range s.state {
+				s.analyze(&s.state[ch])
+			}
+			s.fill = 0
+		}
+	}
+}
+
+// Reset 方法清除缓冲及噪声估计。
+func (s *Suppressor) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for ch := range s.state {
+		st := &s.state[ch]
+		clear(st.input)
+		clear(st.acc)
+		clear(st.output)
+		clear(st.noise)
+		clear(st.spp)
+		clear(st.gain)
+		clear(st.post)
+		st.frames = 0
+	}
+
+	s.fill = 0
+}
+
+// 处理一帧，输出完成的一跳
+func (s *Suppressor) analyze(st *channel) {
+	n, hop := s.size, s.hop
+	x := s.spectrum
+
+	for i := range x {
+		x[i] = complex(st.input[i]*s.window[i], 0)
+	}
+	s.transform.Transform(x)
+
+	st.frames++
+	for k := range st.noise {
+		p := real(x[k])*real(x[k]) + imag(x[k])*imag(x[k])
+
+		// 噪声估计: 起始阶段取平均，之后按语音存在概率加权更新
+		var spp float64
+		if st.frames <= s.warmup {
+			st.noise[k] += (p - st.noise[k]) / float64(st.frames)
+		} else {
+			spp = 1 / (1 + (1+speechSNR)*math.Exp(-p/(st.noise[k]+1e-20)*speechSNR/(1+speechSNR)))
+
+			// 长时间判为语音时限制概率，避免噪声估计停滞
+			st.spp[k] = 0.9*st.spp[k] + 0.1*spp
+			if st.spp[k] > 0.99 {
+				spp = min(spp, 0.99)
+			}
+
+			estimate := spp*st.noise[k] + (1-spp)*p
+			st.noise[k] = noiseSmoothing*st.noise[k] + (1-noiseSmoothing)*estimate
+		}
+
+		// 判决引导法估计先验信噪比，参数化维纳增益；过减因子随强度增大，
+		// 并按语音不存在的概率施加，语音频点保持接近维纳增益
+		noise := st.noise[k] + 1e-20
+		post := p / noise
+		prio := priorSmoothing*st.gain[k]*st.gain[k]*st.post[k] + (1-priorSmoothing)*max(post-1, 0)
+		if st.frames == 1 {
+			prio = max(post-1, 0)
+		}
+
+		over := 1 + (s.over-1)*(1-spp)
+		g := max(prio/(over+prio), s.floor)
+		st.gain[k] = g
+		st.post[k] = post
+
+		x[k] *= complex(g, 0)
+		if k > 0 && k < n/2 {
+			x[n-k] *= complex(g, 0)
+		}
+	}
+
+	s.transform.Inverse(x)
+
+	// 加合成窗后重叠相加
+	for i := range st.acc {
+		st.acc[i] += real(x[i]) * s.window[i]
+	}
+
+	copy(st.output, st.acc[:hop])
+	copy(st.acc, st.acc[hop:])
+	clear(st.acc[n-hop:])
+	copy(st.input, st.input[hop:])
+}
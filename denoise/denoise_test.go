@@ -0,0 +1,181 @@
+package denoise
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/cyberxnomad/wasapi/audioclient"
+	"github.com/cyberxnomad/wasapi/biquad"
+	"github.com/cyberxnomad/wasapi/generator"
+	"github.com/cyberxnomad/wasapi/pcm"
+)
+
+// 合成语音 (带音节包络、基频滑动的谐波信号)，每 250 毫秒一个音节，音节间有停顿
+func speech(rate float64, n int) []float32 {
+	out := make([]float32, n)
+	var phase float64
+
+	for i := range out {
+		t := float64(i) / rate
+		syllable := math.Mod(t, 0.25) / 0.25
+		env := 0.0
+		if syllable < 0.7 && math.Mod(t, 2) < 1.5 {
+			env = math.Sin(math.Pi * syllable / 0.7)
+		}
+
+		f0 := 140 + 30*math.Sin(2*math.Pi*0.7*t)
+		phase += 2 * math.Pi * f0 / rate
+
+		var v float64
+		for h := 1; h <= 20 && float64(h)*f0 < rate/2; h++ {
+			// 共振峰附近的谐波较强
+			formant := math.Exp(-math.Pow(float64(h)*f0-700, 2)/(2*300*300)) + 0.5*math.Exp(-math.Pow(float64(h)*f0-1800, 2)/(2*400*400))
+			v += (0.1 + formant) / float64(h) * math.Sin(float64(h)*phase)
+		}
+
+		out[i] = float32(0.15 * env * v)
+	}
+
+	return out
+}
+
+// 风扇噪声 (粉红噪声与叶片谐音)，滤除麦克风通路中本应由高通滤波器去除的次声成分
+func fan(rate float64, n int, level float64) []float32 {
+	noise := generator.NewNoise(generator.NoisePink, level, 7)
+	r := rand.New(rand.NewSource(3))
+	out := make([]float32, n)
+	var phase float64
+
+	for i := range out {
+		phase += 2 * math.Pi * (180 + r.Float64()) / rate
+		out[i] = noise.Next() + float32(level*0.2*math.Sin(phase))
+	}
+
+	highpass := biquad.NewFilter(biquad.Params{Type: biquad.HighPass, Frequency: 60, Q: math.Sqrt2 / 2}, uint32(rate), 1)
+	highpass.Process(out)
+
+	return out
+}
+
+// 按 10 毫秒一个数据包处理单声道信号
+func suppress(rate uint32, strength float64, in []float32) (out []float32, latency int) {
+	format := pcm.NewFormat(pcm.SampleTypeFloat, rate, 1, 32, audioclient.KSAUDIO_SPEAKER_MONO)
+	suppressor := NewSuppressor(&format)
+	suppressor.SetStrength(strength)
+
+	out = append([]float32(nil), in...)
+	packet := int(rate) / 100
+	for i := 0; i < len(out); i += packet {
+		suppressor.Process(out[i:min(i+packet, len(out))])
+	}
+
+	return out, suppressor.Latency()
+}
+
+func snr(clean, signal []float32, delay, skip int) float64 {
+	var s, e float64
+	for i := skip; i+delay < len(signal); i++ {
+		d := float64(signal[i+delay] - clean[i])
+		s += float64(clean[i]) * float64(clean[i])
+		e += d * d
+	}
+
+	return 10 * math.Log10(s/e)
+}
+
+func power(samples []float32) (p float64) {
+	for _, v := range samples {
+		p += float64(v) * float64(v)
+	}
+
+	return p / float64(len(samples))
+}
+
+func TestSNRImprovement(t *testing.T) {
+	const seconds = 8
+
+	for _, rate := range []uint32{16000, 44100, 48000} {
+		for _, level := range []float64{0.02, 0.05} {
+			n := int(rate) * seconds
+			clean := speech(float64(rate), n)
+			noise := fan(float64(rate), n, level)
+
+			noisy := make([]float32, n)
+			for i := range noisy {
+				noisy[i] = clean[i] + noise[i]
+			}
+			before := snr(clean, noisy, 0, int(rate))
+
+			prev := math.Inf(-1)
+			for _, strength := range []float64{0.5, 0.7, 1} {
+				out, latency := suppress(rate, strength, noisy)
+				after := snr(clean, out, latency, int(rate))
+				t.Logf("%5d Hz  noise %.2f  strength %.1f  SNR %6.2f -> %6.2f dB (%+.2f dB)",
+					rate, level, strength, before, after, after-before)
+
+				if after-before < 2 {
+					t.Errorf("%d Hz noise %.2f strength %.1f: SNR improved by %.2f dB, want >= 2 dB",
+						rate, level, strength, after-before)
+				}
+				// 强度增大时以少量语音失真换取更多噪声衰减，信噪比不应明显下降
+				if after < prev-1 {
+					t.Errorf("%d Hz noise %.2f strength %.1f: SNR %.2f dB fell more than 1 dB below %.2f dB at lower strength",
+						rate, level, strength, after, prev)
+				}
+				prev = after
+			}
+		}
+	}
+}
+
+func TestStrengthAttenuation(t *testing.T) {
+	const rate = 16000
+	noise := fan(rate, rate*4, 0.05)
+
+	// 仅有噪声时，衰减随强度单调增加且差别明显
+	prev := 0.0
+	for _, strength := range []float64{0, 0.25, 0.5, 0.75, 1} {
+		out, latency := suppress(rate, strength, noise)
+		attenuation := 10 * math.Log10(power(noise[rate:len(noise)-latency])/power(out[rate+latency:]))
+		t.Logf("strength %.2f  attenuation %.2f dB", strength, attenuation)
+
+		switch {
+		case strength == 0 && math.Abs(attenuation) > 0.01:
+			t.Errorf("strength 0 attenuated noise by %.2f dB", attenuation)
+		case strength > 0 && attenuation < prev+1.5:
+			t.Errorf("strength %.2f: attenuation %.2f dB, want at least 1.5 dB more than %.2f dB", strength, attenuation, prev)
+		}
+		prev = attenuation
+	}
+
+	if prev < 18 {
+		t.Errorf("strength 1: attenuation %.2f dB, want >= 18 dB", prev)
+	}
+}
+
+func TestStrengthZeroPassthrough(t *testing.T) {
+	format := pcm.NewFormat(pcm.SampleTypeFloat, 48000, 2, 32, audioclient.KSAUDIO_SPEAKER_STEREO)
+	suppressor := NewSuppressor(&format)
+	suppressor.SetStrength(0)
+
+	if suppressor.Strength() != 0 {
+		t.Fatalf("Strength = %v", suppressor.Strength())
+	}
+
+	r := rand.New(rand.NewSource(1))
+	in := make([]float32, 2*48000/2)
+	for i := range in {
+		in[i] = float32(r.NormFloat64() * 0.1)
+	}
+
+	out := append([]float32(nil), in...)
+	suppressor.Process(out)
+
+	latency := suppressor.Latency()
+	for i := 0; i < len(in)-2*latency; i++ {
+		if d := math.Abs(float64(out[i+2*latency] - in[i])); d > 1e-5 {
+			t.Fatalf("sample %d = %v, want %v", i, out[i+2*latency], in[i])
+		}
+	}
+}
@@ -0,0 +1,217 @@
+package drift
+
+import (
+	"errors"
+	"math"
+	"sync"
+
+	"github.com/cyberxnomad/wasapi/resample"
+)
+
+var ErrRateMismatch = errors.New("sample rate mismatch")
+
+const (
+	DefaultKp            = 0.05  // 默认比例增益 (每秒误差)
+	DefaultKi            = 0.002 // 默认积分增益
+	DefaultMaxCorrection = 0.002 // 默认最大校正量 (2000 ppm)
+)
+
+// Stream 表示通过 RATEADJUST 调整采样率的音频流。
+type Stream uint32
+
+const (
+	CaptureStream Stream = iota // 调整捕获流，提高采样率使其产生更多的帧
+	RenderStream                // 调整呈现流，提高采样率使其消耗更多的帧
+)
+
+// RateAdjuster 用于调整音频流的采样率，由 audioclient.IAudioClockAdjustment 实现。
+type RateAdjuster interface {
+	SetSampleRate(samplesPerSec float32) error
+}
+
+// Stats 为漂移补偿器的统计信息。
+type Stats struct {
+	Level      float64 // 平滑后的缓冲帧数
+	Target     int     // 目标缓冲帧数
+	Correction float64 // 当前的校正量 (ppm)
+	Underruns  uint64  // 欠载时补零的帧数
+	Overruns   uint64  // 溢出时丢弃的帧数
+}
+
+// Compensator 在捕获设备与呈现设备之间补偿时钟漂移。
+//
+// 捕获线程调用 Write 写入音频，呈现线程调用 Read 读取音频。补偿器测量中间缓冲区的
+// 填充程度，由 PI 控制器驱动可变比率重采样器，使缓冲区维持在目标深度。
+// 若音频流以 AUDCLNT_STREAMFLAGS_RATEADJUST 初始化，可调用 UseRateAdjust
+// 改为通过 IAudioClockAdjustment 调整设备采样率。
+type Compensator struct {
+	mu sync.Mutex
+
+	channels int
+	inRate   float64
+	outRate  float64
+	target   int
+	capacity int
+
+	fifo       []float32
+	resampler  *resample.Resampler
+	resampled  []float32
+	controller *Controller
+
+	adjuster    RateAdjuster
+	stream      Stream
+	nominalRate float64
+
+	started    bool
+	level      float64
+	correction float64
+	stats      Stats
+}
+
+// NewCompensator 创建漂移补偿器，inRate 及 outRate 为捕获及呈现的采样率，
+// targetFrames 为中间缓冲区的目标深度 (以呈现采样率计)。
+func NewCompensator(channels int, inRate, outRate uint32, targetFrames int) *Compensator {
+	return &Compensator{
+		channels:   channels,
+		inRate:     float64(inRate),
+		outRate:    float64(outRate),
+		target:     targetFrames,
+		capacity:   4 * targetFrames,
+		resampler:  resample.New(channels, inRate, outRate),
+		controller: NewController(DefaultKp, DefaultKi, DefaultMaxCorrection),
+	}
+}
+
+// Controller 方法返回 PI 控制器，可用于调整增益。
+func (c *Compensator) Controller() *Controller {
+	return c.controller
+}
+
+// UseRateAdjust 方法改为通过 RATEADJUST 调整 stream 对应音频流的采样率，不再重采样。
+//
+// 此时捕获及呈现的采样率必须相同。
+func (c *Compensator) UseRateAdjust(adjuster RateAdjuster, stream Stream) (err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.inRate != c.outRate {
+		err = ErrRateMismatch
+		return
+	}
+
+	c.adjuster = adjuster
+	c.stream = stream
+	c.nominalRate = c.outRate
+
+	return
+}
+
+// Write 方法写入捕获的交错采样。
+func (c *Compensator) Write(samples []float32) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.adjuster == nil {
+		c.resampled = c.resampler.Process(c.resampled[:0], samples)
+		samples = c.resampled
+	}
+
+	c.fifo = append(c.fifo, samples...)
+
+	// 溢出时丢弃最旧的帧，回到目标深度
+	if frames := len(c.fifo) / c.channels; frames > c.capacity {
+		drop := frames - c.target
+		n := copy(c.fifo, c.fifo[drop*c.channels:])
+		c.fifo = c.fifo[:n]
+		c.level = float64(c.target)
+		c.stats.Overruns += uint64(drop)
+	}
+}
+
+// Read 方法读取用于呈现的交错采样写入 dst，缓冲不足时补零。
+//
+// 返回补零的帧数。
+func (c *Compensator) Read(dst []float32) (underrun int, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	frames := len(dst) / c.channels
+	level := len(c.fifo) / c.channels
+
+	// 首次达到目标深度前输出静音
+	if !c.started {
+		if level < c.target {
+			clear(dst)
+			return
+		}
+
+		c.started = true
+		c.level = float64(level)
+	}
+
+	n := min(frames, level)
+	copy(dst, c.fifo[:n*c.channels])
+	rest := copy(c.fifo, c.fifo[n*c.channels:])
+	c.fifo = c.fifo[:rest]
+
+	if n < frames {
+		clear(dst[n*c.channels:])
+		underrun = frames - n
+		c.stats.Underruns += uint64(underrun)
+	}
+
+	err = c.update(float64(level), float64(frames)/c.outRate)
+
+	return
+}
+
+// Stats 方法返回统计信息。
+func (c *Compensator) Stats() (stats Stats) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stats = c.stats
+	stats.Level = c.level
+	stats.Target = c.target
+	stats.Correction = c.correction * 1e6
+
+	return
+}
+
+// Reset 方法清空缓冲区并重置控制器。
+func (c *Compensator) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.fifo = c.fifo[:0]
+	c.resampler.Reset()
+	c.resampler.SetRatio(c.outRate / c.inRate)
+	c.controller.Reset()
+	c.started = false
+	c.level = 0
+	c.correction = 0
+}
+
+// 根据读取前的缓冲深度更新校正量
+func (c *Compensator) update(level, dt float64) (err error) {
+	// 缓冲深度随数据包的粒度波动，先做约 1 秒时间常数的平滑
+	c.level += (level - c.level) * (1 - math.Exp(-dt))
+
+	// 缓冲不足时校正量为正，使捕获侧产生更多的帧
+	e := (float64(c.target) - c.level) / c.outRate
+	c.correction = c.controller.Update(e, dt)
+
+	if c.adjuster == nil {
+		c.resampler.SetRatio(c.outRate / c.inRate * (1 + c.correction))
+		return
+	}
+
+	rate := c.nominalRate * (1 + c.correction)
+	if c.stream == RenderStream {
+		rate = c.nominalRate * (1 - c.correction)
+	}
+
+	err = c.adjuster.SetSampleRate(float32(rate))
+
+	return
+}
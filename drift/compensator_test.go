@@ -0,0 +1,106 @@
+package drift
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+// 捕获设备相对呈现设备快 drift (比率) 时，以 10 ms 为周期模拟 seconds 秒。
+// rate 返回捕获设备当前的标称采样率。
+func simulate(c *Compensator, rate func() float64, drift float64, seconds int) {
+	const period = 0.01
+
+	var acc float64
+	in := make([]float32, 0, 1024)
+	out := make([]float32, 480)
+
+	for i := 0; i < seconds*100; i++ {
+		acc += rate() * (1 + drift) * period
+		n := int(acc)
+		acc -= float64(n)
+
+		in = in[:n]
+		for j := range in {
+			in[j] = 0.1
+		}
+		c.Write(in)
+
+		if _, err := c.Read(out); err != nil {
+			panic(err)
+		}
+	}
+}
+
+func checkConverged(t *testing.T, c *Compensator, drift float64) {
+	t.Helper()
+
+	// 校正量抵消漂移，缓冲维持在目标深度附近
+	stats := c.Stats()
+	want := -drift / (1 + drift) * 1e6
+	if math.Abs(stats.Correction-want) > 10 {
+		t.Errorf("correction %.1f ppm, want %.1f ppm", stats.Correction, want)
+	}
+	if math.Abs(stats.Level-float64(stats.Target)) > 100 {
+		t.Errorf("level %.1f frames, want %d", stats.Level, stats.Target)
+	}
+	if stats.Underruns != 0 || stats.Overruns != 0 {
+		t.Errorf("%d frames underrun, %d frames overrun", stats.Underruns, stats.Overruns)
+	}
+}
+
+func TestResamplerConvergence(t *testing.T) {
+	for _, drift := range []float64{200e-6, -200e-6, 1000e-6} {
+		c := NewCompensator(1, 48000, 48000, 960)
+		simulate(c, func() float64 { return 48000 }, drift, 300)
+		checkConverged(t, c, drift)
+	}
+}
+
+type adjuster struct {
+	rate float64
+}
+
+func (a *adjuster) SetSampleRate(samplesPerSec float32) error {
+	a.rate = float64(samplesPerSec)
+	return nil
+}
+
+func TestRateAdjustConvergence(t *testing.T) {
+	for _, drift := range []float64{200e-6, -500e-6} {
+		c := NewCompensator(1, 48000, 48000, 960)
+		a := &adjuster{rate: 48000}
+		if err := c.UseRateAdjust(a, CaptureStream); err != nil {
+			t.Fatal(err)
+		}
+
+		simulate(c, func() float64 { return a.rate }, drift, 300)
+		checkConverged(t, c, drift)
+	}
+}
+
+func TestRateAdjustMismatch(t *testing.T) {
+	c := NewCompensator(2, 44100, 48000, 960)
+	if err := c.UseRateAdjust(&adjuster{}, RenderStream); !errors.Is(err, ErrRateMismatch) {
+		t.Errorf("got %v, want %v", err, ErrRateMismatch)
+	}
+}
+
+func TestControllerAntiWindup(t *testing.T) {
+	c := NewController(1, 1, 0.01)
+
+	// 长时间饱和后积分项不累积，误差反向时输出立即离开饱和
+	for i := 0; i < 1000; i++ {
+		if out := c.Update(1, 0.01); out != 0.01 {
+			t.Fatalf("output %v, want 0.01", out)
+		}
+	}
+	if out := c.Update(-0.001, 0.01); out > 0 {
+		t.Errorf("output %v after reversal, want <= 0", out)
+	}
+
+	c.Reset()
+	if out := c.Update(0.001, 1); math.Abs(out-0.002) > 1e-12 {
+		t.Errorf("output %v after Reset, want 0.002", out)
+	}
+}
@@ -0,0 +1,38 @@
+package drift
+
+// Controller 是带积分限幅的 PI 控制器。
+type Controller struct {
+	Kp            float64 // 比例增益
+	Ki            float64 // 积分增益
+	MaxCorrection float64 // 输出的最大绝对值
+
+	integral float64
+}
+
+// NewController 创建 PI 控制器。
+func NewController(kp, ki, maxCorrection float64) *Controller {
+	return &Controller{Kp: kp, Ki: ki, MaxCorrection: maxCorrection}
+}
+
+// Update 方法根据误差 e 及距上次更新的时间 dt (秒) 计算控制量。
+func (c *Controller) Update(e, dt float64) (output float64) {
+	integral := c.integral + e*dt
+	output = c.Kp*e + c.Ki*integral
+
+	// 输出饱和时停止积分，避免积分饱和
+	switch {
+	case output > c.MaxCorrection:
+		output = c.MaxCorrection
+	case output < -c.MaxCorrection:
+		output = -c.MaxCorrection
+	default:
+		c.integral = integral
+	}
+
+	return
+}
+
+// Reset 方法清除积分项。
+func (c *Controller) Reset() {
+	c.integral = 0
+}
@@ -0,0 +1,152 @@
+package dynamics
+
+import (
+	"sync"
+	"time"
+
+	"github.com/cyberxnomad/wasapi/audioclient"
+	"github.com/cyberxnomad/wasapi/internal/dsp"
+	"github.com/cyberxnomad/wasapi/loudness"
+)
+
+// AGCParams 为自动增益控制参数。
+type AGCParams struct {
+	Target  float64 // 目标响度 (LUFS)
+	MaxGain float64 // 最大提升 (dB)
+	MinGain float64 // 最大衰减 (dB，负值)
+	Rate    float64 // 增益的最大变化速率 (dB/秒)
+	Gate    float64 // 低于此短期响度 (LUFS) 时保持增益不变，避免放大静音及底噪
+	Ceiling float64 // 输出限幅器的上限 (dBFS)
+}
+
+// DefaultAGC 为默认的自动增益控制参数。
+var DefaultAGC = AGCParams{
+	Target:  -23,
+	MaxGain: 20,
+	MinGain: -20,
+	Rate:    3,
+	Gate:    -50,
+	Ceiling: DefaultCeiling,
+}
+
+// agcChunk 为增益更新的间隔
+const agcChunk = 10 * time.Millisecond
+
+// AGC 是以响度为目标的自动增益控制。
+//
+// 按 BS.1770 测量输入的短期响度 (3 秒窗口)，以受限的速率调整增益使输出接近目标响度，
+// 最后经过预读限幅器防止削波。所有声道共用同一增益。
+type AGC struct {
+	mu sync.Mutex
+
+	format   audioclient.WAVEFORMATEXTENSIBLE
+	channels int
+	params   AGCParams
+	meter    *loudness.Meter
+	limiter  *Limiter
+	chunk    int
+	gain     float64 // 当前增益 (dB)
+
+	buf []float32
+}
+
+// NewAGC 创建自动增益控制。
+func NewAGC(format *audioclient.WAVEFORMATEXTENSIBLE, params AGCParams) *AGC {
+	a := &AGC{
+		format:   *format,
+		channels: int(format.Format.Channels),
+		meter:    loudness.NewMeter(format),
+		chunk:    max(int(agcChunk.Seconds()*float64(format.Format.SamplesPerSec)), 1),
+	}
+
+	a.meter.SetHistory(false)
+	a.SetParams(params)
+
+	return a
+}
+
+// SetParams 方法设置参数，可在处理过程中调用。
+func (a *AGC) SetParams(params AGCParams) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.params = params
+
+	// 处理过程中修改参数时保留限幅器的延迟线，避免咔嗒声
+	if a.limiter == nil {
+		a.limiter = NewLimiter(&a.format, params.Ceiling, DefaultLookahead, DefaultRelease, LinkAll)
+	} else {
+		a.limiter.SetCeiling(params.Ceiling)
+	}
+}
+
+// Gain 方法返回当前增益 (dB)。
+func (a *AGC) Gain() float64 {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return a.gain
+}
+
+// Latency 方法返回输出限幅器引入的延迟 (帧)。
+func (a *AGC) Latency() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return a.limiter.Latency()
+}
+
+// Process 方法原地处理交错的 float32 采样。
+func (a *AGC) Process(samples []float32) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.process(samples)
+}
+
+// ProcessBytes 方法按格式解码、处理后原地编码音频数据。
+func (a *AGC) ProcessBytes(data []byte) (err error) {
+	return dsp.ProcessBytes(&a.mu, &a.format, &a.buf, data, true, a.process)
+}
+
+func (a *AGC) process(samples []float32) {
+	step := a.chunk * a.channels
+	for i := 0; i < len(samples); i += step {
+		chunk := samples[i:min(i+step, len(samples))]
+		frames := len(chunk) / a.channels
+
+		a.meter.Process(chunk)
+
+		// 根据测量的短期响度计算目标增益，并限制变化速率
+		target := a.gain
+		if measured := a.meter.ShortTerm(); measured > a.params.Gate {
+			target = min(max(a.params.Target-measured, a.params.MinGain), a.params.MaxGain)
+		}
+
+		limit := a.params.Rate * float64(frames) / float64(a.format.Format.SamplesPerSec)
+		next := a.gain + min(max(target-a.gain, -limit), limit)
+
+		// 块内线性过渡，避免增益阶跃
+		from, to := dbToLinear(a.gain), dbToLinear(next)
+		for f := 0; f < frames; f++ {
+			g := float32(from + (to-from)*float64(f+1)/float64(frames))
+			for c := 0; c < a.channels; c++ {
+				chunk[f*a.channels+c] *= g
+			}
+		}
+
+		a.gain = next
+	}
+
+	a.limiter.Process(samples)
+}
+
+// Reset 方法清除测量及增益状态。
+func (a *AGC) Reset() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.meter.Reset()
+	a.gain = 0
+	a.limiter = NewLimiter(&a.format, a.params.Ceiling, DefaultLookahead, DefaultRelease, LinkAll)
+}
@@ -0,0 +1,82 @@
+package dynamics
+
+import (
+	"math"
+	"testing"
+
+	"github.com/cyberxnomad/wasapi/audioclient"
+	"github.com/cyberxnomad/wasapi/pcm"
+)
+
+func TestAGCSetParamsKeepsDelayLine(t *testing.T) {
+	format := pcm.NewFormat(pcm.SampleTypeFloat, 48000, 1, 32, audioclient.KSAUDIO_SPEAKER_MONO)
+	agc := NewAGC(&format, DefaultAGC)
+
+	var phase float64
+	sine := func(n int) []float32 {
+		buf := make([]float32, n)
+		for i := range buf {
+			buf[i] = float32(0.1 * math.Sin(phase))
+			phase += 2 * math.Pi * 1000 / 48000
+		}
+		return buf
+	}
+
+	// 达到稳态
+	for i := 0; i < 100; i++ {
+		agc.Process(sine(480))
+	}
+
+	params := DefaultAGC
+	params.Ceiling = -3
+	agc.SetParams(params)
+
+	out := sine(480)
+	agc.Process(out)
+
+	// 重新创建限幅器会丢弃延迟线，输出开头出现 Latency 帧的静音
+	var prev float32
+	for i, v := range out {
+		if d := math.Abs(float64(v - prev)); i > 0 && d > 0.1 {
+			t.Fatalf("discontinuity of %.3f at frame %d after SetParams", d, i)
+		}
+		prev = v
+	}
+
+	var energy float64
+	for _, v := range out[:agc.Latency()] {
+		energy += float64(v) * float64(v)
+	}
+	if energy == 0 {
+		t.Fatal("delay line was discarded by SetParams")
+	}
+}
+
+func TestLimiterCeiling(t *testing.T) {
+	format := pcm.NewFormat(pcm.SampleTypeFloat, 48000, 2, 32, audioclient.KSAUDIO_SPEAKER_STEREO)
+	l := NewLimiter(&format, -6, DefaultLookahead, DefaultRelease, LinkAll)
+
+	check := func(ceiling float64) {
+		t.Helper()
+
+		buf := make([]float32, 4800*2)
+		for i := range buf {
+			buf[i] = float32(math.Sin(2 * math.Pi * 440 * float64(i/2) / 48000))
+		}
+		l.Process(buf)
+
+		var peak float64
+		for _, v := range buf {
+			peak = max(peak, math.Abs(float64(v)))
+		}
+
+		if limit := dbToLinear(ceiling); peak > limit+1e-6 {
+			t.Fatalf("peak %.4f exceeds ceiling %.4f", peak, limit)
+		}
+	}
+
+	check(-6)
+	l.SetCeiling(-12)
+	check(-6) // 过渡期间不超过原上限
+	check(-12)
+}
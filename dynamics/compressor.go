@@ -0,0 +1,164 @@
+package dynamics
+
+import (
+	"sync"
+	"time"
+
+	"github.com/cyberxnomad/wasapi/audioclient"
+	"github.com/cyberxnomad/wasapi/internal/dsp"
+)
+
+// CompressorParams 为压缩器参数，电平均以 dBFS 或 dB 表示。
+type CompressorParams struct {
+	Threshold float64       // 门限 (dBFS)
+	Ratio     float64       // 压缩比，例如 4 表示 4:1
+	Knee      float64       // 软拐点宽度 (dB)，0 为硬拐点
+	Attack    time.Duration // 启动时间
+	Release   time.Duration // 释放时间
+	Makeup    float64       // 补偿增益 (dB)
+}
+
+// DefaultCompressor 为默认的压缩器参数。
+var DefaultCompressor = CompressorParams{
+	Threshold: -20,
+	Ratio:     4,
+	Knee:      6,
+	Attack:    10 * time.Millisecond,
+	Release:   150 * time.Millisecond,
+}
+
+// Compressor 是前馈式压缩器，在对数域计算增益并以启动及释放时间平滑。
+type Compressor struct {
+	mu sync.Mutex
+
+	format   audioclient.WAVEFORMATEXTENSIBLE
+	params   CompressorParams
+	attack   float64
+	release  float64
+	group    []int
+	levels   []float64 // 每组的检测电平，复用
+	gains    []float64 // 每组当前的增益衰减 (dB，非正)
+	reduced  float64
+	channels int
+
+	buf []float32
+}
+
+// NewCompressor 创建压缩器。
+func NewCompressor(format *audioclient.WAVEFORMATEXTENSIBLE, params CompressorParams, linking Linking) *Compressor {
+	c := &Compressor{
+		format:   *format,
+		channels: int(format.Format.Channels),
+	}
+
+	var count int
+	c.group, count = groups(c.channels, linking)
+	c.levels = make([]float64, count)
+	c.gains = make([]float64, count)
+	c.SetParams(params)
+
+	return c
+}
+
+// SetParams 方法设置压缩器参数。
+func (c *Compressor) SetParams(params CompressorParams) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	params.Ratio = max(params.Ratio, 1)
+	params.Knee = max(params.Knee, 0)
+
+	rate := float64(c.format.Format.SamplesPerSec)
+	c.params = params
+	c.attack = dsp.Coefficient(params.Attack, rate)
+	c.release = dsp.Coefficient(params.Release, rate)
+}
+
+// GainReduction 方法返回最近一次处理结束时各组中最大的增益衰减 (dB，非负)。
+func (c *Compressor) GainReduction() float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.reduced
+}
+
+// Process 方法原地处理交错的 float32 采样。
+func (c *Compressor) Process(samples []float32) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.process(samples)
+}
+
+// ProcessBytes 方法按格式解码、处理后原地编码音频数据。
+func (c *Compressor) ProcessBytes(data []byte) (err error) {
+	return dsp.ProcessBytes(&c.mu, &c.format, &c.buf, data, true, c.process)
+}
+
+func (c *Compressor) process(samples []float32) {
+	for i := 0; i+c.channels <= len(samples); i += c.channels {
+		frame := samples[i : i+c.channels]
+
+		clear(c.levels)
+		for ch, v := range frame {
+			g := c.group[ch]
+			c.levels[g] = max(c.levels[g], abs(v))
+		}
+
+		for g, level := range c.levels {
+			x := linearToDB(level)
+			target := c.computeGain(x)
+
+			coef := c.release
+			if target < c.gains[g] {
+				coef = c.attack
+			}
+
+			c.gains[g] = target + (c.gains[g]-target)*coef
+			c.levels[g] = dbToLinear(c.gains[g] + c.params.Makeup)
+		}
+
+		for ch := range frame {
+			frame[ch] *= float32(c.levels[c.group[ch]])
+		}
+	}
+
+	c.reduced = 0
+	for _, g := range c.gains {
+		c.reduced = max(c.reduced, -g)
+	}
+}
+
+// Reset 方法清除包络状态。
+func (c *Compressor) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	clear(c.gains)
+	c.reduced = 0
+}
+
+// 静态增益曲线，返回输入电平 x (dBFS) 对应的增益 (dB，非正)
+func (c *Compressor) computeGain(x float64) float64 {
+	p := &c.params
+	over := x - p.Threshold
+
+	switch {
+	case 2*over <= -p.Knee:
+		return 0
+	case 2*over < p.Knee:
+		// 软拐点区间内的二次插值
+		k := over + p.Knee/2
+		return (1/p.Ratio - 1) * k * k / (2 * p.Knee)
+	default:
+		return over/p.Ratio - over
+	}
+}
+
+func abs(v float32) float64 {
+	if v < 0 {
+		return float64(-v)
+	}
+
+	return float64(v)
+}
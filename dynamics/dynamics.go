@@ -0,0 +1,40 @@
+package dynamics
+
+import "math"
+
+// Linking 表示多声道时各声道增益的联动方式。
+type Linking uint32
+
+const (
+	LinkAll   Linking = iota // 所有声道共用同一增益，保持声像
+	LinkPairs                // 相邻两个声道 (0-1、2-3 ...) 为一组联动，适用于多个立体声对
+	LinkNone                 // 各声道独立处理
+)
+
+// 返回每个声道所属的组号及组数
+func groups(channels int, linking Linking) (group []int, count int) {
+	group = make([]int, channels)
+
+	for c := range group {
+		switch linking {
+		case LinkAll:
+			group[c] = 0
+		case LinkPairs:
+			group[c] = c / 2
+		default:
+			group[c] = c
+		}
+
+		count = max(count, group[c]+1)
+	}
+
+	return
+}
+
+func dbToLinear(db float64) float64 {
+	return math.Pow(10, db/20)
+}
+
+func linearToDB(v float64) float64 {
+	return 20 * math.Log10(max(v, 1e-10))
+}
@@ -0,0 +1,206 @@
+package dynamics
+
+import (
+	"sync"
+	"time"
+
+	"github.com/cyberxnomad/wasapi/audioclient"
+	"github.com/cyberxnomad/wasapi/internal/dsp"
+)
+
+const (
+	DefaultCeiling   = -1.0                  // 默认的输出上限 (dBFS)
+	DefaultLookahead = 5 * time.Millisecond  // 默认的预读时长
+	DefaultRelease   = 80 * time.Millisecond // 默认的释放时间
+)
+
+// 单调队列实现的滑动窗口最小值
+type slidingMin struct {
+	values []float64
+	index  []int
+	n      int
+	window int
+}
+
+func (s *slidingMin) push(v float64) float64 {
+	for len(s.values) > 0 && s.values[len(s.values)-1] >= v {
+		s.values = s.values[:len(s.values)-1]
+		s.index = s.index[:len(s.index)-1]
+	}
+
+	s.values = append(s.values, v)
+	s.index = append(s.index, s.n)
+
+	if s.index[0] <= s.n-s.window {
+		s.values = s.values[1:]
+		s.index = s.index[1:]
+	}
+
+	s.n++
+
+	return s.values[0]
+}
+
+// 限幅器中每组的状态
+type limiterGroup struct {
+	min     slidingMin
+	held    float64   // 带释放的保持增益
+	box     []float64 // 平滑用的滑动平均窗口
+	boxSum  float64
+	boxPos  int
+	current float64
+}
+
+// Limiter 是预读式砖墙限幅器，输出的采样峰值不超过上限。
+//
+// 增益先在预读窗口内取最小值并保持，再经过等长的滑动平均平滑，因此增益在峰值到达前已平滑下降。
+// 输出相对输入延迟 Latency 帧。
+type Limiter struct {
+	mu sync.Mutex
+
+	format    audioclient.WAVEFORMATEXTENSIBLE
+	channels  int
+	ceiling   float64
+	lookahead int
+	release   float64
+	group     []int
+	groups    []limiterGroup
+	peaks     []float64
+	delay     []float32 // 延迟线，环形缓冲
+	delayPos  int
+
+	buf []float32
+}
+
+// NewLimiter 创建限幅器，ceiling 为输出上限 (dBFS)。
+func NewLimiter(format *audioclient.WAVEFORMATEXTENSIBLE, ceiling float64, lookahead, release time.Duration, linking Linking) *Limiter {
+	rate := float64(format.Format.SamplesPerSec)
+
+	l := &Limiter{
+		format:    *format,
+		channels:  int(format.Format.Channels),
+		ceiling:   dbToLinear(ceiling),
+		lookahead: max(int(lookahead.Seconds()*rate), 1),
+		release:   dsp.Coefficient(release, rate),
+	}
+
+	var count int
+	l.group, count = groups(l.channels, linking)
+	l.groups = make([]limiterGroup, count)
+	l.peaks = make([]float64, count)
+	l.delay = make([]float32, l.lookahead*l.channels)
+
+	window := l.lookahead + 1
+	for g := range l.groups {
+		l.groups[g] = limiterGroup{
+			min:     slidingMin{window: window},
+			held:    1,
+			box:     make([]float64, window),
+			boxSum:  float64(window),
+			current: 1,
+		}
+
+		for i := range l.groups[g].box {
+			l.groups[g].box[i] = 1
+		}
+	}
+
+	return l
+}
+
+// SetCeiling 方法设置输出上限 (dBFS)，延迟线及增益状态保留，可在处理过程中调用。
+func (l *Limiter) SetCeiling(ceiling float64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.ceiling = dbToLinear(ceiling)
+}
+
+// Latency 方法返回限幅器引入的延迟 (帧)。
+func (l *Limiter) Latency() int {
+	return l.lookahead
+}
+
+// GainReduction 方法返回当前各组中最大的增益衰减 (dB，非负)。
+func (l *Limiter) GainReduction() (db float64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, g := range l.groups {
+		db = max(db, -linearToDB(g.current))
+	}
+
+	return
+}
+
+// Process 方法原地处理交错的 float32 采样，输出延迟 Latency 帧。
+func (l *Limiter) Process(samples []float32) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.process(samples)
+}
+
+// ProcessBytes 方法按格式解码、处理后原地编码音频数据。
+func (l *Limiter) ProcessBytes(data []byte) (err error) {
+	return dsp.ProcessBytes(&l.mu, &l.format, &l.buf, data, true, l.process)
+}
+
+func (l *Limiter) process(samples []float32) {
+	for i := 0; i+l.channels <= len(samples); i += l.channels {
+		frame := samples[i : i+l.channels]
+
+		clear(l.peaks)
+		for ch, v := range frame {
+			g := l.group[ch]
+			l.peaks[g] = max(l.peaks[g], abs(v))
+		}
+
+		for g := range l.groups {
+			l.peaks[g] = l.groups[g].next(l.peaks[g], l.ceiling, l.release)
+		}
+
+		// 输出延迟线中最早的帧并写入新帧
+		delayed := l.delay[l.delayPos*l.channels : (l.delayPos+1)*l.channels]
+		for ch := range frame {
+			out := delayed[ch] * float32(l.peaks[l.group[ch]])
+			delayed[ch] = frame[ch]
+			frame[ch] = out
+		}
+
+		l.delayPos = (l.delayPos + 1) % l.lookahead
+	}
+}
+
+// 输入当前帧的峰值，返回用于延迟输出帧的增益
+func (g *limiterGroup) next(peak, ceiling, release float64) float64 {
+	required := 1.0
+	if peak > ceiling {
+		required = ceiling / peak
+	}
+
+	target := g.min.push(required)
+
+	// 增益立即下降，缓慢释放
+	if target < g.held {
+		g.held = target
+	} else {
+		g.held = target + (g.held-target)*release
+	}
+
+	g.boxSum += g.held - g.box[g.boxPos]
+	g.box[g.boxPos] = g.held
+	g.boxPos = (g.boxPos + 1) % len(g.box)
+
+	// 重新求和以消除累积误差
+	if g.boxPos == 0 {
+		g.boxSum = 0
+		for _, v := range g.box {
+			g.boxSum += v
+		}
+	}
+
+	g.current = g.boxSum / float64(len(g.box))
+
+	return g.current
+}
@@ -0,0 +1,214 @@
+package eq
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"sync"
+
+	"github.com/cyberxnomad/wasapi/audioclient"
+	"github.com/cyberxnomad/wasapi/biquad"
+	"github.com/cyberxnomad/wasapi/internal/dsp"
+)
+
+var ErrInvalidBand = errors.New("invalid band index")
+
+// Band 为均衡器的一个频段。
+type Band struct {
+	biquad.Params
+	Enabled bool `json:"enabled"`
+}
+
+// Preset 为可序列化的均衡器预设。
+type Preset struct {
+	Name   string  `json:"name"`
+	Preamp float64 `json:"preamp"` // 前级增益 (dB)
+	Bands  []Band  `json:"bands"`
+}
+
+// PresetSpeech 为语音带通预设，去除低频隆隆声及高频嘶声，并略微提升清晰度。
+var PresetSpeech = Preset{
+	Name: "speech",
+	Bands: []Band{
+		{Params: biquad.Params{Type: biquad.HighPass, Frequency: 100, Q: 0.7071}, Enabled: true},
+		{Params: biquad.Params{Type: biquad.Peaking, Frequency: 3000, Q: 1, Gain: 3}, Enabled: true},
+		{Params: biquad.Params{Type: biquad.LowPass, Frequency: 8000, Q: 0.7071}, Enabled: true},
+	},
+}
+
+// LoadPreset 从 JSON 读取预设。
+func LoadPreset(r io.Reader) (preset Preset, err error) {
+	err = json.NewDecoder(r).Decode(&preset)
+	return
+}
+
+// Save 方法将预设以 JSON 写入 w。
+func (p Preset) Save(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(p)
+}
+
+// Equalizer 是多频段参数均衡器，每个频段为一个二阶滤波器。
+type Equalizer struct {
+	mu sync.Mutex
+
+	format  audioclient.WAVEFORMATEXTENSIBLE
+	preset  Preset
+	preamp  float32
+	filters []*biquad.Filter // 与频段一一对应，禁用的频段为 nil
+
+	buf []float32
+}
+
+// NewEqualizer 创建不含频段的均衡器。
+func NewEqualizer(format *audioclient.WAVEFORMATEXTENSIBLE) *Equalizer {
+	return &Equalizer{format: *format, preamp: 1}
+}
+
+// Preset 方法返回当前设置。
+func (e *Equalizer) Preset() (preset Preset) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	preset = e.preset
+	preset.Bands = append([]Band(nil), e.preset.Bands...)
+
+	return
+}
+
+// SetPreset 方法应用预设，任一频段无效时不做修改并返回错误。
+func (e *Equalizer) SetPreset(preset Preset) (err error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	rate := float64(e.format.Format.SamplesPerSec)
+	for _, band := range preset.Bands {
+		if err = band.Validate(rate); err != nil {
+			return
+		}
+	}
+
+	e.preset = preset
+	e.preset.Bands = append([]Band(nil), preset.Bands...)
+	e.rebuild()
+
+	return
+}
+
+// SetBand 方法修改一个频段，滤波器状态保留以避免咔嗒声。
+func (e *Equalizer) SetBand(index int, band Band) (err error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if index < 0 || index >= len(e.preset.Bands) {
+		err = ErrInvalidBand
+		return
+	}
+
+	if err = band.Validate(float64(e.format.Format.SamplesPerSec)); err != nil {
+		return
+	}
+
+	e.preset.Bands[index] = band
+
+	switch f := e.filters[index]; {
+	case !band.Enabled:
+		e.filters[index] = nil
+	case f == nil:
+		e.filters[index] = biquad.NewFilter(band.Params, e.format.Format.SamplesPerSec, int(e.format.Format.Channels))
+	default:
+		err = f.SetParams(band.Params)
+	}
+
+	return
+}
+
+// SetPreamp 方法设置前级增益 (dB)，用于抵消提升频段造成的削波。
+func (e *Equalizer) SetPreamp(db float64) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.preset.Preamp = db
+	e.preamp = float32(math.Pow(10, db/20))
+}
+
+// SetFormat 方法在混音格式改变时按新的采样率重新计算所有系数。
+//
+// 在新的采样率下超出奈奎斯特频率的频段会被忽略。
+func (e *Equalizer) SetFormat(format *audioclient.WAVEFORMATEXTENSIBLE) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.format = *format
+	e.rebuild()
+}
+
+// Magnitude 方法返回频率 frequency (Hz) 处的总幅度响应 (dB)，含前级增益。
+func (e *Equalizer) Magnitude(frequency float64) (db float64) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	db = e.preset.Preamp
+	for _, f := range e.filters {
+		if f != nil {
+			db += f.Magnitude(frequency)
+		}
+	}
+
+	return
+}
+
+// Process 方法原地处理交错的 float32 采样。
+func (e *Equalizer) Process(samples []float32) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.process(samples)
+}
+
+// ProcessBytes 方法按格式解码、处理后原地编码音频数据。
+func (e *Equalizer) ProcessBytes(data []byte) (err error) {
+	return dsp.ProcessBytes(&e.mu, &e.format, &e.buf, data, true, e.process)
+}
+
+func (e *Equalizer) process(samples []float32) {
+	if e.preamp != 1 {
+		for i := range samples {
+			samples[i] *= e.preamp
+		}
+	}
+
+	for _, f := range e.filters {
+		if f != nil {
+			f.Process(samples)
+		}
+	}
+}
+
+// Reset 方法清除所有滤波器的状态。
+func (e *Equalizer) Reset() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for _, f := range e.filters {
+		if f != nil {
+			f.Reset()
+		}
+	}
+}
+
+// 按当前设置及格式重新创建滤波器
+func (e *Equalizer) rebuild() {
+	rate := e.format.Format.SamplesPerSec
+	e.preamp = float32(math.Pow(10, e.preset.Preamp/20))
+	e.filters = make([]*biquad.Filter, len(e.preset.Bands))
+
+	for i, band := range e.preset.Bands {
+		if band.Enabled && band.Validate(float64(rate)) == nil {
+			e.filters[i] = biquad.NewFilter(band.Params, rate, int(e.format.Format.Channels))
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package eq
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/cyberxnomad/wasapi/audioclient"
+	"github.com/cyberxnomad/wasapi/biquad"
+	"github.com/cyberxnomad/wasapi/pcm"
+)
+
+var (
+	float32Stereo = pcm.NewFormat(pcm.SampleTypeFloat, 48000, 2, 32, audioclient.KSAUDIO_SPEAKER_STEREO)
+	int16Stereo   = pcm.NewFormat(pcm.SampleTypeInt, 48000, 2, 16, audioclient.KSAUDIO_SPEAKER_STEREO)
+)
+
+// 平坦的设置：无频段、禁用的频段及 0 dB 的峰值/搁架频段
+var flat = []Preset{
+	{Name: "empty"},
+	{Name: "disabled", Bands: []Band{
+		{Params: biquad.Params{Type: biquad.LowPass, Frequency: 1000, Q: 0.7071}},
+		{Params: biquad.Params{Type: biquad.Notch, Frequency: 50, Q: 10}},
+	}},
+	{Name: "zero gain", Bands: []Band{
+		{Params: biquad.Params{Type: biquad.Peaking, Frequency: 1000, Q: 1}, Enabled: true},
+		{Params: biquad.Params{Type: biquad.LowShelf, Frequency: 200, Q: 0.7071}, Enabled: true},
+		{Params: biquad.Params{Type: biquad.HighShelf, Frequency: 6000, Q: 0.7071}, Enabled: true},
+	}},
+}
+
+func TestFlatIdentity(t *testing.T) {
+	for _, preset := range flat {
+		e := NewEqualizer(&float32Stereo)
+		if err := e.SetPreset(preset); err != nil {
+			t.Fatal(err)
+		}
+
+		for _, frequency := range []float64{20, 1000, 20000} {
+			if db := e.Magnitude(frequency); math.Abs(db) > 1e-9 {
+				t.Errorf("%s: Magnitude(%v) = %g dB, want 0", preset.Name, frequency, db)
+			}
+		}
+
+		samples := make([]float32, 4800)
+		for i := range samples {
+			samples[i] = float32(0.5 * math.Sin(float64(i)*0.37) * math.Cos(float64(i)*0.011))
+		}
+		want := append([]float32(nil), samples...)
+
+		e.Process(samples)
+		for i := range samples {
+			if math.Abs(float64(samples[i]-want[i])) > 1e-6 {
+				t.Fatalf("%s: Process sample %d = %g, want %g", preset.Name, i, samples[i], want[i])
+			}
+		}
+
+		// 16 位数据经解码、处理及编码后不变
+		e.SetFormat(&int16Stereo)
+		data := make([]byte, 4800*2)
+		for i := 0; i < 4800; i++ {
+			binary.LittleEndian.PutUint16(data[i*2:], uint16(int16((i*7919)%65536-32768)))
+		}
+		original := append([]byte(nil), data...)
+
+		if err := e.ProcessBytes(data); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(data, original) {
+			t.Errorf("%s: ProcessBytes changed int16 data", preset.Name)
+		}
+	}
+}
+
+func TestPreamp(t *testing.T) {
+	e := NewEqualizer(&float32Stereo)
+	e.SetPreamp(-6)
+
+	samples := []float32{1, -0.5, 0.25, 0}
+	e.Process(samples)
+
+	gain := float32(math.Pow(10, -6.0/20))
+	for i, want := range []float32{gain, -0.5 * gain, 0.25 * gain, 0} {
+		if math.Abs(float64(samples[i]-want)) > 1e-7 {
+			t.Errorf("sample %d = %g, want %g", i, samples[i], want)
+		}
+	}
+	if db := e.Magnitude(1000); db != -6 {
+		t.Errorf("Magnitude = %g, want -6", db)
+	}
+}
+
+func TestMagnitude(t *testing.T) {
+	e := NewEqualizer(&float32Stereo)
+	if err := e.SetPreset(PresetSpeech); err != nil {
+		t.Fatal(err)
+	}
+
+	// 各频段的响应相加
+	var want float64
+	for _, band := range PresetSpeech.Bands {
+		c := biquad.Design(band.Params, 48000)
+		want += c.Magnitude(3000, 48000)
+	}
+	if db := e.Magnitude(3000); math.Abs(db-want) > 1e-9 {
+		t.Errorf("Magnitude(3000) = %g, want %g", db, want)
+	}
+
+	if err := e.SetBand(3, Band{}); err != ErrInvalidBand {
+		t.Errorf("SetBand out of range: err = %v", err)
+	}
+}
+
+func TestPresetJSON(t *testing.T) {
+	var buf bytes.Buffer
+	if err := PresetSpeech.Save(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	preset, err := LoadPreset(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(preset, PresetSpeech) {
+		t.Errorf("round trip = %+v, want %+v", preset, PresetSpeech)
+	}
+}
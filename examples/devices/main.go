@@ -0,0 +1,45 @@
+//go:build windows
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/cyberxnomad/wasapi/com"
+	"github.com/cyberxnomad/wasapi/mmdevice"
+	"golang.org/x/sys/windows"
+)
+
+func main() {
+	// 初始化 COM 库
+	if err := com.CoInitializeEx(0, windows.COINIT_APARTMENTTHREADED); err != nil {
+		panic(err)
+	}
+	defer com.CoUninitialize()
+
+	devices, err := mmdevice.Devices(mmdevice.EAll, mmdevice.DEVICE_STATEMASK_ALL)
+	if err != nil {
+		panic(err)
+	}
+
+	for _, d := range devices {
+		flow := "render"
+		if d.DataFlow == mmdevice.ECapture {
+			flow = "capture"
+		}
+
+		fmt.Printf("%s [%s] state: 0x%X form factor: %d\n", d.FriendlyName, flow, d.State, d.FormFactor)
+		fmt.Printf("  id: %s\n  description: %s\n  container: %s\n", d.ID, d.Description, d.ContainerID)
+
+		if d.State&mmdevice.DEVICE_STATE_ACTIVE != 0 {
+			fmt.Printf("  mix format: %d Hz, %d channels, %d bits\n",
+				d.MixFormat.Format.SamplesPerSec, d.MixFormat.Format.Channels, d.MixFormat.Format.BitsPerSample)
+		}
+	}
+
+	for _, flow := range []mmdevice.EDataFlow{mmdevice.ERender, mmdevice.ECapture} {
+		if d, err := mmdevice.DefaultDevice(flow, mmdevice.EConsole); err == nil {
+			fmt.Printf("default (%d): %s\n", flow, d.FriendlyName)
+		}
+	}
+}
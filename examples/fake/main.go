@@ -0,0 +1,156 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/cyberxnomad/wasapi/audioclient"
+	"github.com/cyberxnomad/wasapi/backend"
+	"github.com/cyberxnomad/wasapi/backend/fake"
+	"github.com/cyberxnomad/wasapi/generator"
+	"github.com/cyberxnomad/wasapi/mmdevice"
+	"github.com/cyberxnomad/wasapi/pcm"
+)
+
+// 应用代码只依赖 backend 中的接口，在 Windows 上传入 backend.NewEnumerator 返回的枚举器，
+// 测试时传入 fake.Enumerator。这里在虚拟时间中运行一个呈现流，中途拔出设备后切换到新的默认设备。
+
+// 打开默认呈现设备，返回音频客户端及呈现服务
+func open(enumerator backend.Enumerator) (client backend.AudioClient, render backend.RenderClient, format audioclient.WAVEFORMATEXTENSIBLE, err error) {
+	device, err := enumerator.GetDefaultAudioEndpoint(mmdevice.ERender, mmdevice.EConsole)
+	if err != nil {
+		return
+	}
+	defer device.Release()
+
+	if client, err = backend.Activate(device); err != nil {
+		return
+	}
+
+	if format, err = client.GetMixFormat(); err == nil {
+		err = client.Initialize(audioclient.AUDCLNT_SHAREMODE_SHARED, 0, 50*10000, 0, &format, nil)
+	}
+	if err == nil {
+		render, err = backend.GetRenderClient(client)
+	}
+	if err == nil {
+		err = client.Start()
+	}
+
+	if err != nil {
+		client.Release()
+		client = nil
+	}
+
+	return
+}
+
+// 填满呈现缓冲区
+func fill(client backend.AudioClient, render backend.RenderClient, gen *generator.Generator) (frames uint32, err error) {
+	size, err := client.GetBufferSize()
+	if err != nil {
+		return
+	}
+
+	padding, err := client.GetCurrentPadding()
+	if err != nil {
+		return
+	}
+
+	if frames = size - padding; frames == 0 {
+		return
+	}
+
+	data, err := render.GetBuffer(frames)
+	if err != nil {
+		return
+	}
+
+	if _, err = gen.ReadBytes(data); err != nil {
+		return
+	}
+
+	err = render.ReleaseBuffer(frames, 0)
+
+	return
+}
+
+func main() {
+	enumerator := fake.NewEnumerator()
+
+	speakers := enumerator.AddDevice(fake.DeviceConfig{
+		ID:        "speakers",
+		Flow:      mmdevice.ERender,
+		MixFormat: pcm.NewFormat(pcm.SampleTypeFloat, 48000, 2, 32, audioclient.KSAUDIO_SPEAKER_STEREO),
+	})
+	headphones := enumerator.AddDevice(fake.DeviceConfig{
+		ID:            "headphones",
+		Flow:          mmdevice.ERender,
+		MixFormat:     pcm.NewFormat(pcm.SampleTypeInt, 44100, 2, 16, audioclient.KSAUDIO_SPEAKER_STEREO),
+		DefaultPeriod: 20 * time.Millisecond,
+		Padding:       fake.PaddingContinuous,
+	})
+
+	var played [2]int
+	speakers.SetRender(func(format *audioclient.WAVEFORMATEXTENSIBLE, data []byte) {
+		played[0] += pcm.Frames(format, len(data))
+	})
+	headphones.SetRender(func(format *audioclient.WAVEFORMATEXTENSIBLE, data []byte) {
+		played[1] += pcm.Frames(format, len(data))
+	})
+
+	client, render, format, err := open(enumerator)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	oscillator := generator.NewOscillator(generator.WaveSine, format.Format.SamplesPerSec, 440, 0.5)
+	gen := generator.NewGenerator(&format, oscillator)
+
+	// 以 10 毫秒为间隔轮询，1 秒后拔出扬声器
+	for i := 0; i < 200; i++ {
+		if i == 100 {
+			enumerator.RemoveDevice("speakers")
+		}
+
+		frames, err := fill(client, render, gen)
+		if errors.Is(err, audioclient.ErrDeviceInvalidated) {
+			fmt.Printf("%v: %v, reopening\n", enumerator.Now(), err)
+
+			render.Release()
+			client.Release()
+
+			if client, render, format, err = open(enumerator); err != nil {
+				log.Fatalln(err)
+			}
+
+			oscillator = generator.NewOscillator(generator.WaveSine, format.Format.SamplesPerSec, 440, 0.5)
+			gen = generator.NewGenerator(&format, oscillator)
+			continue
+		} else if err != nil {
+			log.Fatalln(err)
+		}
+
+		if i%50 == 0 {
+			padding, _ := client.GetCurrentPadding()
+			fmt.Printf("%v: wrote %d frames, padding %d\n", enumerator.Now(), frames, padding)
+		}
+
+		enumerator.Advance(10 * time.Millisecond)
+	}
+
+	glitches := client.(*fake.AudioClient).Glitches()
+	render.Release()
+	client.Release()
+
+	fmt.Printf("speakers played %d frames, headphones played %d frames, glitches %d, outstanding objects %d\n",
+		played[0], played[1], glitches, enumerator.Outstanding())
+
+	// 注入错误
+	enumerator.InjectError("IMMDeviceEnumerator::GetDefaultAudioEndpoint", audioclient.ErrServiceNotRunning, 1)
+	if _, _, _, err = open(enumerator); err != nil {
+		fmt.Println("injected:", err, errors.Is(err, audioclient.ErrServiceNotRunning))
+	}
+}
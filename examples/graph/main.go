@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/cyberxnomad/wasapi/audioclient"
+	"github.com/cyberxnomad/wasapi/dynamics"
+	"github.com/cyberxnomad/wasapi/eq"
+	"github.com/cyberxnomad/wasapi/generator"
+	"github.com/cyberxnomad/wasapi/graph"
+	"github.com/cyberxnomad/wasapi/pcm"
+)
+
+// 不打开任何设备: 以信号发生器为音源，经均衡器及限幅器写入 16 kHz 单声道 WAV 文件，
+// 同时以拉模型输出 48 kHz 5.1 声道 (模拟渲染终结点的混音格式)，再读回文件验证。
+func main() {
+	path := filepath.Join(os.TempDir(), "graph.wav")
+	if len(os.Args) > 1 {
+		path = os.Args[1]
+	}
+
+	source := pcm.NewFormat(pcm.SampleTypeFloat, 44100, 2, 32, audioclient.KSAUDIO_SPEAKER_STEREO)
+	sweep := generator.NewSweep(44100, 50, 15000, 2*time.Second, 0.8)
+	tone := graph.NewGeneratorSource("sweep", &source, sweep, 2*time.Second)
+
+	equalizer := graph.NewEffect("eq", graph.Any, func(format *audioclient.WAVEFORMATEXTENSIBLE) (graph.InPlace, error) {
+		e := eq.NewEqualizer(format)
+		return e, e.SetPreset(eq.PresetSpeech)
+	})
+	limiter := graph.NewEffect("limiter", graph.Any, func(format *audioclient.WAVEFORMATEXTENSIBLE) (graph.InPlace, error) {
+		return dynamics.NewLimiter(format, dynamics.DefaultCeiling, dynamics.DefaultLookahead, dynamics.DefaultRelease, dynamics.LinkAll), nil
+	})
+
+	file, err := os.Create(path)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	defer file.Close()
+
+	fileFormat := pcm.NewFormat(pcm.SampleTypeInt, 16000, 1, 16, audioclient.KSAUDIO_SPEAKER_MONO)
+	sink := graph.NewFileSink("file", file, &fileFormat)
+
+	mixFormat := pcm.NewFormat(pcm.SampleTypeFloat, 48000, 6, 32, audioclient.KSAUDIO_SPEAKER_5POINT1_SURROUND)
+	output := graph.NewOutput("render", &mixFormat, 200*time.Millisecond)
+
+	g := graph.New(10 * time.Millisecond)
+	if err = g.Chain(tone, equalizer, limiter, sink); err != nil {
+		log.Fatalln(err)
+	}
+	if err = g.Connect(limiter, output); err != nil {
+		log.Fatalln(err)
+	}
+
+	if err = g.Build(); err != nil {
+		log.Fatalln(err)
+	}
+	fmt.Print(g.Describe())
+
+	// 按渲染端的周期 (10 毫秒) 拉取数据
+	buf := make([]float32, 480*int(mixFormat.Format.Channels))
+	var rendered int
+	for {
+		frames, err := g.Pull(output, buf)
+		rendered += frames
+		if err != nil {
+			break
+		}
+	}
+
+	if err = sink.Close(); err != nil {
+		log.Fatalln(err)
+	}
+	fmt.Printf("rendered %.3f s\n", float64(rendered)/float64(mixFormat.Format.SamplesPerSec))
+
+	// 读回文件
+	if _, err = file.Seek(0, 0); err != nil {
+		log.Fatalln(err)
+	}
+
+	reader, err := graph.NewFileSource("wav", file)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	check := graph.NewOutput("check", &fileFormat, 3*time.Second)
+	g = graph.New(0)
+	if err = g.Connect(reader, check); err != nil {
+		log.Fatalln(err)
+	}
+	if err = g.Build(); err != nil {
+		log.Fatalln(err)
+	}
+
+	for g.Tick() == nil {
+	}
+
+	format, _ := g.Format(reader)
+	fmt.Printf("%s: %d Hz, %d ch, %.3f s\n", path, format.Format.SamplesPerSec, format.Format.Channels, float64(check.Buffered())/float64(fileFormat.Format.SamplesPerSec))
+}
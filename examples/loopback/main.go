@@ -1,9 +1,9 @@
+//go:build windows
+
 package main
 
 import (
-	"encoding/binary"
 	"fmt"
-	"math"
 	"os"
 	"os/signal"
 	"sync"
@@ -13,6 +13,7 @@ import (
 
 	"github.com/cyberxnomad/wasapi/audioclient"
 	"github.com/cyberxnomad/wasapi/com"
+	"github.com/cyberxnomad/wasapi/meter"
 	"github.com/cyberxnomad/wasapi/mmdevice"
 	"golang.org/x/sys/windows"
 )
@@ -167,6 +168,8 @@ func processCapture(client *audioclient.IAudioCaptureClient, format *audioclient
 		data               []byte
 		numFramesAvailable uint32
 		flags              uint32
+		levels             *meter.Meter
+		lastShow           time.Time
 		err                error
 	)
 
@@ -175,6 +178,8 @@ func processCapture(client *audioclient.IAudioCaptureClient, format *audioclient
 		fmt.Println("Exit Capture")
 	}()
 
+	levels = meter.NewMeter(format, meter.PresetDigital)
+
 	for {
 		select {
 		case <-quit:
@@ -191,18 +196,11 @@ func processCapture(client *audioclient.IAudioCaptureClient, format *audioclient
 				panic(err)
 			}
 
-			// 这里打印只是方便呈现获取到的音频内容
-			// 可以在此按业务需求处理音频内容
-			// 因为此处判断了静音，所以如果卡在了这里，可以尝试播放一些音乐
-			if flags&audioclient.AUDCLNT_BUFFERFLAGS_SILENT == 0 &&
-				format.Format.BitsPerSample == 32 &&
-				format.Format.FormatTag == audioclient.WAVE_FORMAT_EXTENSIBLE &&
-				format.SubFormatTag() == audioclient.WAVE_FORMAT_IEEE_FLOAT {
-				for i := 0; i < int(numFramesAvailable/uint32(format.Format.BlockAlign))/int(format.Format.Channels); i++ {
-					for j := 0; j < int(format.Format.Channels); j++ {
-						fmt.Printf("CH%d: %f\t", j, math.Float32frombits(binary.LittleEndian.Uint32(data[i*int(format.Format.Channels)*4:i*int(format.Format.Channels)*4+4])))
-					}
-					fmt.Println()
+			// GetBuffer 返回的切片长度为帧数，这里按字节数重新构造后送入电平表
+			// 因为此处判断了静音，所以如果电平一直没有变化，可以尝试播放一些音乐
+			if flags&audioclient.AUDCLNT_BUFFERFLAGS_SILENT == 0 && numFramesAvailable > 0 {
+				if err = levels.ProcessBytes(unsafe.Slice(&data[0], int(numFramesAvailable)*int(format.Format.BlockAlign))); err != nil {
+					panic(err)
 				}
 			}
 
@@ -214,7 +212,20 @@ func processCapture(client *audioclient.IAudioCaptureClient, format *audioclient
 				panic(err)
 			}
 		}
+
+		// 每秒打印一次电平
+		if time.Since(lastShow) >= time.Second {
+			lastShow = time.Now()
+			showLevels(levels.Levels())
+		}
+	}
+}
+
+func showLevels(levels []meter.Level) {
+	for _, l := range levels {
+		fmt.Printf("%s: peak %.1f dBFS, true peak %.1f dBFS, RMS %.1f dBFS, clips %d\t", l.Name, meter.DBFS(l.Peak), meter.DBFS(l.TruePeak), meter.DBFS(l.RMS), l.Clips)
 	}
+	fmt.Println()
 }
 
 func showWaveFormat(wf *audioclient.WAVEFORMATEXTENSIBLE) {
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"github.com/cyberxnomad/wasapi/audioclient"
+	"github.com/cyberxnomad/wasapi/backend"
+	"github.com/cyberxnomad/wasapi/backend/fake"
+	"github.com/cyberxnomad/wasapi/generator"
+	"github.com/cyberxnomad/wasapi/mmdevice"
+	"github.com/cyberxnomad/wasapi/pcm"
+)
+
+// 在虚拟设备上运行一个把麦克风直通到扬声器的应用: 麦克风播放 1 kHz 正弦波，扬声器写入 WAV 文件，
+// 同时以环回流录制扬声器的输出。时间由测试推进，每一步都可以检查填充及缓冲区的状态。
+
+// 激活默认设备并以混音格式初始化共享模式的流
+func open(enumerator backend.Enumerator, flow mmdevice.EDataFlow, streamFlags uint32) (client backend.AudioClient, format audioclient.WAVEFORMATEXTENSIBLE, err error) {
+	device, err := enumerator.GetDefaultAudioEndpoint(flow, mmdevice.EConsole)
+	if err != nil {
+		return
+	}
+	defer device.Release()
+
+	if client, err = backend.Activate(device); err != nil {
+		return
+	}
+
+	if format, err = client.GetMixFormat(); err == nil {
+		err = client.Initialize(audioclient.AUDCLNT_SHAREMODE_SHARED, streamFlags, 100*10000, 0, &format, nil)
+	}
+
+	if err != nil {
+		client.Release()
+		client = nil
+	}
+
+	return
+}
+
+// 读取捕获流中的所有数据包，返回解码后的采样
+func drain(capture backend.CaptureClient, format *audioclient.WAVEFORMATEXTENSIBLE, samples []float32) ([]float32, error) {
+	for {
+		frames, err := capture.GetNextPacketSize()
+		if err != nil || frames == 0 {
+			return samples, err
+		}
+
+		data, frames, flags, _, _, err := capture.GetBuffer()
+		if err != nil {
+			return samples, err
+		}
+
+		n := int(frames) * int(format.Format.Channels)
+		out := make([]float32, n)
+		if flags&audioclient.AUDCLNT_BUFFERFLAGS_SILENT == 0 {
+			pcm.Decode(format, data, out)
+		}
+		samples = append(samples, out...)
+
+		if err = capture.ReleaseBuffer(frames); err != nil {
+			return samples, err
+		}
+	}
+}
+
+func rms(samples []float32) float64 {
+	var sum float64
+	for _, v := range samples {
+		sum += float64(v) * float64(v)
+	}
+
+	return math.Sqrt(sum / float64(max(len(samples), 1)))
+}
+
+func main() {
+	enumerator := fake.NewEnumerator()
+
+	micFormat := pcm.NewFormat(pcm.SampleTypeInt, 48000, 1, 16, audioclient.KSAUDIO_SPEAKER_MONO)
+	enumerator.AddGeneratorCapture(fake.DeviceConfig{ID: "mic", MixFormat: micFormat},
+		generator.NewOscillator(generator.WaveSine, 48000, 1000, 0.5))
+
+	var file bytes.Buffer
+	speakers, err := enumerator.AddFileRender(fake.DeviceConfig{
+		ID:        "speakers",
+		MixFormat: pcm.NewFormat(pcm.SampleTypeFloat, 48000, 2, 32, audioclient.KSAUDIO_SPEAKER_STEREO),
+	}, &file)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	mic, micFmt, err := open(enumerator, mmdevice.ECapture, 0)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	defer mic.Release()
+
+	spk, spkFmt, err := open(enumerator, mmdevice.ERender, 0)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	defer spk.Release()
+
+	loop, loopFmt, err := open(enumerator, mmdevice.ERender, audioclient.AUDCLNT_STREAMFLAGS_LOOPBACK)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	defer loop.Release()
+
+	capture, _ := backend.GetCaptureClient(mic)
+	defer capture.Release()
+	render, _ := backend.GetRenderClient(spk)
+	defer render.Release()
+	loopback, _ := backend.GetCaptureClient(loop)
+	defer loopback.Release()
+
+	for _, c := range []backend.AudioClient{mic, spk, loop} {
+		if err = c.Start(); err != nil {
+			log.Fatalln(err)
+		}
+	}
+
+	matrix := pcm.ChannelMatrix(&micFmt, &spkFmt)
+	bufferFrames, _ := spk.GetBufferSize()
+
+	var recorded []float32
+	for step := 0; step < 100; step++ {
+		enumerator.Advance(10 * time.Millisecond)
+
+		input, err := drain(capture, &micFmt, nil)
+		if err != nil {
+			log.Fatalln(err)
+		}
+
+		// 直通到扬声器，缓冲区满时丢弃多余的数据
+		padding, _ := spk.GetCurrentPadding()
+		frames := min(uint32(len(input)), bufferFrames-padding)
+		if frames > 0 {
+			data, err := render.GetBuffer(frames)
+			if err != nil {
+				log.Fatalln(err)
+			}
+
+			out := make([]float32, int(frames)*int(spkFmt.Format.Channels))
+			pcm.Remix(matrix, input[:frames], out)
+			pcm.Encode(&spkFmt, out, data)
+
+			if err = render.ReleaseBuffer(frames, 0); err != nil {
+				log.Fatalln(err)
+			}
+		}
+
+		if recorded, err = drain(loopback, &loopFmt, recorded); err != nil {
+			log.Fatalln(err)
+		}
+
+		if step < 3 || step%25 == 0 {
+			micPadding, _ := mic.GetCurrentPadding()
+			spkPadding, _ := spk.GetCurrentPadding()
+			fmt.Printf("%6v: captured %4d frames, mic padding %4d, speaker padding %4d/%d, loopback %6d frames\n",
+				enumerator.Now(), len(input), micPadding, spkPadding, bufferFrames, len(recorded)/int(loopFmt.Format.Channels))
+		}
+	}
+
+	if err = speakers.Close(); err != nil {
+		log.Fatalln(err)
+	}
+
+	glitches := spk.(*fake.AudioClient).Glitches()
+	fmt.Printf("speaker file: %d frames (%d bytes), glitches %d\n", speakers.Frames(), file.Len(), glitches)
+
+	// 录制的文件作为新的麦克风输入
+	replay, err := enumerator.AddFileCapture(fake.DeviceConfig{ID: "replay"}, bytes.NewReader(file.Bytes()), false)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	enumerator.SetDefault(mmdevice.ECapture, mmdevice.EConsole, "replay")
+
+	in, inFmt, err := open(enumerator, mmdevice.ECapture, 0)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	defer in.Release()
+
+	replayCapture, _ := backend.GetCaptureClient(in)
+	defer replayCapture.Release()
+	in.Start()
+
+	enumerator.Advance(2 * time.Second)
+	padding, _ := in.GetCurrentPadding()
+	replayed, _ := drain(replayCapture, &inFmt, nil)
+
+	fmt.Printf("loopback RMS %.3f, replay of %s: padding %d, RMS %.3f, overflows %d\n",
+		rms(recorded[len(recorded)/2:]), replay.Config().ID, padding, rms(replayed[:len(replayed)/4]),
+		in.(*fake.AudioClient).Glitches())
+}
@@ -0,0 +1,170 @@
+package fft
+
+import (
+	"errors"
+	"math"
+	"math/cmplx"
+)
+
+var ErrSize = errors.New("size must be a power of two")
+
+// FFT 是预先计算旋转因子的基 2 复数 FFT。
+type FFT struct {
+	n       int
+	twiddle []complex128
+	rev     []int
+}
+
+// New 创建长度为 n 的复数 FFT，n 必须为 2 的幂。
+func New(n int) (f *FFT, err error) {
+	if n < 1 || n&(n-1) != 0 {
+		err = ErrSize
+		return
+	}
+
+	f = &FFT{
+		n:       n,
+		twiddle: make([]complex128, n/2),
+		rev:     make([]int, n),
+	}
+
+	for k := range f.twiddle {
+		f.twiddle[k] = cmplx.Rect(1, -2*math.Pi*float64(k)/float64(n))
+	}
+
+	for i, j := 1, 0; i < n; i++ {
+		bit := n >> 1
+		for ; j&bit != 0; bit >>= 1 {
+			j ^= bit
+		}
+		j ^= bit
+		f.rev[i] = j
+	}
+
+	return
+}
+
+// Len 方法返回变换长度。
+func (f *FFT) Len() int {
+	return f.n
+}
+
+// Transform 方法原地计算正变换，len(x) 必须等于变换长度。
+func (f *FFT) Transform(x []complex128) {
+	f.transform(x, false)
+}
+
+// Inverse 方法原地计算逆变换，结果已除以变换长度。
+func (f *FFT) Inverse(x []complex128) {
+	f.transform(x, true)
+
+	scale := complex(1/float64(f.n), 0)
+	for i := range x {
+		x[i] *= scale
+	}
+}
+
+func (f *FFT) transform(x []complex128, inverse bool) {
+	n := f.n
+
+	for i, j := range f.rev {
+		if i < j {
+			x[i], x[j] = x[j], x[i]
+		}
+	}
+
+	for size := 2; size <= n; size <<= 1 {
+		half := size / 2
+		stride := n / size
+
+		for start := 0; start < n; start += size {
+			for k := 0; k < half; k++ {
+				w := f.twiddle[k*stride]
+				if inverse {
+					w = cmplx.Conj(w)
+				}
+
+				a, b := x[start+k], x[start+k+half]*w
+				x[start+k] = a + b
+				x[start+k+half] = a - b
+			}
+		}
+	}
+}
+
+// Real 是实数输入的 FFT，内部使用长度减半的复数 FFT。
+type Real struct {
+	n       int
+	half    *FFT
+	buf     []complex128
+	twiddle []complex128
+}
+
+// NewReal 创建长度为 n 的实数 FFT，n 必须为 2 的幂且不小于 2。
+func NewReal(n int) (r *Real, err error) {
+	if n < 2 {
+		err = ErrSize
+		return
+	}
+
+	var half *FFT
+	if half, err = New(n / 2); err != nil {
+		return
+	}
+
+	r = &Real{
+		n:       n,
+		half:    half,
+		buf:     make([]complex128, n/2),
+		twiddle: make([]complex128, n/2),
+	}
+
+	for k := range r.twiddle {
+		r.twiddle[k] = cmplx.Rect(1, -2*math.Pi*float64(k)/float64(n))
+	}
+
+	return
+}
+
+// Len 方法返回变换长度。
+func (r *Real) Len() int {
+	return r.n
+}
+
+// Transform 方法计算实数序列 src (长度为 n) 的正变换，
+// 将 0 到 n/2 共 n/2+1 个频点写入 dst。
+func (r *Real) Transform(src []float64, dst []complex128) {
+	m := r.n / 2
+
+	for k := 0; k < m; k++ {
+		r.buf[k] = complex(src[2*k], src[2*k+1])
+	}
+
+	r.half.Transform(r.buf)
+
+	// 由偶数及奇数采样的变换合成完整的频谱
+	for k := 0; k <= m; k++ {
+		zk := r.buf[k%m]
+		zc := cmplx.Conj(r.buf[(m-k)%m])
+
+		even := (zk + zc) / 2
+		odd := (zk - zc) / complex(0, 2)
+
+		w := complex(-1, 0)
+		if k < m {
+			w = r.twiddle[k]
+		}
+
+		dst[k] = even + w*odd
+	}
+}
+
+// NextPow2 返回不小于 n 的最小的 2 的幂。
+func NextPow2(n int) int {
+	p := 1
+	for p < n {
+		p <<= 1
+	}
+
+	return p
+}
@@ -0,0 +1,148 @@
+package fft
+
+import (
+	"errors"
+	"math"
+	"math/cmplx"
+	"math/rand"
+	"testing"
+)
+
+// 直接按定义计算的 DFT
+func dft(x []complex128) []complex128 {
+	n := len(x)
+	out := make([]complex128, n)
+	for k := range out {
+		for t, v := range x {
+			out[k] += v * cmplx.Rect(1, -2*math.Pi*float64(k*t)/float64(n))
+		}
+	}
+
+	return out
+}
+
+func randomComplex(r *rand.Rand, n int) []complex128 {
+	x := make([]complex128, n)
+	for i := range x {
+		x[i] = complex(r.NormFloat64(), r.NormFloat64())
+	}
+
+	return x
+}
+
+func TestTransformMatchesDFT(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+
+	for _, n := range []int{1, 2, 4, 8, 64} {
+		f, err := New(n)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		x := randomComplex(r, n)
+		want := dft(x)
+		f.Transform(x)
+
+		for k := range x {
+			if cmplx.Abs(x[k]-want[k]) > 1e-9*float64(n) {
+				t.Fatalf("n=%d bin %d = %v, want %v", n, k, x[k], want[k])
+			}
+		}
+	}
+}
+
+func TestInverseRoundTrip(t *testing.T) {
+	r := rand.New(rand.NewSource(2))
+
+	for _, n := range []int{1, 2, 16, 1024, 8192} {
+		f, err := New(n)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		x := randomComplex(r, n)
+		y := append([]complex128(nil), x...)
+		f.Transform(y)
+		f.Inverse(y)
+
+		for i := range x {
+			if cmplx.Abs(y[i]-x[i]) > 1e-12*math.Log2(float64(n)+1) {
+				t.Fatalf("n=%d sample %d = %v, want %v", n, i, y[i], x[i])
+			}
+		}
+	}
+}
+
+func TestRealMatchesComplex(t *testing.T) {
+	r := rand.New(rand.NewSource(3))
+
+	for _, n := range []int{2, 4, 32, 2048} {
+		real, err := NewReal(n)
+		if err != nil {
+			t.Fatal(err)
+		}
+		complexFFT, _ := New(n)
+
+		src := make([]float64, n)
+		x := make([]complex128, n)
+		for i := range src {
+			src[i] = r.NormFloat64()
+			x[i] = complex(src[i], 0)
+		}
+
+		dst := make([]complex128, n/2+1)
+		real.Transform(src, dst)
+		complexFFT.Transform(x)
+
+		for k := range dst {
+			if cmplx.Abs(dst[k]-x[k]) > 1e-9*float64(n) {
+				t.Fatalf("n=%d bin %d = %v, want %v", n, k, dst[k], x[k])
+			}
+		}
+	}
+}
+
+func TestSineBin(t *testing.T) {
+	const n, bin = 1024, 37
+
+	real, err := NewReal(n)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	src := make([]float64, n)
+	for i := range src {
+		src[i] = math.Sin(2 * math.Pi * bin * float64(i) / n)
+	}
+
+	dst := make([]complex128, n/2+1)
+	real.Transform(src, dst)
+
+	// 整周期的正弦波只落在一个频点，幅度为 n/2
+	for k, x := range dst {
+		want := 0.0
+		if k == bin {
+			want = n / 2
+		}
+		if math.Abs(cmplx.Abs(x)-want) > 1e-9*n {
+			t.Fatalf("bin %d magnitude %v, want %v", k, cmplx.Abs(x), want)
+		}
+	}
+}
+
+func TestSize(t *testing.T) {
+	for _, n := range []int{0, 3, 12} {
+		if _, err := New(n); !errors.Is(err, ErrSize) {
+			t.Errorf("New(%d): err = %v, want ErrSize", n, err)
+		}
+	}
+	if _, err := NewReal(1); !errors.Is(err, ErrSize) {
+		t.Errorf("NewReal(1): err = %v, want ErrSize", err)
+	}
+
+	for n, want := range map[int]int{0: 1, 1: 1, 5: 8, 1024: 1024, 1025: 2048} {
+		if got := NextPow2(n); got != want {
+			t.Errorf("NextPow2(%d) = %d, want %d", n, got, want)
+		}
+	}
+}
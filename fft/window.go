@@ -0,0 +1,60 @@
+package fft
+
+import "math"
+
+// Window 表示窗函数。
+type Window uint32
+
+const (
+	WindowRectangular    Window = iota // 矩形窗
+	WindowHann                         // Hann 窗
+	WindowBlackmanHarris               // 4 项 Blackman-Harris 窗，旁瓣约 -92 dB
+	WindowFlatTop                      // 平顶窗，幅度测量误差最小
+)
+
+func (w Window) String() string {
+	switch w {
+	case WindowHann:
+		return "hann"
+	case WindowBlackmanHarris:
+		return "blackman-harris"
+	case WindowFlatTop:
+		return "flat-top"
+	}
+
+	return "rectangular"
+}
+
+// 余弦和窗的系数
+func (w Window) terms() []float64 {
+	switch w {
+	case WindowHann:
+		return []float64{0.5, 0.5}
+	case WindowBlackmanHarris:
+		return []float64{0.35875, 0.48829, 0.14128, 0.01168}
+	case WindowFlatTop:
+		return []float64{0.21557895, 0.41663158, 0.277263158, 0.083578947, 0.006947368}
+	}
+
+	return []float64{1}
+}
+
+// Coefficients 方法返回长度为 n 的周期窗函数系数，适用于频谱分析。
+func (w Window) Coefficients(n int) (coefficients []float64) {
+	terms := w.terms()
+	coefficients = make([]float64, n)
+
+	for i := range coefficients {
+		var v float64
+		sign := 1.0
+
+		for k, a := range terms {
+			v += sign * a * math.Cos(2*math.Pi*float64(k)*float64(i)/float64(n))
+			sign = -sign
+		}
+
+		coefficients[i] = v
+	}
+
+	return
+}
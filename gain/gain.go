@@ -0,0 +1,297 @@
+package gain
+
+import (
+	"errors"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/cyberxnomad/wasapi/audioclient"
+	"github.com/cyberxnomad/wasapi/internal/dsp"
+)
+
+var (
+	ErrInvalidChannel = errors.New("invalid channel index")
+	ErrInvalidLevel   = errors.New("invalid volume level")
+)
+
+// DefaultRamp 为音量变化的默认过渡时长。
+const DefaultRamp = 10 * time.Millisecond
+
+// Volume 与 audioclient.IAudioStreamVolume 的方法相同，
+// 便于在系统混音器的流音量与软件增益之间切换。
+type Volume interface {
+	GetChannelCount() (count uint32, err error)
+	SetChannelVolume(index uint32, level float32) (err error)
+	GetChannelVolume(index uint32) (level float32, err error)
+	SetAllVolumes(count uint32, levels []float32) (err error)
+	GetAllVolumes(count uint32) (levels []float32, err error)
+}
+
+var _ Volume = (*Stage)(nil)
+
+// 线性过渡的增益
+type ramp struct {
+	current float32
+	target  float32
+	step    float32
+	left    int
+}
+
+func (r *ramp) set(target float32, frames int) {
+	r.target = target
+	if frames <= 0 {
+		r.current = target
+		r.left = 0
+		return
+	}
+
+	r.step = (target - r.current) / float32(frames)
+	r.left = frames
+}
+
+func (r *ramp) next() float32 {
+	if r.left > 0 {
+		if r.left--; r.left == 0 {
+			r.current = r.target
+		} else {
+			r.current += r.step
+		}
+	}
+
+	return r.current
+}
+
+// Stage 是软件增益级，支持每声道增益、无咔嗒声的平滑过渡、淡入淡出及静音。
+//
+// 适用于独占模式或文件等没有系统混音器的场合，方法与 IAudioStreamVolume 相同。
+// 线性音量没有上限，大于 1 表示提升。
+type Stage struct {
+	mu sync.Mutex
+
+	format     audioclient.WAVEFORMATEXTENSIBLE
+	levels     []float32
+	gains      []ramp
+	fade       ramp
+	rampFrames int
+	muted      bool
+
+	buf []float32
+}
+
+// NewStage 创建增益级，所有声道的初始音量为 1。
+func NewStage(format *audioclient.WAVEFORMATEXTENSIBLE) *Stage {
+	s := &Stage{
+		format: *format,
+		levels: make([]float32, format.Format.Channels),
+		gains:  make([]ramp, format.Format.Channels),
+		fade:   ramp{current: 1, target: 1},
+	}
+
+	for i := range s.levels {
+		s.levels[i] = 1
+		s.gains[i] = ramp{current: 1, target: 1}
+	}
+
+	s.SetRamp(DefaultRamp)
+
+	return s
+}
+
+// SetRamp 方法设置音量变化的过渡时长。
+func (s *Stage) SetRamp(duration time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.rampFrames = s.frames(duration)
+}
+
+// GetChannelCount 方法返回声道数。
+func (s *Stage) GetChannelCount() (count uint32, err error) {
+	count = uint32(len(s.levels))
+	return
+}
+
+// SetChannelVolume 方法设置指定声道的线性音量。
+func (s *Stage) SetChannelVolume(index uint32, level float32) (err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if index >= uint32(len(s.levels)) {
+		err = ErrInvalidChannel
+		return
+	}
+
+	if !(level >= 0) {
+		err = ErrInvalidLevel
+		return
+	}
+
+	s.levels[index] = level
+	s.apply(int(index))
+
+	return
+}
+
+// GetChannelVolume 方法返回指定声道的线性音量。
+func (s *Stage) GetChannelVolume(index uint32) (level float32, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if index >= uint32(len(s.levels)) {
+		err = ErrInvalidChannel
+		return
+	}
+
+	level = s.levels[index]
+
+	return
+}
+
+// SetAllVolumes 方法设置所有声道的线性音量，count 必须等于声道数。
+func (s *Stage) SetAllVolumes(count uint32, levels []float32) (err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if count != uint32(len(s.levels)) || len(levels) < int(count) {
+		err = ErrInvalidChannel
+		return
+	}
+
+	for _, level := range levels[:count] {
+		if !(level >= 0) {
+			err = ErrInvalidLevel
+			return
+		}
+	}
+
+	copy(s.levels, levels)
+	for i := range s.levels {
+		s.apply(i)
+	}
+
+	return
+}
+
+// GetAllVolumes 方法返回所有声道的线性音量，count 必须等于声道数。
+func (s *Stage) GetAllVolumes(count uint32) (levels []float32, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if count != uint32(len(s.levels)) {
+		err = ErrInvalidChannel
+		return
+	}
+
+	levels = append([]float32(nil), s.levels...)
+
+	return
+}
+
+// SetChannelVolumeDB 方法以分贝设置指定声道的音量。
+func (s *Stage) SetChannelVolumeDB(index uint32, db float64) (err error) {
+	return s.SetChannelVolume(index, float32(DBToLinear(db)))
+}
+
+// GetChannelVolumeDB 方法以分贝返回指定声道的音量。
+func (s *Stage) GetChannelVolumeDB(index uint32) (db float64, err error) {
+	var level float32
+	if level, err = s.GetChannelVolume(index); err != nil {
+		return
+	}
+
+	db = LinearToDB(float64(level))
+
+	return
+}
+
+// SetMute 方法设置静音状态，静音及取消静音均平滑过渡。
+func (s *Stage) SetMute(mute bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.muted = mute
+	for i := range s.levels {
+		s.apply(i)
+	}
+}
+
+// GetMute 方法返回静音状态。
+func (s *Stage) GetMute() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.muted
+}
+
+// FadeIn 方法从静音开始在 duration 内淡入，通常在开始呈现时调用。
+func (s *Stage) FadeIn(duration time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.fade.current = 0
+	s.fade.set(1, s.frames(duration))
+}
+
+// FadeOut 方法在 duration 内淡出至静音，通常在停止呈现前调用，完成后 Faded 返回 true。
+func (s *Stage) FadeOut(duration time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.fade.set(0, s.frames(duration))
+}
+
+// Faded 方法返回淡出是否已完成。
+func (s *Stage) Faded() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.fade.target == 0 && s.fade.left == 0
+}
+
+// Process 方法对交错的 float32 采样原地应用增益。
+func (s *Stage) Process(samples []float32) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.process(samples)
+}
+
+// ProcessBytes 方法按格式解码、应用增益后原地编码音频数据。
+func (s *Stage) ProcessBytes(data []byte) (err error) {
+	return dsp.ProcessBytes(&s.mu, &s.format, &s.buf, data, true, s.process)
+}
+
+func (s *Stage) process(samples []float32) {
+	channels := len(s.gains)
+	for i := 0; i+channels <= len(samples); i += channels {
+		fade := s.fade.next()
+		for c := range s.gains {
+			samples[i+c] *= s.gains[c].next() * fade
+		}
+	}
+}
+
+// 根据音量及静音状态更新声道的目标增益
+func (s *Stage) apply(index int) {
+	target := s.levels[index]
+	if s.muted {
+		target = 0
+	}
+
+	s.gains[index].set(target, s.rampFrames)
+}
+
+func (s *Stage) frames(duration time.Duration) int {
+	return int(duration.Seconds() * float64(s.format.Format.SamplesPerSec))
+}
+
+// DBToLinear 将分贝换算为线性增益。
+func DBToLinear(db float64) float64 {
+	return math.Pow(10, db/20)
+}
+
+// LinearToDB 将线性增益换算为分贝，0 返回负无穷。
+func LinearToDB(level float64) float64 {
+	return 20 * math.Log10(level)
+}
@@ -0,0 +1,5 @@
+package gain
+
+import "github.com/cyberxnomad/wasapi/audioclient"
+
+var _ Volume = (*audioclient.IAudioStreamVolume)(nil)
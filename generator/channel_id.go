@@ -0,0 +1,95 @@
+package generator
+
+import (
+	"time"
+
+	"github.com/cyberxnomad/wasapi/audioclient"
+	"github.com/cyberxnomad/wasapi/pcm"
+)
+
+// ChannelIdentification 依次在每个声道上播放不含语音的提示音序列以识别声道。
+//
+// 第 n 个声道 (从 0 开始) 播放 n+1 声短音，之后停顿并切换到下一个声道，循环往复。
+type ChannelIdentification struct {
+	format   audioclient.WAVEFORMATEXTENSIBLE
+	channels int
+	speakers []uint32
+	tone     *Oscillator
+
+	beepFrames  int
+	gapFrames   int
+	pauseFrames int
+	rampFrames  int
+
+	channel int
+	pos     int
+	buf     []float32
+}
+
+// NewChannelIdentification 创建声道识别序列，frequency 为提示音频率，
+// beep 为每声短音的时长，pause 为切换声道前的停顿。
+func NewChannelIdentification(format *audioclient.WAVEFORMATEXTENSIBLE, frequency float64, beep, pause time.Duration) *ChannelIdentification {
+	rate := float64(format.Format.SamplesPerSec)
+	beepFrames := max(int(beep.Seconds()*rate), 1)
+
+	return &ChannelIdentification{
+		format:      *format,
+		channels:    int(format.Format.Channels),
+		speakers:    pcm.Speakers(format),
+		tone:        NewOscillator(WaveSine, format.Format.SamplesPerSec, frequency, 0.5),
+		beepFrames:  beepFrames,
+		gapFrames:   beepFrames,
+		pauseFrames: int(pause.Seconds() * rate),
+		rampFrames:  min(int(rate*0.005), beepFrames/2),
+	}
+}
+
+// Current 方法返回当前正在识别的声道索引及其扬声器位置，声道未指定位置时扬声器位置为 0。
+func (id *ChannelIdentification) Current() (channel int, speaker uint32) {
+	return id.channel, id.speakers[id.channel]
+}
+
+// Read 方法生成交错的 float32 帧写入 dst，返回帧数。
+func (id *ChannelIdentification) Read(dst []float32) (frames int) {
+	frames = len(dst) / id.channels
+	clear(dst[:frames*id.channels])
+
+	for i := 0; i < frames; i++ {
+		beeps := id.channel + 1
+		slot := id.beepFrames + id.gapFrames
+		sequence := beeps*slot - id.gapFrames + id.pauseFrames
+
+		if offset := id.pos % slot; id.pos < beeps*slot && offset < id.beepFrames {
+			gain := float32(1)
+			if offset < id.rampFrames {
+				gain = float32(offset) / float32(id.rampFrames)
+			} else if j := id.beepFrames - 1 - offset; j < id.rampFrames {
+				gain = float32(j) / float32(id.rampFrames)
+			}
+
+			dst[i*id.channels+id.channel] = id.tone.Next() * gain
+		}
+
+		if id.pos++; id.pos >= sequence {
+			id.pos = 0
+			id.channel = (id.channel + 1) % id.channels
+			id.tone.Reset()
+		}
+	}
+
+	return
+}
+
+// ReadBytes 方法生成帧并按格式编码写入 dst，返回帧数。
+func (id *ChannelIdentification) ReadBytes(dst []byte) (frames int, err error) {
+	frames = pcm.Frames(&id.format, len(dst))
+	if n := frames * id.channels; cap(id.buf) < n {
+		id.buf = make([]float32, n)
+	}
+
+	buf := id.buf[:frames*id.channels]
+	id.Read(buf)
+	_, err = pcm.Encode(&id.format, buf, dst)
+
+	return
+}
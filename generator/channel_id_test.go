@@ -0,0 +1,61 @@
+package generator
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cyberxnomad/wasapi/audioclient"
+	"github.com/cyberxnomad/wasapi/pcm"
+)
+
+func TestChannelIdentificationPartialMask(t *testing.T) {
+	// 双声道格式但声道掩码只指定了中置
+	format := pcm.NewFormat(pcm.SampleTypeFloat, 8000, 2, 32, audioclient.SPEAKER_FRONT_CENTER)
+	id := NewChannelIdentification(&format, 1000, 10*time.Millisecond, 20*time.Millisecond)
+
+	if channel, speaker := id.Current(); channel != 0 || speaker != audioclient.SPEAKER_FRONT_CENTER {
+		t.Fatalf("Current = %d, %X", channel, speaker)
+	}
+
+	// 第一个声道: 1 声短音 (80 帧) + 停顿 (160 帧)
+	buf := make([]float32, 240*2)
+	id.Read(buf)
+
+	var energy [2]float64
+	for i := 0; i < 240; i++ {
+		for c := 0; c < 2; c++ {
+			energy[c] += float64(buf[i*2+c] * buf[i*2+c])
+		}
+	}
+	if energy[0] == 0 || energy[1] != 0 {
+		t.Fatalf("first sequence energy %v, want only channel 0", energy)
+	}
+
+	if channel, speaker := id.Current(); channel != 1 || speaker != 0 {
+		t.Fatalf("Current = %d, %X, want 1 and 0", channel, speaker)
+	}
+
+	// 第二个声道: 2 声短音
+	buf = make([]float32, 400*2)
+	id.Read(buf)
+
+	beeps, on := 0, false
+	for i := 0; i < 400; i++ {
+		if buf[i*2] != 0 {
+			t.Fatalf("frame %d: channel 0 not silent", i)
+		}
+		if v := buf[i*2+1] != 0; v && !on {
+			beeps++
+			on = true
+		} else if !v && i > 0 && buf[(i-1)*2+1] == 0 {
+			on = false
+		}
+	}
+	if beeps != 2 {
+		t.Fatalf("channel 1 played %d beeps, want 2", beeps)
+	}
+
+	if channel, _ := id.Current(); channel != 0 {
+		t.Fatalf("sequence did not wrap to channel 0, at %d", channel)
+	}
+}
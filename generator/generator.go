@@ -0,0 +1,77 @@
+package generator
+
+import (
+	"github.com/cyberxnomad/wasapi/audioclient"
+	"github.com/cyberxnomad/wasapi/pcm"
+)
+
+// Generator 将单声道信号按声道路由写入交错的音频缓冲区。
+type Generator struct {
+	format   audioclient.WAVEFORMATEXTENSIBLE
+	channels int
+	signal   Signal
+	enabled  []bool
+	buf      []float32
+}
+
+// NewGenerator 创建生成器，默认输出到所有声道。
+func NewGenerator(format *audioclient.WAVEFORMATEXTENSIBLE, signal Signal) *Generator {
+	g := &Generator{
+		format:   *format,
+		channels: int(format.Format.Channels),
+		signal:   signal,
+	}
+	g.SetChannelMask(0)
+
+	return g
+}
+
+// SetSignal 方法替换信号源。
+func (g *Generator) SetSignal(signal Signal) {
+	g.signal = signal
+}
+
+// SetChannelMask 方法设置输出的扬声器位置 (SPEAKER_*)，0 表示所有声道。
+//
+// 格式未指定声道掩码时，掩码的第 n 位对应第 n 个声道。
+func (g *Generator) SetChannelMask(channelMask uint32) {
+	g.enabled = make([]bool, g.channels)
+
+	for i, speaker := range pcm.Speakers(&g.format) {
+		g.enabled[i] = channelMask == 0 || channelMask&speaker != 0
+	}
+}
+
+// Read 方法生成交错的 float32 帧写入 dst，返回帧数。
+func (g *Generator) Read(dst []float32) (frames int) {
+	frames = len(dst) / g.channels
+
+	for i := 0; i < frames; i++ {
+		v := g.signal.Next()
+		frame := dst[i*g.channels : (i+1)*g.channels]
+
+		for c := range frame {
+			if g.enabled[c] {
+				frame[c] = v
+			} else {
+				frame[c] = 0
+			}
+		}
+	}
+
+	return
+}
+
+// ReadBytes 方法生成帧并按格式编码写入 dst，返回帧数。
+func (g *Generator) ReadBytes(dst []byte) (frames int, err error) {
+	frames = pcm.Frames(&g.format, len(dst))
+	if n := frames * g.channels; cap(g.buf) < n {
+		g.buf = make([]float32, n)
+	}
+
+	buf := g.buf[:frames*g.channels]
+	g.Read(buf)
+	_, err = pcm.Encode(&g.format, buf, dst)
+
+	return
+}
@@ -0,0 +1,238 @@
+package generator
+
+import (
+	"math"
+	"math/rand"
+	"time"
+)
+
+// Signal 逐个产生单声道采样。
+type Signal interface {
+	Next() float32
+}
+
+// Waveform 表示周期波形。
+type Waveform uint32
+
+const (
+	WaveSine     Waveform = iota // 正弦波
+	WaveSquare                   // 方波
+	WaveSaw                      // 锯齿波
+	WaveTriangle                 // 三角波
+)
+
+// Oscillator 产生周期波形。
+type Oscillator struct {
+	waveform      Waveform
+	samplesPerSec float64
+	step          float64
+	phase         float64 // 归一化相位，范围为 [0, 1)
+	amplitude     float64
+}
+
+// NewOscillator 创建振荡器，amplitude 为线性幅度 (满幅为 1)。
+func NewOscillator(waveform Waveform, samplesPerSec uint32, frequency, amplitude float64) *Oscillator {
+	return &Oscillator{
+		waveform:      waveform,
+		samplesPerSec: float64(samplesPerSec),
+		step:          frequency / float64(samplesPerSec),
+		amplitude:     amplitude,
+	}
+}
+
+// SetFrequency 方法设置频率，相位保持连续。
+func (o *Oscillator) SetFrequency(frequency float64) {
+	o.step = frequency / o.samplesPerSec
+}
+
+// SetAmplitude 方法设置线性幅度。
+func (o *Oscillator) SetAmplitude(amplitude float64) {
+	o.amplitude = amplitude
+}
+
+// Reset 方法将相位归零。
+func (o *Oscillator) Reset() {
+	o.phase = 0
+}
+
+func (o *Oscillator) Next() float32 {
+	var v float64
+
+	switch p := o.phase; o.waveform {
+	case WaveSquare:
+		v = 1
+		if p >= 0.5 {
+			v = -1
+		}
+	case WaveSaw:
+		v = 2*p - 1
+	case WaveTriangle:
+		v = 1 - 4*math.Abs(p-0.5)
+	default:
+		v = math.Sin(2 * math.Pi * p)
+	}
+
+	o.phase += o.step
+	o.phase -= math.Floor(o.phase)
+
+	return float32(v * o.amplitude)
+}
+
+// NoiseColor 表示噪声的频谱。
+type NoiseColor uint32
+
+const (
+	NoiseWhite NoiseColor = iota // 白噪声，功率谱平坦
+	NoisePink                    // 粉红噪声，每倍频程 -3 dB
+	NoiseBrown                   // 布朗噪声，每倍频程 -6 dB
+)
+
+// Noise 产生噪声。
+type Noise struct {
+	color     NoiseColor
+	amplitude float64
+	rand      *rand.Rand
+
+	b   [7]float64 // 粉红噪声滤波器状态
+	sum float64    // 布朗噪声积分器
+}
+
+// NewNoise 创建噪声源，amplitude 为近似峰值幅度，seed 为随机数种子。
+func NewNoise(color NoiseColor, amplitude float64, seed int64) *Noise {
+	return &Noise{
+		color:     color,
+		amplitude: amplitude,
+		rand:      rand.New(rand.NewSource(seed)),
+	}
+}
+
+func (n *Noise) Next() float32 {
+	white := n.rand.Float64()*2 - 1
+
+	var v float64
+	switch n.color {
+	case NoisePink:
+		// Paul Kellet 的粉红噪声滤波器
+		b := &n.b
+		b[0] = 0.99886*b[0] + white*0.0555179
+		b[1] = 0.99332*b[1] + white*0.0750759
+		b[2] = 0.96900*b[2] + white*0.1538520
+		b[3] = 0.86650*b[3] + white*0.3104856
+		b[4] = 0.55000*b[4] + white*0.5329522
+		b[5] = -0.7616*b[5] - white*0.0168980
+		v = (b[0] + b[1] + b[2] + b[3] + b[4] + b[5] + b[6] + white*0.5362) * 0.11
+		b[6] = white * 0.115926
+	case NoiseBrown:
+		// 带泄漏的积分器，避免直流漂移
+		n.sum = 0.998*n.sum + white*0.025
+		v = n.sum
+	default:
+		v = white
+	}
+
+	return float32(min(max(v, -1), 1) * n.amplitude)
+}
+
+// Sweep 产生对数 (指数) 扫频正弦信号，扫频结束后从头重复。
+type Sweep struct {
+	samplesPerSec float64
+	f0, f1        float64
+	frames        int
+	amplitude     float64
+	linear        bool
+
+	pos   int
+	phase float64
+}
+
+// NewSweep 创建从 f0 到 f1、时长为 duration 的对数扫频。
+//
+// 对数扫频要求 f0 及 f1 均为正数，否则改为线性扫频。
+func NewSweep(samplesPerSec uint32, f0, f1 float64, duration time.Duration, amplitude float64) *Sweep {
+	return &Sweep{
+		linear:        f0 <= 0 || f1 <= 0,
+		samplesPerSec: float64(samplesPerSec),
+		f0:            f0,
+		f1:            f1,
+		frames:        max(int(duration.Seconds()*float64(samplesPerSec)), 1),
+		amplitude:     amplitude,
+	}
+}
+
+// Frequency 方法返回当前的瞬时频率。
+func (s *Sweep) Frequency() float64 {
+	if s.linear {
+		return s.f0 + (s.f1-s.f0)*float64(s.pos)/float64(s.frames)
+	}
+
+	return s.f0 * math.Pow(s.f1/s.f0, float64(s.pos)/float64(s.frames))
+}
+
+func (s *Sweep) Next() float32 {
+	v := math.Sin(2 * math.Pi * s.phase)
+
+	s.phase += s.Frequency() / s.samplesPerSec
+	s.phase -= math.Floor(s.phase)
+
+	if s.pos++; s.pos >= s.frames {
+		s.pos = 0
+		s.phase = 0
+	}
+
+	return float32(v * s.amplitude)
+}
+
+// Impulse 产生周期性的单采样脉冲。
+type Impulse struct {
+	period    int
+	amplitude float32
+	pos       int
+}
+
+// NewImpulse 创建间隔为 period 的脉冲序列，第一个采样即为脉冲。
+func NewImpulse(samplesPerSec uint32, period time.Duration, amplitude float64) *Impulse {
+	return &Impulse{
+		period:    max(int(period.Seconds()*float64(samplesPerSec)), 1),
+		amplitude: float32(amplitude),
+	}
+}
+
+func (i *Impulse) Next() (v float32) {
+	if i.pos == 0 {
+		v = i.amplitude
+	}
+
+	i.pos = (i.pos + 1) % i.period
+
+	return
+}
+
+// MultiTone 产生多个等幅正弦波之和。
+type MultiTone struct {
+	oscillators []*Oscillator
+}
+
+// NewMultiTone 创建多音信号，amplitude 为总的峰值幅度上限。
+//
+// 各分量采用 Schroeder 相位以降低峰值因数。
+func NewMultiTone(samplesPerSec uint32, frequencies []float64, amplitude float64) *MultiTone {
+	m := &MultiTone{}
+	n := len(frequencies)
+
+	for k, f := range frequencies {
+		o := NewOscillator(WaveSine, samplesPerSec, f, amplitude/float64(n))
+		o.phase = float64(k*(k+1)) / (2 * float64(n))
+		o.phase -= math.Floor(o.phase)
+		m.oscillators = append(m.oscillators, o)
+	}
+
+	return m
+}
+
+func (m *MultiTone) Next() (v float32) {
+	for _, o := range m.oscillators {
+		v += o.Next()
+	}
+
+	return
+}
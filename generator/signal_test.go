@@ -0,0 +1,36 @@
+package generator
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestSweep(t *testing.T) {
+	// 对数扫频在中点处为几何平均频率
+	s := NewSweep(1000, 10, 1000, time.Second, 1)
+	for i := 0; i < 500; i++ {
+		s.Next()
+	}
+	if f := s.Frequency(); math.Abs(f-100) > 1e-9 {
+		t.Errorf("log sweep at half = %v Hz, want 100", f)
+	}
+
+	// f0 为 0 时无法对数扫频，改为线性扫频且不产生 NaN
+	s = NewSweep(1000, 0, 400, time.Second, 1)
+	for i := 0; i < 1000; i++ {
+		if i == 250 {
+			if f := s.Frequency(); math.Abs(f-100) > 1e-9 {
+				t.Errorf("linear sweep at quarter = %v Hz, want 100", f)
+			}
+		}
+		if v := s.Next(); math.IsNaN(float64(v)) || v < -1 || v > 1 {
+			t.Fatalf("sample %d = %v", i, v)
+		}
+	}
+
+	// 结束后从头重复
+	if f := s.Frequency(); f != 0 {
+		t.Errorf("frequency after wrap = %v, want 0", f)
+	}
+}
@@ -0,0 +1,99 @@
+package graph
+
+import (
+	"fmt"
+
+	"github.com/cyberxnomad/wasapi/audioclient"
+	"github.com/cyberxnomad/wasapi/pcm"
+	"github.com/cyberxnomad/wasapi/resample"
+)
+
+// Resampler 是采样率转换节点。
+type Resampler struct {
+	samplesPerSec uint32
+	resampler     *resample.Resampler
+	buf           []float32
+}
+
+// NewResampler 创建采样率转换节点，samplesPerSec 为输出采样率。
+func NewResampler(samplesPerSec uint32) *Resampler {
+	return &Resampler{samplesPerSec: samplesPerSec}
+}
+
+// Name 方法实现 Node 接口。
+func (r *Resampler) Name() string {
+	return fmt.Sprintf("resample %d Hz", r.samplesPerSec)
+}
+
+// Accepts 方法实现 Processor 接口。
+func (r *Resampler) Accepts() Constraint {
+	return Any
+}
+
+// Configure 方法实现 Processor 接口。
+func (r *Resampler) Configure(input *audioclient.WAVEFORMATEXTENSIBLE, maxFrames int) (output audioclient.WAVEFORMATEXTENSIBLE, err error) {
+	channels := int(input.Format.Channels)
+	ratio := float64(r.samplesPerSec) / float64(input.Format.SamplesPerSec)
+
+	r.resampler = resample.New(channels, input.Format.SamplesPerSec, r.samplesPerSec)
+	r.buf = make([]float32, 0, (int(float64(maxFrames)*ratio)+2)*channels)
+	output = floatFormat(r.samplesPerSec, input.Format.Channels, channelMask(input))
+
+	return
+}
+
+// Process 方法实现 Processor 接口。
+func (r *Resampler) Process(src []float32) []float32 {
+	r.buf = r.resampler.Process(r.buf[:0], src)
+	return r.buf
+}
+
+// ChannelMixer 是声道映射节点，按 pcm.ChannelMatrix 转换声道布局。
+type ChannelMixer struct {
+	channelMask uint32
+	channels    uint16
+	matrix      [][]float32
+	outChannels int
+	inChannels  int
+	buf         []float32
+}
+
+// NewChannelMixer 创建声道映射节点，channelMask 及 channels 为输出的声道布局。
+func NewChannelMixer(channelMask uint32, channels uint16) *ChannelMixer {
+	return &ChannelMixer{channelMask: channelMask, channels: channels}
+}
+
+// Name 方法实现 Node 接口。
+func (m *ChannelMixer) Name() string {
+	return fmt.Sprintf("remix %d ch", m.channels)
+}
+
+// Accepts 方法实现 Processor 接口。
+func (m *ChannelMixer) Accepts() Constraint {
+	return Any
+}
+
+// Configure 方法实现 Processor 接口。
+func (m *ChannelMixer) Configure(input *audioclient.WAVEFORMATEXTENSIBLE, maxFrames int) (output audioclient.WAVEFORMATEXTENSIBLE, err error) {
+	in := internal(input)
+	output = floatFormat(input.Format.SamplesPerSec, m.channels, m.channelMask)
+
+	m.matrix = pcm.ChannelMatrix(&in, &output)
+	m.inChannels = int(input.Format.Channels)
+	m.outChannels = int(m.channels)
+	m.buf = make([]float32, maxFrames*m.outChannels)
+
+	return
+}
+
+// Process 方法实现 Processor 接口。
+func (m *ChannelMixer) Process(src []float32) []float32 {
+	frames := len(src) / m.inChannels
+	if cap(m.buf) < frames*m.outChannels {
+		m.buf = make([]float32, frames*m.outChannels)
+	}
+
+	n := pcm.Remix(m.matrix, src, m.buf[:frames*m.outChannels])
+
+	return m.buf[:n*m.outChannels]
+}
@@ -0,0 +1,67 @@
+package graph
+
+import "github.com/cyberxnomad/wasapi/audioclient"
+
+// InPlace 是原地处理交错 float32 采样的效果器，
+// 例如 eq.Equalizer、dynamics.Limiter、gain.Stage 及 denoise.Suppressor。
+type InPlace interface {
+	Process(samples []float32)
+}
+
+// Effect 是包装 InPlace 效果器的处理节点，输出格式与输入相同。
+type Effect struct {
+	name    string
+	accepts Constraint
+	factory func(format *audioclient.WAVEFORMATEXTENSIBLE) (InPlace, error)
+	effect  InPlace
+	buf     []float32
+}
+
+// NewEffect 创建效果节点，factory 在 Build 时以协商后的格式创建效果器。
+func NewEffect(name string, accepts Constraint, factory func(format *audioclient.WAVEFORMATEXTENSIBLE) (InPlace, error)) *Effect {
+	return &Effect{
+		name:    name,
+		accepts: accepts,
+		factory: factory,
+	}
+}
+
+// Effect 方法返回 Build 时创建的效果器，用于调整参数。
+func (e *Effect) Effect() InPlace {
+	return e.effect
+}
+
+// Name 方法实现 Node 接口。
+func (e *Effect) Name() string {
+	return e.name
+}
+
+// Accepts 方法实现 Processor 接口。
+func (e *Effect) Accepts() Constraint {
+	return e.accepts
+}
+
+// Configure 方法实现 Processor 接口。
+func (e *Effect) Configure(input *audioclient.WAVEFORMATEXTENSIBLE, maxFrames int) (output audioclient.WAVEFORMATEXTENSIBLE, err error) {
+	if e.effect, err = e.factory(input); err != nil {
+		return
+	}
+
+	e.buf = make([]float32, maxFrames*int(input.Format.Channels))
+	output = *input
+
+	return
+}
+
+// Process 方法实现 Processor 接口，效果器作用于副本，不修改输入。
+func (e *Effect) Process(src []float32) []float32 {
+	if cap(e.buf) < len(src) {
+		e.buf = make([]float32, len(src))
+	}
+
+	buf := e.buf[:len(src)]
+	copy(buf, src)
+	e.effect.Process(buf)
+
+	return buf
+}
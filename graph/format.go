@@ -0,0 +1,140 @@
+package graph
+
+import (
+	"fmt"
+	"math/bits"
+	"slices"
+
+	"github.com/cyberxnomad/wasapi/audioclient"
+	"github.com/cyberxnomad/wasapi/pcm"
+)
+
+// Constraint 描述节点接受的输入格式，各字段为空时不限。
+//
+// 图内部传递的采样均为交错的 float32，因此只约束采样率及声道布局。
+type Constraint struct {
+	SampleRates []uint32 // 接受的采样率
+	Channels    []uint16 // 接受的声道数
+	ChannelMask uint32   // 要求的声道掩码
+}
+
+// Any 接受任意格式。
+var Any = Constraint{}
+
+// Exact 返回只接受 format 的采样率及声道布局的约束。
+func Exact(format *audioclient.WAVEFORMATEXTENSIBLE) Constraint {
+	return Constraint{
+		SampleRates: []uint32{format.Format.SamplesPerSec},
+		Channels:    []uint16{format.Format.Channels},
+		ChannelMask: channelMask(format),
+	}
+}
+
+// Accepts 方法返回是否接受 format。
+func (c Constraint) Accepts(format *audioclient.WAVEFORMATEXTENSIBLE) bool {
+	if len(c.SampleRates) > 0 && !slices.Contains(c.SampleRates, format.Format.SamplesPerSec) {
+		return false
+	}
+
+	if len(c.Channels) > 0 && !slices.Contains(c.Channels, format.Format.Channels) {
+		return false
+	}
+
+	return c.ChannelMask == 0 || c.ChannelMask == channelMask(format)
+}
+
+// String 方法返回约束的描述。
+func (c Constraint) String() string {
+	rates, channels, mask := "any", "any", "any"
+	if len(c.SampleRates) > 0 {
+		rates = fmt.Sprint(c.SampleRates)
+	}
+	if len(c.Channels) > 0 {
+		channels = fmt.Sprint(c.Channels)
+	}
+	if c.ChannelMask != 0 {
+		mask = fmt.Sprintf("0x%X", c.ChannelMask)
+	}
+
+	return fmt.Sprintf("rate %s, channels %s, mask %s", rates, channels, mask)
+}
+
+// 返回满足约束且最接近 format 的格式，约束无法满足时 ok 为 false
+func (c Constraint) nearest(format *audioclient.WAVEFORMATEXTENSIBLE) (out audioclient.WAVEFORMATEXTENSIBLE, ok bool) {
+	rate := format.Format.SamplesPerSec
+	if len(c.SampleRates) > 0 && !slices.Contains(c.SampleRates, rate) {
+		// 优先选择不低于原采样率的最小值，避免丢失频带
+		var above, below uint32
+		for _, r := range c.SampleRates {
+			if r >= rate && (above == 0 || r < above) {
+				above = r
+			}
+			if r < rate && r > below {
+				below = r
+			}
+		}
+
+		if rate = above; rate == 0 {
+			rate = below
+		}
+	}
+
+	channels, mask := format.Format.Channels, channelMask(format)
+	switch {
+	case c.ChannelMask != 0:
+		mask = c.ChannelMask
+		channels = uint16(bits.OnesCount32(mask))
+		if len(c.Channels) > 0 && !slices.Contains(c.Channels, channels) {
+			return
+		}
+
+	case len(c.Channels) > 0 && !slices.Contains(c.Channels, channels):
+		// 选择声道数最接近的，相同时取较多的
+		best := c.Channels[0]
+		for _, n := range c.Channels[1:] {
+			if d, bd := distance(n, channels), distance(best, channels); d < bd || d == bd && n > best {
+				best = n
+			}
+		}
+		channels = best
+		mask = pcm.DefaultChannelMask(int(channels))
+	}
+
+	if rate == 0 || channels == 0 {
+		return
+	}
+
+	return floatFormat(rate, channels, mask), true
+}
+
+func distance(a, b uint16) int {
+	if a > b {
+		return int(a - b)
+	}
+
+	return int(b - a)
+}
+
+// 图内部使用的 float32 格式
+func floatFormat(samplesPerSec uint32, channels uint16, channelMask uint32) audioclient.WAVEFORMATEXTENSIBLE {
+	return pcm.NewFormat(pcm.SampleTypeFloat, samplesPerSec, channels, 32, channelMask)
+}
+
+// 转换为图内部使用的 float32 格式，保留采样率及声道布局
+func internal(format *audioclient.WAVEFORMATEXTENSIBLE) audioclient.WAVEFORMATEXTENSIBLE {
+	return floatFormat(format.Format.SamplesPerSec, format.Format.Channels, channelMask(format))
+}
+
+// 格式的声道掩码，未指定时采用声道数对应的默认布局
+func channelMask(format *audioclient.WAVEFORMATEXTENSIBLE) uint32 {
+	if format.Format.FormatTag == audioclient.WAVE_FORMAT_EXTENSIBLE && format.ChannelMask != 0 {
+		return format.ChannelMask
+	}
+
+	return pcm.DefaultChannelMask(int(format.Format.Channels))
+}
+
+// 格式的简短描述
+func describe(format *audioclient.WAVEFORMATEXTENSIBLE) string {
+	return fmt.Sprintf("%d Hz, %d ch, mask 0x%X", format.Format.SamplesPerSec, format.Format.Channels, channelMask(format))
+}
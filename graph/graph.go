@@ -0,0 +1,547 @@
+package graph
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cyberxnomad/wasapi/audioclient"
+)
+
+var (
+	ErrNotBuilt    = errors.New("graph not built")
+	ErrUnknownNode = errors.New("node not in graph")
+	ErrNodeType    = errors.New("invalid node type for connection")
+	ErrConnected   = errors.New("node already has an input")
+	ErrNoInput     = errors.New("node has no input")
+	ErrCycle       = errors.New("graph contains a cycle")
+	ErrFormat      = errors.New("no acceptable format")
+)
+
+// DefaultBlock 为 Tick 时每个音源默认读取的时长。
+const DefaultBlock = 10 * time.Millisecond
+
+// Node 是图中的节点。
+type Node interface {
+	Name() string
+}
+
+// Source 是产生音频的节点。
+//
+// Format 返回音源的格式，Read 读取该格式采样率及声道布局的交错 float32 采样，返回帧数，
+// 结束时返回 io.EOF。由 Graph.Push 送入数据的音源在 Read 中返回 0 帧。
+type Source interface {
+	Node
+	Format() audioclient.WAVEFORMATEXTENSIBLE
+	Read(dst []float32) (frames int, err error)
+}
+
+// Processor 是处理音频的节点。
+//
+// Configure 在 Build 时以协商后的输入格式及单次处理的最大帧数调用，返回输出格式，
+// 之后 Process 不应再分配内存。Process 不得修改 src，返回的切片在下次调用前有效。
+type Processor interface {
+	Node
+	Accepts() Constraint
+	Configure(input *audioclient.WAVEFORMATEXTENSIBLE, maxFrames int) (output audioclient.WAVEFORMATEXTENSIBLE, err error)
+	Process(src []float32) (dst []float32)
+}
+
+// Sink 是消费音频的节点，Write 不得修改 src。
+type Sink interface {
+	Node
+	Accepts() Constraint
+	Configure(input *audioclient.WAVEFORMATEXTENSIBLE, maxFrames int) (err error)
+	Write(src []float32) (err error)
+}
+
+type vertex struct {
+	node      Node
+	input     *vertex
+	outputs   []*vertex
+	auto      bool                             // 由 Build 自动插入的转换器
+	format    audioclient.WAVEFORMATEXTENSIBLE // 输出格式，接收端为输入格式
+	maxFrames int                              // 单次输出的最大帧数
+	buf       []float32                        // 音源的读取缓冲
+	eof       bool
+}
+
+// Graph 是音频处理图。
+//
+// 每个处理节点及接收端有且只有一个输入，节点的输出可以连接到多个节点。Build 从各音源出发协商格式，
+// 输入格式不满足节点的约束时自动插入采样率转换及声道映射节点。
+//
+// 推模型下由 Push 送入捕获数据，或由 Tick 从音源读取一块数据，沿图传递到所有接收端；
+// 拉模型下由 Pull 按需调用 Tick，直到 Output 中有足够的数据。
+type Graph struct {
+	mu sync.Mutex
+
+	block    time.Duration
+	vertices []*vertex
+	index    map[Node]*vertex
+	built    bool
+}
+
+// New 创建处理图，block 为 Tick 时每个音源读取的时长，为 0 时使用 DefaultBlock。
+func New(block time.Duration) *Graph {
+	if block <= 0 {
+		block = DefaultBlock
+	}
+
+	return &Graph{
+		block: block,
+		index: make(map[Node]*vertex),
+	}
+}
+
+// Add 方法添加节点，已添加的节点会被忽略。
+func (g *Graph) Add(nodes ...Node) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	for _, node := range nodes {
+		g.add(node)
+	}
+}
+
+func (g *Graph) add(node Node) *vertex {
+	if v, ok := g.index[node]; ok {
+		return v
+	}
+
+	v := &vertex{node: node}
+	g.vertices = append(g.vertices, v)
+	g.index[node] = v
+	g.built = false
+
+	return v
+}
+
+// Connect 方法连接两个节点，节点未添加时自动添加。
+func (g *Graph) Connect(from, to Node) (err error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	return g.connect(from, to)
+}
+
+func (g *Graph) connect(from, to Node) (err error) {
+	switch from.(type) {
+	case Source, Processor:
+	default:
+		return fmt.Errorf("%w: %s has no output", ErrNodeType, from.Name())
+	}
+
+	switch to.(type) {
+	case Processor, Sink:
+	default:
+		return fmt.Errorf("%w: %s has no input", ErrNodeType, to.Name())
+	}
+
+	g.removeConverters()
+
+	src, dst := g.add(from), g.add(to)
+	if dst.input != nil {
+		return fmt.Errorf("%w: %s", ErrConnected, to.Name())
+	}
+
+	dst.input = src
+	src.outputs = append(src.outputs, dst)
+	g.built = false
+
+	return
+}
+
+// Chain 方法依次连接节点。
+func (g *Graph) Chain(nodes ...Node) (err error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	for i := 1; i < len(nodes); i++ {
+		if err = g.connect(nodes[i-1], nodes[i]); err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+// Build 方法检查图的结构，协商格式并配置所有节点。
+//
+// 可以在不打开任何设备的情况下调用，用于验证图的描述；修改连接后需要重新调用。
+func (g *Graph) Build() (err error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.removeConverters()
+	g.built = false
+
+	for _, v := range g.vertices {
+		if _, ok := v.node.(Source); !ok && v.input == nil {
+			return fmt.Errorf("%w: %s", ErrNoInput, v.node.Name())
+		}
+
+		// 沿输入回溯，超过节点数仍未到达音源即存在环
+		n, u := 0, v
+		for ; u.input != nil && n <= len(g.vertices); u = u.input {
+			n++
+		}
+		if u.input != nil {
+			return fmt.Errorf("%w: %s", ErrCycle, v.node.Name())
+		}
+	}
+
+	for _, v := range g.vertices {
+		source, ok := v.node.(Source)
+		if !ok {
+			continue
+		}
+
+		format := source.Format()
+		v.format = internal(&format)
+		v.maxFrames = g.frames(format.Format.SamplesPerSec)
+		v.buf = make([]float32, v.maxFrames*int(format.Format.Channels))
+		v.eof = false
+
+		for _, out := range append([]*vertex(nil), v.outputs...) {
+			if err = g.configure(v, out); err != nil {
+				return
+			}
+		}
+	}
+
+	g.built = true
+
+	return
+}
+
+// 配置 from 到 to 的连接，必要时插入转换器
+func (g *Graph) configure(from, to *vertex) (err error) {
+	var accepts Constraint
+	switch node := to.node.(type) {
+	case Processor:
+		accepts = node.Accepts()
+	case Sink:
+		accepts = node.Accepts()
+	}
+
+	if !accepts.Accepts(&from.format) {
+		target, ok := accepts.nearest(&from.format)
+		if !ok {
+			return fmt.Errorf("%w: %s requires %s", ErrFormat, to.node.Name(), accepts)
+		}
+
+		// 先减少声道再重采样，或先重采样再增加声道，以减少计算量
+		var converters []Node
+		remix := target.Format.Channels != from.format.Format.Channels || channelMask(&target) != channelMask(&from.format)
+		resample := target.Format.SamplesPerSec != from.format.Format.SamplesPerSec
+
+		if remix && target.Format.Channels <= from.format.Format.Channels {
+			converters = append(converters, NewChannelMixer(channelMask(&target), target.Format.Channels))
+			remix = false
+		}
+		if resample {
+			converters = append(converters, NewResampler(target.Format.SamplesPerSec))
+		}
+		if remix {
+			converters = append(converters, NewChannelMixer(channelMask(&target), target.Format.Channels))
+		}
+
+		for _, node := range converters {
+			from = g.insert(from, to, node)
+			if err = g.setup(from); err != nil {
+				return
+			}
+		}
+	}
+
+	if err = g.setup(to); err != nil {
+		return
+	}
+
+	for _, out := range to.outputs {
+		if err = g.configure(to, out); err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+// 以输入节点的格式配置节点
+func (g *Graph) setup(v *vertex) (err error) {
+	in := v.input
+
+	switch node := v.node.(type) {
+	case Processor:
+		if !node.Accepts().Accepts(&in.format) {
+			return fmt.Errorf("%w: %s does not accept %s", ErrFormat, node.Name(), describe(&in.format))
+		}
+
+		var out audioclient.WAVEFORMATEXTENSIBLE
+		if out, err = node.Configure(&in.format, in.maxFrames); err != nil {
+			return fmt.Errorf("configure %s: %w", node.Name(), err)
+		}
+
+		v.format = internal(&out)
+		ratio := float64(out.Format.SamplesPerSec) / float64(in.format.Format.SamplesPerSec)
+		v.maxFrames = int(math.Ceil(float64(in.maxFrames)*ratio)) + 2
+
+	case Sink:
+		if !node.Accepts().Accepts(&in.format) {
+			return fmt.Errorf("%w: %s does not accept %s", ErrFormat, node.Name(), describe(&in.format))
+		}
+
+		if err = node.Configure(&in.format, in.maxFrames); err != nil {
+			return fmt.Errorf("configure %s: %w", node.Name(), err)
+		}
+
+		v.format = in.format
+		v.maxFrames = in.maxFrames
+	}
+
+	return
+}
+
+// 在 from 与 to 之间插入自动转换节点
+func (g *Graph) insert(from, to *vertex, node Node) *vertex {
+	v := &vertex{node: node, input: from, outputs: []*vertex{to}, auto: true}
+
+	for i, out := range from.outputs {
+		if out == to {
+			from.outputs[i] = v
+		}
+	}
+	to.input = v
+
+	g.vertices = append(g.vertices, v)
+	g.index[node] = v
+
+	return v
+}
+
+// 移除自动插入的转换节点，恢复原有的连接
+func (g *Graph) removeConverters() {
+	kept := g.vertices[:0]
+	for _, v := range g.vertices {
+		if !v.auto {
+			kept = append(kept, v)
+			continue
+		}
+
+		delete(g.index, v.node)
+	}
+	clear(g.vertices[len(kept):])
+	g.vertices = kept
+
+	for _, v := range g.vertices {
+		for v.input != nil && v.input.auto {
+			v.input = v.input.input
+		}
+
+		for i, out := range v.outputs {
+			for out.auto {
+				out = out.outputs[0]
+			}
+			v.outputs[i] = out
+		}
+	}
+}
+
+// 一块数据在指定采样率下的帧数
+func (g *Graph) frames(samplesPerSec uint32) int {
+	return max(int(g.block.Seconds()*float64(samplesPerSec)), 1)
+}
+
+// Format 方法返回节点协商后的输出格式 (接收端为输入格式)，需在 Build 之后调用。
+func (g *Graph) Format(node Node) (format audioclient.WAVEFORMATEXTENSIBLE, err error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	v, ok := g.index[node]
+	switch {
+	case !ok:
+		err = ErrUnknownNode
+	case !g.built:
+		err = ErrNotBuilt
+	default:
+		format = v.format
+	}
+
+	return
+}
+
+// Describe 方法返回图的文本描述，Build 之后包括协商的格式及自动插入的转换节点 (以 * 标记)。
+func (g *Graph) Describe() string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	var b strings.Builder
+
+	var walk func(v *vertex, depth int)
+	walk = func(v *vertex, depth int) {
+		b.WriteString(strings.Repeat("  ", depth))
+		if depth > 0 {
+			b.WriteString("-> ")
+		}
+		if v.auto {
+			b.WriteString("*")
+		}
+		b.WriteString(v.node.Name())
+
+		if g.built {
+			if _, sink := v.node.(Sink); !sink {
+				fmt.Fprintf(&b, " [%s]", describe(&v.format))
+			}
+		}
+		b.WriteString("\n")
+
+		for _, out := range v.outputs {
+			walk(out, depth+1)
+		}
+	}
+
+	for _, v := range g.vertices {
+		if v.input == nil && !v.auto {
+			walk(v, 0)
+		}
+	}
+
+	return b.String()
+}
+
+// Push 方法将音源的交错 float32 采样沿图传递到所有接收端，用于捕获回调等推模型。
+func (g *Graph) Push(source Node, samples []float32) (err error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if !g.built {
+		return ErrNotBuilt
+	}
+
+	v, ok := g.index[source]
+	if !ok {
+		return ErrUnknownNode
+	}
+
+	if _, ok = source.(Source); !ok {
+		return fmt.Errorf("%w: %s is not a source", ErrNodeType, source.Name())
+	}
+
+	// 按最大帧数分块传递
+	chunk := v.maxFrames * int(v.format.Format.Channels)
+	for len(samples) > 0 {
+		n := min(chunk, len(samples))
+		if e := g.propagate(v, samples[:n]); e != nil && err == nil {
+			err = e
+		}
+		samples = samples[n:]
+	}
+
+	return
+}
+
+// Tick 方法从每个音源读取一块数据并沿图传递，所有音源都结束后返回 io.EOF。
+func (g *Graph) Tick() (err error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	_, err = g.tick()
+
+	return
+}
+
+// 从每个音源读取一块数据，返回读到的总帧数
+func (g *Graph) tick() (frames int, err error) {
+	if !g.built {
+		return 0, ErrNotBuilt
+	}
+
+	running := false
+	for _, v := range g.vertices {
+		source, ok := v.node.(Source)
+		if !ok || v.eof {
+			continue
+		}
+
+		n, e := source.Read(v.buf)
+		frames += n
+		if n > 0 {
+			if e := g.propagate(v, v.buf[:n*int(v.format.Format.Channels)]); e != nil && err == nil {
+				err = e
+			}
+		}
+
+		switch {
+		case errors.Is(e, io.EOF):
+			v.eof = true
+		case e != nil:
+			if err == nil {
+				err = fmt.Errorf("read %s: %w", source.Name(), e)
+			}
+			running = true
+		default:
+			running = true
+		}
+	}
+
+	if err == nil && !running {
+		err = io.EOF
+	}
+
+	return
+}
+
+// Pull 方法按需调用 Tick，直到 out 中有 len(dst) 个采样、所有音源结束或某次 Tick 没有读到数据
+// (例如只有 PushSource 的图)，然后读取到 dst，不足的部分填充静音，
+// 用于向 IAudioRenderClient 提供数据的拉模型。所有音源结束且没有剩余数据时返回 io.EOF。
+func (g *Graph) Pull(out *Output, dst []float32) (frames int, err error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if _, ok := g.index[out]; !ok {
+		return 0, ErrUnknownNode
+	}
+
+	for out.buffered() < len(dst) {
+		var n int
+		if n, err = g.tick(); err != nil || n == 0 {
+			break
+		}
+	}
+
+	frames = out.Read(dst)
+	if errors.Is(err, io.EOF) && frames > 0 {
+		err = nil
+	}
+
+	return
+}
+
+// 将数据传递给节点的所有输出
+func (g *Graph) propagate(v *vertex, samples []float32) (err error) {
+	for _, out := range v.outputs {
+		var e error
+
+		switch node := out.node.(type) {
+		case Processor:
+			if result := node.Process(samples); len(result) > 0 {
+				e = g.propagate(out, result)
+			}
+		case Sink:
+			if e = node.Write(samples); e != nil {
+				e = fmt.Errorf("write %s: %w", node.Name(), e)
+			}
+		}
+
+		if e != nil && err == nil {
+			err = e
+		}
+	}
+
+	return
+}
@@ -0,0 +1,283 @@
+package graph
+
+import (
+	"errors"
+	"io"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cyberxnomad/wasapi/audioclient"
+	"github.com/cyberxnomad/wasapi/generator"
+	"github.com/cyberxnomad/wasapi/pcm"
+)
+
+// 乘以固定增益的效果器
+type scale float32
+
+func (s scale) Process(samples []float32) {
+	for i := range samples {
+		samples[i] *= float32(s)
+	}
+}
+
+func newScale(name string, accepts Constraint, gain float32) *Effect {
+	return NewEffect(name, accepts, func(format *audioclient.WAVEFORMATEXTENSIBLE) (InPlace, error) {
+		return scale(gain), nil
+	})
+}
+
+// 各声道的均方根值
+func channelRMS(samples []float32, channels int) []float64 {
+	rms := make([]float64, channels)
+	for i, v := range samples {
+		rms[i%channels] += float64(v) * float64(v)
+	}
+	for c := range rms {
+		rms[c] = math.Sqrt(rms[c] / float64(len(samples)/channels))
+	}
+
+	return rms
+}
+
+func TestConverterInsertion(t *testing.T) {
+	stereo := pcm.NewFormat(pcm.SampleTypeFloat, 44100, 2, 32, audioclient.KSAUDIO_SPEAKER_STEREO)
+	surround := pcm.NewFormat(pcm.SampleTypeFloat, 48000, 6, 32, audioclient.KSAUDIO_SPEAKER_5POINT1_SURROUND)
+	mono := pcm.NewFormat(pcm.SampleTypeInt, 16000, 1, 16, audioclient.KSAUDIO_SPEAKER_MONO)
+
+	tone := NewGeneratorSource("tone", &stereo, generator.NewOscillator(generator.WaveSine, 44100, 1000, 0.5), time.Second)
+	effect := newScale("scale", Any, 0.5)
+	render := NewOutput("render", &surround, 200*time.Millisecond)
+	record := NewOutput("record", &mono, time.Second)
+
+	g := New(10 * time.Millisecond)
+	if err := g.Chain(tone, effect, render); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.Connect(effect, record); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := g.Format(render); !errors.Is(err, ErrNotBuilt) {
+		t.Fatalf("Format before Build: err = %v", err)
+	}
+
+	if err := g.Build(); err != nil {
+		t.Fatal(err)
+	}
+
+	// 增加声道时先重采样，减少声道时先混音
+	want := strings.Join([]string{
+		"tone [44100 Hz, 2 ch, mask 0x3]",
+		"  -> scale [44100 Hz, 2 ch, mask 0x3]",
+		"    -> *resample 48000 Hz [48000 Hz, 2 ch, mask 0x3]",
+		"      -> *remix 6 ch [48000 Hz, 6 ch, mask 0x60F]",
+		"        -> render",
+		"    -> *remix 1 ch [44100 Hz, 1 ch, mask 0x4]",
+		"      -> *resample 16000 Hz [16000 Hz, 1 ch, mask 0x4]",
+		"        -> record",
+		"",
+	}, "\n")
+	if got := g.Describe(); got != want {
+		t.Fatalf("Describe =\n%s\nwant:\n%s", got, want)
+	}
+
+	// 重新 Build 不会重复插入转换节点
+	if err := g.Build(); err != nil {
+		t.Fatal(err)
+	}
+	if got := g.Describe(); got != want {
+		t.Fatalf("Describe after rebuild =\n%s\nwant:\n%s", got, want)
+	}
+
+	format, err := g.Format(record)
+	if err != nil || format.Format.SamplesPerSec != 16000 || format.Format.Channels != 1 {
+		t.Fatalf("Format(record) = %d Hz %d ch, %v", format.Format.SamplesPerSec, format.Format.Channels, err)
+	}
+
+	buf := make([]float32, 480*6)
+	var rendered int
+	var samples []float32
+	for {
+		frames, err := g.Pull(render, buf)
+		rendered += frames
+		samples = append(samples, buf[:frames*6]...)
+
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if rendered < 47900 || rendered > 48100 {
+		t.Fatalf("rendered %d frames, want about 48000", rendered)
+	}
+
+	// 立体声映射到 5.1 的前置左右声道，其余声道静音
+	rms := channelRMS(samples[4800*6:], 6)
+	for c, v := range rms {
+		if want := []float64{0.25 / math.Sqrt2, 0.25 / math.Sqrt2, 0, 0, 0, 0}[c]; math.Abs(v-want) > 0.005 {
+			t.Errorf("render channel %d rms %.4f, want %.4f", c, v, want)
+		}
+	}
+
+	// 单声道为左右声道各衰减 3 dB 之和
+	if frames := record.Buffered(); frames < 15900 || frames > 16100 {
+		t.Fatalf("record buffered %d frames, want about 16000", frames)
+	}
+	recorded := make([]float32, record.Buffered())
+	record.Read(recorded)
+	if rms := channelRMS(recorded[1600:], 1)[0]; math.Abs(rms-0.25) > 0.005 {
+		t.Errorf("record rms %.4f, want 0.25", rms)
+	}
+}
+
+func TestBuildErrors(t *testing.T) {
+	stereo := pcm.NewFormat(pcm.SampleTypeFloat, 48000, 2, 32, audioclient.KSAUDIO_SPEAKER_STEREO)
+	tone := func() Source {
+		return NewGeneratorSource("tone", &stereo, generator.NewOscillator(generator.WaveSine, 48000, 440, 0.5), 0)
+	}
+
+	t.Run("no input", func(t *testing.T) {
+		g := New(0)
+		g.Add(tone(), NewOutput("render", &stereo, 0))
+
+		if err := g.Build(); !errors.Is(err, ErrNoInput) {
+			t.Fatalf("Build: err = %v", err)
+		}
+	})
+
+	t.Run("cycle", func(t *testing.T) {
+		a, b := newScale("a", Any, 1), newScale("b", Any, 1)
+
+		g := New(0)
+		if err := g.Chain(a, b, a); err != nil {
+			t.Fatal(err)
+		}
+		if err := g.Connect(tone(), NewOutput("render", &stereo, 0)); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := g.Build(); !errors.Is(err, ErrCycle) {
+			t.Fatalf("Build: err = %v", err)
+		}
+	})
+
+	t.Run("format", func(t *testing.T) {
+		// 要求的声道掩码与声道数矛盾，无法满足
+		effect := newScale("impossible", Constraint{Channels: []uint16{1}, ChannelMask: audioclient.KSAUDIO_SPEAKER_STEREO}, 1)
+
+		g := New(0)
+		if err := g.Chain(tone(), effect, NewOutput("render", &stereo, 0)); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := g.Build(); !errors.Is(err, ErrFormat) {
+			t.Fatalf("Build: err = %v", err)
+		}
+
+		if err := g.Tick(); !errors.Is(err, ErrNotBuilt) {
+			t.Fatalf("Tick after failed Build: err = %v", err)
+		}
+	})
+
+	t.Run("connections", func(t *testing.T) {
+		g := New(0)
+		render := NewOutput("render", &stereo, 0)
+
+		if err := g.Connect(render, newScale("a", Any, 1)); !errors.Is(err, ErrNodeType) {
+			t.Fatalf("Connect from sink: err = %v", err)
+		}
+		if err := g.Connect(newScale("a", Any, 1), tone()); !errors.Is(err, ErrNodeType) {
+			t.Fatalf("Connect to source: err = %v", err)
+		}
+		if err := g.Connect(tone(), render); err != nil {
+			t.Fatal(err)
+		}
+		if err := g.Connect(tone(), render); !errors.Is(err, ErrConnected) {
+			t.Fatalf("second input: err = %v", err)
+		}
+	})
+}
+
+func TestPullPushSource(t *testing.T) {
+	format := pcm.NewFormat(pcm.SampleTypeFloat, 48000, 2, 32, audioclient.KSAUDIO_SPEAKER_STEREO)
+	capture := NewPushSource("capture", &format)
+	render := NewOutput("render", &format, 100*time.Millisecond)
+
+	g := New(0)
+	if err := g.Connect(capture, render); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.Build(); err != nil {
+		t.Fatal(err)
+	}
+
+	pull := func(dst []float32) (frames int, err error) {
+		t.Helper()
+
+		done := make(chan struct{})
+		go func() {
+			frames, err = g.Pull(render, dst)
+			close(done)
+		}()
+
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatal("Pull did not return")
+		}
+
+		return
+	}
+
+	// 没有送入数据时返回 0 帧静音，而不是一直等待
+	buf := make([]float32, 480*2)
+	for i := range buf {
+		buf[i] = 1
+	}
+	if frames, err := pull(buf); frames != 0 || err != nil {
+		t.Fatalf("Pull on empty graph = %d, %v", frames, err)
+	}
+	for i, v := range buf {
+		if v != 0 {
+			t.Fatalf("sample %d = %v, want silence", i, v)
+		}
+	}
+
+	// 不足一个周期的数据后补静音
+	pushed := make([]float32, 100*2)
+	for i := range pushed {
+		pushed[i] = float32(i + 1)
+	}
+	data := make([]byte, len(pushed)*4)
+	if _, err := pcm.Encode(&format, pushed, data); err != nil {
+		t.Fatal(err)
+	}
+	if err := capture.PushBytes(g, data); err != nil {
+		t.Fatal(err)
+	}
+
+	frames, err := pull(buf)
+	if frames != 100 || err != nil {
+		t.Fatalf("Pull = %d, %v, want 100 frames", frames, err)
+	}
+	for i, v := range buf {
+		var want float32
+		if i < len(pushed) {
+			want = pushed[i]
+		}
+		if v != want {
+			t.Fatalf("sample %d = %v, want %v", i, v, want)
+		}
+	}
+
+	if err = capture.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if frames, err = pull(buf); frames != 0 || !errors.Is(err, io.EOF) {
+		t.Fatalf("Pull after Close = %d, %v, want io.EOF", frames, err)
+	}
+}
@@ -0,0 +1,408 @@
+package graph
+
+import (
+	"errors"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/cyberxnomad/wasapi/audioclient"
+	"github.com/cyberxnomad/wasapi/generator"
+	"github.com/cyberxnomad/wasapi/pcm"
+	"github.com/cyberxnomad/wasapi/wav"
+)
+
+// GeneratorSource 是信号发生器音源。
+type GeneratorSource struct {
+	name      string
+	format    audioclient.WAVEFORMATEXTENSIBLE
+	generator *generator.Generator
+	remaining int64 // 剩余帧数，-1 表示无限
+}
+
+// NewGeneratorSource 创建信号发生器音源，duration 为时长，为 0 时无限。
+func NewGeneratorSource(name string, format *audioclient.WAVEFORMATEXTENSIBLE, signal generator.Signal, duration time.Duration) *GeneratorSource {
+	s := &GeneratorSource{
+		name:      name,
+		format:    internal(format),
+		remaining: -1,
+	}
+
+	s.generator = generator.NewGenerator(&s.format, signal)
+	if duration > 0 {
+		s.remaining = int64(duration.Seconds() * float64(format.Format.SamplesPerSec))
+	}
+
+	return s
+}
+
+// Generator 方法返回底层的信号发生器。
+func (s *GeneratorSource) Generator() *generator.Generator {
+	return s.generator
+}
+
+// Name 方法实现 Node 接口。
+func (s *GeneratorSource) Name() string {
+	return s.name
+}
+
+// Format 方法实现 Source 接口。
+func (s *GeneratorSource) Format() audioclient.WAVEFORMATEXTENSIBLE {
+	return s.format
+}
+
+// Read 方法实现 Source 接口。
+func (s *GeneratorSource) Read(dst []float32) (frames int, err error) {
+	if s.remaining == 0 {
+		return 0, io.EOF
+	}
+
+	channels := int(s.format.Format.Channels)
+	if s.remaining > 0 {
+		dst = dst[:min(int64(len(dst)/channels), s.remaining)*int64(channels)]
+	}
+
+	frames = s.generator.Read(dst)
+	if s.remaining > 0 {
+		s.remaining -= int64(frames)
+	}
+
+	return
+}
+
+// FileSource 是读取 WAV 文件的音源。
+type FileSource struct {
+	name   string
+	reader *wav.Reader
+	format audioclient.WAVEFORMATEXTENSIBLE
+	buf    []byte
+}
+
+// NewFileSource 创建读取 WAV 文件的音源，支持 pcm 包可以解码的格式。
+func NewFileSource(name string, r io.Reader) (s *FileSource, err error) {
+	var reader *wav.Reader
+	if reader, err = wav.NewReader(r); err != nil {
+		return
+	}
+
+	if pcm.SampleTypeOf(reader.Format()) == pcm.SampleTypeUnknown {
+		err = pcm.ErrUnsupportedFormat
+		return
+	}
+
+	s = &FileSource{
+		name:   name,
+		reader: reader,
+		format: *reader.Format(),
+	}
+
+	return
+}
+
+// Name 方法实现 Node 接口。
+func (s *FileSource) Name() string {
+	return s.name
+}
+
+// Format 方法实现 Source 接口，返回文件的格式。
+func (s *FileSource) Format() audioclient.WAVEFORMATEXTENSIBLE {
+	return s.format
+}
+
+// Read 方法实现 Source 接口。
+func (s *FileSource) Read(dst []float32) (frames int, err error) {
+	channels := int(s.format.Format.Channels)
+	size := (len(dst) / channels) * int(s.format.Format.BlockAlign)
+
+	if cap(s.buf) < size {
+		s.buf = make([]byte, size)
+	}
+
+	n, err := io.ReadFull(s.reader, s.buf[:size])
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		err = io.EOF
+	}
+
+	frames = n / int(s.format.Format.BlockAlign)
+	if _, e := pcm.Decode(&s.format, s.buf[:frames*int(s.format.Format.BlockAlign)], dst); e != nil {
+		err = e
+	}
+
+	return
+}
+
+// PushSource 是由 Graph.Push 送入数据的音源，例如捕获流。
+type PushSource struct {
+	mu sync.Mutex
+
+	name   string
+	format audioclient.WAVEFORMATEXTENSIBLE
+	closed bool
+
+	// push 在 PushBytes 解码及传递期间持有，保护 buf；
+	// 与 mu 分开，避免与 Tick 持有图的锁后读取音源时形成锁序反转
+	push sync.Mutex
+	buf  []float32
+}
+
+// NewPushSource 创建推模型音源，format 为送入数据的格式 (例如捕获流的混音格式)。
+func NewPushSource(name string, format *audioclient.WAVEFORMATEXTENSIBLE) *PushSource {
+	return &PushSource{
+		name:   name,
+		format: *format,
+	}
+}
+
+// Name 方法实现 Node 接口。
+func (s *PushSource) Name() string {
+	return s.name
+}
+
+// Format 方法实现 Source 接口。
+func (s *PushSource) Format() audioclient.WAVEFORMATEXTENSIBLE {
+	return s.format
+}
+
+// Read 方法实现 Source 接口，关闭前返回 0 帧，关闭后返回 io.EOF。
+func (s *PushSource) Read(dst []float32) (frames int, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		err = io.EOF
+	}
+
+	return
+}
+
+// Close 方法关闭音源，之后 Tick 将其视为已结束。
+func (s *PushSource) Close() (err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.closed = true
+
+	return
+}
+
+// PushBytes 方法按音源格式解码数据 (例如 IAudioCaptureClient::GetBuffer 返回的数据) 并沿图传递。
+func (s *PushSource) PushBytes(g *Graph, data []byte) (err error) {
+	size := int(s.format.Format.BitsPerSample / 8)
+	if size == 0 {
+		return pcm.ErrUnsupportedFormat
+	}
+
+	s.push.Lock()
+	defer s.push.Unlock()
+
+	if cap(s.buf) < len(data)/size {
+		s.buf = make([]float32, len(data)/size)
+	}
+
+	var n int
+	if n, err = pcm.Decode(&s.format, data, s.buf[:len(data)/size]); err != nil {
+		return
+	}
+
+	return g.Push(s, s.buf[:n])
+}
+
+// FileSink 是写入 WAV 文件的接收端。
+type FileSink struct {
+	name   string
+	format audioclient.WAVEFORMATEXTENSIBLE
+	w      io.Writer
+	writer *wav.Writer
+	buf    []byte
+}
+
+// NewFileSink 创建写入 WAV 文件的接收端，format 为文件的格式，只接受该格式的采样率及声道布局。
+func NewFileSink(name string, w io.Writer, format *audioclient.WAVEFORMATEXTENSIBLE) *FileSink {
+	return &FileSink{
+		name:   name,
+		format: *format,
+		w:      w,
+	}
+}
+
+// Name 方法实现 Node 接口。
+func (s *FileSink) Name() string {
+	return s.name
+}
+
+// Accepts 方法实现 Sink 接口。
+func (s *FileSink) Accepts() Constraint {
+	return Exact(&s.format)
+}
+
+// Configure 方法实现 Sink 接口，首次调用时写入文件头。
+func (s *FileSink) Configure(input *audioclient.WAVEFORMATEXTENSIBLE, maxFrames int) (err error) {
+	if s.writer == nil {
+		if s.writer, err = wav.NewWriter(s.w, &s.format); err != nil {
+			return
+		}
+	}
+
+	s.buf = make([]byte, maxFrames*int(s.format.Format.BlockAlign))
+
+	return
+}
+
+// Write 方法实现 Sink 接口。
+func (s *FileSink) Write(src []float32) (err error) {
+	frames := len(src) / int(s.format.Format.Channels)
+	size := frames * int(s.format.Format.BlockAlign)
+
+	if cap(s.buf) < size {
+		s.buf = make([]byte, size)
+	}
+
+	if _, err = pcm.Encode(&s.format, src, s.buf[:size]); err != nil {
+		return
+	}
+
+	_, err = s.writer.Write(s.buf[:size])
+
+	return
+}
+
+// Close 方法完成文件，可以定位时回写文件头中的长度。
+func (s *FileSink) Close() (err error) {
+	if s.writer == nil {
+		return
+	}
+
+	return s.writer.Close()
+}
+
+// Output 是拉模型的接收端，数据写入环形缓冲，由 Graph.Pull 或 Read 读取，
+// 例如向 IAudioRenderClient 提供数据。
+type Output struct {
+	mu sync.Mutex
+
+	name     string
+	format   audioclient.WAVEFORMATEXTENSIBLE
+	capacity time.Duration
+	ring     []float32
+	start    int
+	size     int
+	overruns uint64
+	buf      []float32
+}
+
+// NewOutput 创建拉模型接收端，format 为输出格式 (例如终结点的混音格式)，只接受该格式的采样率及声道布局；
+// capacity 为环形缓冲的时长，缓冲满时丢弃最早的数据。
+func NewOutput(name string, format *audioclient.WAVEFORMATEXTENSIBLE, capacity time.Duration) *Output {
+	return &Output{
+		name:     name,
+		format:   *format,
+		capacity: capacity,
+	}
+}
+
+// Name 方法实现 Node 接口。
+func (o *Output) Name() string {
+	return o.name
+}
+
+// Accepts 方法实现 Sink 接口。
+func (o *Output) Accepts() Constraint {
+	return Exact(&o.format)
+}
+
+// Configure 方法实现 Sink 接口。
+func (o *Output) Configure(input *audioclient.WAVEFORMATEXTENSIBLE, maxFrames int) (err error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	frames := max(int(o.capacity.Seconds()*float64(o.format.Format.SamplesPerSec)), 4*maxFrames)
+	o.ring = make([]float32, frames*int(o.format.Format.Channels))
+	o.start, o.size = 0, 0
+
+	return
+}
+
+// Write 方法实现 Sink 接口。
+func (o *Output) Write(src []float32) (err error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if len(o.ring) == 0 {
+		return ErrNotBuilt
+	}
+
+	// 缓冲不足时丢弃最早的整帧
+	if over := o.size + len(src) - len(o.ring); over > 0 {
+		channels := int(o.format.Format.Channels)
+		over = (over + channels - 1) / channels * channels
+		o.start = (o.start + over) % len(o.ring)
+		o.size -= min(over, o.size)
+		o.overruns++
+
+		if len(src) > len(o.ring) {
+			src = src[len(src)-len(o.ring):]
+		}
+	}
+
+	for len(src) > 0 {
+		end := (o.start + o.size) % len(o.ring)
+		n := copy(o.ring[end:min(len(o.ring), end+len(o.ring)-o.size)], src)
+		o.size += n
+		src = src[n:]
+	}
+
+	return
+}
+
+// Buffered 方法返回缓冲中的帧数。
+func (o *Output) Buffered() int {
+	return o.buffered() / int(o.format.Format.Channels)
+}
+
+func (o *Output) buffered() int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	return o.size
+}
+
+// Overruns 方法返回因缓冲已满而丢弃数据的次数。
+func (o *Output) Overruns() uint64 {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	return o.overruns
+}
+
+// Read 方法读取交错的 float32 采样，返回帧数，缓冲不足的部分填充静音。
+func (o *Output) Read(dst []float32) (frames int) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	channels := int(o.format.Format.Channels)
+	n := min(len(dst)/channels*channels, o.size)
+
+	for copied := 0; copied < n; {
+		c := copy(dst[copied:n], o.ring[o.start:])
+		o.start = (o.start + c) % len(o.ring)
+		copied += c
+	}
+
+	o.size -= n
+	clear(dst[n:])
+
+	return n / channels
+}
+
+// ReadBytes 方法读取数据并按输出格式编码到 dst，返回有效的帧数，不足的部分为静音。
+func (o *Output) ReadBytes(dst []byte) (frames int, err error) {
+	samples := pcm.Frames(&o.format, len(dst)) * int(o.format.Format.Channels)
+	if cap(o.buf) < samples {
+		o.buf = make([]float32, samples)
+	}
+
+	frames = o.Read(o.buf[:samples])
+	_, err = pcm.Encode(&o.format, o.buf[:samples], dst)
+
+	return
+}
@@ -0,0 +1,148 @@
+package graph
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cyberxnomad/wasapi/audioclient"
+	"github.com/cyberxnomad/wasapi/pcm"
+	"github.com/cyberxnomad/wasapi/wav"
+)
+
+func TestFileSourceSink(t *testing.T) {
+	format := pcm.NewFormat(pcm.SampleTypeInt, 48000, 2, 16, audioclient.KSAUDIO_SPEAKER_STEREO)
+
+	// 1234 帧，不是块长的整数倍
+	payload := make([]byte, 1234*int(format.Format.BlockAlign))
+	for i := range payload {
+		payload[i] = byte(i * 31)
+	}
+	input := append(wav.Header(&format, uint32(len(payload))), payload...)
+
+	source, err := NewFileSource("in", bytes.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := source.Format(); got != format {
+		t.Fatalf("FileSource format = %+v", got)
+	}
+
+	path := filepath.Join(t.TempDir(), "out.wav")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	sink := NewFileSink("out", f, &format)
+
+	g := New(10 * time.Millisecond)
+	if err = g.Connect(source, sink); err != nil {
+		t.Fatal(err)
+	}
+	if err = g.Build(); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; ; i++ {
+		if err = g.Tick(); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		if i > 100 {
+			t.Fatal("source did not end")
+		}
+	}
+	if err = sink.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// 相同格式时逐字节复制，关闭后文件头中的长度为实际长度
+	output, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(output, input) {
+		t.Fatalf("output file is %d bytes, want a copy of the %d byte input", len(output), len(input))
+	}
+}
+
+func TestFileSourceUnsupported(t *testing.T) {
+	format := pcm.NewFormat(pcm.SampleTypeInt, 48000, 2, 16, audioclient.KSAUDIO_SPEAKER_STEREO)
+	format.SubFormat.Data1 = 0x1234
+
+	if _, err := NewFileSource("in", bytes.NewReader(wav.Header(&format, 0))); !errors.Is(err, pcm.ErrUnsupportedFormat) {
+		t.Fatalf("unsupported format: err = %v", err)
+	}
+	if _, err := NewFileSource("in", bytes.NewReader([]byte("RIFF"))); err == nil {
+		t.Fatal("truncated header accepted")
+	}
+}
+
+func TestPushBytesConcurrent(t *testing.T) {
+	format := pcm.NewFormat(pcm.SampleTypeInt, 48000, 1, 16, audioclient.KSAUDIO_SPEAKER_MONO)
+	capture := NewPushSource("capture", &format)
+	render := NewOutput("render", &format, time.Second)
+
+	g := New(0)
+	if err := g.Connect(capture, render); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.Build(); err != nil {
+		t.Fatal(err)
+	}
+
+	// 每次送入的数据各自为常数，解码缓冲被并发覆盖时会混入其他值
+	var wg sync.WaitGroup
+	for w := 1; w <= 4; w++ {
+		wg.Add(1)
+		go func(value byte) {
+			defer wg.Done()
+
+			data := bytes.Repeat([]byte{0, value}, 480)
+			for i := 0; i < 20; i++ {
+				if err := capture.PushBytes(g, data); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}(byte(w))
+	}
+
+	// 同时拉取
+	done := make(chan struct{})
+	var pulled []float32
+	go func() {
+		defer close(done)
+
+		buf := make([]float32, 480)
+		for i := 0; i < 20; i++ {
+			n, _ := g.Pull(render, buf)
+			pulled = append(pulled, buf[:n]...)
+		}
+	}()
+
+	wg.Wait()
+	<-done
+
+	rest := make([]float32, render.Buffered())
+	render.Read(rest)
+	pulled = append(pulled, rest...)
+
+	if len(pulled) != 4*20*480 {
+		t.Fatalf("received %d frames, want %d", len(pulled), 4*20*480)
+	}
+	for i := 0; i < len(pulled); i += 480 {
+		for _, v := range pulled[i : i+480] {
+			if v != pulled[i] {
+				t.Fatalf("frames %d-%d mix pushes: %v and %v", i, i+479, pulled[i], v)
+			}
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package httpstream
+
+import (
+	"sync"
+
+	"github.com/cyberxnomad/wasapi/audioclient"
+)
+
+// 每个收听者默认缓冲的数据包数
+const DefaultListenerBuffer = 64
+
+// Broadcaster 将一个捕获流分发给多个收听者。
+//
+// 收听者的缓冲区满时 (处理速度跟不上捕获) 会被断开，不会阻塞捕获线程。
+type Broadcaster struct {
+	mu sync.Mutex
+
+	format    audioclient.WAVEFORMATEXTENSIBLE
+	listeners map[chan []byte]struct{}
+	dropped   uint64
+	closed    bool
+}
+
+// NewBroadcaster 创建分发器，format 为捕获流的格式。
+func NewBroadcaster(format *audioclient.WAVEFORMATEXTENSIBLE) *Broadcaster {
+	return &Broadcaster{
+		format:    *format,
+		listeners: make(map[chan []byte]struct{}),
+	}
+}
+
+// Format 方法返回流的格式。
+func (b *Broadcaster) Format() audioclient.WAVEFORMATEXTENSIBLE {
+	return b.format
+}
+
+// Subscribe 方法添加一个收听者，buffer 为缓冲的数据包数。
+//
+// 收听者被断开或分发器关闭时 ch 将被关闭，不再需要时调用 cancel。
+func (b *Broadcaster) Subscribe(buffer int) (ch <-chan []byte, cancel func()) {
+	c := make(chan []byte, buffer)
+
+	b.mu.Lock()
+	if b.closed {
+		close(c)
+	} else {
+		b.listeners[c] = struct{}{}
+	}
+	b.mu.Unlock()
+
+	cancel = func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+
+		if _, ok := b.listeners[c]; ok {
+			delete(b.listeners, c)
+			close(c)
+		}
+	}
+
+	return c, cancel
+}
+
+// Write 方法分发一个数据包，data 会被复制，调用后可以立即释放捕获缓冲区。
+//
+// 带有 AUDCLNT_BUFFERFLAGS_SILENT 标志的数据包应由调用方替换为静音。
+func (b *Broadcaster) Write(data []byte) (n int, err error) {
+	packet := append([]byte(nil), data...)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for c := range b.listeners {
+		select {
+		case c <- packet:
+		default:
+			// 收听者过慢
+			delete(b.listeners, c)
+			close(c)
+			b.dropped++
+		}
+	}
+
+	n = len(data)
+
+	return
+}
+
+// Listeners 方法返回当前的收听者数。
+func (b *Broadcaster) Listeners() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return len(b.listeners)
+}
+
+// Dropped 方法返回因过慢而被断开的收听者数。
+func (b *Broadcaster) Dropped() uint64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.dropped
+}
+
+// Close 方法断开所有收听者，之后的订阅将立即结束。
+func (b *Broadcaster) Close() (err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.closed = true
+	for c := range b.listeners {
+		delete(b.listeners, c)
+		close(c)
+	}
+
+	return
+}
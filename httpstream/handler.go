@@ -0,0 +1,74 @@
+package httpstream
+
+import (
+	"net/http"
+
+	"github.com/cyberxnomad/wasapi/wav"
+)
+
+// Handler 以长度不定的 WAV 流向每个 HTTP 客户端提供捕获的音频。
+type Handler struct {
+	broadcaster *Broadcaster
+	buffer      int
+}
+
+// NewHandler 创建 HTTP 处理器。
+func NewHandler(broadcaster *Broadcaster) *Handler {
+	return &Handler{
+		broadcaster: broadcaster,
+		buffer:      DefaultListenerBuffer,
+	}
+}
+
+// SetListenerBuffer 方法设置每个客户端缓冲的数据包数，超出后客户端将被断开。
+func (h *Handler) SetListenerBuffer(buffer int) {
+	h.buffer = buffer
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "audio/wav")
+	w.Header().Set("Cache-Control", "no-cache, no-store")
+	w.WriteHeader(http.StatusOK)
+
+	if r.Method == http.MethodHead {
+		return
+	}
+
+	ch, cancel := h.broadcaster.Subscribe(h.buffer)
+	defer cancel()
+
+	format := h.broadcaster.Format()
+	if _, err := w.Write(wav.Header(&format, wav.UnknownSize)); err != nil {
+		return
+	}
+
+	flusher, _ := w.(http.Flusher)
+	if flusher != nil {
+		flusher.Flush()
+	}
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case packet, ok := <-ch:
+			if !ok {
+				return
+			}
+
+			if _, err := w.Write(packet); err != nil {
+				return
+			}
+
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+	}
+}
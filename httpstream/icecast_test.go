@@ -0,0 +1,154 @@
+package httpstream
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cyberxnomad/wasapi/audioclient"
+	"github.com/cyberxnomad/wasapi/pcm"
+	"github.com/cyberxnomad/wasapi/wav"
+)
+
+var stereo = pcm.NewFormat(pcm.SampleTypeInt, 48000, 2, 16, audioclient.KSAUDIO_SPEAKER_STEREO)
+
+var info = SourceInfo{Name: "test stream", Genre: "ambient", Public: true}
+
+// 检查 source 请求头，返回错误描述
+func checkRequest(r *http.Request) string {
+	user, password, ok := r.BasicAuth()
+	switch {
+	case r.Method != http.MethodPut || r.URL.Path != "/live":
+		return "request " + r.Method + " " + r.URL.Path
+	case !ok || user != "source" || password != "hackme":
+		return "credentials " + user + ":" + password
+	case r.Header.Get("Content-Type") != "audio/wav":
+		return "content type " + r.Header.Get("Content-Type")
+	case r.Header.Get("Ice-Name") != info.Name || r.Header.Get("Ice-Genre") != info.Genre || r.Header.Get("Ice-Public") != "1":
+		return "ice headers " + r.Header.Get("Ice-Name")
+	case r.Header.Get("Ice-Description") != "":
+		return "empty Ice-Description sent"
+	case r.Header.Get("Expect") != "100-continue":
+		return "expect " + r.Header.Get("Expect")
+	}
+
+	return ""
+}
+
+// 服务器收到的请求体
+type received struct {
+	body []byte
+	err  string
+}
+
+func dial(t *testing.T, server *httptest.Server) *SourceClient {
+	t.Helper()
+
+	url := strings.Replace(server.URL, "http://", "http://source:hackme@", 1) + "/live"
+
+	done := make(chan struct{})
+	var c *SourceClient
+	var err error
+	go func() {
+		c, err = DialIcecast(url, &stereo, info)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("DialIcecast did not return")
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return c
+}
+
+// 推送数据后关闭，检查服务器收到 WAV 头及数据
+func stream(t *testing.T, c *SourceClient, result <-chan received) {
+	t.Helper()
+
+	data := []byte("0123456789abcdef")
+	if _, err := c.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	c.Close()
+
+	select {
+	case r := <-result:
+		if r.err != "" {
+			t.Fatal(r.err)
+		}
+		if want := append(wav.Header(&stereo, wav.UnknownSize), data...); !bytes.Equal(r.body, want) {
+			t.Fatalf("server received % X, want % X", r.body, want)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("server did not receive the stream")
+	}
+}
+
+func TestIcecastHandshake(t *testing.T) {
+	for _, status := range []string{
+		// Icecast 2.4 回应 100 Continue 后开始读取数据
+		"HTTP/1.1 100 Continue\r\n\r\n",
+		// 不支持 100-continue 的服务器直接回应 200，没有 Content-Length 的响应体持续到连接关闭
+		"HTTP/1.1 200 OK\r\nServer: Icecast 2.4.4\r\n\r\n",
+	} {
+		// source 请求没有 Content-Length，由处理函数接管连接读取数据
+		result := make(chan received, 1)
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if msg := checkRequest(r); msg != "" {
+				result <- received{err: msg}
+				http.Error(w, msg, http.StatusBadRequest)
+				return
+			}
+
+			conn, buf, err := w.(http.Hijacker).Hijack()
+			if err != nil {
+				result <- received{err: err.Error()}
+				return
+			}
+			defer conn.Close()
+
+			buf.WriteString(status)
+			buf.Flush()
+
+			body, _ := io.ReadAll(buf)
+			result <- received{body: body}
+		}))
+
+		stream(t, dial(t, server), result)
+		server.Close()
+	}
+}
+
+func TestIcecastRejected(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "authentication required", http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	if _, err := DialIcecast(server.URL+"/live", &stereo, info); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Fatalf("err = %v, want 401", err)
+	}
+}
+
+func TestIcecastHeaderInjection(t *testing.T) {
+	for _, info := range []SourceInfo{
+		{Name: "name\r\nX-Injected: 1"},
+		{Description: "line\nbreak"},
+		{URL: "http://example.com/\r"},
+	} {
+		// 在连接之前拒绝
+		if _, err := DialIcecast("http://127.0.0.1:1/live", &stereo, info); !errors.Is(err, ErrInvalidHeader) {
+			t.Errorf("%+v: err = %v, want ErrInvalidHeader", info, err)
+		}
+	}
+}
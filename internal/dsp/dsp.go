@@ -0,0 +1,52 @@
+// Package dsp 提供各音频处理器共用的辅助函数。
+package dsp
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/cyberxnomad/wasapi/audioclient"
+	"github.com/cyberxnomad/wasapi/pcm"
+)
+
+// Coefficient 返回时间常数对应的单极点滤波器系数，tau 不大于 0 时返回 0 (瞬时响应)。
+func Coefficient(tau time.Duration, samplesPerSec float64) float64 {
+	if tau <= 0 {
+		return 0
+	}
+
+	return math.Exp(-1 / (tau.Seconds() * samplesPerSec))
+}
+
+// ProcessBytes 持有 mu 按 format 将 data 解码到可复用的缓冲区 buf，调用 process 处理，
+// encode 为 true 时再原地编码回 data。
+//
+// format 及 buf 由 mu 保护，process 在持有 mu 时调用，不能再获取 mu。
+func ProcessBytes(mu sync.Locker, format *audioclient.WAVEFORMATEXTENSIBLE, buf *[]float32, data []byte, encode bool, process func(samples []float32)) (err error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	size := int(format.Format.BitsPerSample / 8)
+	if size == 0 {
+		err = pcm.ErrUnsupportedFormat
+		return
+	}
+
+	if n := len(data) / size; cap(*buf) < n {
+		*buf = make([]float32, n)
+	}
+
+	var n int
+	if n, err = pcm.Decode(format, data, (*buf)[:len(data)/size]); err != nil {
+		return
+	}
+
+	process((*buf)[:n])
+
+	if encode {
+		_, err = pcm.Encode(format, (*buf)[:n], data)
+	}
+
+	return
+}
@@ -0,0 +1,27 @@
+// Package nettest 提供网络相关测试共用的辅助函数。
+package nettest
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+// Loopback 创建本地 UDP 回环连接，send 发送的数据报由 recv 接收，测试结束时自动关闭。
+func Loopback(t testing.TB) (send net.Conn, recv net.PacketConn) {
+	t.Helper()
+
+	recv, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { recv.Close() })
+	recv.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	if send, err = net.Dial("udp", recv.LocalAddr().String()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { send.Close() })
+
+	return
+}
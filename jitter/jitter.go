@@ -0,0 +1,408 @@
+package jitter
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/cyberxnomad/wasapi/audioclient"
+	"github.com/cyberxnomad/wasapi/pcm"
+)
+
+var ErrInvalidPayload = errors.New("invalid payload")
+
+// Concealment 表示丢包时的隐藏方式。
+type Concealment uint32
+
+const (
+	ConcealSilence Concealment = iota // 输出静音
+	ConcealRepeat                     // 重复最近一个块，之后输出静音
+	ConcealFade                       // 重复最近一个块并线性淡出，之后输出静音
+)
+
+// Stats 为抖动缓冲区的统计信息。
+type Stats struct {
+	Received        uint64  // 接收的数据包数 (含迟到，不含重复)
+	Lost            uint64  // 根据序列号推断的丢包数
+	Late            uint64  // 迟到而被丢弃的数据包数
+	Duplicate       uint64  // 重复的数据包数
+	ConcealedFrames uint64  // 隐藏 (补偿) 的帧数
+	DroppedFrames   uint64  // 为降低延迟而丢弃的帧数
+	Jitter          float64 // 到达间隔抖动，单位为帧
+	Depth           uint32  // 当前缓冲的帧数
+	TargetDepth     uint32  // 当前的目标缓冲深度，单位为帧
+}
+
+type packet struct {
+	ts      int64
+	frames  int64
+	samples []float32
+}
+
+func (p *packet) end() int64 {
+	return p.ts + p.frames
+}
+
+// Buffer 是按序列号及时间戳重排的自适应抖动缓冲区。
+//
+// 网络线程调用 Push 写入数据包，呈现线程调用 Pop 按块取出固定帧数的音频。
+// 时间戳以帧为单位 (即采样率时钟)。
+type Buffer struct {
+	mu sync.Mutex
+
+	format      audioclient.WAVEFORMATEXTENSIBLE
+	channels    int
+	blockFrames int64
+	concealment Concealment
+	minDepth    int64
+	maxDepth    int64
+	rampFrames  int64
+
+	packets []*packet
+	started bool
+	playTS  int64
+	highest int64
+
+	// 时间戳及序列号展开
+	expected uint64 // Reset 之前各段的期望数据包数
+	haveRef  bool
+	lastTS   uint32
+	extTS    int64
+	baseSeq  int64
+	maxSeq   int64
+	lastSeq  uint16
+
+	// RFC 3550 到达间隔抖动估计
+	epoch       time.Time
+	haveTransit bool
+	transit     float64
+	jitter      float64
+
+	// 丢包隐藏
+	history    []float32
+	concealRun int64
+
+	out   []float32 // Pop 的输出缓冲，避免在呈现线程上分配
+	stats Stats
+}
+
+// NewBuffer 创建抖动缓冲区，blockFrames 为每次 Pop 输出的帧数，
+// 通常由 PeriodFrames 根据 GetDevicePeriod 的结果计算。
+func NewBuffer(format *audioclient.WAVEFORMATEXTENSIBLE, blockFrames uint32) *Buffer {
+	b := &Buffer{
+		format:      *format,
+		channels:    int(format.Format.Channels),
+		blockFrames: int64(blockFrames),
+		concealment: ConcealFade,
+		minDepth:    int64(blockFrames),
+		maxDepth:    max(int64(format.Format.SamplesPerSec)/2, 2*int64(blockFrames)),
+		rampFrames:  min(int64(blockFrames), int64(format.Format.SamplesPerSec)/200+1),
+	}
+	b.history = make([]float32, int(blockFrames)*b.channels)
+	b.out = make([]float32, int(blockFrames)*b.channels)
+
+	return b
+}
+
+// PeriodFrames 将 GetDevicePeriod 返回的周期 (100 纳秒单位) 换算为帧数。
+func PeriodFrames(hnsDevicePeriod uint64, samplesPerSec uint32) uint32 {
+	return uint32((hnsDevicePeriod*uint64(samplesPerSec) + 5000000) / 10000000)
+}
+
+// SetConcealment 方法设置丢包隐藏方式，默认为 ConcealFade。
+func (b *Buffer) SetConcealment(concealment Concealment) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.concealment = concealment
+}
+
+// SetDepthRange 方法设置自适应目标深度的范围，单位为帧。
+//
+// 默认最小为一个块，最大为 500 毫秒。
+func (b *Buffer) SetDepthRange(minFrames, maxFrames uint32) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.minDepth = max(int64(minFrames), b.blockFrames)
+	b.maxDepth = max(int64(maxFrames), b.minDepth)
+}
+
+// Stats 方法返回统计信息。
+func (b *Buffer) Stats() (stats Stats) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	stats = b.stats
+	stats.Jitter = b.jitter
+	stats.Depth = uint32(max(b.highest-b.playTS, 0))
+	stats.TargetDepth = uint32(b.targetDepth())
+
+	if expected := b.expectedPackets(); expected > stats.Received {
+		stats.Lost = expected - stats.Received
+	}
+
+	return
+}
+
+// Reset 方法清空缓冲区并重新开始预缓冲，统计信息保留。
+func (b *Buffer) Reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	// 序列号参考重新建立，已期望的数据包数累计保留，与 Received 一致
+	b.expected = b.expectedPackets()
+
+	b.packets = b.packets[:0]
+	b.started = false
+	b.haveRef = false
+	b.haveTransit = false
+	b.jitter = 0
+	b.concealRun = 0
+	clear(b.history)
+}
+
+// Push 方法写入一个数据包。
+//
+// timestamp 为数据包首帧的 RTP 风格时间戳 (帧)，payload 为按格式交错排列的音频数据，
+// arrival 为数据包到达时间，用于估计抖动。
+func (b *Buffer) Push(seq uint16, timestamp uint32, payload []byte, arrival time.Time) (err error) {
+	frames := pcm.Frames(&b.format, len(payload))
+	if frames == 0 {
+		err = ErrInvalidPayload
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	ts := b.unwrap(seq, timestamp)
+	b.updateJitter(ts, arrival)
+
+	p := &packet{ts: ts, frames: int64(frames)}
+	i := sort.Search(len(b.packets), func(i int) bool { return b.packets[i].ts >= ts })
+	if i < len(b.packets) && b.packets[i].ts == ts {
+		b.stats.Duplicate++
+		return
+	}
+
+	b.stats.Received++
+
+	if b.started && p.end() <= b.playTS {
+		b.stats.Late++
+		return
+	}
+
+	p.samples = make([]float32, frames*b.channels)
+	if _, err = pcm.Decode(&b.format, payload, p.samples); err != nil {
+		return
+	}
+
+	b.packets = append(b.packets, nil)
+	copy(b.packets[i+1:], b.packets[i:])
+	b.packets[i] = p
+
+	if !b.started && (len(b.packets) == 1 || ts < b.playTS) {
+		b.playTS = ts
+	}
+
+	b.highest = max(b.highest, p.end())
+
+	return
+}
+
+// Pop 方法取出一个块的音频写入 dst，dst 长度至少为 blockFrames*BlockAlign 字节。
+//
+// 返回该块中被隐藏 (补偿) 的帧数。
+func (b *Buffer) Pop(dst []byte) (concealed uint32, err error) {
+	if len(dst) < int(b.blockFrames)*int(b.format.Format.BlockAlign) {
+		err = errors.New("buffer too small")
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	out := b.out
+
+	if !b.started {
+		if len(b.packets) == 0 || b.highest-b.playTS < b.targetDepth() {
+			concealed = uint32(b.conceal(out))
+			_, err = pcm.Encode(&b.format, out, dst)
+			return
+		}
+
+		b.started = true
+	}
+
+	b.adjustLatency()
+
+	var filled int64
+	for filled < b.blockFrames {
+		pos := b.playTS + filled
+
+		for len(b.packets) > 0 && b.packets[0].end() <= pos {
+			b.packets = b.packets[1:]
+		}
+
+		if len(b.packets) > 0 && b.packets[0].ts <= pos {
+			p := b.packets[0]
+			offset := pos - p.ts
+			n := min(p.end()-pos, b.blockFrames-filled)
+			seg := out[int(filled)*b.channels : int(filled+n)*b.channels]
+			copy(seg, p.samples[int(offset)*b.channels:])
+			b.recover(seg)
+			filled += n
+			continue
+		}
+
+		gapEnd := b.blockFrames
+		if len(b.packets) > 0 {
+			gapEnd = min(gapEnd, b.packets[0].ts-b.playTS)
+		}
+
+		concealed += uint32(b.conceal(out[int(filled)*b.channels : int(gapEnd)*b.channels]))
+		filled = gapEnd
+	}
+
+	b.playTS += b.blockFrames
+
+	// 长时间欠载后重新预缓冲
+	if len(b.packets) == 0 && b.concealRun > b.maxDepth {
+		b.started = false
+	}
+
+	_, err = pcm.Encode(&b.format, out, dst)
+
+	return
+}
+
+// 根据估计的抖动计算目标缓冲深度
+func (b *Buffer) targetDepth() int64 {
+	target := b.blockFrames + int64(math.Ceil(3*b.jitter))
+
+	return min(max(target, b.minDepth), b.maxDepth)
+}
+
+// 缓冲过深时丢弃多余的帧，发送端暂停后的时间戳跳变则直接重新同步
+func (b *Buffer) adjustLatency() {
+	if len(b.packets) > 0 && b.packets[0].ts-b.playTS > b.maxDepth {
+		b.playTS = b.packets[0].ts
+	}
+
+	target := b.targetDepth()
+	if level := b.highest - b.playTS; level > target+2*b.blockFrames {
+		skip := level - target
+		b.playTS += skip
+		b.stats.DroppedFrames += uint64(skip)
+	}
+}
+
+// 根据序列号范围计算期望接收的数据包数
+func (b *Buffer) expectedPackets() uint64 {
+	if !b.haveRef {
+		return b.expected
+	}
+
+	return b.expected + uint64(b.maxSeq-b.baseSeq+1)
+}
+
+// 展开 32 位时间戳及 16 位序列号
+func (b *Buffer) unwrap(seq uint16, timestamp uint32) int64 {
+	if !b.haveRef {
+		b.haveRef = true
+		b.lastTS = timestamp
+		b.extTS = int64(timestamp)
+		b.baseSeq = int64(seq)
+		b.maxSeq = int64(seq)
+		b.lastSeq = seq
+		b.highest = b.extTS
+
+		return b.extTS
+	}
+
+	ts := b.extTS + int64(int32(timestamp-b.lastTS))
+	if ts > b.extTS {
+		b.extTS = ts
+		b.lastTS = timestamp
+	}
+
+	if s := b.maxSeq + int64(int16(seq-b.lastSeq)); s > b.maxSeq {
+		b.maxSeq = s
+		b.lastSeq = seq
+	}
+
+	return ts
+}
+
+// 按 RFC 3550 6.4.1 估计到达间隔抖动
+func (b *Buffer) updateJitter(ts int64, arrival time.Time) {
+	if b.epoch.IsZero() {
+		b.epoch = arrival
+	}
+
+	transit := arrival.Sub(b.epoch).Seconds()*float64(b.format.Format.SamplesPerSec) - float64(ts)
+	if b.haveTransit {
+		b.jitter += (math.Abs(transit-b.transit) - b.jitter) / 16
+	}
+
+	b.transit = transit
+	b.haveTransit = true
+}
+
+// 对缺失的帧进行补偿，返回补偿的帧数
+func (b *Buffer) conceal(seg []float32) int {
+	frames := int64(len(seg) / b.channels)
+
+	for i := int64(0); i < frames; i++ {
+		run := b.concealRun + i
+		frame := seg[int(i)*b.channels : int(i+1)*b.channels]
+
+		if b.concealment == ConcealSilence || run >= b.blockFrames {
+			clear(frame)
+			continue
+		}
+
+		src := b.history[int(run)*b.channels:]
+		gain := float32(1)
+		if b.concealment == ConcealFade {
+			gain = 1 - float32(run+1)/float32(b.blockFrames)
+		}
+
+		for c := range frame {
+			frame[c] = src[c] * gain
+		}
+	}
+
+	b.concealRun += frames
+	b.stats.ConcealedFrames += uint64(frames)
+
+	return int(frames)
+}
+
+// 补偿结束后对恢复的音频做短暂淡入，并记录历史
+func (b *Buffer) recover(seg []float32) {
+	frames := int64(len(seg) / b.channels)
+
+	if b.concealRun > 0 {
+		for i := int64(0); i < min(frames, b.rampFrames); i++ {
+			gain := float32(i+1) / float32(b.rampFrames+1)
+			for c := 0; c < b.channels; c++ {
+				seg[int(i)*b.channels+c] *= gain
+			}
+		}
+
+		b.concealRun = 0
+	}
+
+	// 保留最近一个块的音频用于补偿
+	n := len(b.history)
+	if len(seg) >= n {
+		copy(b.history, seg[len(seg)-n:])
+	} else {
+		copy(b.history, b.history[len(seg):])
+		copy(b.history[n-len(seg):], seg)
+	}
+}
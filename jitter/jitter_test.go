@@ -0,0 +1,223 @@
+package jitter
+
+import (
+	"encoding/binary"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/cyberxnomad/wasapi/audioclient"
+)
+
+const block = 480
+
+var (
+	mono = audioclient.WAVEFORMATEXTENSIBLE{Format: audioclient.WAVEFORMATEX{
+		FormatTag:      audioclient.WAVE_FORMAT_PCM,
+		Channels:       1,
+		SamplesPerSec:  48000,
+		AvgBytesPerSec: 48000 * 2,
+		BlockAlign:     2,
+		BitsPerSample:  16,
+	}}
+	epoch = time.Unix(1700000000, 0)
+)
+
+// 一个块长、样本值均为 value 的数据包
+func payload(value int16) []byte {
+	data := make([]byte, 2*block)
+	for i := 0; i < block; i++ {
+		binary.LittleEndian.PutUint16(data[2*i:], uint16(value))
+	}
+
+	return data
+}
+
+// 按时间戳准时到达，抖动为 0 (时间戳按有符号数计算，回绕时到达时间仍然连续)
+func push(t *testing.T, b *Buffer, seq uint16, ts uint32, value int16) {
+	t.Helper()
+
+	arrival := epoch.Add(time.Duration(int32(ts)) * time.Second / 48000)
+	if err := b.Push(seq, ts, payload(value), arrival); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func pop(t *testing.T, b *Buffer) (samples []int16, concealed uint32) {
+	t.Helper()
+
+	data := make([]byte, 2*block)
+	concealed, err := b.Pop(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	samples = make([]int16, block)
+	for i := range samples {
+		samples[i] = int16(binary.LittleEndian.Uint16(data[2*i:]))
+	}
+
+	return
+}
+
+// 检查一个块的所有样本均为 value 且没有补偿
+func popValue(t *testing.T, b *Buffer, value int16) {
+	t.Helper()
+
+	samples, concealed := pop(t, b)
+	if concealed != 0 {
+		t.Fatalf("block with value %d: %d frames concealed", value, concealed)
+	}
+	for i, v := range samples {
+		if v != value {
+			t.Fatalf("frame %d = %d, want %d", i, v, value)
+		}
+	}
+}
+
+func TestReorder(t *testing.T) {
+	b := NewBuffer(&mono, block)
+
+	push(t, b, 1, block, 2)
+	push(t, b, 0, 0, 1)
+	popValue(t, b, 1)
+
+	push(t, b, 3, 3*block, 4)
+	push(t, b, 2, 2*block, 3)
+	popValue(t, b, 2)
+	popValue(t, b, 3)
+	popValue(t, b, 4)
+
+	if stats := b.Stats(); stats.Received != 4 || stats.Lost != 0 || stats.ConcealedFrames != 0 || stats.DroppedFrames != 0 {
+		t.Fatalf("stats = %+v", stats)
+	}
+}
+
+func TestDuplicateAndLate(t *testing.T) {
+	b := NewBuffer(&mono, block)
+
+	push(t, b, 0, 0, 1)
+	push(t, b, 0, 0, 1)
+	push(t, b, 2, 2*block, 3)
+	popValue(t, b, 1)
+
+	// 序列号 1 在其播放时间之后才到达
+	if _, concealed := pop(t, b); concealed != block {
+		t.Fatalf("%d frames concealed, want %d", concealed, block)
+	}
+	push(t, b, 1, block, 2)
+
+	stats := b.Stats()
+	if stats.Received != 3 || stats.Duplicate != 1 || stats.Late != 1 || stats.Lost != 0 {
+		t.Fatalf("stats = %+v", stats)
+	}
+
+	// 补偿之后淡入
+	if samples, _ := pop(t, b); samples[block-1] != 3 {
+		t.Fatalf("last frame = %d, want 3", samples[block-1])
+	}
+}
+
+func TestWrap(t *testing.T) {
+	b := NewBuffer(&mono, block)
+
+	// 序列号及时间戳同时回绕，且乱序到达
+	var ts0 uint32 = math.MaxUint32 - 2*block + 1
+	push(t, b, 65534, ts0, 1)
+	push(t, b, 0, ts0+2*block, 3)
+	push(t, b, 65535, ts0+block, 2)
+	for value := int16(1); value <= 3; value++ {
+		if value == 2 {
+			push(t, b, 1, ts0+3*block, 4)
+		}
+		popValue(t, b, value)
+	}
+	popValue(t, b, 4)
+
+	if stats := b.Stats(); stats.Received != 4 || stats.Lost != 0 {
+		t.Fatalf("stats = %+v", stats)
+	}
+}
+
+func TestLostAcrossReset(t *testing.T) {
+	b := NewBuffer(&mono, block)
+
+	// 第一段丢失序列号 1
+	push(t, b, 0, 0, 1)
+	push(t, b, 2, 2*block, 3)
+	if lost := b.Stats().Lost; lost != 1 {
+		t.Fatalf("Lost = %d, want 1", lost)
+	}
+
+	// 重置后发送端重新开始，丢包数保留且新的一段完整时不再增加
+	b.Reset()
+	push(t, b, 1000, 50000, 1)
+	push(t, b, 1001, 50000+block, 2)
+
+	if stats := b.Stats(); stats.Received != 4 || stats.Lost != 1 {
+		t.Fatalf("after Reset: stats = %+v, want 4 received, 1 lost", stats)
+	}
+
+	push(t, b, 1003, 50000+3*block, 4)
+	if lost := b.Stats().Lost; lost != 2 {
+		t.Fatalf("after Reset: Lost = %d, want 2", lost)
+	}
+}
+
+func TestConcealment(t *testing.T) {
+	const value = 10000
+
+	for _, tt := range []struct {
+		concealment Concealment
+		want        func(frame int) float64
+	}{
+		{ConcealSilence, func(int) float64 { return 0 }},
+		{ConcealRepeat, func(int) float64 { return value }},
+		{ConcealFade, func(frame int) float64 { return value * (1 - float64(frame+1)/block) }},
+	} {
+		b := NewBuffer(&mono, block)
+		b.SetConcealment(tt.concealment)
+
+		// 序列号 2 丢失
+		push(t, b, 0, 0, value)
+		push(t, b, 1, block, value)
+		popValue(t, b, value)
+		popValue(t, b, value)
+		push(t, b, 3, 3*block, value)
+
+		samples, concealed := pop(t, b)
+		if concealed != block {
+			t.Fatalf("concealment %d: %d frames concealed, want %d", tt.concealment, concealed, block)
+		}
+		for i, v := range samples {
+			if want := tt.want(i); math.Abs(float64(v)-want) > 1 {
+				t.Fatalf("concealment %d: frame %d = %d, want %.0f", tt.concealment, i, v, want)
+			}
+		}
+
+		// 恢复时淡入，之后恢复原值
+		samples, concealed = pop(t, b)
+		if concealed != 0 || samples[0] >= value || samples[block-1] != value {
+			t.Fatalf("concealment %d: recovered block starts at %d, ends at %d, %d concealed",
+				tt.concealment, samples[0], samples[block-1], concealed)
+		}
+		for i := 1; i < block; i++ {
+			if samples[i] < samples[i-1] {
+				t.Fatalf("concealment %d: recovery ramp not monotonic at frame %d", tt.concealment, i)
+			}
+		}
+
+		// 数据耗尽后补偿一个块，超过一个块的连续丢包均输出静音
+		pop(t, b)
+		samples, _ = pop(t, b)
+		for i, v := range samples {
+			if v != 0 {
+				t.Fatalf("concealment %d: frame %d = %d after a long gap, want silence", tt.concealment, i, v)
+			}
+		}
+
+		if stats := b.Stats(); stats.Lost != 1 {
+			t.Fatalf("concealment %d: Lost = %d, want 1", tt.concealment, stats.Lost)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package latency
+
+import (
+	"errors"
+	"math"
+
+	"github.com/cyberxnomad/wasapi/fft"
+)
+
+var ErrNotDetected = errors.New("probe signal not detected")
+
+// MinPeak 为判定检测到探测信号的最小归一化相关系数。
+const MinPeak = 0.2
+
+// Correlation 为互相关的结果。
+type Correlation struct {
+	Lag        float64 // 参考信号在录音中的起始位置，单位为帧，含亚采样插值
+	Peak       float64 // 峰值处的归一化相关系数，范围为 [0, 1]
+	Confidence float64 // 1 减去次高峰与主峰之比，范围为 [0, 1]
+}
+
+// Correlate 计算参考信号 reference 在录音 recorded 中的位置。
+//
+// 使用基于 FFT 的互相关，并按录音窗口的能量归一化，因此结果与录音电平无关。
+func Correlate(reference, recorded []float32) (result Correlation, err error) {
+	m := len(reference)
+	lags := len(recorded) - m + 1
+	if m == 0 || lags <= 0 {
+		err = ErrNotDetected
+		return
+	}
+
+	n := fft.NextPow2(len(recorded) + m)
+	transform, _ := fft.New(n)
+	a := make([]complex128, n)
+	b := make([]complex128, n)
+
+	var refEnergy float64
+	for i, v := range reference {
+		a[i] = complex(float64(v), 0)
+		refEnergy += float64(v) * float64(v)
+	}
+	for i, v := range recorded {
+		b[i] = complex(float64(v), 0)
+	}
+
+	transform.Transform(a)
+	transform.Transform(b)
+	for i := range b {
+		b[i] *= complex(real(a[i]), -imag(a[i]))
+	}
+	transform.Inverse(b)
+
+	// 滑动窗口能量，用于归一化
+	corr := make([]float64, lags)
+	var energy float64
+	for i := 0; i < m; i++ {
+		energy += float64(recorded[i]) * float64(recorded[i])
+	}
+
+	for k := 0; k < lags; k++ {
+		if k > 0 {
+			out, in := float64(recorded[k-1]), float64(recorded[k+m-1])
+			energy = max(energy-out*out+in*in, 0)
+		}
+
+		if denom := math.Sqrt(refEnergy * energy); denom > 1e-12 {
+			corr[k] = real(b[k]) / denom
+		}
+	}
+
+	best := 0
+	for k := range corr {
+		if math.Abs(corr[k]) > math.Abs(corr[best]) {
+			best = k
+		}
+	}
+
+	peak := math.Abs(corr[best])
+	if peak < MinPeak {
+		err = ErrNotDetected
+		return
+	}
+
+	// 排除主峰附近后的次高峰
+	guard := max(m/8, 8)
+	var second float64
+	for k := range corr {
+		if k < best-guard || k > best+guard {
+			second = max(second, math.Abs(corr[k]))
+		}
+	}
+
+	result.Lag = float64(best)
+	if best > 0 && best < lags-1 {
+		y0, y1, y2 := math.Abs(corr[best-1]), peak, math.Abs(corr[best+1])
+		if d := y0 - 2*y1 + y2; d != 0 {
+			result.Lag += 0.5 * (y0 - y2) / d
+		}
+	}
+
+	result.Peak = min(peak, 1)
+	result.Confidence = 1 - second/peak
+
+	return
+}
@@ -0,0 +1,171 @@
+package latency
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+// 模拟的呈现到捕获通路: 延迟 delay 帧、增益 gain 并叠加白噪声
+func simulate(t *testing.T, signal []float32, rate uint32, delay int, gain, noise float64) Result {
+	t.Helper()
+
+	const block = 480
+	probe := NewProbe(signal, rate, 2, 1, 500*time.Millisecond)
+	r := rand.New(rand.NewSource(1))
+
+	var played []float32
+	render := make([]float32, block*2)
+	capture := make([]float32, block)
+
+	for k := 0; !probe.Done(); k++ {
+		if k > 1000 {
+			t.Fatal("probe never finished")
+		}
+
+		qpc := uint64(k) * block * 10000000 / uint64(rate)
+
+		probe.Render(render, qpc)
+		for i := 0; i < block; i++ {
+			played = append(played, render[i*2])
+		}
+
+		for i := range capture {
+			var v float64
+			if j := k*block + i - delay; j >= 0 {
+				v = gain * float64(played[j])
+			}
+			capture[i] = float32(v + noise*r.NormFloat64())
+		}
+
+		probe.Capture(capture, qpc)
+	}
+
+	result, err := probe.Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return result
+}
+
+func TestProbeChirp(t *testing.T) {
+	for _, delay := range []int{0, 37, 480, 4321} {
+		signal := Chirp(48000, 100, 20000, 48000/2)
+		result := simulate(t, signal, 48000, delay, 0.3, 0.01)
+
+		if math.Abs(result.Frames-float64(delay)) > 0.5 {
+			t.Errorf("delay %d: measured %.2f frames", delay, result.Frames)
+		}
+
+		want := time.Duration(delay) * time.Second / 48000
+		if d := result.Latency - want; d < -20*time.Microsecond || d > 20*time.Microsecond {
+			t.Errorf("delay %d: latency %v, want %v", delay, result.Latency, want)
+		}
+
+		if result.Peak < 0.9 || result.Confidence < 0.5 {
+			t.Errorf("delay %d: peak %.3f confidence %.3f", delay, result.Peak, result.Confidence)
+		}
+	}
+}
+
+func TestProbeMLS(t *testing.T) {
+	// 噪声与信号电平相当时 MLS 仍能可靠检测
+	result := simulate(t, MLS(14), 44100, 1234, 0.5, 0.25)
+
+	if math.Abs(result.Frames-1234) > 0.5 {
+		t.Fatalf("measured %.2f frames, want 1234", result.Frames)
+	}
+
+	if result.Confidence < 0.5 {
+		t.Fatalf("confidence %.3f", result.Confidence)
+	}
+}
+
+func TestProbeNotDetected(t *testing.T) {
+	probe := NewProbe(Chirp(16000, 100, 7000, 4000), 16000, 1, 1, 100*time.Millisecond)
+
+	if _, err := probe.Result(); !errors.Is(err, ErrNotDetected) {
+		t.Fatalf("Result before render: err = %v", err)
+	}
+
+	r := rand.New(rand.NewSource(2))
+	buf := make([]float32, 160)
+	for k := 0; !probe.Done(); k++ {
+		probe.Render(buf, uint64(k)*100000)
+		for i := range buf {
+			buf[i] = float32(0.1 * r.NormFloat64())
+		}
+		probe.Capture(buf, uint64(k)*100000)
+	}
+
+	if _, err := probe.Result(); !errors.Is(err, ErrNotDetected) {
+		t.Fatalf("Result on noise only: err = %v", err)
+	}
+}
+
+func TestCorrelateSubSample(t *testing.T) {
+	// 带限信号的分数延迟，用 sinc 插值构造
+	const delay = 100.4
+	reference := Chirp(48000, 200, 4000, 2048)
+
+	recorded := make([]float32, 4096)
+	for i := range recorded {
+		var v float64
+		for j, s := range reference {
+			x := float64(i) - delay - float64(j)
+			if x == 0 {
+				v += float64(s)
+			} else if math.Abs(x) < 64 {
+				v += float64(s) * math.Sin(math.Pi*x) / (math.Pi * x)
+			}
+		}
+		recorded[i] = float32(v)
+	}
+
+	result, err := Correlate(reference, recorded)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if math.Abs(result.Lag-delay) > 0.15 {
+		t.Fatalf("lag %.3f, want %.1f", result.Lag, delay)
+	}
+}
+
+func TestMLS(t *testing.T) {
+	for order := 2; order <= 18; order++ {
+		signal := MLS(order)
+		if len(signal) != 1<<order-1 {
+			t.Fatalf("order %d: length %d", order, len(signal))
+		}
+
+		// 最大长度序列中 +1 比 -1 多一个
+		var sum float32
+		for _, v := range signal {
+			sum += v
+		}
+		if sum != 0.5 {
+			t.Errorf("order %d: sum %v, want 0.5", order, sum)
+		}
+	}
+
+	// 循环自相关在非零延迟处为 -1/N
+	signal := MLS(10)
+	n := len(signal)
+	for _, lag := range []int{1, 17, 500} {
+		var acc float64
+		for i := range signal {
+			acc += float64(signal[i]) * float64(signal[(i+lag)%n]) * 4
+		}
+		if acc != -1 {
+			t.Errorf("autocorrelation at lag %d = %v, want -1", lag, acc)
+		}
+	}
+
+	if MLS(1) != nil || MLS(19) != nil {
+		t.Error("unsupported order returned a sequence")
+	}
+}
@@ -0,0 +1,159 @@
+package latency
+
+import (
+	"sync"
+	"time"
+)
+
+// Result 为一次延迟测量的结果。
+type Result struct {
+	Latency    time.Duration // 呈现到捕获的延迟
+	Frames     float64       // 以帧为单位的延迟
+	Peak       float64       // 归一化相关系数
+	Confidence float64       // 置信度，范围为 [0, 1]
+}
+
+type mark struct {
+	index int
+	qpc   uint64
+}
+
+// Probe 将探测信号写入呈现流，并在捕获流 (或环回流) 中查找它以测量往返延迟。
+//
+// 呈现线程调用 Render 填充呈现缓冲区，捕获线程调用 Capture 提交捕获的数据，
+// Done 返回 true 后调用 Result 获取结果。qpc 为缓冲区首帧对应的 QPC 时间 (100 纳秒单位)，
+// 例如呈现时由 IAudioClock 推算，捕获时取 GetBuffer 返回的 QPCPosition。
+type Probe struct {
+	mu sync.Mutex
+
+	signal          []float32
+	samplesPerSec   float64
+	renderChannels  int
+	captureChannels int
+	leadIn          int
+	maxFrames       int
+
+	rendered   int
+	signalQPC  uint64
+	haveSignal bool
+	recording  []float32
+	marks      []mark
+}
+
+// NewProbe 创建延迟探测器，signal 为单声道探测信号 (参见 Chirp 及 MLS)，
+// maxLatency 为可测量的最大延迟。
+func NewProbe(signal []float32, samplesPerSec uint32, renderChannels, captureChannels int, maxLatency time.Duration) *Probe {
+	rate := float64(samplesPerSec)
+	leadIn := int(rate / 10)
+
+	return &Probe{
+		signal:          signal,
+		samplesPerSec:   rate,
+		renderChannels:  renderChannels,
+		captureChannels: captureChannels,
+		leadIn:          leadIn,
+		maxFrames:       leadIn + len(signal) + int(maxLatency.Seconds()*rate),
+	}
+}
+
+// Render 方法将探测信号写入交错的呈现缓冲区 dst，信号之前有 100 毫秒的静音。
+func (p *Probe) Render(dst []float32, qpc uint64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	frames := len(dst) / p.renderChannels
+	for i := 0; i < frames; i++ {
+		var v float32
+		if j := p.rendered + i - p.leadIn; j >= 0 && j < len(p.signal) {
+			v = p.signal[j]
+		}
+
+		if p.rendered+i == p.leadIn {
+			p.signalQPC = qpc + uint64(float64(i)*1e7/p.samplesPerSec)
+			p.haveSignal = true
+		}
+
+		for c := 0; c < p.renderChannels; c++ {
+			dst[i*p.renderChannels+c] = v
+		}
+	}
+
+	p.rendered += frames
+}
+
+// Capture 方法提交捕获的交错采样 src，各声道混合为单声道后记录。
+func (p *Probe) Capture(src []float32, qpc uint64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if len(p.recording) >= p.maxFrames {
+		return
+	}
+
+	p.marks = append(p.marks, mark{index: len(p.recording), qpc: qpc})
+
+	frames := len(src) / p.captureChannels
+	for i := 0; i < frames; i++ {
+		var sum float32
+		for c := 0; c < p.captureChannels; c++ {
+			sum += src[i*p.captureChannels+c]
+		}
+
+		p.recording = append(p.recording, sum/float32(p.captureChannels))
+	}
+}
+
+// Done 方法返回是否已录制足够的数据。
+func (p *Probe) Done() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.haveSignal && len(p.recording) >= p.maxFrames
+}
+
+// Reset 方法清除状态以便重新测量。
+func (p *Probe) Reset() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.rendered = 0
+	p.haveSignal = false
+	p.recording = p.recording[:0]
+	p.marks = p.marks[:0]
+}
+
+// Result 方法对录音与探测信号做互相关并计算延迟。
+func (p *Probe) Result() (result Result, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !p.haveSignal {
+		err = ErrNotDetected
+		return
+	}
+
+	var corr Correlation
+	if corr, err = Correlate(p.signal, p.recording); err != nil {
+		return
+	}
+
+	// 找到包含到达位置的捕获缓冲区，换算为 QPC 时间
+	arrival := corr.Lag
+	m := p.marks[0]
+	for _, mk := range p.marks {
+		if float64(mk.index) > arrival {
+			break
+		}
+		m = mk
+	}
+
+	arrivalQPC := float64(m.qpc) + (arrival-float64(m.index))*1e7/p.samplesPerSec
+	hns := arrivalQPC - float64(p.signalQPC)
+
+	result.Latency = time.Duration(hns * 100)
+	result.Frames = hns * p.samplesPerSec / 1e7
+	result.Peak = corr.Peak
+	result.Confidence = corr.Confidence
+
+	return
+}
@@ -0,0 +1,61 @@
+package latency
+
+import "math"
+
+// Chirp 生成从 f0 扫频到 f1 的线性扫频信号，首尾各有 5 毫秒的淡入淡出。
+func Chirp(samplesPerSec uint32, f0, f1 float64, frames int) (signal []float32) {
+	signal = make([]float32, frames)
+	rate := float64(samplesPerSec)
+	duration := float64(frames) / rate
+	ramp := min(int(rate*0.005), frames/2)
+
+	for i := range signal {
+		t := float64(i) / rate
+		phase := 2 * math.Pi * (f0*t + (f1-f0)*t*t/(2*duration))
+		v := math.Sin(phase)
+
+		if i < ramp {
+			v *= float64(i) / float64(ramp)
+		} else if j := frames - 1 - i; j < ramp {
+			v *= float64(j) / float64(ramp)
+		}
+
+		signal[i] = float32(v * 0.5)
+	}
+
+	return
+}
+
+// 最大长度序列的反馈抽头 (Galois LFSR)，以阶数为索引
+var mlsTaps = [...]uint32{
+	2: 0x3, 3: 0x6, 4: 0xC, 5: 0x14, 6: 0x30, 7: 0x60, 8: 0xB8,
+	9: 0x110, 10: 0x240, 11: 0x500, 12: 0xE08, 13: 0x1C80,
+	14: 0x3802, 15: 0x6000, 16: 0xD008, 17: 0x12000, 18: 0x20400,
+}
+
+// MLS 生成阶数为 order (2 到 18) 的最大长度序列，长度为 2^order-1，幅度为 ±0.5。
+func MLS(order int) (signal []float32) {
+	if order < 2 || order >= len(mlsTaps) {
+		return
+	}
+
+	taps := mlsTaps[order]
+	state := uint32(1)
+	signal = make([]float32, 1<<order-1)
+
+	for i := range signal {
+		if state&1 != 0 {
+			signal[i] = 0.5
+		} else {
+			signal[i] = -0.5
+		}
+
+		lsb := state & 1
+		state >>= 1
+		if lsb != 0 {
+			state ^= taps
+		}
+	}
+
+	return
+}
@@ -0,0 +1,45 @@
+package loudness
+
+import (
+	"math"
+
+	"github.com/cyberxnomad/wasapi/biquad"
+)
+
+// 按 BS.1770 的模拟原型为任意采样率计算 K 计权滤波器 (高架及高通两级)
+func kWeighting(samplesPerSec float64) (shelf, highPass biquad.Coefficients) {
+	// 第一级: 模拟头部声学效应的高架滤波器
+	f0 := 1681.974450955533
+	g := 3.999843853973347
+	q := 0.7071752369554196
+
+	k := math.Tan(math.Pi * f0 / samplesPerSec)
+	vh := math.Pow(10, g/20)
+	vb := math.Pow(vh, 0.4996667741545416)
+	a0 := 1 + k/q + k*k
+
+	shelf = biquad.Coefficients{
+		B0: (vh + vb*k/q + k*k) / a0,
+		B1: 2 * (k*k - vh) / a0,
+		B2: (vh - vb*k/q + k*k) / a0,
+		A1: 2 * (k*k - 1) / a0,
+		A2: (1 - k/q + k*k) / a0,
+	}
+
+	// 第二级: RLB 高通滤波器
+	f0 = 38.13547087602444
+	q = 0.5003270373238773
+
+	k = math.Tan(math.Pi * f0 / samplesPerSec)
+	a0 = 1 + k/q + k*k
+
+	highPass = biquad.Coefficients{
+		B0: 1,
+		B1: -2,
+		B2: 1,
+		A1: 2 * (k*k - 1) / a0,
+		A2: (1 - k/q + k*k) / a0,
+	}
+
+	return
+}
@@ -0,0 +1,283 @@
+package loudness
+
+import (
+	"math"
+	"sort"
+	"sync"
+
+	"github.com/cyberxnomad/wasapi/audioclient"
+	"github.com/cyberxnomad/wasapi/biquad"
+	"github.com/cyberxnomad/wasapi/internal/dsp"
+	"github.com/cyberxnomad/wasapi/meter"
+	"github.com/cyberxnomad/wasapi/pcm"
+)
+
+const (
+	AbsoluteGate    = -70.0 // 绝对门限 (LUFS)
+	RelativeGate    = -10.0 // 综合响度的相对门限 (LU)
+	RangeGate       = -20.0 // 响度范围的相对门限 (LU)
+	subBlocksPerSec = 10    // 每秒的子块数，即测量的更新间隔为 100 毫秒
+	momentaryBlocks = 4     // 瞬时响度的窗口为 400 毫秒
+	shortTermBlocks = 30    // 短期响度的窗口为 3 秒
+)
+
+type channel struct {
+	weight   float64
+	shelf    biquad.Coefficients
+	highPass biquad.Coefficients
+	states   [2]biquad.State
+	sum      float64
+	truePeak meter.TruePeak
+	peak     float64
+}
+
+// Meter 是 ITU-R BS.1770-4 / EBU R128 响度表。
+//
+// 提供瞬时 (M)、短期 (S)、综合 (I) 响度，响度范围 (LRA) 及真峰值。
+type Meter struct {
+	mu sync.Mutex
+
+	format   audioclient.WAVEFORMATEXTENSIBLE
+	channels []channel
+
+	subBlockFrames int
+	frames         int
+	subBlocks      []float64 // 最近 3 秒子块的加权能量，环形缓冲
+	subBlockCount  int
+
+	blocks     []float64 // 400 毫秒门限块的能量，用于综合响度
+	shortTerms []float64 // 3 秒短期能量，用于响度范围
+	history    bool
+
+	buf []float32
+}
+
+// NewMeter 创建响度表，声道权重由声道掩码决定：环绕声道 +1.5 dB，低频声道不计入。
+func NewMeter(format *audioclient.WAVEFORMATEXTENSIBLE) *Meter {
+	rate := float64(format.Format.SamplesPerSec)
+
+	m := &Meter{
+		format:         *format,
+		channels:       make([]channel, format.Format.Channels),
+		subBlockFrames: max(int(format.Format.SamplesPerSec)/subBlocksPerSec, 1),
+		subBlocks:      make([]float64, shortTermBlocks),
+		history:        true,
+	}
+
+	for i, speaker := range pcm.Speakers(format) {
+		ch := &m.channels[i]
+		ch.weight = ChannelWeight(speaker)
+		ch.shelf, ch.highPass = kWeighting(rate)
+	}
+
+	return m
+}
+
+// ChannelWeight 返回扬声器位置 (SPEAKER_*) 的 BS.1770 声道权重。
+func ChannelWeight(speaker uint32) float64 {
+	switch speaker {
+	case audioclient.SPEAKER_LOW_FREQUENCY:
+		return 0
+	case audioclient.SPEAKER_BACK_LEFT, audioclient.SPEAKER_BACK_RIGHT,
+		audioclient.SPEAKER_SIDE_LEFT, audioclient.SPEAKER_SIDE_RIGHT,
+		audioclient.SPEAKER_BACK_CENTER:
+		return 1.41
+	}
+
+	return 1
+}
+
+// SetHistory 方法设置是否记录综合响度及响度范围所需的历史，默认记录。
+//
+// 长时间运行且只需要瞬时及短期响度时可以关闭，以避免历史无限增长。
+func (m *Meter) SetHistory(enabled bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.history = enabled
+	if !enabled {
+		m.blocks = nil
+		m.shortTerms = nil
+	}
+}
+
+// Process 方法处理交错的 float32 采样。
+func (m *Meter) Process(samples []float32) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.process(samples)
+}
+
+// ProcessBytes 方法按格式解码并处理交错的音频数据。
+func (m *Meter) ProcessBytes(data []byte) (err error) {
+	return dsp.ProcessBytes(&m.mu, &m.format, &m.buf, data, false, m.process)
+}
+
+func (m *Meter) process(samples []float32) {
+	n := len(m.channels)
+	for i := 0; i+n <= len(samples); i += n {
+		for c := range m.channels {
+			ch := &m.channels[c]
+			v := float64(samples[i+c])
+
+			ch.peak = max(ch.peak, ch.truePeak.Process(v))
+
+			y := ch.states[1].Process(&ch.highPass, ch.states[0].Process(&ch.shelf, v))
+			ch.sum += y * y
+		}
+
+		if m.frames++; m.frames == m.subBlockFrames {
+			m.finishSubBlock()
+		}
+	}
+}
+
+// 完成一个 100 毫秒子块，更新门限块及短期能量
+func (m *Meter) finishSubBlock() {
+	var energy float64
+	for c := range m.channels {
+		ch := &m.channels[c]
+		energy += ch.weight * ch.sum / float64(m.frames)
+		ch.sum = 0
+	}
+
+	m.subBlocks[m.subBlockCount%shortTermBlocks] = energy
+	m.subBlockCount++
+	m.frames = 0
+
+	if !m.history {
+		return
+	}
+
+	if m.subBlockCount >= momentaryBlocks {
+		m.blocks = append(m.blocks, m.window(momentaryBlocks))
+	}
+
+	if m.subBlockCount >= shortTermBlocks {
+		m.shortTerms = append(m.shortTerms, m.window(shortTermBlocks))
+	}
+}
+
+// 最近 n 个子块的平均能量
+func (m *Meter) window(n int) (energy float64) {
+	n = min(n, m.subBlockCount)
+	if n == 0 {
+		return
+	}
+
+	for i := 1; i <= n; i++ {
+		energy += m.subBlocks[(m.subBlockCount-i)%shortTermBlocks]
+	}
+
+	return energy / float64(n)
+}
+
+// Momentary 方法返回瞬时响度 (400 毫秒窗口)，单位为 LUFS。
+func (m *Meter) Momentary() float64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return Loudness(m.window(momentaryBlocks))
+}
+
+// ShortTerm 方法返回短期响度 (3 秒窗口)，单位为 LUFS。
+func (m *Meter) ShortTerm() float64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return Loudness(m.window(shortTermBlocks))
+}
+
+// Integrated 方法返回自开始以来的综合响度，单位为 LUFS。
+func (m *Meter) Integrated() float64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	gated := gate(m.blocks, AbsoluteGate)
+	if len(gated) == 0 {
+		return math.Inf(-1)
+	}
+
+	gated = gate(gated, Loudness(mean(gated))+RelativeGate)
+
+	return Loudness(mean(gated))
+}
+
+// LoudnessRange 方法返回响度范围 (EBU Tech 3342)，单位为 LU。
+func (m *Meter) LoudnessRange() float64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	gated := gate(m.shortTerms, AbsoluteGate)
+	if len(gated) == 0 {
+		return 0
+	}
+
+	gated = gate(gated, Loudness(mean(gated))+RangeGate)
+	if len(gated) == 0 {
+		return 0
+	}
+
+	sort.Float64s(gated)
+	low := gated[int(math.Round(0.10*float64(len(gated)-1)))]
+	high := gated[int(math.Round(0.95*float64(len(gated)-1)))]
+
+	return Loudness(high) - Loudness(low)
+}
+
+// TruePeak 方法返回所有声道的最大真峰值，单位为 dBTP。
+func (m *Meter) TruePeak() (peak float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, ch := range m.channels {
+		peak = max(peak, ch.peak)
+	}
+
+	return 20 * math.Log10(peak)
+}
+
+// Reset 方法清除所有测量结果。
+func (m *Meter) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for c := range m.channels {
+		ch := &m.channels[c]
+		ch.states = [2]biquad.State{}
+		ch.sum = 0
+		ch.truePeak = meter.TruePeak{}
+		ch.peak = 0
+	}
+
+	m.frames = 0
+	m.subBlockCount = 0
+	clear(m.subBlocks)
+	m.blocks = m.blocks[:0]
+	m.shortTerms = m.shortTerms[:0]
+}
+
+// Loudness 将加权均方能量换算为响度，单位为 LUFS。
+func Loudness(energy float64) float64 {
+	return -0.691 + 10*math.Log10(energy)
+}
+
+// 返回响度高于门限的块
+func gate(blocks []float64, threshold float64) (gated []float64) {
+	for _, e := range blocks {
+		if Loudness(e) > threshold {
+			gated = append(gated, e)
+		}
+	}
+
+	return
+}
+
+func mean(values []float64) (m float64) {
+	for _, v := range values {
+		m += v
+	}
+
+	return m / float64(len(values))
+}
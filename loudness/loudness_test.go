@@ -0,0 +1,126 @@
+package loudness
+
+import (
+	"math"
+	"testing"
+
+	"github.com/cyberxnomad/wasapi/audioclient"
+	"github.com/cyberxnomad/wasapi/generator"
+	"github.com/cyberxnomad/wasapi/pcm"
+)
+
+const samplesPerSec = 48000
+
+// 一段 1 kHz 正弦信号，level 为峰值电平 (dBFS)
+type segment struct {
+	level   float64
+	seconds float64
+}
+
+// 按顺序将各段信号送入响度表
+func measure(format *audioclient.WAVEFORMATEXTENSIBLE, segments []segment) *Meter {
+	m := NewMeter(format)
+	tone := generator.NewOscillator(generator.WaveSine, format.Format.SamplesPerSec, 1000, 1)
+	gen := generator.NewGenerator(format, tone)
+	channels := int(format.Format.Channels)
+
+	for _, s := range segments {
+		tone.SetAmplitude(math.Pow(10, s.level/20))
+
+		buf := make([]float32, int(s.seconds*float64(format.Format.SamplesPerSec))*channels)
+		gen.Read(buf)
+		m.Process(buf)
+	}
+
+	return m
+}
+
+// EBU Tech 3341 及 Tech 3342 中可由正弦信号合成的测试用例
+func TestEBUVectors(t *testing.T) {
+	integrated := func(m *Meter) float64 { return m.Integrated() }
+	shortTerm := func(m *Meter) float64 { return m.ShortTerm() }
+	momentary := func(m *Meter) float64 { return m.Momentary() }
+	lra := func(m *Meter) float64 { return m.LoudnessRange() }
+
+	cases := []struct {
+		name     string
+		segments []segment
+		measure  func(m *Meter) float64
+		expected float64
+		tol      float64
+	}{
+		{"Tech 3341 #1 M", []segment{{-23, 20}}, momentary, -23, 0.1},
+		{"Tech 3341 #1 S", []segment{{-23, 20}}, shortTerm, -23, 0.1},
+		{"Tech 3341 #1 I", []segment{{-23, 20}}, integrated, -23, 0.1},
+		{"Tech 3341 #2 I", []segment{{-33, 20}}, integrated, -33, 0.1},
+		{"Tech 3341 #3 I", []segment{{-36, 10}, {-23, 60}, {-36, 10}}, integrated, -23, 0.1},
+		{"Tech 3341 #4 I", []segment{{-72, 10}, {-36, 10}, {-23, 60}, {-36, 10}, {-72, 10}}, integrated, -23, 0.1},
+		{"Tech 3341 #5 I", []segment{{-26, 20}, {-20, 20.1}, {-26, 20}}, integrated, -23, 0.1},
+		{"Tech 3342 #1 LRA", []segment{{-20, 20}, {-30, 20}}, lra, 10, 1},
+		{"Tech 3342 #2 LRA", []segment{{-20, 20}, {-15, 20}}, lra, 5, 1},
+		{"Tech 3342 #3 LRA", []segment{{-40, 20}, {-20, 20}}, lra, 20, 1},
+		{"Tech 3342 #4 LRA", []segment{{-50, 20}, {-35, 20}, {-20, 20}, {-35, 20}, {-50, 20}}, lra, 15, 1},
+	}
+
+	format := pcm.NewFormat(pcm.SampleTypeFloat, samplesPerSec, 2, 32, audioclient.KSAUDIO_SPEAKER_STEREO)
+
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := c.measure(measure(&format, c.segments)); math.Abs(got-c.expected) > c.tol {
+				t.Errorf("got %.2f, expected %.1f ± %.1f", got, c.expected, c.tol)
+			}
+		})
+	}
+}
+
+func TestSurroundWeighting(t *testing.T) {
+	// 5.1 中仅 LFE 有信号时不计入响度，环绕声道较前置声道高 1.5 dB
+	format := pcm.NewFormat(pcm.SampleTypeFloat, samplesPerSec, 6, 32, audioclient.KSAUDIO_SPEAKER_5POINT1_SURROUND)
+
+	level := func(mask uint32) float64 {
+		m := NewMeter(&format)
+		tone := generator.NewOscillator(generator.WaveSine, samplesPerSec, 1000, math.Pow(10, -23.0/20))
+		gen := generator.NewGenerator(&format, tone)
+		gen.SetChannelMask(mask)
+
+		buf := make([]float32, 5*samplesPerSec*6)
+		gen.Read(buf)
+		m.Process(buf)
+
+		return m.Integrated()
+	}
+
+	front := level(audioclient.SPEAKER_FRONT_LEFT)
+	side := level(audioclient.SPEAKER_SIDE_LEFT)
+	lfe := level(audioclient.SPEAKER_LOW_FREQUENCY)
+
+	// 单声道 -23 dBFS 正弦为 -26 LUFS (立体声两个声道相加为 -23)
+	if math.Abs(front+26) > 0.1 {
+		t.Errorf("front left: %.2f LUFS, want -26", front)
+	}
+	if d := side - front; math.Abs(d-1.5) > 0.05 {
+		t.Errorf("side - front = %.2f LU, want 1.5", d)
+	}
+	if !math.IsInf(lfe, -1) && lfe > AbsoluteGate {
+		t.Errorf("lfe only: %.2f LUFS, want gated", lfe)
+	}
+}
+
+func TestTruePeak(t *testing.T) {
+	format := pcm.NewFormat(pcm.SampleTypeFloat, samplesPerSec, 1, 32, audioclient.KSAUDIO_SPEAKER_MONO)
+	m := NewMeter(&format)
+
+	// fs/4 正弦，相位 45 度时采样峰值比真峰值低 3 dB
+	buf := make([]float32, samplesPerSec)
+	for i := range buf {
+		buf[i] = float32(0.5 * math.Sin(math.Pi/2*float64(i)+math.Pi/4))
+	}
+	m.Process(buf)
+
+	if got := m.TruePeak(); math.Abs(got-20*math.Log10(0.5)) > 0.5 {
+		t.Fatalf("true peak %.2f dBTP, want %.2f", got, 20*math.Log10(0.5))
+	}
+}
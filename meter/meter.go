@@ -0,0 +1,206 @@
+package meter
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/cyberxnomad/wasapi/audioclient"
+	"github.com/cyberxnomad/wasapi/internal/dsp"
+	"github.com/cyberxnomad/wasapi/pcm"
+)
+
+// DefaultClipThreshold 为默认的削波阈值 (线性)，约为 -0.001 dBFS。
+const DefaultClipThreshold = 0.9999
+
+// Ballistics 为电平表的动态特性，均为指数时间常数。
+type Ballistics struct {
+	Attack      time.Duration // 电平上升的时间常数，0 表示瞬时
+	Release     time.Duration // 电平下降的时间常数
+	Hold        time.Duration // 峰值保持时间，0 表示不保持
+	Integration time.Duration // RMS 的积分时间常数
+}
+
+var (
+	// PresetDigital 为数字峰值表，瞬时上升，约 1.7 秒下降 20 dB。
+	PresetDigital = Ballistics{Release: 740 * time.Millisecond, Hold: time.Second, Integration: 300 * time.Millisecond}
+
+	// PresetVU 为 VU 表，上升及下降约 300 毫秒。
+	PresetVU = Ballistics{Attack: 65 * time.Millisecond, Release: 65 * time.Millisecond, Integration: 300 * time.Millisecond}
+
+	// PresetPPM 为准峰值节目表 (IEC 60268-10 IIb 型)，10 毫秒积分，约 2.8 秒下降 24 dB。
+	PresetPPM = Ballistics{Attack: 2500 * time.Microsecond, Release: 1015 * time.Millisecond, Integration: 300 * time.Millisecond}
+)
+
+// Level 为单个声道的电平，除 DB 外均为线性值。
+type Level struct {
+	Speaker  uint32  // 扬声器位置 (SPEAKER_*)，0 表示未指定
+	Name     string  // 声道名称，例如 "front left"
+	Peak     float64 // 自上次读取以来的采样峰值
+	TruePeak float64 // 自上次读取以来的真峰值 (4 倍过采样)
+	Level    float64 // 按动态特性平滑后的电平
+	Hold     float64 // 峰值保持
+	RMS      float64 // 均方根电平
+	Clips    uint64  // 削波的采样数
+}
+
+type channel struct {
+	truePeak TruePeak
+
+	peak      float64
+	truePk    float64
+	level     float64
+	hold      float64
+	holdCount int
+	meanSq    float64
+	clips     uint64
+}
+
+// Meter 是多声道的峰值、真峰值及 RMS 电平表。
+type Meter struct {
+	mu sync.Mutex
+
+	format   audioclient.WAVEFORMATEXTENSIBLE
+	speakers []uint32
+	channels []channel
+
+	attack      float64
+	release     float64
+	integration float64
+	holdFrames  int
+	threshold   float64
+
+	buf []float32
+}
+
+// NewMeter 创建电平表。
+func NewMeter(format *audioclient.WAVEFORMATEXTENSIBLE, ballistics Ballistics) *Meter {
+	m := &Meter{
+		format:    *format,
+		speakers:  pcm.Speakers(format),
+		channels:  make([]channel, format.Format.Channels),
+		threshold: DefaultClipThreshold,
+	}
+	m.SetBallistics(ballistics)
+
+	return m
+}
+
+// SetBallistics 方法设置动态特性。
+func (m *Meter) SetBallistics(ballistics Ballistics) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	rate := float64(m.format.Format.SamplesPerSec)
+	m.attack = dsp.Coefficient(ballistics.Attack, rate)
+	m.release = dsp.Coefficient(ballistics.Release, rate)
+	m.integration = dsp.Coefficient(ballistics.Integration, rate)
+	m.holdFrames = int(ballistics.Hold.Seconds() * rate)
+}
+
+// SetClipThreshold 方法设置削波阈值 (线性)。
+func (m *Meter) SetClipThreshold(threshold float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.threshold = threshold
+}
+
+// Process 方法处理交错的 float32 采样。
+func (m *Meter) Process(samples []float32) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.process(samples)
+}
+
+// ProcessBytes 方法按格式解码并处理交错的音频数据。
+func (m *Meter) ProcessBytes(data []byte) (err error) {
+	return dsp.ProcessBytes(&m.mu, &m.format, &m.buf, data, false, m.process)
+}
+
+func (m *Meter) process(samples []float32) {
+	n := len(m.channels)
+	for i := 0; i+n <= len(samples); i += n {
+		for c := range m.channels {
+			m.channels[c].process(m, float64(samples[i+c]))
+		}
+	}
+}
+
+// Levels 方法返回各声道的电平，并重新开始统计 Peak 及 TruePeak。
+func (m *Meter) Levels() (levels []Level) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	levels = make([]Level, len(m.channels))
+	for i := range m.channels {
+		ch := &m.channels[i]
+		levels[i] = Level{
+			Speaker:  m.speakers[i],
+			Name:     pcm.SpeakerName(m.speakers[i]),
+			Peak:     ch.peak,
+			TruePeak: ch.truePk,
+			Level:    ch.level,
+			Hold:     ch.hold,
+			RMS:      math.Sqrt(ch.meanSq),
+			Clips:    ch.clips,
+		}
+
+		ch.peak = 0
+		ch.truePk = 0
+	}
+
+	return
+}
+
+// ResetClips 方法清除削波计数。
+func (m *Meter) ResetClips() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := range m.channels {
+		m.channels[i].clips = 0
+	}
+}
+
+// Reset 方法清除所有状态。
+func (m *Meter) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	clear(m.channels)
+}
+
+func (ch *channel) process(m *Meter, v float64) {
+	x := math.Abs(v)
+
+	ch.peak = max(ch.peak, x)
+	ch.truePk = max(ch.truePk, ch.truePeak.Process(v))
+
+	if x >= m.threshold {
+		ch.clips++
+	}
+
+	if x > ch.level {
+		ch.level = x + (ch.level-x)*m.attack
+	} else {
+		ch.level = x + (ch.level-x)*m.release
+	}
+
+	if x >= ch.hold {
+		ch.hold = x
+		ch.holdCount = m.holdFrames
+	} else if ch.holdCount > 0 {
+		ch.holdCount--
+	} else {
+		ch.hold = ch.level
+	}
+
+	ch.meanSq += (v*v - ch.meanSq) * (1 - m.integration)
+}
+
+// DBFS 将线性幅度换算为 dBFS，0 返回负无穷。
+func DBFS(v float64) float64 {
+	return 20 * math.Log10(v)
+}
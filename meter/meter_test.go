@@ -0,0 +1,62 @@
+package meter
+
+import (
+	"math"
+	"testing"
+
+	"github.com/cyberxnomad/wasapi/audioclient"
+	"github.com/cyberxnomad/wasapi/pcm"
+)
+
+func TestLevelsPartialChannelMask(t *testing.T) {
+	// 声道掩码只指定了一个扬声器的双声道格式
+	format := pcm.NewFormat(pcm.SampleTypeFloat, 48000, 2, 32, audioclient.SPEAKER_FRONT_CENTER)
+	m := NewMeter(&format, PresetDigital)
+
+	samples := make([]float32, 4800*2)
+	for i := 0; i < 4800; i++ {
+		samples[i*2] = 0.5
+		samples[i*2+1] = -0.25
+	}
+	m.Process(samples)
+
+	levels := m.Levels()
+	if len(levels) != 2 {
+		t.Fatalf("Levels returned %d channels, want 2", len(levels))
+	}
+
+	if levels[0].Speaker != audioclient.SPEAKER_FRONT_CENTER || levels[0].Name != "front center" {
+		t.Errorf("channel 0: speaker %X %q", levels[0].Speaker, levels[0].Name)
+	}
+	if levels[1].Speaker != 0 || levels[1].Name != "unknown" {
+		t.Errorf("channel 1: speaker %X %q", levels[1].Speaker, levels[1].Name)
+	}
+
+	if math.Abs(levels[0].Peak-0.5) > 1e-6 || math.Abs(levels[1].Peak-0.25) > 1e-6 {
+		t.Errorf("peaks %v %v, want 0.5 0.25", levels[0].Peak, levels[1].Peak)
+	}
+}
+
+func TestLevelsPeakAndClips(t *testing.T) {
+	format := pcm.NewFormat(pcm.SampleTypeFloat, 48000, 1, 32, audioclient.KSAUDIO_SPEAKER_MONO)
+	m := NewMeter(&format, PresetDigital)
+
+	samples := make([]float32, 480)
+	for i := range samples {
+		samples[i] = float32(0.5 * math.Sin(2*math.Pi*1000*float64(i)/48000))
+	}
+	samples[100] = 1
+
+	m.Process(samples)
+	level := m.Levels()[0]
+
+	if level.Peak != 1 || level.Clips != 1 {
+		t.Fatalf("peak %v clips %d, want 1 and 1", level.Peak, level.Clips)
+	}
+
+	// 读取后重新开始统计峰值
+	m.Process(make([]float32, 480))
+	if level = m.Levels()[0]; level.Peak != 0 || level.Clips != 1 {
+		t.Fatalf("after silence: peak %v clips %d", level.Peak, level.Clips)
+	}
+}
@@ -0,0 +1,54 @@
+package meter
+
+import "math"
+
+const (
+	oversample = 4  // 过采样倍数
+	phaseTaps  = 12 // 每个相位的抽头数
+)
+
+// 4 倍过采样的多相插值滤波器，参照 ITU-R BS.1770 附录 2 的真峰值测量
+var truePeakTable = makeTruePeakTable()
+
+func makeTruePeakTable() (table [oversample][phaseTaps]float64) {
+	n := oversample * phaseTaps
+	center := float64(n-1) / 2
+
+	for i := 0; i < n; i++ {
+		x := (float64(i) - center) / oversample
+		w := 0.5 - 0.5*math.Cos(2*math.Pi*(float64(i)+0.5)/float64(n))
+
+		v := w
+		if x != 0 {
+			v *= math.Sin(math.Pi*x) / (math.Pi * x)
+		}
+
+		table[i%oversample][i/oversample] = v
+	}
+
+	return
+}
+
+// TruePeak 是单声道的真峰值检测器，对信号做 4 倍过采样后取绝对值的最大值。
+type TruePeak struct {
+	history [phaseTaps]float64
+	pos     int
+}
+
+// Process 方法写入一个采样，返回插值后 4 个采样的最大绝对值。
+func (t *TruePeak) Process(v float64) (peak float64) {
+	t.history[t.pos] = v
+	t.pos = (t.pos + 1) % phaseTaps
+
+	for p := range truePeakTable {
+		var acc float64
+		for j, k := range truePeakTable[p] {
+			// 第 j 个抽头对应较早的第 j 个采样
+			acc += k * t.history[(t.pos+phaseTaps-1-j)%phaseTaps]
+		}
+
+		peak = max(peak, math.Abs(acc))
+	}
+
+	return
+}
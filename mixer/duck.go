@@ -0,0 +1,274 @@
+package mixer
+
+import (
+	"time"
+
+	"github.com/cyberxnomad/wasapi/gain"
+	"github.com/cyberxnomad/wasapi/internal/dsp"
+)
+
+// DuckParams 为闪避的参数。
+type DuckParams struct {
+	Depth     float64       // 衰减量 (dB，正值)
+	Threshold float64       // 键信号峰值超过该电平 (dBFS) 时触发
+	Attack    time.Duration // 衰减的时间常数
+	Release   time.Duration // 恢复的时间常数
+	Hold      time.Duration // 键信号消失后保持衰减的时长
+}
+
+// DefaultDuck 为适用于语音提示压低背景音乐的参数。
+var DefaultDuck = DuckParams{
+	Depth:     12,
+	Threshold: -45,
+	Attack:    20 * time.Millisecond,
+	Release:   400 * time.Millisecond,
+	Hold:      300 * time.Millisecond,
+}
+
+// Ducker 是侧链闪避器，键信号活动时按平滑过渡衰减目标音源。
+//
+// 键信号可以是同一混音器中的音源 (例如语音合成)、通过 Key 送入的外部采样 (例如麦克风)，
+// 或由 SetKeyActive 直接指定 (例如语音活动检测的事件)，任一活动即触发。
+// 多个闪避器作用于同一音源时增益相乘。
+type Ducker struct {
+	mixer   *Mixer
+	params  DuckParams
+	keys    map[*Input]bool
+	targets map[*Input]bool
+
+	forced   bool    // 由 SetKeyActive 指定的状态
+	external float32 // 自上次混音以来外部键信号的峰值
+	hold     int     // 剩余的保持帧数
+	active   bool
+	closed   bool
+	gain     float64
+	curve    []float32
+
+	releasing map[*Input]*release // 已移除、正在恢复增益的目标
+}
+
+// 已移除的目标独立恢复的增益
+type release struct {
+	gain  float64
+	curve []float32
+}
+
+// 增益与 1 的差小于该值时视为已恢复
+const recovered = 1e-5
+
+// NewDucker 创建闪避器并添加到混音器。
+func NewDucker(m *Mixer, params DuckParams) *Ducker {
+	d := &Ducker{
+		mixer:     m,
+		params:    params,
+		keys:      make(map[*Input]bool),
+		targets:   make(map[*Input]bool),
+		releasing: make(map[*Input]*release),
+		gain:      1,
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.duckers = append(m.duckers, d)
+
+	return d
+}
+
+// SetParams 方法设置参数。
+func (d *Ducker) SetParams(params DuckParams) {
+	d.mixer.mu.Lock()
+	defer d.mixer.mu.Unlock()
+
+	d.params = params
+}
+
+// Params 方法返回参数。
+func (d *Ducker) Params() DuckParams {
+	d.mixer.mu.Lock()
+	defer d.mixer.mu.Unlock()
+
+	return d.params
+}
+
+// AddKey 方法将音源作为键信号。
+func (d *Ducker) AddKey(in *Input) {
+	d.mixer.mu.Lock()
+	defer d.mixer.mu.Unlock()
+
+	d.keys[in] = true
+}
+
+// RemoveKey 方法移除作为键信号的音源。
+func (d *Ducker) RemoveKey(in *Input) {
+	d.mixer.mu.Lock()
+	defer d.mixer.mu.Unlock()
+
+	delete(d.keys, in)
+}
+
+// AddTarget 方法添加被衰减的音源，闪避器关闭后调用无效。
+func (d *Ducker) AddTarget(in *Input) {
+	d.mixer.mu.Lock()
+	defer d.mixer.mu.Unlock()
+
+	if d.closed {
+		return
+	}
+
+	d.targets[in] = true
+	delete(d.releasing, in)
+}
+
+// RemoveTarget 方法移除被衰减的音源，该音源按恢复时间常数平滑恢复原有增益。
+func (d *Ducker) RemoveTarget(in *Input) {
+	d.mixer.mu.Lock()
+	defer d.mixer.mu.Unlock()
+
+	if !d.targets[in] {
+		return
+	}
+
+	delete(d.targets, in)
+	if 1-d.gain > recovered {
+		d.releasing[in] = &release{gain: d.gain}
+	}
+}
+
+// SetKeyActive 方法直接指定键信号是否活动，例如在语音活动检测的开始及结束事件时调用。
+func (d *Ducker) SetKeyActive(active bool) {
+	d.mixer.mu.Lock()
+	defer d.mixer.mu.Unlock()
+
+	d.forced = active
+}
+
+// Key 方法送入外部键信号的交错 float32 采样，例如麦克风捕获的数据，在下次混音时生效。
+func (d *Ducker) Key(samples []float32) {
+	d.mixer.mu.Lock()
+	defer d.mixer.mu.Unlock()
+
+	for _, v := range samples {
+		d.external = max(d.external, v, -v)
+	}
+}
+
+// Active 方法返回当前是否处于闪避状态 (包括保持时间)。
+func (d *Ducker) Active() bool {
+	d.mixer.mu.Lock()
+	defer d.mixer.mu.Unlock()
+
+	return d.active
+}
+
+// Attenuation 方法返回当前的衰减量 (dB，非负)。
+func (d *Ducker) Attenuation() float64 {
+	d.mixer.mu.Lock()
+	defer d.mixer.mu.Unlock()
+
+	return max(-gain.LinearToDB(d.gain), 0)
+}
+
+// Close 方法关闭闪避器，目标音源按恢复时间常数平滑恢复原有增益后从混音器中移除。
+func (d *Ducker) Close() {
+	d.mixer.mu.Lock()
+	defer d.mixer.mu.Unlock()
+
+	d.closed = true
+}
+
+// 返回本次混音作用于音源的增益曲线，不作用于该音源时返回 nil
+func (d *Ducker) curveFor(in *Input) []float32 {
+	if d.targets[in] {
+		return d.curve
+	}
+	if r := d.releasing[in]; r != nil {
+		return r.curve
+	}
+
+	return nil
+}
+
+// 根据键信号计算本次混音的增益曲线，闪避器关闭且目标均已恢复时返回 false
+func (d *Ducker) update(frames int) bool {
+	rate := float64(d.mixer.format.Format.SamplesPerSec)
+	threshold := float32(gain.DBToLinear(d.params.Threshold))
+	release := dsp.Coefficient(d.params.Release, rate)
+
+	// 已恢复或已移除的目标不再保留
+	for in, r := range d.releasing {
+		if in.done || 1-r.gain <= recovered {
+			delete(d.releasing, in)
+			continue
+		}
+
+		if cap(r.curve) < frames {
+			r.curve = make([]float32, frames)
+		}
+		r.curve = r.curve[:frames]
+
+		for i := range r.curve {
+			r.gain = 1 + (r.gain-1)*release
+			r.curve[i] = float32(r.gain)
+		}
+	}
+
+	if d.closed {
+		d.forced = false
+		clear(d.keys)
+		d.hold = 0
+
+		if 1-d.gain <= recovered && len(d.releasing) == 0 {
+			d.gain = 1
+			d.active = false
+			return false
+		}
+	}
+
+	key := !d.closed && (d.forced || d.external > threshold)
+	for in := range d.keys {
+		if in.done {
+			delete(d.keys, in)
+			continue
+		}
+
+		key = key || in.peak > threshold
+	}
+	d.external = 0
+
+	for in := range d.targets {
+		if in.done {
+			delete(d.targets, in)
+		}
+	}
+
+	if key {
+		d.hold = int(d.params.Hold.Seconds() * rate)
+	} else {
+		d.hold = max(d.hold-frames, 0)
+	}
+	d.active = key || d.hold > 0
+
+	target := 1.0
+	if d.active {
+		target = gain.DBToLinear(-d.params.Depth)
+	}
+
+	attack := dsp.Coefficient(d.params.Attack, rate)
+
+	if cap(d.curve) < frames {
+		d.curve = make([]float32, frames)
+	}
+	d.curve = d.curve[:frames]
+
+	for i := range d.curve {
+		if target < d.gain {
+			d.gain = target + (d.gain-target)*attack
+		} else {
+			d.gain = target + (d.gain-target)*release
+		}
+		d.curve[i] = float32(d.gain)
+	}
+
+	return true
+}
@@ -0,0 +1,166 @@
+package mixer
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/cyberxnomad/wasapi/gain"
+)
+
+var duckParams = DuckParams{
+	Depth:     12,
+	Threshold: -30,
+	Attack:    20 * time.Millisecond,
+	Release:   200 * time.Millisecond,
+	Hold:      100 * time.Millisecond,
+}
+
+// 混音器中只有一个直流目标音源，输出即为闪避增益
+func newDuckMixer() (m *Mixer, target *Input, d *Ducker) {
+	m = NewMixer(&mono)
+	m.SetHeadroom(0)
+	target = m.Add(constant(1), &mono)
+	d = NewDucker(m, duckParams)
+	d.AddTarget(target)
+
+	return
+}
+
+// 以 10 毫秒的块读取 duration 的输出
+func readFor(m *Mixer, duration time.Duration) (out []float32) {
+	block := make([]float32, rate/100)
+	for n := 0; n < int(duration.Seconds()*rate); n += len(block) {
+		m.Read(block)
+		out = append(out, block...)
+	}
+
+	return
+}
+
+// 单极点过渡: 经过 frames 帧后从 from 趋向 to 的值
+func settle(from, to float64, tau time.Duration, frames int) float64 {
+	return to + (from-to)*math.Exp(-float64(frames)/(tau.Seconds()*rate))
+}
+
+func TestDuckTiming(t *testing.T) {
+	m, _, d := newDuckMixer()
+	floor := gain.DBToLinear(-duckParams.Depth)
+	attack := int(duckParams.Attack.Seconds() * rate)
+	release := int(duckParams.Release.Seconds() * rate)
+	hold := int(duckParams.Hold.Seconds() * rate)
+
+	d.SetKeyActive(true)
+	out := readFor(m, 300*time.Millisecond)
+
+	// 一个时间常数后完成约 63% 的衰减
+	for _, frames := range []int{attack / 2, attack, 3 * attack} {
+		if got, want := float64(out[frames-1]), settle(1, floor, duckParams.Attack, frames); math.Abs(got-want) > 1e-4 {
+			t.Errorf("attack: gain after %d frames = %.5f, want %.5f", frames, got, want)
+		}
+	}
+	if a := d.Attenuation(); math.Abs(a-duckParams.Depth) > 0.01 || !d.Active() {
+		t.Fatalf("Attenuation = %.3f dB, Active = %v", a, d.Active())
+	}
+
+	d.SetKeyActive(false)
+	out = readFor(m, time.Second)
+
+	// 保持期间 (按块计) 维持衰减，之后开始恢复
+	start := -1
+	for i, v := range out {
+		if float64(v) > floor+1e-4 {
+			start = i
+			break
+		}
+	}
+	if block := rate / 100; start < hold-block || start > hold {
+		t.Fatalf("release started after %d frames, want hold of %d frames", start, hold)
+	}
+
+	for _, frames := range []int{release / 2, release, 3 * release} {
+		if got, want := float64(out[start+frames-1]), settle(floor, 1, duckParams.Release, frames); math.Abs(got-want) > 1e-3 {
+			t.Errorf("release: gain after %d frames = %.5f, want %.5f", frames, got, want)
+		}
+	}
+	if d.Active() {
+		t.Error("still active after release")
+	}
+}
+
+func TestDuckThreshold(t *testing.T) {
+	threshold := float32(gain.DBToLinear(duckParams.Threshold))
+
+	for _, tt := range []struct {
+		name  string
+		level float32
+		duck  bool
+	}{
+		{"below", threshold * 0.9, false},
+		{"above", threshold * 1.1, true},
+	} {
+		// 外部键信号
+		m, _, d := newDuckMixer()
+		for i := 0; i < 10; i++ {
+			d.Key([]float32{tt.level, -tt.level})
+			readFor(m, 10*time.Millisecond)
+		}
+		if d.Active() != tt.duck {
+			t.Errorf("external key %s threshold: Active = %v", tt.name, d.Active())
+		}
+
+		// 混音器中的键音源
+		m, _, d = newDuckMixer()
+		d.AddKey(m.Add(constant(tt.level), &mono))
+		out := readFor(m, 100*time.Millisecond)
+		if d.Active() != tt.duck {
+			t.Errorf("input key %s threshold: Active = %v", tt.name, d.Active())
+		}
+
+		// 输出为键音源加上 (可能被闪避的) 目标
+		if got := float64(out[len(out)-1] - tt.level); (got < 0.5) != tt.duck {
+			t.Errorf("input key %s threshold: target gain %.3f", tt.name, got)
+		}
+	}
+}
+
+func TestDuckRemoveRamp(t *testing.T) {
+	floor := gain.DBToLinear(-duckParams.Depth)
+	release := int(duckParams.Release.Seconds() * rate)
+
+	for _, tt := range []struct {
+		name   string
+		remove func(target *Input, d *Ducker)
+	}{
+		{"RemoveTarget", func(target *Input, d *Ducker) { d.RemoveTarget(target) }},
+		{"Close", func(target *Input, d *Ducker) { d.Close() }},
+	} {
+		m, target, d := newDuckMixer()
+		d.SetKeyActive(true)
+		readFor(m, 300*time.Millisecond)
+
+		// 键信号仍然活动，移除后目标按恢复时间常数平滑恢复，而不是跳变
+		tt.remove(target, d)
+		out := readFor(m, 3*time.Second)
+
+		if step := float64(out[0]) - floor; step > 1e-3 {
+			t.Errorf("%s: gain jumped by %.4f", tt.name, step)
+		}
+		if got, want := float64(out[release-1]), settle(floor, 1, duckParams.Release, release); math.Abs(got-want) > 1e-3 {
+			t.Errorf("%s: gain after release time constant = %.5f, want %.5f", tt.name, got, want)
+		}
+		if got := out[len(out)-1]; got != 1 {
+			t.Errorf("%s: final gain %v, want 1", tt.name, got)
+		}
+	}
+
+	// 关闭的闪避器恢复后从混音器中移除
+	m, _, d := newDuckMixer()
+	d.SetKeyActive(true)
+	readFor(m, 100*time.Millisecond)
+	d.Close()
+	readFor(m, 3*time.Second)
+	if len(m.duckers) != 0 {
+		t.Errorf("%d duckers left after Close", len(m.duckers))
+	}
+}
@@ -0,0 +1,289 @@
+package mixer
+
+import (
+	"errors"
+	"io"
+	"math"
+	"time"
+
+	"github.com/cyberxnomad/wasapi/audioclient"
+	"github.com/cyberxnomad/wasapi/gain"
+	"github.com/cyberxnomad/wasapi/pcm"
+	"github.com/cyberxnomad/wasapi/resample"
+)
+
+// Input 是混音器中的一个音源及其增益、声像、静音设置。
+type Input struct {
+	mixer  *Mixer
+	source Source
+	format audioclient.WAVEFORMATEXTENSIBLE
+
+	channels  int
+	resampler *resample.Resampler
+	matrix    [][]float32
+	stage     *gain.Stage
+
+	level    float32
+	pan      float64
+	removing bool
+	eof      bool
+	err      error
+	done     bool
+
+	pending []float32 // 已转换为混音采样率、尚未混音的帧 (音源声道数)
+	buf     []float32
+	out     []float32 // 本次混音的输出 (混音格式)
+	frames  int       // 本次混音输出的帧数
+	peak    float32   // 本次混音输出的峰值，用作闪避的键信号
+	curve   []float32 // 本次混音的闪避增益
+}
+
+func newInput(m *Mixer, source Source, format *audioclient.WAVEFORMATEXTENSIBLE) *Input {
+	in := &Input{
+		mixer:    m,
+		source:   source,
+		format:   *format,
+		channels: int(format.Format.Channels),
+		matrix:   pcm.ChannelMatrix(format, &m.format),
+		stage:    gain.NewStage(&m.format),
+		level:    1,
+	}
+
+	if format.Format.SamplesPerSec != m.format.Format.SamplesPerSec {
+		in.resampler = resample.New(in.channels, format.Format.SamplesPerSec, m.format.Format.SamplesPerSec)
+	}
+
+	return in
+}
+
+// Format 方法返回音源的格式。
+func (in *Input) Format() audioclient.WAVEFORMATEXTENSIBLE {
+	return in.format
+}
+
+// SetGain 方法设置线性增益，变化平滑过渡。
+func (in *Input) SetGain(level float32) (err error) {
+	if !(level >= 0) {
+		err = gain.ErrInvalidLevel
+		return
+	}
+
+	in.mixer.mu.Lock()
+	defer in.mixer.mu.Unlock()
+
+	in.level = level
+
+	return in.apply()
+}
+
+// SetGainDB 方法以分贝设置增益。
+func (in *Input) SetGainDB(db float64) (err error) {
+	return in.SetGain(float32(gain.DBToLinear(db)))
+}
+
+// Gain 方法返回线性增益。
+func (in *Input) Gain() float32 {
+	in.mixer.mu.Lock()
+	defer in.mixer.mu.Unlock()
+
+	return in.level
+}
+
+// SetPan 方法设置声像，范围为 [-1, 1]，-1 为全左，1 为全右。
+//
+// 采用等功率声像律，居中时左右增益均为 1；只作用于混音格式中左右两侧的声道。
+func (in *Input) SetPan(pan float64) (err error) {
+	in.mixer.mu.Lock()
+	defer in.mixer.mu.Unlock()
+
+	in.pan = min(max(pan, -1), 1)
+
+	return in.apply()
+}
+
+// Pan 方法返回声像。
+func (in *Input) Pan() float64 {
+	in.mixer.mu.Lock()
+	defer in.mixer.mu.Unlock()
+
+	return in.pan
+}
+
+// SetMute 方法设置静音状态，变化平滑过渡。
+func (in *Input) SetMute(mute bool) {
+	in.stage.SetMute(mute)
+}
+
+// Muted 方法返回静音状态。
+func (in *Input) Muted() bool {
+	return in.stage.GetMute()
+}
+
+// Remove 方法在 DefaultFade 内淡出后将音源从混音器中移除。
+func (in *Input) Remove() {
+	in.RemoveAfter(DefaultFade)
+}
+
+// RemoveAfter 方法在 fade 内淡出后将音源从混音器中移除。
+func (in *Input) RemoveAfter(fade time.Duration) {
+	in.mixer.mu.Lock()
+	defer in.mixer.mu.Unlock()
+
+	in.removing = true
+	in.stage.FadeOut(fade)
+}
+
+// Done 方法返回音源是否已从混音器中移除 (读取结束或调用了 Remove)。
+func (in *Input) Done() bool {
+	in.mixer.mu.Lock()
+	defer in.mixer.mu.Unlock()
+
+	return in.done
+}
+
+// Err 方法返回音源读取时发生的错误，io.EOF 不视为错误。
+func (in *Input) Err() error {
+	in.mixer.mu.Lock()
+	defer in.mixer.mu.Unlock()
+
+	return in.err
+}
+
+// 按增益及声像更新各声道的目标增益
+func (in *Input) apply() error {
+	left := float32(math.Cos((in.pan+1)*math.Pi/4) * math.Sqrt2)
+	right := float32(math.Sin((in.pan+1)*math.Pi/4) * math.Sqrt2)
+
+	levels := make([]float32, in.mixer.channels)
+	for i, speaker := range in.mixer.speakers {
+		levels[i] = in.level
+
+		switch {
+		case speaker&leftSpeakers != 0 && len(in.mixer.speakers) > 1:
+			levels[i] *= left
+		case speaker&rightSpeakers != 0 && len(in.mixer.speakers) > 1:
+			levels[i] *= right
+		}
+	}
+
+	return in.stage.SetAllVolumes(uint32(len(levels)), levels)
+}
+
+const (
+	leftSpeakers = audioclient.SPEAKER_FRONT_LEFT | audioclient.SPEAKER_BACK_LEFT | audioclient.SPEAKER_FRONT_LEFT_OF_CENTER |
+		audioclient.SPEAKER_SIDE_LEFT | audioclient.SPEAKER_TOP_FRONT_LEFT | audioclient.SPEAKER_TOP_BACK_LEFT
+	rightSpeakers = audioclient.SPEAKER_FRONT_RIGHT | audioclient.SPEAKER_BACK_RIGHT | audioclient.SPEAKER_FRONT_RIGHT_OF_CENTER |
+		audioclient.SPEAKER_SIDE_RIGHT | audioclient.SPEAKER_TOP_FRONT_RIGHT | audioclient.SPEAKER_TOP_BACK_RIGHT
+)
+
+// 读取 frames 帧并转换为混音格式写入 in.out，返回有效的帧数，其余部分为静音
+func (in *Input) read(frames int) int {
+	ch := in.channels
+	samples := frames * in.mixer.channels
+
+	if cap(in.out) < samples {
+		in.out = make([]float32, samples)
+		in.curve = make([]float32, frames)
+	}
+	in.out = in.out[:samples]
+	dst := in.out
+	clear(dst)
+
+	for !in.eof && len(in.pending)/ch < frames {
+		need := frames - len(in.pending)/ch
+		if in.resampler != nil {
+			need = int(math.Ceil(float64(need)/in.resampler.Ratio())) + 1
+		}
+
+		if cap(in.buf) < need*ch {
+			in.buf = make([]float32, need*ch)
+		}
+
+		n, err := in.source.Read(in.buf[:need*ch])
+		in.convert(in.buf[:n*ch])
+
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				in.err = err
+			}
+
+			// 冲出重采样器中剩余的帧
+			if in.resampler != nil {
+				in.convert(make([]float32, 2*in.resampler.Latency()*ch))
+			}
+
+			in.eof = true
+		}
+
+		if n == 0 {
+			break
+		}
+	}
+
+	n := min(frames, len(in.pending)/ch)
+	pcm.Remix(in.matrix, in.pending[:n*ch], dst)
+	// 静音部分同样推进增益过渡，音源暂时没有数据时淡出也能完成
+	in.stage.Process(dst)
+
+	in.peak = 0
+	for _, v := range dst {
+		in.peak = max(in.peak, v, -v)
+	}
+
+	rest := copy(in.pending, in.pending[n*ch:])
+	in.pending = in.pending[:rest]
+
+	return n
+}
+
+// 应用所有以该音源为目标的闪避器的增益
+func (in *Input) duck(frames int) {
+	curve := in.curve[:frames]
+	ducked := false
+
+	for _, d := range in.mixer.duckers {
+		gains := d.curveFor(in)
+		if gains == nil {
+			continue
+		}
+
+		if !ducked {
+			copy(curve, gains[:frames])
+			ducked = true
+			continue
+		}
+
+		for i, g := range gains[:frames] {
+			curve[i] *= g
+		}
+	}
+
+	if !ducked {
+		return
+	}
+
+	ch := in.mixer.channels
+	for i := 0; i < in.frames; i++ {
+		for c := 0; c < ch; c++ {
+			in.out[i*ch+c] *= curve[i]
+		}
+	}
+}
+
+// 转换到混音采样率后追加到待混音的帧
+func (in *Input) convert(src []float32) {
+	if in.resampler != nil {
+		in.pending = in.resampler.Process(in.pending, src)
+	} else {
+		in.pending = append(in.pending, src...)
+	}
+}
+
+// 音源是否可以移除
+func (in *Input) finished() bool {
+	if in.removing {
+		return in.stage.Faded()
+	}
+
+	return in.eof && len(in.pending) == 0
+}
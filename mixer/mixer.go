@@ -0,0 +1,170 @@
+package mixer
+
+import (
+	"sync"
+	"time"
+
+	"github.com/cyberxnomad/wasapi/audioclient"
+	"github.com/cyberxnomad/wasapi/dynamics"
+	"github.com/cyberxnomad/wasapi/gain"
+	"github.com/cyberxnomad/wasapi/pcm"
+)
+
+const (
+	DefaultHeadroom = 6.0                   // 默认的混音余量 (dB)
+	DefaultFade     = 10 * time.Millisecond // 移除音源时的默认淡出时长
+)
+
+// Source 是混音器的音源。
+//
+// Read 读取交错的 float32 采样 (音源自身的格式)，返回读取的帧数；
+// 暂时没有数据时返回 0 帧，该音源在本次混音中输出静音；返回 io.EOF 表示结束，音源将被移除。
+type Source interface {
+	Read(dst []float32) (frames int, err error)
+}
+
+// Mixer 将多个不同格式的音源转换为终结点的混音格式后相加。
+//
+// 每个音源独立进行采样率转换及声道映射，并应用平滑过渡的增益、声像及静音；
+// 相加后应用混音余量，并可选地经过限幅器。音源可以在运行中添加或移除，
+// 变化发生在两次 Read 之间，移除时先淡出。
+type Mixer struct {
+	mu sync.Mutex
+
+	format   audioclient.WAVEFORMATEXTENSIBLE
+	channels int
+	speakers []uint32
+	inputs   []*Input
+	duckers  []*Ducker
+	headroom float32
+	limiter  *dynamics.Limiter
+
+	encode []float32
+}
+
+// NewMixer 创建混音器，format 为终结点的混音格式 (通常由 IAudioClient::GetMixFormat 获取)。
+func NewMixer(format *audioclient.WAVEFORMATEXTENSIBLE) *Mixer {
+	return &Mixer{
+		format:   *format,
+		channels: int(format.Format.Channels),
+		speakers: pcm.Speakers(format),
+		headroom: float32(gain.DBToLinear(-DefaultHeadroom)),
+	}
+}
+
+// Format 方法返回混音格式。
+func (m *Mixer) Format() audioclient.WAVEFORMATEXTENSIBLE {
+	return m.format
+}
+
+// SetHeadroom 方法设置混音余量 (dB)，相加后的信号衰减该值。
+func (m *Mixer) SetHeadroom(db float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.headroom = float32(gain.DBToLinear(-db))
+}
+
+// SetLimiter 方法启用或禁用输出限幅器，启用时输出延迟 dynamics.DefaultLookahead。
+func (m *Mixer) SetLimiter(enabled bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	switch {
+	case enabled && m.limiter == nil:
+		m.limiter = dynamics.NewLimiter(&m.format, dynamics.DefaultCeiling, dynamics.DefaultLookahead, dynamics.DefaultRelease, dynamics.LinkAll)
+	case !enabled:
+		m.limiter = nil
+	}
+}
+
+// Add 方法添加音源，format 为音源的格式，返回用于控制该音源的 Input。
+func (m *Mixer) Add(source Source, format *audioclient.WAVEFORMATEXTENSIBLE) *Input {
+	in := newInput(m, source, format)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.inputs = append(m.inputs, in)
+
+	return in
+}
+
+// Inputs 方法返回当前的所有音源。
+func (m *Mixer) Inputs() []*Input {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append([]*Input(nil), m.inputs...)
+}
+
+// Read 方法混音并将交错的 float32 采样写入 dst，dst 的长度应为帧数乘以声道数。
+func (m *Mixer) Read(dst []float32) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.read(dst)
+}
+
+// ReadBytes 方法混音并按混音格式编码到 dst，通常直接写入 IAudioRenderClient::GetBuffer 返回的缓冲区。
+func (m *Mixer) ReadBytes(dst []byte) (err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	frames := pcm.Frames(&m.format, len(dst))
+	samples := frames * m.channels
+
+	if cap(m.encode) < samples {
+		m.encode = make([]float32, samples)
+	}
+
+	m.read(m.encode[:samples])
+	_, err = pcm.Encode(&m.format, m.encode[:samples], dst)
+
+	return
+}
+
+func (m *Mixer) read(dst []float32) {
+	frames := len(dst) / m.channels
+	samples := frames * m.channels
+	clear(dst)
+
+	// 先读取所有音源，键信号的电平在同一块内生效
+	for _, in := range m.inputs {
+		in.frames = in.read(frames)
+	}
+
+	duckers := m.duckers[:0]
+	for _, d := range m.duckers {
+		if d.update(frames) {
+			duckers = append(duckers, d)
+		}
+	}
+	clear(m.duckers[len(duckers):])
+	m.duckers = duckers
+
+	active := m.inputs[:0]
+	for _, in := range m.inputs {
+		in.duck(frames)
+		for i, v := range in.out[:in.frames*m.channels] {
+			dst[i] += v
+		}
+
+		if !in.finished() {
+			active = append(active, in)
+		} else {
+			in.done = true
+		}
+	}
+
+	clear(m.inputs[len(active):])
+	m.inputs = active
+
+	for i := range dst[:samples] {
+		dst[i] *= m.headroom
+	}
+
+	if m.limiter != nil {
+		m.limiter.Process(dst[:samples])
+	}
+}
@@ -0,0 +1,182 @@
+package mixer
+
+import (
+	"encoding/binary"
+	"math"
+	"testing"
+
+	"github.com/cyberxnomad/wasapi/audioclient"
+	"github.com/cyberxnomad/wasapi/dynamics"
+	"github.com/cyberxnomad/wasapi/gain"
+	"github.com/cyberxnomad/wasapi/pcm"
+)
+
+const rate = 48000
+
+var (
+	mono      = pcm.NewFormat(pcm.SampleTypeFloat, rate, 1, 32, audioclient.KSAUDIO_SPEAKER_MONO)
+	monoInt16 = pcm.NewFormat(pcm.SampleTypeInt, rate, 1, 16, audioclient.KSAUDIO_SPEAKER_MONO)
+)
+
+// 按帧序号生成单声道采样的无限音源
+type funcSource struct {
+	frame  int
+	sample func(frame int) float32
+}
+
+func (s *funcSource) Read(dst []float32) (frames int, err error) {
+	for i := range dst {
+		dst[i] = s.sample(s.frame)
+		s.frame++
+	}
+
+	return len(dst), nil
+}
+
+func sine(frequency, amplitude float64) *funcSource {
+	return &funcSource{sample: func(frame int) float32 {
+		return float32(amplitude * math.Sin(2*math.Pi*frequency*float64(frame)/rate))
+	}}
+}
+
+func constant(v float32) *funcSource {
+	return &funcSource{sample: func(int) float32 { return v }}
+}
+
+func TestAddRemoveContinuity(t *testing.T) {
+	m := NewMixer(&mono)
+	m.SetHeadroom(0)
+
+	a := m.Add(sine(440, 0.5), &mono)
+
+	// 两个正弦之和的最大斜率，加上淡出引入的变化
+	maxStep := 2*math.Pi*440/rate*0.5 + 2*math.Pi*1000/rate*0.25 + 0.5/(DefaultFade.Seconds()*rate) + 1e-6
+
+	var out []float32
+	block := make([]float32, 480)
+	for k := 0; k < 20; k++ {
+		switch k {
+		case 5:
+			m.Add(sine(1000, 0.25), &mono)
+		case 10:
+			a.Remove()
+		}
+
+		m.Read(block)
+		out = append(out, block...)
+	}
+
+	for i := 1; i < len(out); i++ {
+		if step := math.Abs(float64(out[i] - out[i-1])); step > maxStep {
+			t.Fatalf("frame %d: step %.4f exceeds %.4f", i, step, maxStep)
+		}
+	}
+
+	// 添加前后原有音源不受影响，新音源从第一帧起完整混入
+	for i := 0; i < 10*480; i++ {
+		want := 0.5 * math.Sin(2*math.Pi*440*float64(i)/rate)
+		if i >= 5*480 {
+			want += 0.25 * math.Sin(2*math.Pi*1000*float64(i-5*480)/rate)
+		}
+		if math.Abs(float64(out[i])-want) > 1e-5 {
+			t.Fatalf("frame %d = %v, want %v", i, out[i], want)
+		}
+	}
+
+	// 淡出完成后只剩新音源
+	if !a.Done() || len(m.Inputs()) != 1 {
+		t.Fatalf("after fade: Done = %v, %d inputs", a.Done(), len(m.Inputs()))
+	}
+	for i := 12 * 480; i < len(out); i++ {
+		want := 0.25 * math.Sin(2*math.Pi*1000*float64(i-5*480)/rate)
+		if math.Abs(float64(out[i])-want) > 1e-5 {
+			t.Fatalf("frame %d = %v after removal, want %v", i, out[i], want)
+		}
+	}
+}
+
+func TestGainRamp(t *testing.T) {
+	m := NewMixer(&mono)
+	m.SetHeadroom(0)
+	in := m.Add(constant(1), &mono)
+
+	ramp := int(gain.DefaultRamp.Seconds() * rate)
+	block := make([]float32, 2*ramp)
+
+	m.Read(block)
+	if block[0] != 1 {
+		t.Fatalf("initial output %v, want 1", block[0])
+	}
+
+	for _, tt := range []struct {
+		name string
+		set  func()
+		from float32
+		to   float32
+	}{
+		{"gain", func() { in.SetGain(0.5) }, 1, 0.5},
+		{"gain dB", func() { in.SetGainDB(0) }, 0.5, 1},
+		{"mute", func() { in.SetMute(true) }, 1, 0},
+		{"unmute", func() { in.SetMute(false) }, 0, 1},
+	} {
+		tt.set()
+		m.Read(block)
+
+		// 在 DefaultRamp 内线性过渡，之后保持目标增益
+		step := (tt.to - tt.from) / float32(ramp)
+		for i, v := range block {
+			want := tt.to
+			if i < ramp {
+				want = tt.from + step*float32(i+1)
+			}
+			if math.Abs(float64(v-want)) > 1e-4 {
+				t.Fatalf("%s: frame %d = %v, want %v", tt.name, i, v, want)
+			}
+		}
+	}
+}
+
+func TestOverflow(t *testing.T) {
+	read := func(m *Mixer) (peak int16) {
+		t.Helper()
+
+		data := make([]byte, 2*rate/10)
+		for k := 0; k < 5; k++ {
+			if err := m.ReadBytes(data); err != nil {
+				t.Fatal(err)
+			}
+		}
+		for i := 0; i < len(data); i += 2 {
+			peak = max(peak, int16(binary.LittleEndian.Uint16(data[i:])))
+		}
+
+		return
+	}
+
+	mix := func(headroom float64, limiter bool) *Mixer {
+		m := NewMixer(&monoInt16)
+		m.SetHeadroom(headroom)
+		m.SetLimiter(limiter)
+		m.Add(constant(0.9), &mono)
+		m.Add(constant(0.9), &mono)
+
+		return m
+	}
+
+	// 无余量时和为 1.8，编码时饱和到满幅而不是回绕
+	if peak := read(mix(0, false)); peak != math.MaxInt16 {
+		t.Errorf("without headroom: peak %d, want %d", peak, math.MaxInt16)
+	}
+
+	// 默认余量 6 dB 使和回到满幅以内
+	want := 1.8 * gain.DBToLinear(-DefaultHeadroom) * 32768
+	if peak := read(mix(DefaultHeadroom, false)); math.Abs(float64(peak)-want) > 2 {
+		t.Errorf("with headroom: peak %d, want %.0f", peak, want)
+	}
+
+	// 限幅器将输出限制在上限以内
+	ceiling := gain.DBToLinear(dynamics.DefaultCeiling) * 32768
+	if peak := read(mix(0, true)); float64(peak) > ceiling+1 || float64(peak) < ceiling*0.95 {
+		t.Errorf("with limiter: peak %d, want about %.0f", peak, ceiling)
+	}
+}
@@ -0,0 +1,104 @@
+package mixer
+
+import (
+	"errors"
+	"io"
+	"sync"
+
+	"github.com/cyberxnomad/wasapi/audioclient"
+	"github.com/cyberxnomad/wasapi/pcm"
+)
+
+var ErrClosed = errors.New("stream closed")
+
+// Stream 是由调用方写入数据的音源，例如逐段合成的语音或解码中的音乐。
+//
+// 写入的数据按格式解码后排队，关闭后读完剩余数据即结束。
+type Stream struct {
+	mu sync.Mutex
+
+	format   audioclient.WAVEFORMATEXTENSIBLE
+	channels int
+	queue    []float32
+	closed   bool
+}
+
+// NewStream 创建音源，format 为写入数据的格式。
+func NewStream(format *audioclient.WAVEFORMATEXTENSIBLE) *Stream {
+	return &Stream{
+		format:   *format,
+		channels: int(format.Format.Channels),
+	}
+}
+
+// Format 方法返回音源的格式。
+func (s *Stream) Format() *audioclient.WAVEFORMATEXTENSIBLE {
+	return &s.format
+}
+
+// Write 方法按格式解码并排队音频数据。
+func (s *Stream) Write(data []byte) (n int, err error) {
+	size := int(s.format.Format.BitsPerSample / 8)
+	if size == 0 {
+		err = pcm.ErrUnsupportedFormat
+		return
+	}
+
+	samples := make([]float32, len(data)/size)
+	if _, err = pcm.Decode(&s.format, data, samples); err != nil {
+		return
+	}
+
+	return len(data), s.WriteFloat(samples)
+}
+
+// WriteFloat 方法排队交错的 float32 采样。
+func (s *Stream) WriteFloat(samples []float32) (err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		err = ErrClosed
+		return
+	}
+
+	s.queue = append(s.queue, samples...)
+
+	return
+}
+
+// Buffered 方法返回排队中的帧数。
+func (s *Stream) Buffered() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.queue) / s.channels
+}
+
+// Close 方法关闭音源，剩余的数据读完后音源结束。
+func (s *Stream) Close() (err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.closed = true
+
+	return
+}
+
+// Read 方法实现 Source 接口。
+func (s *Stream) Read(dst []float32) (frames int, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	frames = min(len(dst), len(s.queue)) / s.channels
+	n := copy(dst, s.queue[:frames*s.channels])
+
+	rest := copy(s.queue, s.queue[n:])
+	s.queue = s.queue[:rest]
+
+	if s.closed && len(s.queue) < s.channels {
+		err = io.EOF
+	}
+
+	return
+}
@@ -0,0 +1,21 @@
+package mmdevice
+
+import (
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// CLSCTX_ALL 为 CoCreateInstance 及 IMMDevice::Activate 使用的全部类上下文。
+const CLSCTX_ALL = windows.CLSCTX_INPROC_SERVER | windows.CLSCTX_INPROC_HANDLER | windows.CLSCTX_LOCAL_SERVER | windows.CLSCTX_REMOTE_SERVER
+
+// DECLSPEC_UUID("BCDE0395-E52F-467C-8E3D-C4579291692E")
+var _CLSID_MMDeviceEnumerator = windows.GUID{Data1: 0xBCDE0395, Data2: 0xE52F, Data3: 0x467C, Data4: [8]byte{0x8E, 0x3D, 0xC4, 0x57, 0x92, 0x91, 0x69, 0x2E}}
+
+func CLSID_MMDeviceEnumerator() windows.GUID {
+	return _CLSID_MMDeviceEnumerator
+}
+
+func ToType[T IMMDevice | IMMDeviceCollection | IMMDeviceEnumerator | IMMEndpoint | IMMNotificationClient](v unsafe.Pointer) *T {
+	return (*T)(v)
+}
@@ -0,0 +1,171 @@
+//go:build windows
+
+package mmdevice
+
+import (
+	"github.com/cyberxnomad/wasapi/audioclient"
+	"github.com/cyberxnomad/wasapi/com"
+)
+
+// Device 为已解析属性的音频终结点设备描述。
+type Device struct {
+	ID           string                           // 终结点 ID，可用于 IMMDeviceEnumerator::GetDevice
+	FriendlyName string                           // 友好名称，如 "扬声器 (Realtek High Definition Audio)"
+	Description  string                           // 设备描述，如 "扬声器"
+	ContainerID  string                           // 所属物理设备的容器 ID，同一物理设备的终结点相同
+	State        uint32                           // DEVICE_STATE_XXX
+	DataFlow     EDataFlow                        // ERender 或 ECapture
+	FormFactor   EndpointFormFactor               // 属性缺失时为 UnknownFormFactor
+	MixFormat    audioclient.WAVEFORMATEXTENSIBLE // 仅活动设备有效，否则为零值
+}
+
+// Devices 列出数据流方向为 flow、状态与 stateMask 匹配的音频终结点设备。
+//
+// 调用方需已初始化 COM 库，所有 COM 对象均在返回前释放。
+func Devices(flow EDataFlow, stateMask uint32) (devices []Device, err error) {
+	var enumerator *IMMDeviceEnumerator
+	if enumerator, err = newEnumerator(); err != nil {
+		return
+	}
+	defer enumerator.Release()
+
+	var collection *IMMDeviceCollection
+	if collection, err = enumerator.EnumAudioEndpoints(flow, stateMask); err != nil {
+		return
+	}
+	defer collection.Release()
+
+	var count uint
+	if count, err = collection.GetCount(); err != nil {
+		return
+	}
+
+	devices = make([]Device, 0, count)
+	for i := uint32(0); i < uint32(count); i++ {
+		var device *IMMDevice
+		if device, err = collection.Item(i); err != nil {
+			devices = nil
+			return
+		}
+
+		var d Device
+		d, err = resolve(device)
+		device.Release()
+
+		if err != nil {
+			devices = nil
+			return
+		}
+
+		devices = append(devices, d)
+	}
+
+	return
+}
+
+// DefaultDevice 返回数据流方向为 flow、角色为 role 的默认音频终结点设备。
+//
+// 调用方需已初始化 COM 库，所有 COM 对象均在返回前释放。
+func DefaultDevice(flow EDataFlow, role ERole) (device Device, err error) {
+	var enumerator *IMMDeviceEnumerator
+	if enumerator, err = newEnumerator(); err != nil {
+		return
+	}
+	defer enumerator.Release()
+
+	var endpoint *IMMDevice
+	if endpoint, err = enumerator.GetDefaultAudioEndpoint(flow, role); err != nil {
+		return
+	}
+	defer endpoint.Release()
+
+	return resolve(endpoint)
+}
+
+func newEnumerator() (enumerator *IMMDeviceEnumerator, err error) {
+	clsid := CLSID_MMDeviceEnumerator()
+	iid := IID_IMMDeviceEnumerator()
+
+	v, err := com.CoCreateInstance(&clsid, nil, CLSCTX_ALL, &iid)
+	if err != nil {
+		return
+	}
+
+	enumerator = ToType[IMMDeviceEnumerator](v)
+
+	return
+}
+
+// 读取设备的 ID、状态、数据流方向及属性存储中的属性
+func resolve(device *IMMDevice) (d Device, err error) {
+	if d.ID, err = device.GetId(); err != nil {
+		return
+	}
+
+	if d.State, err = device.GetState(); err != nil {
+		return
+	}
+
+	v, err := device.QueryInterface(IID_IMMEndpoint())
+	if err != nil {
+		return
+	}
+
+	endpoint := ToType[IMMEndpoint](v)
+	d.DataFlow, err = endpoint.GetDataFlow()
+	endpoint.Release()
+
+	if err != nil {
+		return
+	}
+
+	properties, err := device.OpenPropertyStore(com.STGM_READ)
+	if err != nil {
+		return
+	}
+	defer properties.Release()
+
+	d.FriendlyName = stringProperty(properties, com.PKEY_Device_FriendlyName())
+	d.Description = stringProperty(properties, com.PKEY_Device_DeviceDesc())
+
+	if propVar, e := properties.GetValue(com.PKEY_Device_ContainerId()); e == nil {
+		if propVar.Vt == com.VT_CLSID && propVar.PuuidVal() != nil {
+			d.ContainerID = propVar.PuuidVal().String()
+		}
+		com.PropVariantClear(&propVar)
+	}
+
+	d.FormFactor = UnknownFormFactor
+	if propVar, e := properties.GetValue(com.PKEY_AudioEndpoint_FormFactor()); e == nil {
+		if propVar.Vt == com.VT_UI4 {
+			d.FormFactor = EndpointFormFactor(propVar.UlVal())
+		}
+		com.PropVariantClear(&propVar)
+	}
+
+	// 混音格式需激活 IAudioClient 获取，非活动设备无法激活
+	if d.State&DEVICE_STATE_ACTIVE != 0 {
+		if v, e := device.Activate(audioclient.IID_IAudioClient(), CLSCTX_ALL, nil); e == nil {
+			client := audioclient.ToType[audioclient.IAudioClient](v)
+			d.MixFormat, _ = client.GetMixFormat()
+			client.Release()
+		}
+	}
+
+	return
+}
+
+// 读取字符串属性，属性缺失或类型不符时返回空字符串
+func stringProperty(properties *com.IPropertyStore, key com.PROPERTYKEY) (s string) {
+	propVar, err := properties.GetValue(key)
+	if err != nil {
+		return
+	}
+
+	if propVar.Vt == com.VT_LPWSTR && propVar.PwszVal() != nil {
+		s = propVar.PwszValString()
+	}
+	com.PropVariantClear(&propVar)
+
+	return
+}
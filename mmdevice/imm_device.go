@@ -1,3 +1,5 @@
+//go:build windows
+
 package mmdevice
 
 import (
@@ -39,6 +41,22 @@ func (device *IMMDevice) Release() (err error) {
 	return
 }
 
+// QueryInterface 方法检索设备对象上指定接口的指针，如 IMMEndpoint。
+// 不需要时需主动调用 Release 方法。
+func (device *IMMDevice) QueryInterface(iid windows.GUID) (v unsafe.Pointer, err error) {
+	r, _, _ := syscall.SyscallN(device.vtbl.QueryInterface, uintptr(unsafe.Pointer(device)),
+		uintptr(unsafe.Pointer(&iid)),
+		uintptr(unsafe.Pointer(&v)),
+	)
+
+	if com.HRESULT(r) != com.HRESULT(windows.S_OK) {
+		err = fmt.Errorf("IMMDevice::QueryInterface failed with code: 0x%08X", com.HRESULT(r))
+		return
+	}
+
+	return
+}
+
 // Activate 方法创建具有指定接口的 COM 对象。
 // 不需要时需主动调用 Release 方法。
 func (device *IMMDevice) Activate(iid windows.GUID, clsCtx uint32, activationParams *com.PROPVARIANT) (ppInterface unsafe.Pointer, err error) {
@@ -0,0 +1,261 @@
+package pcm
+
+import "github.com/cyberxnomad/wasapi/audioclient"
+
+// Speakers 返回格式中每个声道对应的扬声器位置，长度总是等于声道数。
+//
+// 格式未指定声道掩码时，第 n 个声道对应掩码的第 n 位。声道掩码中置位的个数少于声道数时
+// (Windows 允许这种格式)，多出的声道没有对应的扬声器位置，返回 0。
+func Speakers(format *audioclient.WAVEFORMATEXTENSIBLE) (speakers []uint32) {
+	channels := int(format.Format.Channels)
+	speakers = make([]uint32, channels)
+
+	mask := format.ChannelMask
+	if format.Format.FormatTag != audioclient.WAVE_FORMAT_EXTENSIBLE {
+		mask = 0
+	}
+
+	i := 0
+	for bit := uint32(1); bit != 0 && i < channels; bit <<= 1 {
+		if mask == 0 || mask&bit != 0 {
+			speakers[i] = bit
+			i++
+		}
+	}
+
+	return
+}
+
+var speakerNames = map[uint32]string{
+	audioclient.SPEAKER_FRONT_LEFT:            "front left",
+	audioclient.SPEAKER_FRONT_RIGHT:           "front right",
+	audioclient.SPEAKER_FRONT_CENTER:          "front center",
+	audioclient.SPEAKER_LOW_FREQUENCY:         "low frequency",
+	audioclient.SPEAKER_BACK_LEFT:             "back left",
+	audioclient.SPEAKER_BACK_RIGHT:            "back right",
+	audioclient.SPEAKER_FRONT_LEFT_OF_CENTER:  "front left of center",
+	audioclient.SPEAKER_FRONT_RIGHT_OF_CENTER: "front right of center",
+	audioclient.SPEAKER_BACK_CENTER:           "back center",
+	audioclient.SPEAKER_SIDE_LEFT:             "side left",
+	audioclient.SPEAKER_SIDE_RIGHT:            "side right",
+	audioclient.SPEAKER_TOP_CENTER:            "top center",
+	audioclient.SPEAKER_TOP_FRONT_LEFT:        "top front left",
+	audioclient.SPEAKER_TOP_FRONT_CENTER:      "top front center",
+	audioclient.SPEAKER_TOP_FRONT_RIGHT:       "top front right",
+	audioclient.SPEAKER_TOP_BACK_LEFT:         "top back left",
+	audioclient.SPEAKER_TOP_BACK_CENTER:       "top back center",
+	audioclient.SPEAKER_TOP_BACK_RIGHT:        "top back right",
+}
+
+// SpeakerName 返回扬声器位置 (SPEAKER_*) 的名称，例如 "front left"。
+func SpeakerName(speaker uint32) string {
+	if name, ok := speakerNames[speaker]; ok {
+		return name
+	}
+
+	return "unknown"
+}
+
+// DefaultChannelMask 返回声道数对应的默认声道掩码，没有对应的布局时返回 0。
+func DefaultChannelMask(channels int) uint32 {
+	switch channels {
+	case 1:
+		return audioclient.KSAUDIO_SPEAKER_MONO
+	case 2:
+		return audioclient.KSAUDIO_SPEAKER_STEREO
+	case 4:
+		return audioclient.KSAUDIO_SPEAKER_QUAD
+	case 6:
+		return audioclient.KSAUDIO_SPEAKER_5POINT1_SURROUND
+	case 8:
+		return audioclient.KSAUDIO_SPEAKER_7POINT1_SURROUND
+	}
+
+	return 0
+}
+
+// 声道在目标布局中缺失时的替代扬声器及增益
+type route struct {
+	speaker uint32
+	weight  float32
+}
+
+const minus3dB = 0.70710677
+
+// 每个扬声器按顺序尝试的替代方案，第一个所有扬声器都存在的方案生效
+var fallbacks = map[uint32][][]route{
+	audioclient.SPEAKER_FRONT_LEFT: {
+		{{audioclient.SPEAKER_FRONT_CENTER, minus3dB}},
+	},
+	audioclient.SPEAKER_FRONT_RIGHT: {
+		{{audioclient.SPEAKER_FRONT_CENTER, minus3dB}},
+	},
+	audioclient.SPEAKER_FRONT_CENTER: {
+		{{audioclient.SPEAKER_FRONT_LEFT, minus3dB}, {audioclient.SPEAKER_FRONT_RIGHT, minus3dB}},
+	},
+	audioclient.SPEAKER_FRONT_LEFT_OF_CENTER: {
+		{{audioclient.SPEAKER_FRONT_LEFT, 1}},
+		{{audioclient.SPEAKER_FRONT_CENTER, minus3dB}},
+	},
+	audioclient.SPEAKER_FRONT_RIGHT_OF_CENTER: {
+		{{audioclient.SPEAKER_FRONT_RIGHT, 1}},
+		{{audioclient.SPEAKER_FRONT_CENTER, minus3dB}},
+	},
+	audioclient.SPEAKER_BACK_LEFT: {
+		{{audioclient.SPEAKER_SIDE_LEFT, 1}},
+		{{audioclient.SPEAKER_FRONT_LEFT, minus3dB}},
+		{{audioclient.SPEAKER_FRONT_CENTER, 0.5}},
+	},
+	audioclient.SPEAKER_BACK_RIGHT: {
+		{{audioclient.SPEAKER_SIDE_RIGHT, 1}},
+		{{audioclient.SPEAKER_FRONT_RIGHT, minus3dB}},
+		{{audioclient.SPEAKER_FRONT_CENTER, 0.5}},
+	},
+	audioclient.SPEAKER_SIDE_LEFT: {
+		{{audioclient.SPEAKER_BACK_LEFT, 1}},
+		{{audioclient.SPEAKER_FRONT_LEFT, minus3dB}},
+		{{audioclient.SPEAKER_FRONT_CENTER, 0.5}},
+	},
+	audioclient.SPEAKER_SIDE_RIGHT: {
+		{{audioclient.SPEAKER_BACK_RIGHT, 1}},
+		{{audioclient.SPEAKER_FRONT_RIGHT, minus3dB}},
+		{{audioclient.SPEAKER_FRONT_CENTER, 0.5}},
+	},
+	audioclient.SPEAKER_BACK_CENTER: {
+		{{audioclient.SPEAKER_BACK_LEFT, minus3dB}, {audioclient.SPEAKER_BACK_RIGHT, minus3dB}},
+		{{audioclient.SPEAKER_SIDE_LEFT, minus3dB}, {audioclient.SPEAKER_SIDE_RIGHT, minus3dB}},
+		{{audioclient.SPEAKER_FRONT_LEFT, 0.5}, {audioclient.SPEAKER_FRONT_RIGHT, 0.5}},
+		{{audioclient.SPEAKER_FRONT_CENTER, 0.5}},
+	},
+	audioclient.SPEAKER_TOP_CENTER: {
+		{{audioclient.SPEAKER_FRONT_LEFT, 0.5}, {audioclient.SPEAKER_FRONT_RIGHT, 0.5}},
+		{{audioclient.SPEAKER_FRONT_CENTER, minus3dB}},
+	},
+	audioclient.SPEAKER_TOP_FRONT_LEFT: {
+		{{audioclient.SPEAKER_FRONT_LEFT, minus3dB}},
+		{{audioclient.SPEAKER_FRONT_CENTER, 0.5}},
+	},
+	audioclient.SPEAKER_TOP_FRONT_CENTER: {
+		{{audioclient.SPEAKER_FRONT_CENTER, minus3dB}},
+		{{audioclient.SPEAKER_FRONT_LEFT, 0.5}, {audioclient.SPEAKER_FRONT_RIGHT, 0.5}},
+	},
+	audioclient.SPEAKER_TOP_FRONT_RIGHT: {
+		{{audioclient.SPEAKER_FRONT_RIGHT, minus3dB}},
+		{{audioclient.SPEAKER_FRONT_CENTER, 0.5}},
+	},
+	audioclient.SPEAKER_TOP_BACK_LEFT: {
+		{{audioclient.SPEAKER_BACK_LEFT, minus3dB}},
+		{{audioclient.SPEAKER_SIDE_LEFT, minus3dB}},
+		{{audioclient.SPEAKER_FRONT_LEFT, 0.5}},
+		{{audioclient.SPEAKER_FRONT_CENTER, 0.5}},
+	},
+	audioclient.SPEAKER_TOP_BACK_CENTER: {
+		{{audioclient.SPEAKER_BACK_CENTER, minus3dB}},
+		{{audioclient.SPEAKER_BACK_LEFT, 0.5}, {audioclient.SPEAKER_BACK_RIGHT, 0.5}},
+		{{audioclient.SPEAKER_FRONT_LEFT, 0.5}, {audioclient.SPEAKER_FRONT_RIGHT, 0.5}},
+		{{audioclient.SPEAKER_FRONT_CENTER, 0.5}},
+	},
+	audioclient.SPEAKER_TOP_BACK_RIGHT: {
+		{{audioclient.SPEAKER_BACK_RIGHT, minus3dB}},
+		{{audioclient.SPEAKER_SIDE_RIGHT, minus3dB}},
+		{{audioclient.SPEAKER_FRONT_RIGHT, 0.5}},
+		{{audioclient.SPEAKER_FRONT_CENTER, 0.5}},
+	},
+}
+
+// 返回格式的扬声器布局，未指定声道掩码时采用声道数对应的默认布局
+func layout(format *audioclient.WAVEFORMATEXTENSIBLE) []uint32 {
+	if format.Format.FormatTag != audioclient.WAVE_FORMAT_EXTENSIBLE || format.ChannelMask == 0 {
+		if mask := DefaultChannelMask(int(format.Format.Channels)); mask != 0 {
+			f := *format
+			f.Format.FormatTag = audioclient.WAVE_FORMAT_EXTENSIBLE
+			f.ChannelMask = mask
+			return Speakers(&f)
+		}
+	}
+
+	return Speakers(format)
+}
+
+// ChannelMatrix 返回从 src 的声道布局转换到 dst 的声道布局的混音矩阵，大小为 dst 声道数 × src 声道数，
+// matrix[i][j] 为源声道 j 对目标声道 i 的增益。
+//
+// 相同位置的声道直接对应；目标中缺失的声道按相邻位置折叠 (侧/后声道互换，其次并入前置声道，
+// 左右并入中置时衰减 3 dB)，LFE 在目标中缺失时丢弃。未指定声道掩码时采用默认布局，
+// 没有默认布局的声道按序号对应；声道掩码未覆盖的声道 (没有扬声器位置) 对应目标中同序号的
+// 同类声道，不存在时丢弃。
+func ChannelMatrix(src, dst *audioclient.WAVEFORMATEXTENSIBLE) (matrix [][]float32) {
+	srcSpeakers, dstSpeakers := layout(src), layout(dst)
+
+	index := make(map[uint32]int, len(dstSpeakers))
+	for i, speaker := range dstSpeakers {
+		if speaker != 0 {
+			index[speaker] = i
+		}
+	}
+
+	matrix = make([][]float32, len(dstSpeakers))
+	for i := range matrix {
+		matrix[i] = make([]float32, len(srcSpeakers))
+	}
+
+	for j, speaker := range srcSpeakers {
+		if speaker == 0 {
+			if j < len(dstSpeakers) && dstSpeakers[j] == 0 {
+				matrix[j][j] = 1
+			}
+			continue
+		}
+
+		if i, ok := index[speaker]; ok {
+			matrix[i][j] = 1
+			continue
+		}
+
+		for _, routes := range fallbacks[speaker] {
+			found := true
+			for _, r := range routes {
+				if _, ok := index[r.speaker]; !ok {
+					found = false
+					break
+				}
+			}
+
+			if !found {
+				continue
+			}
+
+			for _, r := range routes {
+				matrix[index[r.speaker]][j] = r.weight
+			}
+			break
+		}
+	}
+
+	return
+}
+
+// Remix 使用混音矩阵将交错的 src 转换为交错的 dst，返回转换的帧数。
+func Remix(matrix [][]float32, src, dst []float32) (frames int) {
+	if len(matrix) == 0 || len(matrix[0]) == 0 {
+		return
+	}
+
+	in, out := len(matrix[0]), len(matrix)
+	frames = min(len(src)/in, len(dst)/out)
+
+	for f := 0; f < frames; f++ {
+		s := src[f*in : (f+1)*in]
+		d := dst[f*out : (f+1)*out]
+
+		for i, row := range matrix {
+			var acc float32
+			for j, w := range row {
+				acc += w * s[j]
+			}
+			d[i] = acc
+		}
+	}
+
+	return
+}
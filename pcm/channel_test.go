@@ -0,0 +1,143 @@
+package pcm
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/cyberxnomad/wasapi/audioclient"
+)
+
+func TestSpeakers(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		channels uint16
+		mask     uint32
+		want     []uint32
+	}{
+		{"stereo", 2, audioclient.KSAUDIO_SPEAKER_STEREO,
+			[]uint32{audioclient.SPEAKER_FRONT_LEFT, audioclient.SPEAKER_FRONT_RIGHT}},
+		{"no mask", 3, 0,
+			[]uint32{audioclient.SPEAKER_FRONT_LEFT, audioclient.SPEAKER_FRONT_RIGHT, audioclient.SPEAKER_FRONT_CENTER}},
+		{"partial mask", 2, audioclient.SPEAKER_FRONT_CENTER,
+			[]uint32{audioclient.SPEAKER_FRONT_CENTER, 0}},
+		{"partial 5.1", 8, audioclient.KSAUDIO_SPEAKER_5POINT1_SURROUND,
+			[]uint32{
+				audioclient.SPEAKER_FRONT_LEFT, audioclient.SPEAKER_FRONT_RIGHT, audioclient.SPEAKER_FRONT_CENTER,
+				audioclient.SPEAKER_LOW_FREQUENCY, audioclient.SPEAKER_SIDE_LEFT, audioclient.SPEAKER_SIDE_RIGHT, 0, 0,
+			}},
+		{"excess mask", 1, audioclient.KSAUDIO_SPEAKER_STEREO,
+			[]uint32{audioclient.SPEAKER_FRONT_LEFT}},
+	} {
+		format := NewFormat(SampleTypeFloat, 48000, tc.channels, 32, tc.mask)
+		if got := Speakers(&format); !slices.Equal(got, tc.want) {
+			t.Errorf("%s: Speakers = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+
+	// 非 WAVE_FORMAT_EXTENSIBLE 格式忽略声道掩码
+	format := NewFormat(SampleTypeInt, 44100, 2, 16, audioclient.SPEAKER_FRONT_CENTER)
+	format.Format.FormatTag = audioclient.WAVE_FORMAT_PCM
+	if got := Speakers(&format); !slices.Equal(got, []uint32{audioclient.SPEAKER_FRONT_LEFT, audioclient.SPEAKER_FRONT_RIGHT}) {
+		t.Errorf("WAVE_FORMAT_PCM: Speakers = %v", got)
+	}
+
+	// 声道数超过掩码位数
+	format = NewFormat(SampleTypeFloat, 48000, 34, 32, 0)
+	if got := Speakers(&format); len(got) != 34 || got[31] != 1<<31 || got[32] != 0 || got[33] != 0 {
+		t.Errorf("34 channels: Speakers = %v", got)
+	}
+}
+
+func TestChannelMatrix(t *testing.T) {
+	stereo := NewFormat(SampleTypeFloat, 48000, 2, 32, audioclient.KSAUDIO_SPEAKER_STEREO)
+	mono := NewFormat(SampleTypeFloat, 48000, 1, 32, audioclient.KSAUDIO_SPEAKER_MONO)
+	surround := NewFormat(SampleTypeFloat, 48000, 6, 32, audioclient.KSAUDIO_SPEAKER_5POINT1_SURROUND)
+
+	// 8 声道但掩码只覆盖 5.1，后两个声道没有扬声器位置
+	partial := NewFormat(SampleTypeFloat, 48000, 8, 32, audioclient.KSAUDIO_SPEAKER_5POINT1_SURROUND)
+	// 3 声道但掩码只有中置
+	center := NewFormat(SampleTypeFloat, 48000, 3, 32, audioclient.SPEAKER_FRONT_CENTER)
+
+	const h = minus3dB
+	for _, tc := range []struct {
+		name     string
+		src, dst *audioclient.WAVEFORMATEXTENSIBLE
+		want     [][]float32
+	}{
+		{"stereo to mono", &stereo, &mono, [][]float32{{h, h}}},
+		{"mono to stereo", &mono, &stereo, [][]float32{{h}, {h}}},
+		{"5.1 to stereo", &surround, &stereo, [][]float32{
+			{1, 0, h, 0, h, 0},
+			{0, 1, h, 0, 0, h},
+		}},
+		{"partial to same", &partial, &partial, [][]float32{
+			{1, 0, 0, 0, 0, 0, 0, 0},
+			{0, 1, 0, 0, 0, 0, 0, 0},
+			{0, 0, 1, 0, 0, 0, 0, 0},
+			{0, 0, 0, 1, 0, 0, 0, 0},
+			{0, 0, 0, 0, 1, 0, 0, 0},
+			{0, 0, 0, 0, 0, 1, 0, 0},
+			{0, 0, 0, 0, 0, 0, 1, 0},
+			{0, 0, 0, 0, 0, 0, 0, 1},
+		}},
+		{"partial to stereo", &partial, &stereo, [][]float32{
+			{1, 0, h, 0, h, 0, 0, 0},
+			{0, 1, h, 0, 0, h, 0, 0},
+		}},
+		{"stereo to partial", &stereo, &partial, [][]float32{
+			{1, 0}, {0, 1}, {0, 0}, {0, 0}, {0, 0}, {0, 0}, {0, 0}, {0, 0},
+		}},
+		{"center to partial", &center, &partial, [][]float32{
+			{0, 0, 0}, {0, 0, 0}, {1, 0, 0}, {0, 0, 0}, {0, 0, 0}, {0, 0, 0}, {0, 0, 0}, {0, 0, 0},
+		}},
+		{"partial to center", &partial, &center, [][]float32{
+			{h, h, 1, 0, 0.5, 0.5, 0, 0},
+			{0, 0, 0, 0, 0, 0, 0, 0},
+			{0, 0, 0, 0, 0, 0, 0, 0},
+		}},
+	} {
+		got := ChannelMatrix(tc.src, tc.dst)
+		if len(got) != int(tc.dst.Format.Channels) {
+			t.Errorf("%s: %d rows, want %d", tc.name, len(got), tc.dst.Format.Channels)
+			continue
+		}
+
+		for i := range got {
+			if !slices.Equal(got[i], tc.want[i]) {
+				t.Errorf("%s: row %d = %v, want %v", tc.name, i, got[i], tc.want[i])
+			}
+		}
+	}
+}
+
+func TestRemixPartialMask(t *testing.T) {
+	src := NewFormat(SampleTypeFloat, 48000, 3, 32, audioclient.KSAUDIO_SPEAKER_STEREO)
+	dst := NewFormat(SampleTypeFloat, 48000, 3, 32, audioclient.SPEAKER_FRONT_CENTER)
+	matrix := ChannelMatrix(&src, &dst)
+
+	// 每帧 3 个源声道: 左、右、未分配
+	in := []float32{
+		0.2, 0.4, 0.9,
+		-0.2, 0.2, -0.9,
+	}
+	out := make([]float32, 3*3)
+	for i := range out {
+		out[i] = 7
+	}
+
+	if frames := Remix(matrix, in, out); frames != 2 {
+		t.Fatalf("Remix converted %d frames, want 2", frames)
+	}
+
+	const h = minus3dB
+	want := []float32{
+		0.2*h + 0.4*h, 0, 0.9,
+		-0.2*h + 0.2*h, 0, -0.9,
+		7, 7, 7,
+	}
+	for i := range want {
+		if d := out[i] - want[i]; d > 1e-6 || d < -1e-6 {
+			t.Fatalf("out = %v, want %v", out, want)
+		}
+	}
+}
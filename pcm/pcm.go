@@ -0,0 +1,180 @@
+package pcm
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+
+	"github.com/cyberxnomad/wasapi/audioclient"
+)
+
+var ErrUnsupportedFormat = errors.New("unsupported format")
+
+type SampleType uint32
+
+const (
+	SampleTypeUnknown SampleType = iota
+	SampleTypeInt                // 整数 PCM
+	SampleTypeFloat              // IEEE 浮点
+)
+
+// SampleTypeOf 返回格式的采样类型。
+func SampleTypeOf(format *audioclient.WAVEFORMATEXTENSIBLE) SampleType {
+	tag := format.Format.FormatTag
+	if tag == audioclient.WAVE_FORMAT_EXTENSIBLE {
+		tag = format.SubFormatTag()
+	}
+
+	switch tag {
+	case audioclient.WAVE_FORMAT_PCM:
+		return SampleTypeInt
+	case audioclient.WAVE_FORMAT_IEEE_FLOAT:
+		return SampleTypeFloat
+	}
+
+	return SampleTypeUnknown
+}
+
+// Frames 返回 n 字节数据包含的帧数。
+func Frames(format *audioclient.WAVEFORMATEXTENSIBLE, n int) int {
+	if format.Format.BlockAlign == 0 {
+		return 0
+	}
+
+	return n / int(format.Format.BlockAlign)
+}
+
+// Decode 将 data 中的交错采样解码为 [-1, 1] 范围内的 float32 并写入 dst。
+//
+// 返回写入的采样数 (帧数乘以通道数)。
+func Decode(format *audioclient.WAVEFORMATEXTENSIBLE, data []byte, dst []float32) (n int, err error) {
+	size := int(format.Format.BitsPerSample / 8)
+	if size == 0 {
+		err = ErrUnsupportedFormat
+		return
+	}
+
+	n = min(len(data)/size, len(dst))
+
+	switch sampleType, bits := SampleTypeOf(format), format.Format.BitsPerSample; {
+	case sampleType == SampleTypeInt && bits == 8:
+		for i := 0; i < n; i++ {
+			dst[i] = float32(int(data[i])-128) / 128
+		}
+	case sampleType == SampleTypeInt && bits == 16:
+		for i := 0; i < n; i++ {
+			dst[i] = float32(int16(binary.LittleEndian.Uint16(data[i*2:]))) / (1 << 15)
+		}
+	case sampleType == SampleTypeInt && bits == 24:
+		for i := 0; i < n; i++ {
+			v := int32(data[i*3]) | int32(data[i*3+1])<<8 | int32(int8(data[i*3+2]))<<16
+			dst[i] = float32(v) / (1 << 23)
+		}
+	case sampleType == SampleTypeInt && bits == 32:
+		for i := 0; i < n; i++ {
+			dst[i] = float32(float64(int32(binary.LittleEndian.Uint32(data[i*4:]))) / (1 << 31))
+		}
+	case sampleType == SampleTypeFloat && bits == 32:
+		for i := 0; i < n; i++ {
+			dst[i] = math.Float32frombits(binary.LittleEndian.Uint32(data[i*4:]))
+		}
+	case sampleType == SampleTypeFloat && bits == 64:
+		for i := 0; i < n; i++ {
+			dst[i] = float32(math.Float64frombits(binary.LittleEndian.Uint64(data[i*8:])))
+		}
+	default:
+		n = 0
+		err = ErrUnsupportedFormat
+	}
+
+	return
+}
+
+// Encode 将 float32 采样按格式编码写入 dst，整数格式会对超出 [-1, 1] 的采样进行限幅。
+//
+// 返回写入的采样数。
+func Encode(format *audioclient.WAVEFORMATEXTENSIBLE, src []float32, dst []byte) (n int, err error) {
+	size := int(format.Format.BitsPerSample / 8)
+	if size == 0 {
+		err = ErrUnsupportedFormat
+		return
+	}
+
+	n = min(len(dst)/size, len(src))
+
+	switch sampleType, bits := SampleTypeOf(format), format.Format.BitsPerSample; {
+	case sampleType == SampleTypeInt && bits == 8:
+		for i := 0; i < n; i++ {
+			dst[i] = uint8(Quantize(src[i], 1<<7) + 128)
+		}
+	case sampleType == SampleTypeInt && bits == 16:
+		for i := 0; i < n; i++ {
+			binary.LittleEndian.PutUint16(dst[i*2:], uint16(int16(Quantize(src[i], 1<<15))))
+		}
+	case sampleType == SampleTypeInt && bits == 24:
+		for i := 0; i < n; i++ {
+			v := int32(Quantize(src[i], 1<<23))
+			dst[i*3] = byte(v)
+			dst[i*3+1] = byte(v >> 8)
+			dst[i*3+2] = byte(v >> 16)
+		}
+	case sampleType == SampleTypeInt && bits == 32:
+		for i := 0; i < n; i++ {
+			binary.LittleEndian.PutUint32(dst[i*4:], uint32(int32(Quantize(src[i], 1<<31))))
+		}
+	case sampleType == SampleTypeFloat && bits == 32:
+		for i := 0; i < n; i++ {
+			binary.LittleEndian.PutUint32(dst[i*4:], math.Float32bits(src[i]))
+		}
+	case sampleType == SampleTypeFloat && bits == 64:
+		for i := 0; i < n; i++ {
+			binary.LittleEndian.PutUint64(dst[i*8:], math.Float64bits(float64(src[i])))
+		}
+	default:
+		n = 0
+		err = ErrUnsupportedFormat
+	}
+
+	return
+}
+
+// Quantize 将 [-1, 1] 的采样量化为 [-scale, scale-1] 的整数，NaN 量化为 0。
+func Quantize(v float32, scale float64) int64 {
+	q := math.Round(float64(v) * scale)
+	if math.IsNaN(q) {
+		return 0
+	}
+
+	if q > scale-1 {
+		q = scale - 1
+	} else if q < -scale {
+		q = -scale
+	}
+
+	return int64(q)
+}
+
+// NewFormat 构造 WAVEFORMATEXTENSIBLE 格式，channelMask 为 0 时表示未指定声道位置。
+func NewFormat(sampleType SampleType, samplesPerSec uint32, channels uint16, bitsPerSample uint16, channelMask uint32) (format audioclient.WAVEFORMATEXTENSIBLE) {
+	blockAlign := channels * (bitsPerSample / 8)
+
+	format.Format = audioclient.WAVEFORMATEX{
+		FormatTag:      audioclient.WAVE_FORMAT_EXTENSIBLE,
+		Channels:       channels,
+		SamplesPerSec:  samplesPerSec,
+		AvgBytesPerSec: samplesPerSec * uint32(blockAlign),
+		BlockAlign:     blockAlign,
+		BitsPerSample:  bitsPerSample,
+		CbSize:         22,
+	}
+	format.Samples = bitsPerSample
+	format.ChannelMask = channelMask
+
+	if sampleType == SampleTypeFloat {
+		format.SubFormat = audioclient.KSDATAFORMAT_SUBTYPE_IEEE_FLOAT()
+	} else {
+		format.SubFormat = audioclient.KSDATAFORMAT_SUBTYPE_PCM()
+	}
+
+	return
+}
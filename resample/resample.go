@@ -0,0 +1,155 @@
+package resample
+
+import "math"
+
+const (
+	halfTaps = 16  // 每侧的抽头数
+	phases   = 256 // 分数相位的个数
+)
+
+// Resampler 是可变比率的带限 (加窗 sinc) 重采样器，作用于交错的 float32 采样。
+//
+// 比率可以在处理过程中连续微调，用于时钟漂移补偿；也可以用于固定的采样率转换。
+type Resampler struct {
+	channels int
+	ratio    float64 // 输出采样率 / 输入采样率
+	cutoff   float64
+	table    []float32
+
+	buf []float32 // 尚未完全消耗的输入帧，开头为历史帧
+	pos float64   // 下一个输出帧在 buf 中的位置 (帧)
+}
+
+// New 创建重采样器，inRate 及 outRate 为输入及输出的采样率。
+func New(channels int, inRate, outRate uint32) *Resampler {
+	r := &Resampler{
+		channels: channels,
+		buf:      make([]float32, (2*halfTaps-1)*channels),
+		pos:      halfTaps - 1,
+	}
+	r.SetRatio(float64(outRate) / float64(inRate))
+
+	return r
+}
+
+// Ratio 方法返回当前比率 (输出采样率 / 输入采样率)。
+func (r *Resampler) Ratio() float64 {
+	return r.ratio
+}
+
+// SetRatio 方法设置比率 (输出采样率 / 输入采样率)，可在处理过程中调用。
+func (r *Resampler) SetRatio(ratio float64) {
+	r.ratio = ratio
+
+	// 降采样时降低截止频率以避免混叠，比率的微小变化不重新计算滤波器
+	cutoff := min(1, ratio) * 0.95
+	if r.table == nil || math.Abs(cutoff-r.cutoff) > 0.005 {
+		r.cutoff = cutoff
+		r.table = makeTable(cutoff)
+	}
+}
+
+// Latency 方法返回重采样器引入的延迟，单位为输入帧。
+func (r *Resampler) Latency() int {
+	return halfTaps
+}
+
+// Reset 方法清除历史状态。
+func (r *Resampler) Reset() {
+	r.buf = r.buf[:(2*halfTaps-1)*r.channels]
+	clear(r.buf)
+	r.pos = halfTaps - 1
+}
+
+// Process 方法处理输入帧，将输出帧追加到 dst 并返回。
+func (r *Resampler) Process(dst []float32, src []float32) []float32 {
+	ch := r.channels
+	r.buf = append(r.buf, src...)
+	frames := len(r.buf) / ch
+	step := 1 / r.ratio
+
+	for {
+		center := int(r.pos)
+		if center+halfTaps >= frames {
+			break
+		}
+
+		frac := r.pos - float64(center)
+		phase := frac * phases
+		p := int(phase)
+		mix := float32(phase - float64(p))
+
+		k0 := r.table[p*2*halfTaps : (p+1)*2*halfTaps]
+		k1 := r.table[(p+1)*2*halfTaps : (p+2)*2*halfTaps]
+
+		start := center - halfTaps + 1
+		for c := 0; c < ch; c++ {
+			var acc float32
+			for t := 0; t < 2*halfTaps; t++ {
+				k := k0[t] + (k1[t]-k0[t])*mix
+				acc += k * r.buf[(start+t)*ch+c]
+			}
+			dst = append(dst, acc)
+		}
+
+		r.pos += step
+	}
+
+	// 丢弃不再需要的输入帧
+	drop := min(int(r.pos)-halfTaps+1, frames)
+	if drop > 0 {
+		n := copy(r.buf, r.buf[drop*ch:])
+		r.buf = r.buf[:n]
+		r.pos -= float64(drop)
+	}
+
+	return dst
+}
+
+// 生成多相滤波器表，第 p 行对应分数位置 p/phases，共 phases+1 行便于插值
+func makeTable(cutoff float64) []float32 {
+	table := make([]float32, (phases+1)*2*halfTaps)
+
+	for p := 0; p <= phases; p++ {
+		frac := float64(p) / phases
+
+		for t := 0; t < 2*halfTaps; t++ {
+			// 抽头 t 对应输入帧 center-halfTaps+1+t，与输出位置的距离
+			x := float64(t-halfTaps+1) - frac
+			table[p*2*halfTaps+t] = float32(cutoff * sinc(cutoff*x) * kaiser(x/halfTaps, 8))
+		}
+	}
+
+	return table
+}
+
+func sinc(x float64) float64 {
+	if x == 0 {
+		return 1
+	}
+
+	return math.Sin(math.Pi*x) / (math.Pi * x)
+}
+
+// Kaiser 窗，x 范围为 [-1, 1]
+func kaiser(x, beta float64) float64 {
+	if x <= -1 || x >= 1 {
+		return 0
+	}
+
+	return bessel0(beta*math.Sqrt(1-x*x)) / bessel0(beta)
+}
+
+// 第一类零阶修正贝塞尔函数
+func bessel0(x float64) float64 {
+	sum, term := 1.0, 1.0
+	for k := 1; k < 50; k++ {
+		term *= (x / (2 * float64(k))) * (x / (2 * float64(k)))
+		sum += term
+		if term < sum*1e-12 {
+			break
+		}
+	}
+
+	return sum
+}
@@ -0,0 +1,134 @@
+package resample
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// 以随机大小的块处理 src，返回全部输出
+func process(r *Resampler, src []float32, rng *rand.Rand) (out []float32) {
+	for len(src) > 0 {
+		n := min(len(src), (rng.Intn(500)+1)*r.channels)
+		out = r.Process(out, src[:n])
+		src = src[n:]
+	}
+
+	return
+}
+
+func TestOutputLength(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+
+	for _, tt := range []struct {
+		channels        int
+		inRate, outRate uint32
+	}{
+		{1, 48000, 48000},
+		{2, 44100, 48000},
+		{2, 48000, 44100},
+		{1, 48000, 16000},
+		{6, 8000, 48000},
+	} {
+		const frames = 100000
+
+		src := make([]float32, frames*tt.channels)
+		for i := range src {
+			src[i] = rng.Float32()*2 - 1
+		}
+
+		// 分块处理与一次处理的结果相同，相位累积的舍入误差最多使末尾相差一帧
+		whole := New(tt.channels, tt.inRate, tt.outRate).Process(nil, src)
+		chunked := process(New(tt.channels, tt.inRate, tt.outRate), src, rng)
+
+		if len(whole)%tt.channels != 0 || len(chunked)%tt.channels != 0 || abs(len(whole)-len(chunked)) > tt.channels {
+			t.Fatalf("%d -> %d Hz: %d samples in one call, %d in chunks", tt.inRate, tt.outRate, len(whole), len(chunked))
+		}
+		for i := range whole[:min(len(whole), len(chunked))] {
+			if math.Abs(float64(whole[i]-chunked[i])) > 1e-5 {
+				t.Fatalf("%d -> %d Hz: sample %d differs: %v, %v", tt.inRate, tt.outRate, i, whole[i], chunked[i])
+			}
+		}
+
+		// 输出帧数为输入帧数乘以比率，开头的延迟由最后 Latency 帧输入补足
+		want := float64(frames) * float64(tt.outRate) / float64(tt.inRate)
+		if got := float64(len(whole) / tt.channels); math.Abs(got-want) > 2 {
+			t.Errorf("%d -> %d Hz: %v output frames, want %.1f", tt.inRate, tt.outRate, got, want)
+		}
+	}
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+// 正弦经重采样后的幅度 (dB)，跳过开头的暂态
+func gain(inRate, outRate uint32, frequency float64) float64 {
+	src := make([]float32, int(inRate))
+	for i := range src {
+		src[i] = float32(0.5 * math.Sin(2*math.Pi*frequency*float64(i)/float64(inRate)))
+	}
+
+	out := New(1, inRate, outRate).Process(nil, src)
+	out = out[len(out)/4:]
+
+	var sum float64
+	for _, v := range out {
+		sum += float64(v) * float64(v)
+	}
+
+	return 20 * math.Log10(math.Sqrt(2*sum/float64(len(out)))/0.5)
+}
+
+func TestFrequencyResponse(t *testing.T) {
+	for _, tt := range []struct {
+		inRate, outRate uint32
+		frequency       float64
+		min, max        float64 // 允许的增益范围 (dB)
+	}{
+		// 通带 (输出奈奎斯特频率的一半以下) 平坦
+		{44100, 48000, 1000, -0.05, 0.05},
+		{44100, 48000, 17000, -0.05, 0.05},
+		{48000, 44100, 17000, -0.05, 0.05},
+		{48000, 16000, 300, -0.05, 0.05},
+		{48000, 16000, 4000, -0.05, 0.05},
+		{16000, 48000, 4000, -0.05, 0.05},
+
+		// 截止频率为输出奈奎斯特频率的 0.95 倍
+		{48000, 16000, 7600, -7, -3},
+
+		// 降采样时超出输出奈奎斯特频率的成分被滤除，不混叠到通带
+		{48000, 16000, 11000, math.Inf(-1), -50},
+		{48000, 16000, 12000, math.Inf(-1), -80},
+		{48000, 44100, 23500, math.Inf(-1), -30},
+	} {
+		if g := gain(tt.inRate, tt.outRate, tt.frequency); g < tt.min || g > tt.max {
+			t.Errorf("%d -> %d Hz at %v Hz: gain %.3f dB, want [%v, %v]", tt.inRate, tt.outRate, tt.frequency, g, tt.min, tt.max)
+		}
+	}
+}
+
+func TestSetRatio(t *testing.T) {
+	// 比率微调时输出连续，不产生跳变
+	r := New(1, 48000, 48000)
+	var out []float32
+	for k := 0; k < 100; k++ {
+		r.SetRatio(1 + 0.002*math.Sin(float64(k)/10))
+
+		src := make([]float32, 480)
+		for i := range src {
+			src[i] = float32(math.Sin(2 * math.Pi * 440 * float64(k*480+i) / 48000))
+		}
+		out = r.Process(out, src)
+	}
+
+	maxStep := 2 * math.Pi * 440 / 48000 * 1.01
+	for i := 100; i < len(out); i++ {
+		if step := math.Abs(float64(out[i] - out[i-1])); step > maxStep {
+			t.Fatalf("sample %d: step %.5f exceeds %.5f", i, step, maxStep)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package rtp
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+var (
+	ErrShortPacket = errors.New("packet too short")
+	ErrVersion     = errors.New("unsupported rtp version")
+)
+
+const headerSize = 12
+
+// Header 为 RTP 固定头 (RFC 3550 5.1)，不支持头扩展的内容。
+type Header struct {
+	Marker         bool
+	PayloadType    uint8
+	SequenceNumber uint16
+	Timestamp      uint32
+	SSRC           uint32
+	CSRC           []uint32
+}
+
+// Size 方法返回编码后头的字节数。
+func (h *Header) Size() int {
+	return headerSize + 4*len(h.CSRC)
+}
+
+// Marshal 方法将头编码到 buf 开头，返回写入的字节数。
+func (h *Header) Marshal(buf []byte) (n int, err error) {
+	if len(buf) < h.Size() || len(h.CSRC) > 15 {
+		err = ErrShortPacket
+		return
+	}
+
+	buf[0] = 2<<6 | uint8(len(h.CSRC))
+	buf[1] = h.PayloadType & 0x7F
+	if h.Marker {
+		buf[1] |= 0x80
+	}
+
+	binary.BigEndian.PutUint16(buf[2:4], h.SequenceNumber)
+	binary.BigEndian.PutUint32(buf[4:8], h.Timestamp)
+	binary.BigEndian.PutUint32(buf[8:12], h.SSRC)
+
+	for i, csrc := range h.CSRC {
+		binary.BigEndian.PutUint32(buf[12+4*i:], csrc)
+	}
+
+	n = h.Size()
+
+	return
+}
+
+// Unmarshal 方法从 buf 解码 RTP 包，返回负载 (已去除填充)。
+func (h *Header) Unmarshal(buf []byte) (payload []byte, err error) {
+	if len(buf) < headerSize {
+		err = ErrShortPacket
+		return
+	}
+
+	if buf[0]>>6 != 2 {
+		err = ErrVersion
+		return
+	}
+
+	padding := buf[0]&0x20 != 0
+	extension := buf[0]&0x10 != 0
+	cc := int(buf[0] & 0x0F)
+
+	h.Marker = buf[1]&0x80 != 0
+	h.PayloadType = buf[1] & 0x7F
+	h.SequenceNumber = binary.BigEndian.Uint16(buf[2:4])
+	h.Timestamp = binary.BigEndian.Uint32(buf[4:8])
+	h.SSRC = binary.BigEndian.Uint32(buf[8:12])
+
+	offset := headerSize + 4*cc
+	if len(buf) < offset {
+		err = ErrShortPacket
+		return
+	}
+
+	h.CSRC = h.CSRC[:0]
+	for i := 0; i < cc; i++ {
+		h.CSRC = append(h.CSRC, binary.BigEndian.Uint32(buf[12+4*i:]))
+	}
+
+	// 跳过头扩展
+	if extension {
+		if len(buf) < offset+4 {
+			err = ErrShortPacket
+			return
+		}
+
+		offset += 4 + 4*int(binary.BigEndian.Uint16(buf[offset+2:]))
+		if len(buf) < offset {
+			err = ErrShortPacket
+			return
+		}
+	}
+
+	end := len(buf)
+	if padding {
+		end -= int(buf[end-1])
+		if end < offset {
+			err = ErrShortPacket
+			return
+		}
+	}
+
+	payload = buf[offset:end]
+
+	return
+}
@@ -0,0 +1,116 @@
+package rtp
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestHeaderRoundTrip(t *testing.T) {
+	h := Header{
+		Marker:         true,
+		PayloadType:    96,
+		SequenceNumber: 0xBEEF,
+		Timestamp:      0xDEADBEEF,
+		SSRC:           0x01020304,
+		CSRC:           []uint32{0x11111111, 0x22222222, 0x33333333},
+	}
+
+	buf := make([]byte, h.Size()+4)
+	n, err := h.Marshal(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 12+3*4 {
+		t.Fatalf("Marshal wrote %d bytes, want 24", n)
+	}
+
+	want := []byte{
+		0x83, 0xE0, 0xBE, 0xEF,
+		0xDE, 0xAD, 0xBE, 0xEF,
+		0x01, 0x02, 0x03, 0x04,
+		0x11, 0x11, 0x11, 0x11,
+		0x22, 0x22, 0x22, 0x22,
+		0x33, 0x33, 0x33, 0x33,
+	}
+	if !bytes.Equal(buf[:n], want) {
+		t.Fatalf("Marshal = % X, want % X", buf[:n], want)
+	}
+
+	copy(buf[n:], "data")
+
+	var got Header
+	payload, err := got.Unmarshal(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got.Marker != h.Marker || got.PayloadType != h.PayloadType || got.SequenceNumber != h.SequenceNumber ||
+		got.Timestamp != h.Timestamp || got.SSRC != h.SSRC || !slices.Equal(got.CSRC, h.CSRC) {
+		t.Fatalf("Unmarshal = %+v, want %+v", got, h)
+	}
+	if string(payload) != "data" {
+		t.Fatalf("payload = %q, want %q", payload, "data")
+	}
+}
+
+func TestHeaderTooManyCSRC(t *testing.T) {
+	h := Header{CSRC: make([]uint32, 16)}
+	if _, err := h.Marshal(make([]byte, 128)); !errors.Is(err, ErrShortPacket) {
+		t.Fatalf("Marshal with 16 CSRC: err = %v", err)
+	}
+}
+
+func TestHeaderExtensionAndPadding(t *testing.T) {
+	// V=2 P=1 X=1 CC=1
+	buf := []byte{
+		0xB1, 0x0A, 0x00, 0x01,
+		0x00, 0x00, 0x00, 0x02,
+		0x00, 0x00, 0x00, 0x03,
+		0x00, 0x00, 0x00, 0x04, // CSRC
+		0xBE, 0xDE, 0x00, 0x02, // 扩展头，长度 2 个字
+		0x10, 0xAA, 0x00, 0x00,
+		0x21, 0xBB, 0xCC, 0x00,
+		'p', 'c', 'm', // 负载
+		0x00, 0x00, 0x03, // 填充
+	}
+
+	var h Header
+	payload, err := h.Unmarshal(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if h.PayloadType != 10 || h.SequenceNumber != 1 || h.Timestamp != 2 || h.SSRC != 3 || !slices.Equal(h.CSRC, []uint32{4}) {
+		t.Fatalf("Unmarshal = %+v", h)
+	}
+	if string(payload) != "pcm" {
+		t.Fatalf("payload = %q, want %q", payload, "pcm")
+	}
+
+	// 扩展长度超出包长
+	binary.BigEndian.PutUint16(buf[18:], 100)
+	if _, err = h.Unmarshal(buf); !errors.Is(err, ErrShortPacket) {
+		t.Fatalf("truncated extension: err = %v", err)
+	}
+}
+
+func TestHeaderErrors(t *testing.T) {
+	var h Header
+
+	if _, err := h.Unmarshal(make([]byte, 11)); !errors.Is(err, ErrShortPacket) {
+		t.Fatalf("short packet: err = %v", err)
+	}
+
+	if _, err := h.Unmarshal(make([]byte, 12)); !errors.Is(err, ErrVersion) {
+		t.Fatalf("version 0: err = %v", err)
+	}
+
+	// CC=2 但只有一个 CSRC
+	buf := append([]byte{0x82, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}, 0, 0, 0, 1)
+	if _, err := h.Unmarshal(buf); !errors.Is(err, ErrShortPacket) {
+		t.Fatalf("truncated CSRC: err = %v", err)
+	}
+}
@@ -0,0 +1,228 @@
+package rtp
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/cyberxnomad/wasapi/audioclient"
+	"github.com/cyberxnomad/wasapi/internal/nettest"
+	"github.com/cyberxnomad/wasapi/pcm"
+)
+
+// 生成 frames 帧双声道 16 位斜坡信号
+func ramp(start, frames int) []byte {
+	data := make([]byte, frames*4)
+	for i := 0; i < frames*2; i++ {
+		binary.LittleEndian.PutUint16(data[i*2:], uint16(int16((start*2+i)*7)))
+	}
+
+	return data
+}
+
+func TestSenderReceiverLoopback(t *testing.T) {
+	send, recv := nettest.Loopback(t)
+	format := pcm.NewFormat(pcm.SampleTypeInt, 48000, 2, 16, audioclient.KSAUDIO_SPEAKER_STEREO)
+
+	sender, err := NewSender(send, nil, &format, L16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sender.SetPayloadType(PayloadTypeDynamic)
+	sender.seq = 0xFFFE // 验证序列号回绕
+
+	receiver := NewReceiver(recv, nil, &format, L16)
+	receiver.SetPayloadType(PayloadTypeDynamic)
+
+	// 2.5 毫秒一个捕获数据包，5 毫秒一个 RTP 包 (20 毫秒的双声道 L16 超出最大负载长度)
+	const chunk = 120
+	sender.SetPacketTime(5 * time.Millisecond)
+	var sent []byte
+	for pos := 0; pos < 10*chunk; pos += chunk {
+		data := ramp(pos, chunk)
+		sent = append(sent, data...)
+
+		if err := sender.Send(data, uint64(pos), uint64(pos)*10000000/48000); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	wantSeq := []uint16{0xFFFE, 0xFFFF, 0, 1, 2}
+	var received []byte
+	for i, seq := range wantSeq {
+		header, data, err := receiver.ReadPacket()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if header.SequenceNumber != seq {
+			t.Errorf("packet %d: sequence %d, want %d", i, header.SequenceNumber, seq)
+		}
+		if want := sender.TimestampAt(uint64(i * 2 * chunk)); header.Timestamp != want {
+			t.Errorf("packet %d: timestamp %d, want %d", i, header.Timestamp, want)
+		}
+		if header.Marker != (i == 0) {
+			t.Errorf("packet %d: marker %v", i, header.Marker)
+		}
+		if header.SSRC != sender.SSRC() {
+			t.Errorf("packet %d: ssrc %08X, want %08X", i, header.SSRC, sender.SSRC())
+		}
+
+		received = append(received, data...)
+	}
+
+	if !bytes.Equal(received, sent) {
+		t.Fatal("received audio differs from sent audio")
+	}
+
+	if ssrc, ok := receiver.SSRC(); !ok || ssrc != sender.SSRC() {
+		t.Fatalf("receiver locked ssrc %08X (%v), want %08X", ssrc, ok, sender.SSRC())
+	}
+
+	if report := sender.Report(); report.PacketCount != 5 || report.OctetCount != uint32(len(sent)) {
+		t.Fatalf("report counts %d packets %d octets, want 5 and %d", report.PacketCount, report.OctetCount, len(sent))
+	}
+}
+
+func TestSenderDiscontinuity(t *testing.T) {
+	send, recv := nettest.Loopback(t)
+	format := pcm.NewFormat(pcm.SampleTypeInt, 48000, 2, 16, audioclient.KSAUDIO_SPEAKER_STEREO)
+
+	sender, err := NewSender(send, nil, &format, L16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	receiver := NewReceiver(recv, nil, &format, L16)
+
+	const chunk = 120
+	sender.SetPacketTime(5 * time.Millisecond)
+
+	// 两个捕获包恰好组成一个 RTP 包，发送后暂存为空
+	for _, pos := range []int{0, chunk, 10 * chunk, 11 * chunk, 13 * chunk} {
+		if err := sender.Send(ramp(pos, chunk), uint64(pos), 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := sender.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []struct {
+		pos    int
+		marker bool
+	}{
+		{0, true},
+		{10 * chunk, true}, // 暂存为空时的位置跳变
+		{13 * chunk, true}, // 跳变后刷新的不完整包
+	}
+
+	for i, w := range want {
+		header, _, err := receiver.ReadPacket()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if header.Timestamp != sender.TimestampAt(uint64(w.pos)) || header.Marker != w.marker {
+			t.Errorf("packet %d: timestamp %d marker %v, want %d %v",
+				i, header.Timestamp, header.Marker, sender.TimestampAt(uint64(w.pos)), w.marker)
+		}
+	}
+}
+
+func TestReceiverIgnoresOtherSources(t *testing.T) {
+	send, recv := nettest.Loopback(t)
+	format := pcm.NewFormat(pcm.SampleTypeInt, 48000, 1, 16, audioclient.KSAUDIO_SPEAKER_MONO)
+	receiver := NewReceiver(recv, nil, &format, L16)
+
+	buf := make([]byte, 12+4)
+	for _, ssrc := range []uint32{1, 2, 1} {
+		h := Header{PayloadType: PayloadTypeDynamic, SSRC: ssrc, SequenceNumber: uint16(ssrc)}
+		h.Marshal(buf)
+		if _, err := send.Write(buf); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for i := 0; i < 2; i++ {
+		header, _, err := receiver.ReadPacket()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if header.SSRC != 1 {
+			t.Fatalf("packet %d from ssrc %d, want 1", i, header.SSRC)
+		}
+	}
+}
+
+func TestSenderReportRoundTrip(t *testing.T) {
+	now := time.Unix(1700000000, 250000000)
+	sr := SenderReport{SSRC: 7, NTPTime: ToNTP(now), RTPTime: 48000, PacketCount: 3, OctetCount: 5760}
+
+	buf, err := sr.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got SenderReport
+	if err = got.Unmarshal(buf); err != nil {
+		t.Fatal(err)
+	}
+
+	if got.SSRC != sr.SSRC || got.NTPTime != sr.NTPTime || got.RTPTime != sr.RTPTime ||
+		got.PacketCount != sr.PacketCount || got.OctetCount != sr.OctetCount {
+		t.Fatalf("Unmarshal = %+v, want %+v", got, sr)
+	}
+
+	if d := FromNTP(sr.NTPTime).Sub(now); d < -time.Microsecond || d > time.Microsecond {
+		t.Fatalf("NTP round trip off by %v", d)
+	}
+}
+
+func TestSenderReportTime(t *testing.T) {
+	send, _ := nettest.Loopback(t)
+	format := pcm.NewFormat(pcm.SampleTypeInt, 48000, 2, 16, audioclient.KSAUDIO_SPEAKER_STEREO)
+
+	sender, err := NewSender(send, nil, &format, L16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sender.SetPacketTime(5 * time.Millisecond)
+
+	// QPC 1 秒对应 ref，数据包在 QPC 1.5 秒 (ref 之后 0.5 秒) 时捕获
+	ref := time.Unix(1700000000, 0)
+	sender.SetTimeReference(10000000, ref)
+	if err = sender.Send(ramp(0, 240), 4800, 15000000); err != nil {
+		t.Fatal(err)
+	}
+
+	// NTP 时间由捕获时的 QPC 换算，与调用 Send 的时间无关
+	now := ref.Add(500*time.Millisecond + 250*time.Millisecond)
+	report := sender.report(now)
+	if report.NTPTime != ToNTP(now) {
+		t.Fatalf("NTPTime = %X, want %X", report.NTPTime, ToNTP(now))
+	}
+	if want := sender.TimestampAt(4800) + 12000; report.RTPTime != want {
+		t.Fatalf("RTPTime = %d, want %d (250 ms after the packet)", report.RTPTime, want)
+	}
+}
+
+func TestNewSenderFormat(t *testing.T) {
+	stereo := pcm.NewFormat(pcm.SampleTypeInt, 48000, 2, 16, audioclient.KSAUDIO_SPEAKER_STEREO)
+	wide := pcm.NewFormat(pcm.SampleTypeFloat, 48000, 512, 32, 0)
+
+	for _, tt := range []struct {
+		name          string
+		format        audioclient.WAVEFORMATEXTENSIBLE
+		payloadFormat PayloadFormat
+	}{
+		{"zero format", audioclient.WAVEFORMATEXTENSIBLE{}, L16},
+		{"unknown payload", stereo, PayloadFormat(99)},
+		{"frame exceeds payload", wide, L24},
+	} {
+		if _, err := NewSender(nil, nil, &tt.format, tt.payloadFormat); !errors.Is(err, ErrFormat) {
+			t.Errorf("%s: err = %v, want ErrFormat", tt.name, err)
+		}
+	}
+}
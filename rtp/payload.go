@@ -0,0 +1,166 @@
+package rtp
+
+import (
+	"encoding/binary"
+
+	"github.com/cyberxnomad/wasapi/pcm"
+)
+
+// PayloadFormat 为 RTP 负载格式 (RFC 3551, RFC 3190)。
+type PayloadFormat uint32
+
+const (
+	L16  PayloadFormat = iota // 16 位有符号整数，网络字节序
+	L24                       // 24 位有符号整数，网络字节序
+	PCMU                      // G.711 μ-law
+)
+
+// 静态负载类型 (RFC 3551)
+const (
+	PayloadTypePCMU      uint8 = 0
+	PayloadTypeL16Stereo uint8 = 10 // 44100 Hz 双声道
+	PayloadTypeL16Mono   uint8 = 11 // 44100 Hz 单声道
+	PayloadTypeDynamic   uint8 = 96
+)
+
+func (f PayloadFormat) String() string {
+	switch f {
+	case L16:
+		return "L16"
+	case L24:
+		return "L24"
+	case PCMU:
+		return "PCMU"
+	}
+
+	return "unknown"
+}
+
+// SampleSize 方法返回每个采样的字节数。
+func (f PayloadFormat) SampleSize() int {
+	switch f {
+	case L16:
+		return 2
+	case L24:
+		return 3
+	case PCMU:
+		return 1
+	}
+
+	return 0
+}
+
+// PayloadType 方法返回格式对应的默认负载类型，没有静态类型时返回 PayloadTypeDynamic。
+func (f PayloadFormat) PayloadType(samplesPerSec uint32, channels uint16) uint8 {
+	switch {
+	case f == PCMU && samplesPerSec == 8000 && channels == 1:
+		return PayloadTypePCMU
+	case f == L16 && samplesPerSec == 44100 && channels == 2:
+		return PayloadTypeL16Stereo
+	case f == L16 && samplesPerSec == 44100 && channels == 1:
+		return PayloadTypeL16Mono
+	}
+
+	return PayloadTypeDynamic
+}
+
+// Encode 方法将 float32 采样编码为负载，返回写入的字节数。
+func (f PayloadFormat) Encode(src []float32, dst []byte) (n int) {
+	size := f.SampleSize()
+	if size == 0 {
+		return
+	}
+
+	count := min(len(src), len(dst)/size)
+
+	switch f {
+	case L16:
+		for i := 0; i < count; i++ {
+			binary.BigEndian.PutUint16(dst[i*2:], uint16(int16(pcm.Quantize(src[i], 1<<15))))
+		}
+	case L24:
+		for i := 0; i < count; i++ {
+			v := int32(pcm.Quantize(src[i], 1<<23))
+			dst[i*3] = byte(v >> 16)
+			dst[i*3+1] = byte(v >> 8)
+			dst[i*3+2] = byte(v)
+		}
+	case PCMU:
+		for i := 0; i < count; i++ {
+			dst[i] = linearToULaw(int16(pcm.Quantize(src[i], 1<<15)))
+		}
+	}
+
+	n = count * size
+
+	return
+}
+
+// Decode 方法将负载解码为 float32 采样，返回写入的采样数。
+func (f PayloadFormat) Decode(src []byte, dst []float32) (n int) {
+	size := f.SampleSize()
+	if size == 0 {
+		return
+	}
+
+	n = min(len(src)/size, len(dst))
+
+	switch f {
+	case L16:
+		for i := 0; i < n; i++ {
+			dst[i] = float32(int16(binary.BigEndian.Uint16(src[i*2:]))) / (1 << 15)
+		}
+	case L24:
+		for i := 0; i < n; i++ {
+			v := int32(int8(src[i*3]))<<16 | int32(src[i*3+1])<<8 | int32(src[i*3+2])
+			dst[i] = float32(v) / (1 << 23)
+		}
+	case PCMU:
+		for i := 0; i < n; i++ {
+			dst[i] = float32(uLawToLinear(src[i])) / (1 << 15)
+		}
+	}
+
+	return
+}
+
+// G.711 μ-law 编码
+func linearToULaw(sample int16) byte {
+	const (
+		bias = 0x84
+		clip = 32635
+	)
+
+	v := int32(sample)
+	sign := byte(0)
+	if v < 0 {
+		sign = 0x80
+		v = -v
+	}
+
+	v = min(v, clip) + bias
+
+	exponent := byte(7)
+	for mask := int32(0x4000); v&mask == 0 && exponent > 0; mask >>= 1 {
+		exponent--
+	}
+
+	mantissa := byte(v>>(exponent+3)) & 0x0F
+
+	return ^(sign | exponent<<4 | mantissa)
+}
+
+// G.711 μ-law 解码
+func uLawToLinear(u byte) int16 {
+	u = ^u
+	exponent := (u >> 4) & 0x07
+	mantissa := int32(u & 0x0F)
+	v := ((mantissa << 3) + 0x84) << exponent
+	v -= 0x84
+
+	if u&0x80 != 0 {
+		return int16(-v)
+	}
+
+	return int16(v)
+}
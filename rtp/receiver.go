@@ -0,0 +1,187 @@
+package rtp
+
+import (
+	"errors"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/cyberxnomad/wasapi/audioclient"
+	"github.com/cyberxnomad/wasapi/jitter"
+	"github.com/cyberxnomad/wasapi/pcm"
+)
+
+// Receiver 接收 RTP 音频并将负载转换为呈现流的格式。
+//
+// 接收端锁定第一个收到的同步源，其他同步源的包将被忽略。
+type Receiver struct {
+	conn net.PacketConn
+	ctrl net.PacketConn
+
+	format        audioclient.WAVEFORMATEXTENSIBLE
+	payloadFormat PayloadFormat
+	payloadType   int
+	channels      int
+
+	buf     []byte
+	samples []float32
+
+	// 以下字段由 mu 保护，Run 所在的协程与调用 SSRC、ReadReport 的协程并发访问
+	mu         sync.Mutex
+	ssrc       uint32
+	locked     bool
+	report     SenderReport
+	haveReport bool
+}
+
+// NewReceiver 创建 RTP 接收端，conn 用于接收 RTP，ctrl 用于接收 RTCP，可以为 nil。
+//
+// format 为输出数据的格式，其声道数及采样率须与发送端一致。
+func NewReceiver(conn, ctrl net.PacketConn, format *audioclient.WAVEFORMATEXTENSIBLE, payloadFormat PayloadFormat) *Receiver {
+	return &Receiver{
+		conn:          conn,
+		ctrl:          ctrl,
+		format:        *format,
+		payloadFormat: payloadFormat,
+		payloadType:   -1,
+		channels:      int(format.Format.Channels),
+		buf:           make([]byte, 65536),
+	}
+}
+
+// SetPayloadType 方法设置接收的负载类型，默认接收任意负载类型。
+func (r *Receiver) SetPayloadType(payloadType uint8) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.payloadType = int(payloadType & 0x7F)
+}
+
+// SSRC 方法返回锁定的同步源标识。
+func (r *Receiver) SSRC() (ssrc uint32, ok bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.ssrc, r.locked
+}
+
+// ReadPacket 方法读取下一个 RTP 包，data 为转换为输出格式的音频数据。
+func (r *Receiver) ReadPacket() (header Header, data []byte, err error) {
+	for {
+		var (
+			n       int
+			payload []byte
+		)
+
+		if n, _, err = r.conn.ReadFrom(r.buf); err != nil {
+			return
+		}
+
+		if payload, err = header.Unmarshal(r.buf[:n]); err != nil {
+			continue
+		}
+
+		if !r.accept(&header) {
+			continue
+		}
+
+		frames := len(payload) / (r.channels * r.payloadFormat.SampleSize())
+		if frames == 0 {
+			continue
+		}
+
+		if len(r.samples) < frames*r.channels {
+			r.samples = make([]float32, frames*r.channels)
+		}
+		samples := r.samples[:frames*r.channels]
+		r.payloadFormat.Decode(payload, samples)
+
+		data = make([]byte, frames*int(r.format.Format.BlockAlign))
+		_, err = pcm.Encode(&r.format, samples, data)
+
+		return
+	}
+}
+
+// 检查负载类型及同步源，首个通过检查的包锁定同步源
+func (r *Receiver) accept(header *Header) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.payloadType >= 0 && int(header.PayloadType) != r.payloadType {
+		return false
+	}
+
+	if !r.locked {
+		r.locked = true
+		r.ssrc = header.SSRC
+	}
+
+	return header.SSRC == r.ssrc
+}
+
+// Run 方法持续接收 RTP 包并写入抖动缓冲区，直到读取出错。
+func (r *Receiver) Run(buffer *jitter.Buffer) (err error) {
+	for {
+		var (
+			header Header
+			data   []byte
+		)
+
+		if header, data, err = r.ReadPacket(); err != nil {
+			return
+		}
+
+		if err = buffer.Push(header.SequenceNumber, header.Timestamp, data, time.Now()); err != nil {
+			return
+		}
+	}
+}
+
+// ReadReport 方法从 RTCP 连接读取下一个发送端报告，并记录时间戳映射。
+func (r *Receiver) ReadReport() (report SenderReport, err error) {
+	if r.ctrl == nil {
+		err = errors.New("no rtcp connection")
+		return
+	}
+
+	buf := make([]byte, 1500)
+	for {
+		var n int
+		if n, _, err = r.ctrl.ReadFrom(buf); err != nil {
+			return
+		}
+
+		if report.Unmarshal(buf[:n]) != nil {
+			continue
+		}
+
+		r.mu.Lock()
+		if r.locked && report.SSRC != r.ssrc {
+			r.mu.Unlock()
+			continue
+		}
+
+		r.report = report
+		r.haveReport = true
+		r.mu.Unlock()
+
+		return
+	}
+}
+
+// TimeAt 方法根据最近的发送端报告将 RTP 时间戳换算为发送端挂钟时间。
+func (r *Receiver) TimeAt(timestamp uint32) (t time.Time, ok bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.haveReport {
+		return
+	}
+
+	delta := time.Duration(int32(timestamp-r.report.RTPTime)) * time.Second / time.Duration(r.format.Format.SamplesPerSec)
+	t = FromNTP(r.report.NTPTime).Add(delta)
+	ok = true
+
+	return
+}
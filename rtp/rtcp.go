@@ -0,0 +1,140 @@
+package rtp
+
+import (
+	"encoding/binary"
+	"errors"
+	"time"
+)
+
+var ErrNoSenderReport = errors.New("no sender report")
+
+const rtcpTypeSR = 200
+
+// NTP 纪元 (1900) 与 Unix 纪元 (1970) 之间的秒数
+const ntpEpochOffset = 2208988800
+
+// ToNTP 将时间转换为 64 位 NTP 时间戳。
+func ToNTP(t time.Time) uint64 {
+	sec := uint64(t.Unix() + ntpEpochOffset)
+	frac := uint64(t.Nanosecond()) << 32 / uint64(time.Second)
+
+	return sec<<32 | frac
+}
+
+// FromNTP 将 64 位 NTP 时间戳转换为时间。
+func FromNTP(ntp uint64) time.Time {
+	sec := int64(ntp>>32) - ntpEpochOffset
+	nsec := (ntp & 0xFFFFFFFF) * uint64(time.Second) >> 32
+
+	return time.Unix(sec, int64(nsec))
+}
+
+// ReceptionReport 为 RTCP 报告块。
+type ReceptionReport struct {
+	SSRC             uint32
+	FractionLost     uint8
+	TotalLost        uint32 // 24 位
+	HighestSequence  uint32
+	Jitter           uint32
+	LastSenderReport uint32
+	DelaySinceLastSR uint32
+}
+
+// SenderReport 为 RTCP 发送端报告 (RFC 3550 6.4.1)，
+// 用于将 RTP 时间戳映射到 NTP 挂钟时间。
+type SenderReport struct {
+	SSRC        uint32
+	NTPTime     uint64
+	RTPTime     uint32
+	PacketCount uint32
+	OctetCount  uint32
+	Reports     []ReceptionReport
+}
+
+// Marshal 方法编码发送端报告。
+func (sr *SenderReport) Marshal() (buf []byte, err error) {
+	if len(sr.Reports) > 31 {
+		err = errors.New("too many reception reports")
+		return
+	}
+
+	buf = make([]byte, 28+24*len(sr.Reports))
+
+	buf[0] = 2<<6 | uint8(len(sr.Reports))
+	buf[1] = rtcpTypeSR
+	binary.BigEndian.PutUint16(buf[2:4], uint16(len(buf)/4-1))
+	binary.BigEndian.PutUint32(buf[4:8], sr.SSRC)
+	binary.BigEndian.PutUint64(buf[8:16], sr.NTPTime)
+	binary.BigEndian.PutUint32(buf[16:20], sr.RTPTime)
+	binary.BigEndian.PutUint32(buf[20:24], sr.PacketCount)
+	binary.BigEndian.PutUint32(buf[24:28], sr.OctetCount)
+
+	for i, rr := range sr.Reports {
+		b := buf[28+24*i:]
+		binary.BigEndian.PutUint32(b[0:4], rr.SSRC)
+		binary.BigEndian.PutUint32(b[4:8], uint32(rr.FractionLost)<<24|rr.TotalLost&0xFFFFFF)
+		binary.BigEndian.PutUint32(b[8:12], rr.HighestSequence)
+		binary.BigEndian.PutUint32(b[12:16], rr.Jitter)
+		binary.BigEndian.PutUint32(b[16:20], rr.LastSenderReport)
+		binary.BigEndian.PutUint32(b[20:24], rr.DelaySinceLastSR)
+	}
+
+	return
+}
+
+// Unmarshal 方法从 (复合) RTCP 包中解码第一个发送端报告。
+func (sr *SenderReport) Unmarshal(buf []byte) (err error) {
+	for len(buf) >= 4 {
+		if buf[0]>>6 != 2 {
+			err = ErrVersion
+			return
+		}
+
+		size := 4 * (int(binary.BigEndian.Uint16(buf[2:4])) + 1)
+		if len(buf) < size {
+			err = ErrShortPacket
+			return
+		}
+
+		if buf[1] == rtcpTypeSR {
+			return sr.unmarshal(buf[:size])
+		}
+
+		buf = buf[size:]
+	}
+
+	err = ErrNoSenderReport
+
+	return
+}
+
+func (sr *SenderReport) unmarshal(buf []byte) (err error) {
+	rc := int(buf[0] & 0x1F)
+	if len(buf) < 28+24*rc {
+		err = ErrShortPacket
+		return
+	}
+
+	sr.SSRC = binary.BigEndian.Uint32(buf[4:8])
+	sr.NTPTime = binary.BigEndian.Uint64(buf[8:16])
+	sr.RTPTime = binary.BigEndian.Uint32(buf[16:20])
+	sr.PacketCount = binary.BigEndian.Uint32(buf[20:24])
+	sr.OctetCount = binary.BigEndian.Uint32(buf[24:28])
+
+	sr.Reports = sr.Reports[:0]
+	for i := 0; i < rc; i++ {
+		b := buf[28+24*i:]
+		lost := binary.BigEndian.Uint32(b[4:8])
+		sr.Reports = append(sr.Reports, ReceptionReport{
+			SSRC:             binary.BigEndian.Uint32(b[0:4]),
+			FractionLost:     uint8(lost >> 24),
+			TotalLost:        lost & 0xFFFFFF,
+			HighestSequence:  binary.BigEndian.Uint32(b[8:12]),
+			Jitter:           binary.BigEndian.Uint32(b[12:16]),
+			LastSenderReport: binary.BigEndian.Uint32(b[16:20]),
+			DelaySinceLastSR: binary.BigEndian.Uint32(b[20:24]),
+		})
+	}
+
+	return
+}
@@ -0,0 +1,237 @@
+package rtp
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cyberxnomad/wasapi/audioclient"
+)
+
+var ErrInvalidSDP = errors.New("invalid sdp")
+
+// Description 为单个音频流的 SDP 会话描述 (RFC 4566)。
+type Description struct {
+	SessionName   string
+	SessionID     uint64
+	Origin        string // 发送端地址 (o=)
+	Address       string // 连接地址 (c=)
+	TTL           uint8  // 多播 TTL，单播时为 0
+	Port          int
+	PayloadType   uint8
+	PayloadFormat PayloadFormat
+	SamplesPerSec uint32
+	Channels      uint16
+	PacketTime    time.Duration
+	Attributes    []string // 额外的媒体级属性，不含 "a=" 前缀
+}
+
+// NewDescription 根据格式创建 SDP 描述，地址及端口需由调用方填写。
+func NewDescription(format *audioclient.WAVEFORMATEXTENSIBLE, payloadFormat PayloadFormat, payloadType uint8) Description {
+	return Description{
+		SessionName:   "wasapi",
+		SessionID:     uint64(time.Now().Unix()),
+		PayloadType:   payloadType,
+		PayloadFormat: payloadFormat,
+		SamplesPerSec: format.Format.SamplesPerSec,
+		Channels:      format.Format.Channels,
+	}
+}
+
+// String 方法生成 SDP 文本。
+func (d *Description) String() string {
+	var b strings.Builder
+
+	line := func(format string, args ...any) {
+		fmt.Fprintf(&b, format, args...)
+		b.WriteString("\r\n")
+	}
+
+	line("v=0")
+	line("o=- %d %d IN %s %s", d.SessionID, d.SessionID, addrType(d.Origin), d.Origin)
+	line("s=%s", d.SessionName)
+
+	if d.TTL > 0 && addrType(d.Address) == "IP4" {
+		line("c=IN IP4 %s/%d", d.Address, d.TTL)
+	} else {
+		line("c=IN %s %s", addrType(d.Address), d.Address)
+	}
+
+	line("t=0 0")
+	line("m=audio %d RTP/AVP %d", d.Port, d.PayloadType)
+
+	if d.Channels > 1 {
+		line("a=rtpmap:%d %s/%d/%d", d.PayloadType, d.PayloadFormat, d.SamplesPerSec, d.Channels)
+	} else {
+		line("a=rtpmap:%d %s/%d", d.PayloadType, d.PayloadFormat, d.SamplesPerSec)
+	}
+
+	if d.PacketTime > 0 {
+		line("a=ptime:%s", strconv.FormatFloat(d.PacketTime.Seconds()*1000, 'f', -1, 64))
+	}
+
+	for _, attr := range d.Attributes {
+		line("a=%s", attr)
+	}
+
+	return b.String()
+}
+
+// ParseDescription 解析 String 方法生成的 SDP 文本，仅解析第一个音频媒体描述。
+//
+// 无法识别的媒体级属性 (rtpmap 及 ptime 以外) 按原样保存在 Attributes 中。
+func ParseDescription(text string) (d Description, err error) {
+	var media, rtpmap bool
+
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSuffix(line, "\r")
+		if line == "" {
+			continue
+		}
+
+		if len(line) < 2 || line[1] != '=' {
+			err = ErrInvalidSDP
+			return
+		}
+
+		value := line[2:]
+
+		switch line[0] {
+		case 'v':
+			if value != "0" {
+				err = ErrInvalidSDP
+				return
+			}
+		case 'o':
+			// o=<username> <sess-id> <sess-version> <nettype> <addrtype> <unicast-address>
+			fields := strings.Fields(value)
+			if len(fields) != 6 {
+				err = ErrInvalidSDP
+				return
+			}
+
+			if d.SessionID, err = strconv.ParseUint(fields[1], 10, 64); err != nil {
+				return
+			}
+			d.Origin = fields[5]
+		case 's':
+			d.SessionName = value
+		case 'c':
+			// c=<nettype> <addrtype> <connection-address>[/<ttl>]
+			fields := strings.Fields(value)
+			if len(fields) != 3 {
+				err = ErrInvalidSDP
+				return
+			}
+
+			addr, ttl, found := strings.Cut(fields[2], "/")
+			d.Address = addr
+			if found && fields[1] == "IP4" {
+				var v uint64
+				if v, err = strconv.ParseUint(ttl, 10, 8); err != nil {
+					return
+				}
+				d.TTL = uint8(v)
+			}
+		case 'm':
+			if media {
+				return
+			}
+
+			// m=audio <port> RTP/AVP <fmt>
+			fields := strings.Fields(value)
+			if len(fields) < 4 || fields[0] != "audio" {
+				err = ErrInvalidSDP
+				return
+			}
+
+			if d.Port, err = strconv.Atoi(fields[1]); err != nil {
+				return
+			}
+
+			var pt uint64
+			if pt, err = strconv.ParseUint(fields[3], 10, 7); err != nil {
+				return
+			}
+			d.PayloadType = uint8(pt)
+			media = true
+		case 'a':
+			if !media {
+				continue
+			}
+
+			if v, ok := strings.CutPrefix(value, "rtpmap:"); ok {
+				if err = d.parseRTPMap(v); err != nil {
+					return
+				}
+				rtpmap = true
+			} else if v, ok := strings.CutPrefix(value, "ptime:"); ok {
+				var ms float64
+				if ms, err = strconv.ParseFloat(v, 64); err != nil {
+					return
+				}
+				d.PacketTime = time.Duration(ms * float64(time.Millisecond))
+			} else {
+				d.Attributes = append(d.Attributes, value)
+			}
+		}
+	}
+
+	if !media || !rtpmap {
+		err = ErrInvalidSDP
+	}
+
+	return
+}
+
+// 解析 rtpmap 属性: <payload type> <encoding name>/<clock rate>[/<channels>]
+func (d *Description) parseRTPMap(value string) (err error) {
+	pt, encoding, found := strings.Cut(value, " ")
+	if !found || pt != strconv.Itoa(int(d.PayloadType)) {
+		return ErrInvalidSDP
+	}
+
+	fields := strings.Split(encoding, "/")
+	if len(fields) < 2 {
+		return ErrInvalidSDP
+	}
+
+	switch strings.ToUpper(fields[0]) {
+	case "L16":
+		d.PayloadFormat = L16
+	case "L24":
+		d.PayloadFormat = L24
+	case "PCMU":
+		d.PayloadFormat = PCMU
+	default:
+		return ErrInvalidSDP
+	}
+
+	rate, err := strconv.ParseUint(fields[1], 10, 32)
+	if err != nil {
+		return
+	}
+	d.SamplesPerSec = uint32(rate)
+
+	d.Channels = 1
+	if len(fields) > 2 {
+		var channels uint64
+		if channels, err = strconv.ParseUint(fields[2], 10, 16); err != nil {
+			return
+		}
+		d.Channels = uint16(channels)
+	}
+
+	return
+}
+
+func addrType(addr string) string {
+	if ip := net.ParseIP(addr); ip != nil && ip.To4() == nil {
+		return "IP6"
+	}
+
+	return "IP4"
+}
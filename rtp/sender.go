@@ -0,0 +1,307 @@
+package rtp
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/cyberxnomad/wasapi/audioclient"
+	"github.com/cyberxnomad/wasapi/clock"
+	"github.com/cyberxnomad/wasapi/pcm"
+)
+
+// 负载的最大字节数，避免 IP 分片
+const maxPayloadSize = 1200
+
+var ErrFormat = errors.New("unsupported rtp sender format")
+
+// Sender 将捕获的音频打包为 RTP 发送。
+//
+// RTP 时间戳由 GetBuffer 返回的 devicePosition (帧) 加上随机偏移得到，
+// 发送端报告中的 NTP 时间由 QPCPosition 按同一时刻的 QPC 位置与挂钟时间换算，见 SetTimeReference。
+type Sender struct {
+	mu sync.Mutex
+
+	conn net.Conn
+	ctrl net.Conn
+
+	format        audioclient.WAVEFORMATEXTENSIBLE
+	payloadFormat PayloadFormat
+	payloadType   uint8
+	packetFrames  int
+	channels      int
+
+	ssrc     uint32
+	seq      uint16
+	tsOffset uint32
+
+	started    bool
+	marker     bool
+	nextPos    uint64 // 下一次 Send 预期的设备位置
+	pending    []float32
+	pendingPos uint64
+	pendingQPC uint64
+	samples    []float32
+
+	// QPC 与挂钟时间的参考点
+	haveRef  bool
+	refQPC   uint64
+	refTime  time.Time
+	lastTS   uint32
+	lastTime time.Time
+
+	packetCount uint32
+	octetCount  uint32
+
+	buf []byte
+}
+
+// NewSender 创建 RTP 发送端，conn 用于发送 RTP，ctrl 用于发送 RTCP，可以为 nil。
+//
+// 默认包时长为 20 毫秒，受限于最大负载长度。format 无法解码或一帧超出最大负载长度时返回 ErrFormat。
+func NewSender(conn, ctrl net.Conn, format *audioclient.WAVEFORMATEXTENSIBLE, payloadFormat PayloadFormat) (s *Sender, err error) {
+	channels := int(format.Format.Channels)
+	if payloadFormat.SampleSize() == 0 || channels == 0 || channels*payloadFormat.SampleSize() > maxPayloadSize ||
+		format.Format.SamplesPerSec == 0 || format.Format.BlockAlign == 0 || pcm.SampleTypeOf(format) == pcm.SampleTypeUnknown {
+		err = ErrFormat
+		return
+	}
+
+	s = &Sender{
+		conn:          conn,
+		ctrl:          ctrl,
+		format:        *format,
+		payloadFormat: payloadFormat,
+		payloadType:   payloadFormat.PayloadType(format.Format.SamplesPerSec, format.Format.Channels),
+		channels:      channels,
+		ssrc:          random32(),
+		seq:           uint16(random32()),
+		tsOffset:      random32(),
+		marker:        true,
+	}
+	s.SetPacketTime(20 * time.Millisecond)
+
+	return
+}
+
+// SSRC 方法返回同步源标识。
+func (s *Sender) SSRC() uint32 {
+	return s.ssrc
+}
+
+// PayloadType 方法返回负载类型。
+func (s *Sender) PayloadType() uint8 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.payloadType
+}
+
+// SetPayloadType 方法设置负载类型。
+func (s *Sender) SetPayloadType(payloadType uint8) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.payloadType = payloadType & 0x7F
+}
+
+// SetPacketTime 方法设置每个 RTP 包包含的音频时长，受限于最大负载长度。
+func (s *Sender) SetPacketTime(packetTime time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	frames := int(packetTime * time.Duration(s.format.Format.SamplesPerSec) / time.Second)
+	limit := maxPayloadSize / (s.channels * s.payloadFormat.SampleSize())
+
+	s.packetFrames = min(max(frames, 1), limit)
+}
+
+// SetTimeReference 方法设置同一时刻的 QPC 位置 (100 纳秒单位) 与挂钟时间，用于将数据包的 QPCPosition 换算为
+// 发送端报告中的 NTP 时间。
+//
+// 未设置时，在 Windows 上于收到第一个数据包时读取性能计数器及挂钟时间；其他平台上以第一个数据包的
+// QPCPosition 对应收到该数据包的时间，NTP 时间将晚于实际捕获时间。
+func (s *Sender) SetTimeReference(QPCPosition uint64, t time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.haveRef = true
+	s.refQPC = QPCPosition
+	s.refTime = t
+}
+
+// TimestampAt 方法返回设备位置对应的 RTP 时间戳。
+func (s *Sender) TimestampAt(devicePosition uint64) uint32 {
+	return s.tsOffset + uint32(devicePosition)
+}
+
+// Send 方法发送一个捕获数据包。
+//
+// data 为 GetBuffer 返回的数据，devicePosition 及 QPCPosition 为 GetBuffer 的对应返回值。
+// 不足一个 RTP 包的数据会暂存到下一次调用，设备位置不连续时将先发送暂存的数据并设置标记位。
+func (s *Sender) Send(data []byte, devicePosition uint64, QPCPosition uint64) (err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	frames := pcm.Frames(&s.format, len(data))
+	if frames == 0 {
+		return
+	}
+
+	if !s.started {
+		s.started = true
+
+		if !s.haveRef {
+			s.haveRef = true
+			if qpc, e := clock.QPCPosition(); e == nil {
+				s.refQPC, s.refTime = qpc, time.Now()
+			} else {
+				s.refQPC, s.refTime = QPCPosition, time.Now()
+			}
+		}
+	} else if devicePosition != s.nextPos {
+		if err = s.flush(); err != nil {
+			return
+		}
+
+		s.marker = true
+	}
+	s.nextPos = devicePosition + uint64(frames)
+
+	if len(s.pending) == 0 {
+		s.pendingPos = devicePosition
+		s.pendingQPC = QPCPosition
+	}
+
+	if cap(s.samples) < frames*s.channels {
+		s.samples = make([]float32, frames*s.channels)
+	}
+	samples := s.samples[:frames*s.channels]
+	if _, err = pcm.Decode(&s.format, data, samples); err != nil {
+		return
+	}
+	s.pending = append(s.pending, samples...)
+
+	sent := 0
+	for size := s.packetFrames * s.channels; len(s.pending)-sent >= size; sent += size {
+		if err = s.sendPacket(s.pending[sent : sent+size]); err != nil {
+			break
+		}
+	}
+
+	// 将剩余的数据移到开头，复用底层数组
+	s.pending = s.pending[:copy(s.pending, s.pending[sent:])]
+
+	return
+}
+
+// Flush 方法立即发送暂存的数据。
+func (s *Sender) Flush() (err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.flush()
+}
+
+// SendReport 方法发送 RTCP 发送端报告。
+func (s *Sender) SendReport() (err error) {
+	s.mu.Lock()
+	report := s.report(time.Now())
+	s.mu.Unlock()
+
+	if s.ctrl == nil {
+		return
+	}
+
+	var buf []byte
+	if buf, err = report.Marshal(); err != nil {
+		return
+	}
+
+	_, err = s.ctrl.Write(buf)
+
+	return
+}
+
+// Report 方法返回当前时刻的发送端报告。
+func (s *Sender) Report() SenderReport {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.report(time.Now())
+}
+
+func (s *Sender) report(now time.Time) SenderReport {
+	rtpTime := s.lastTS
+	if !s.lastTime.IsZero() {
+		rtpTime += uint32(now.Sub(s.lastTime).Seconds() * float64(s.format.Format.SamplesPerSec))
+	}
+
+	return SenderReport{
+		SSRC:        s.ssrc,
+		NTPTime:     ToNTP(now),
+		RTPTime:     rtpTime,
+		PacketCount: s.packetCount,
+		OctetCount:  s.octetCount,
+	}
+}
+
+func (s *Sender) flush() (err error) {
+	if len(s.pending) == 0 {
+		return
+	}
+
+	err = s.sendPacket(s.pending)
+	s.pending = s.pending[:0]
+
+	return
+}
+
+// 发送 samples 中的采样并推进暂存位置
+func (s *Sender) sendPacket(samples []float32) (err error) {
+	frames := len(samples) / s.channels
+
+	header := Header{
+		Marker:         s.marker,
+		PayloadType:    s.payloadType,
+		SequenceNumber: s.seq,
+		Timestamp:      s.TimestampAt(s.pendingPos),
+		SSRC:           s.ssrc,
+	}
+
+	size := header.Size() + len(samples)*s.payloadFormat.SampleSize()
+	if cap(s.buf) < size {
+		s.buf = make([]byte, size)
+	}
+	buf := s.buf[:size]
+
+	n, _ := header.Marshal(buf)
+	n += s.payloadFormat.Encode(samples, buf[n:])
+
+	if _, err = s.conn.Write(buf[:n]); err != nil {
+		return
+	}
+
+	// QPCPosition 以 100 纳秒为单位
+	s.lastTS = header.Timestamp
+	s.lastTime = s.refTime.Add(time.Duration(s.pendingQPC-s.refQPC) * 100)
+
+	s.marker = false
+	s.seq++
+	s.packetCount++
+	s.octetCount += uint32(n - header.Size())
+	s.pendingPos += uint64(frames)
+	s.pendingQPC += uint64(frames) * 10000000 / uint64(s.format.Format.SamplesPerSec)
+
+	return
+}
+
+func random32() uint32 {
+	var b [4]byte
+	rand.Read(b[:])
+
+	return binary.BigEndian.Uint32(b[:])
+}
@@ -0,0 +1,360 @@
+package spectrum
+
+import (
+	"errors"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/cyberxnomad/wasapi/audioclient"
+	"github.com/cyberxnomad/wasapi/fft"
+	"github.com/cyberxnomad/wasapi/internal/dsp"
+)
+
+var ErrOverlap = errors.New("overlap must be in [0, 1)")
+
+const (
+	Floor            = -140.0      // 电平下限 (dBFS)
+	DefaultPeakHold  = time.Second // 默认的峰值保持时间
+	DefaultPeakDecay = 20.0        // 默认的峰值下降速率 (dB/秒)
+	minBandFrequency = 20.0        // 频带分析的最低频率 (Hz)
+	maxBandFrequency = 20000.0     // 频带分析的最高频率 (Hz)
+	bandReference    = 1000.0      // 频带中心频率的参考 (Hz)
+)
+
+// Bandwidth 表示频带划分方式。
+type Bandwidth uint32
+
+const (
+	BandNone        Bandwidth = 0 // 不划分频带
+	BandOctave      Bandwidth = 1 // 倍频程
+	BandThirdOctave Bandwidth = 3 // 1/3 倍频程
+)
+
+// Band 为一个频带的电平。
+type Band struct {
+	Center float64 // 中心频率 (Hz)
+	Low    float64 // 下边界频率 (Hz)
+	High   float64 // 上边界频率 (Hz)
+	Level  float64 // 电平 (dBFS)
+	Peak   float64 // 峰值保持 (dBFS)
+}
+
+// Frame 为一次读取的频谱。
+type Frame struct {
+	Magnitudes []float64 // 各频点的幅度 (dBFS)，满幅正弦波的峰值约为 0 dBFS
+	Peaks      []float64 // 各频点的峰值保持 (dBFS)
+	Bands      []Band    // 各频带的电平，未设置频带时为空
+	Count      int       // 自上次读取以来计算的 STFT 帧数
+}
+
+type band struct {
+	Band
+	first, last int // 包含的频点范围
+	hold        time.Duration
+}
+
+// Analyzer 是基于重叠 STFT 的频谱分析器。
+//
+// 捕获线程调用 Process 写入音频，各声道混合为单声道后按跳跃长度计算加窗 FFT；
+// 显示线程以固定帧率调用 Snapshot，得到自上次读取以来各 STFT 帧的功率平均。
+type Analyzer struct {
+	mu sync.Mutex
+
+	format   audioclient.WAVEFORMATEXTENSIBLE
+	channels int
+	size     int
+	hop      int
+	window   []float64
+	scale    float64 // 幅度归一化系数
+	enbw     float64 // 等效噪声带宽 (频点数)
+	real     *fft.Real
+
+	ring    []float64 // 最近 size 个单声道采样，环形缓冲
+	ringPos int
+	pending int // 距下一次变换的采样数
+
+	frame    []float64
+	spectrum []complex128
+	power    []float64 // 自上次读取以来的功率累加
+	count    int
+	latest   []float64 // 最近一帧的功率
+
+	peaks     []float64
+	holds     []time.Duration
+	peakHold  time.Duration
+	peakDecay float64
+	hopTime   time.Duration
+
+	bandwidth Bandwidth
+	bands     []band
+
+	buf []float32
+}
+
+// NewAnalyzer 创建频谱分析器，size 为 FFT 长度 (2 的幂)，overlap 为相邻帧的重叠比例，例如 0.75。
+func NewAnalyzer(format *audioclient.WAVEFORMATEXTENSIBLE, size int, window fft.Window, overlap float64) (a *Analyzer, err error) {
+	if overlap < 0 || overlap >= 1 {
+		err = ErrOverlap
+		return
+	}
+
+	var real *fft.Real
+	if real, err = fft.NewReal(size); err != nil {
+		return
+	}
+
+	bins := size/2 + 1
+	hop := max(int(float64(size)*(1-overlap)), 1)
+
+	a = &Analyzer{
+		format:    *format,
+		channels:  int(format.Format.Channels),
+		size:      size,
+		hop:       hop,
+		window:    window.Coefficients(size),
+		real:      real,
+		ring:      make([]float64, size),
+		pending:   size,
+		frame:     make([]float64, size),
+		spectrum:  make([]complex128, bins),
+		power:     make([]float64, bins),
+		latest:    make([]float64, bins),
+		peaks:     make([]float64, bins),
+		holds:     make([]time.Duration, bins),
+		peakHold:  DefaultPeakHold,
+		peakDecay: DefaultPeakDecay,
+		hopTime:   time.Duration(float64(hop) / float64(format.Format.SamplesPerSec) * float64(time.Second)),
+	}
+
+	var sum, sumSq float64
+	for _, w := range a.window {
+		sum += w
+		sumSq += w * w
+	}
+
+	a.scale = 2 / sum
+	a.enbw = float64(size) * sumSq / (sum * sum)
+
+	for i := range a.peaks {
+		a.peaks[i] = Floor
+	}
+
+	return
+}
+
+// Frequencies 方法返回各频点的频率 (Hz)。
+func (a *Analyzer) Frequencies() (frequencies []float64) {
+	frequencies = make([]float64, a.size/2+1)
+	for i := range frequencies {
+		frequencies[i] = float64(i) * float64(a.format.Format.SamplesPerSec) / float64(a.size)
+	}
+
+	return
+}
+
+// FrameRate 方法返回每秒计算的 STFT 帧数。
+func (a *Analyzer) FrameRate() float64 {
+	return float64(a.format.Format.SamplesPerSec) / float64(a.hop)
+}
+
+// SetPeakHold 方法设置峰值保持时间及之后的下降速率 (dB/秒)。
+func (a *Analyzer) SetPeakHold(hold time.Duration, decay float64) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.peakHold = hold
+	a.peakDecay = decay
+}
+
+// SetBands 方法设置频带划分方式，频带范围为 20 Hz 至 20 kHz (不超过奈奎斯特频率)。
+func (a *Analyzer) SetBands(bandwidth Bandwidth) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.bandwidth = bandwidth
+	a.bands = nil
+	if bandwidth == BandNone {
+		return
+	}
+
+	b := float64(bandwidth)
+	nyquist := float64(a.format.Format.SamplesPerSec) / 2
+	binWidth := 2 * nyquist / float64(a.size)
+
+	// 包含 20 Hz 及 20 kHz 的频带也计入
+	lowest := math.Ceil(b*math.Log2(minBandFrequency/bandReference) - 0.5)
+	highest := math.Floor(b*math.Log2(maxBandFrequency/bandReference) + 0.5)
+
+	for k := lowest; k <= highest; k++ {
+		center := bandReference * math.Pow(2, k/b)
+		low := center * math.Pow(2, -1/(2*b))
+		high := center * math.Pow(2, 1/(2*b))
+		if high > nyquist {
+			break
+		}
+
+		first := int(math.Ceil(low / binWidth))
+		last := int(math.Ceil(high/binWidth)) - 1
+		if last < first {
+			// 频带窄于一个频点时取中心频率所在的频点
+			first = int(math.Round(center / binWidth))
+			last = first
+		}
+
+		a.bands = append(a.bands, band{
+			Band:  Band{Center: center, Low: low, High: high, Level: Floor, Peak: Floor},
+			first: first,
+			last:  last,
+		})
+	}
+}
+
+// Process 方法写入交错的 float32 采样。
+func (a *Analyzer) Process(samples []float32) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.process(samples)
+}
+
+// ProcessBytes 方法按格式解码并写入音频数据。
+func (a *Analyzer) ProcessBytes(data []byte) (err error) {
+	return dsp.ProcessBytes(&a.mu, &a.format, &a.buf, data, false, a.process)
+}
+
+func (a *Analyzer) process(samples []float32) {
+	for i := 0; i+a.channels <= len(samples); i += a.channels {
+		var v float64
+		for c := 0; c < a.channels; c++ {
+			v += float64(samples[i+c])
+		}
+
+		a.ring[a.ringPos] = v / float64(a.channels)
+		a.ringPos = (a.ringPos + 1) % a.size
+
+		if a.pending--; a.pending == 0 {
+			a.analyze()
+			a.pending = a.hop
+		}
+	}
+}
+
+// Snapshot 方法返回自上次读取以来的平均频谱，没有新帧时返回最近一帧。
+func (a *Analyzer) Snapshot() (frame Frame) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	power := a.latest
+	if a.count > 0 {
+		power = make([]float64, len(a.power))
+		for i, p := range a.power {
+			power[i] = p / float64(a.count)
+		}
+	}
+
+	frame.Count = a.count
+	frame.Magnitudes = make([]float64, len(power))
+	for i, p := range power {
+		frame.Magnitudes[i] = toDB(p)
+	}
+
+	frame.Peaks = append([]float64(nil), a.peaks...)
+
+	for i := range a.bands {
+		b := &a.bands[i]
+		b.Level = toDB(a.bandPower(power, b))
+		frame.Bands = append(frame.Bands, b.Band)
+	}
+
+	clear(a.power)
+	a.count = 0
+
+	return
+}
+
+// Reset 方法清除所有状态。
+func (a *Analyzer) Reset() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	clear(a.ring)
+	clear(a.power)
+	clear(a.latest)
+	clear(a.holds)
+	a.ringPos = 0
+	a.pending = a.size
+	a.count = 0
+
+	for i := range a.peaks {
+		a.peaks[i] = Floor
+	}
+
+	for i := range a.bands {
+		a.bands[i].Level = Floor
+		a.bands[i].Peak = Floor
+		a.bands[i].hold = 0
+	}
+}
+
+// 对最近 size 个采样加窗并变换，更新功率累加及峰值保持
+func (a *Analyzer) analyze() {
+	for i := range a.frame {
+		a.frame[i] = a.ring[(a.ringPos+i)%a.size] * a.window[i]
+	}
+
+	a.real.Transform(a.frame, a.spectrum)
+
+	for i, x := range a.spectrum {
+		m := math.Hypot(real(x), imag(x)) * a.scale
+		if i == 0 || i == len(a.spectrum)-1 {
+			m /= 2
+		}
+
+		p := m * m
+		a.latest[i] = p
+		a.power[i] += p
+		a.peaks[i], a.holds[i] = a.hold(a.peaks[i], a.holds[i], toDB(p))
+	}
+
+	for i := range a.bands {
+		b := &a.bands[i]
+		b.Peak, b.hold = a.hold(b.Peak, b.hold, toDB(a.bandPower(a.latest, b)))
+	}
+
+	a.count++
+}
+
+// 频带内各频点的功率之和，按窗函数的等效噪声带宽修正
+func (a *Analyzer) bandPower(power []float64, b *band) (sum float64) {
+	for i := b.first; i <= b.last && i < len(power); i++ {
+		sum += power[i]
+	}
+
+	if b.last > b.first {
+		sum /= a.enbw
+	}
+
+	return
+}
+
+// 更新峰值保持，返回新的峰值及剩余保持时间
+func (a *Analyzer) hold(peak float64, hold time.Duration, level float64) (float64, time.Duration) {
+	if level >= peak {
+		return level, a.peakHold
+	}
+
+	if hold > 0 {
+		return peak, hold - a.hopTime
+	}
+
+	return max(peak-a.peakDecay*a.hopTime.Seconds(), level, Floor), 0
+}
+
+func toDB(power float64) float64 {
+	if power <= 0 {
+		return Floor
+	}
+
+	return max(10*math.Log10(power), Floor)
+}
@@ -0,0 +1,86 @@
+package spectrum
+
+import (
+	"encoding/binary"
+	"math"
+	"testing"
+
+	"github.com/cyberxnomad/wasapi/audioclient"
+	"github.com/cyberxnomad/wasapi/fft"
+	"github.com/cyberxnomad/wasapi/pcm"
+)
+
+func TestSineBin(t *testing.T) {
+	const rate, size = 48000, 4096
+	format := pcm.NewFormat(pcm.SampleTypeFloat, rate, 2, 32, audioclient.KSAUDIO_SPEAKER_STEREO)
+
+	for _, window := range []fft.Window{fft.WindowHann, fft.WindowBlackmanHarris, fft.WindowFlatTop} {
+		a, err := NewAnalyzer(&format, size, window, 0.75)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// 频率位于第 85 个频点的中心，两个声道相同，混合后幅度为 0.5
+		freq := 85.0 * rate / size
+		samples := make([]float32, rate*2)
+		for i := 0; i < rate; i++ {
+			v := float32(0.5 * math.Sin(2*math.Pi*freq*float64(i)/rate))
+			samples[2*i], samples[2*i+1] = v, v
+		}
+		a.Process(samples)
+
+		frame := a.Snapshot()
+		if want := (rate - size + a.hop) / a.hop; frame.Count != want {
+			t.Errorf("%v: Count = %d, want %d", window, frame.Count, want)
+		}
+
+		peak := 0
+		for i, m := range frame.Magnitudes {
+			if m > frame.Magnitudes[peak] {
+				peak = i
+			}
+		}
+
+		if got := a.Frequencies()[peak]; got != freq {
+			t.Errorf("%v: peak at %.2f Hz, want %.2f Hz", window, got, freq)
+		}
+		if want := 20 * math.Log10(0.5); math.Abs(frame.Magnitudes[peak]-want) > 0.01 {
+			t.Errorf("%v: peak %.3f dBFS, want %.3f dBFS", window, frame.Magnitudes[peak], want)
+		}
+
+		// 远离正弦波的频点接近电平下限
+		if m := frame.Magnitudes[len(frame.Magnitudes)/2]; m > -90 {
+			t.Errorf("%v: bin far from the tone at %.1f dBFS", window, m)
+		}
+	}
+}
+
+func TestProcessBytesBands(t *testing.T) {
+	const rate = 48000
+	format := pcm.NewFormat(pcm.SampleTypeInt, rate, 1, 16, audioclient.KSAUDIO_SPEAKER_MONO)
+
+	a, err := NewAnalyzer(&format, 8192, fft.WindowHann, 0.5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.SetBands(BandOctave)
+
+	data := make([]byte, rate*2)
+	for i := 0; i < rate; i++ {
+		v := int16(math.Round(16384 * math.Sin(2*math.Pi*1000*float64(i)/rate)))
+		binary.LittleEndian.PutUint16(data[2*i:], uint16(v))
+	}
+	if err = a.ProcessBytes(data); err != nil {
+		t.Fatal(err)
+	}
+
+	// 1 kHz 倍频程频带包含正弦波的全部能量
+	for _, b := range a.Snapshot().Bands {
+		if want := 20 * math.Log10(0.5); b.Center == 1000 && math.Abs(b.Level-want) > 0.2 {
+			t.Errorf("band %.0f Hz level %.2f dBFS, want %.2f", b.Center, b.Level, want)
+		}
+		if (b.High < 500 || b.Low > 2000) && b.Level > -60 {
+			t.Errorf("band %.0f Hz level %.2f dBFS, want below -60", b.Center, b.Level)
+		}
+	}
+}
@@ -0,0 +1,336 @@
+package vad
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/cyberxnomad/wasapi/audioclient"
+	"github.com/cyberxnomad/wasapi/biquad"
+	"github.com/cyberxnomad/wasapi/internal/dsp"
+)
+
+const (
+	DefaultThreshold = 9.0                    // 默认的语音判定门限，高于噪声基底的分贝数
+	DefaultHangover  = 300 * time.Millisecond // 默认的拖尾时长
+	DefaultPreRoll   = 200 * time.Millisecond // 默认的预录时长
+	DefaultOnset     = 30 * time.Millisecond  // 默认的起始确认时长
+
+	frameDuration = 10 * time.Millisecond // 分析帧的时长
+	minEnergy     = -60.0                 // 低于此电平 (dBFS) 的帧一律视为非语音
+)
+
+// EventType 表示语音活动事件的类型。
+type EventType uint32
+
+const (
+	EventStart EventType = iota // 语音开始
+	EventStop                   // 语音结束
+)
+
+func (t EventType) String() string {
+	if t == EventStart {
+		return "start"
+	}
+
+	return "stop"
+}
+
+// Event 为语音活动事件。
+type Event struct {
+	Type     EventType
+	Position uint64 // 事件对应的帧位置 (设备位置)，开始事件已包含预录部分
+}
+
+// Features 为一个分析帧的特征。
+type Features struct {
+	Energy     float64 // 电平 (dBFS)
+	NoiseFloor float64 // 估计的噪声基底 (dBFS)
+	BandRatio  float64 // 语音频带 (100-4000 Hz) 能量占比
+	ZeroCross  float64 // 过零率 (每个采样)
+	Speech     bool    // 是否判定为语音
+}
+
+type frame struct {
+	position uint64
+	samples  []float32
+}
+
+// Detector 是基于能量及频谱特征的语音活动检测器。
+//
+// 音频被分成 10 毫秒的分析帧，结合 AUDCLNT_BUFFERFLAGS_SILENT 标志、相对于自适应噪声基底的
+// 电平、语音频带能量占比及过零率判定。Process 方法返回应转发的音频，语音开始时包含预录部分，
+// 语音结束后包含拖尾部分；因此输出相对输入最多延迟一个分析帧。
+type Detector struct {
+	mu sync.Mutex
+
+	format     audioclient.WAVEFORMATEXTENSIBLE
+	channels   int
+	frameLen   int
+	threshold  float64
+	hangover   int // 帧数
+	preRoll    int // 帧数
+	onset      int // 帧数
+	highPass   biquad.Coefficients
+	lowPass    biquad.Coefficients
+	hpState    biquad.State
+	lpState    biquad.State
+	zeroCrossY float32
+
+	pending    []float32
+	pendingPos uint64
+	history    []frame // 未激活时的最近若干帧，用于预录
+	active     bool
+	speechRun  int
+	silenceRun int
+	noiseFloor float64
+	haveFloor  bool
+	features   Features
+
+	out []float32
+	buf []float32
+}
+
+// NewDetector 创建语音活动检测器。
+func NewDetector(format *audioclient.WAVEFORMATEXTENSIBLE) *Detector {
+	rate := float64(format.Format.SamplesPerSec)
+
+	d := &Detector{
+		format:    *format,
+		channels:  int(format.Format.Channels),
+		frameLen:  max(int(frameDuration.Seconds()*rate), 1),
+		threshold: DefaultThreshold,
+		highPass:  biquad.Design(biquad.Params{Type: biquad.HighPass, Frequency: 100, Q: 0.7071}, rate),
+		lowPass:   biquad.Design(biquad.Params{Type: biquad.LowPass, Frequency: 4000, Q: 0.7071}, rate),
+	}
+	d.SetHangover(DefaultHangover)
+	d.SetPreRoll(DefaultPreRoll)
+	d.SetOnset(DefaultOnset)
+
+	return d
+}
+
+// SetThreshold 方法设置语音判定门限，即高于噪声基底的分贝数。
+func (d *Detector) SetThreshold(db float64) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.threshold = db
+}
+
+// SetHangover 方法设置拖尾时长，语音停止后在该时长内仍保持激活。
+func (d *Detector) SetHangover(hangover time.Duration) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.hangover = d.frames(hangover)
+}
+
+// SetPreRoll 方法设置预录时长，语音开始事件及输出会提前该时长。
+func (d *Detector) SetPreRoll(preRoll time.Duration) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.preRoll = d.frames(preRoll)
+}
+
+// SetOnset 方法设置起始确认时长，连续检测到该时长的语音才触发开始事件。
+func (d *Detector) SetOnset(onset time.Duration) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.onset = max(d.frames(onset), 1)
+}
+
+// Active 方法返回当前是否处于语音激活状态。
+func (d *Detector) Active() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.active
+}
+
+// Features 方法返回最近一个分析帧的特征。
+func (d *Detector) Features() Features {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.features
+}
+
+// Reset 方法清除所有状态。
+func (d *Detector) Reset() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.pending = d.pending[:0]
+	d.history = d.history[:0]
+	d.active = false
+	d.speechRun = 0
+	d.silenceRun = 0
+	d.haveFloor = false
+	d.hpState.Reset()
+	d.lpState.Reset()
+}
+
+// Process 方法处理捕获的交错 float32 采样。
+//
+// flags 及 devicePosition 为 GetBuffer 返回的值，带有 AUDCLNT_BUFFERFLAGS_SILENT 标志时
+// samples 的内容被视为静音。返回应转发的音频及本次产生的事件，返回的切片在下次调用前有效。
+func (d *Detector) Process(samples []float32, flags uint32, devicePosition uint64) (out []float32, events []Event) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.process(samples, flags, devicePosition)
+}
+
+// ProcessBytes 方法按格式解码并处理捕获的数据，参见 Process。
+func (d *Detector) ProcessBytes(data []byte, flags uint32, devicePosition uint64) (out []float32, events []Event, err error) {
+	err = dsp.ProcessBytes(&d.mu, &d.format, &d.buf, data, false, func(samples []float32) {
+		out, events = d.process(samples, flags, devicePosition)
+	})
+
+	return
+}
+
+func (d *Detector) process(samples []float32, flags uint32, devicePosition uint64) (out []float32, events []Event) {
+	d.out = d.out[:0]
+	silent := flags&audioclient.AUDCLNT_BUFFERFLAGS_SILENT != 0
+
+	frames := len(samples) / d.channels
+	for i := 0; i < frames; {
+		if len(d.pending) == 0 {
+			d.pendingPos = devicePosition + uint64(i)
+		}
+
+		n := min(d.frameLen-len(d.pending)/d.channels, frames-i)
+		if silent {
+			for j := 0; j < n*d.channels; j++ {
+				d.pending = append(d.pending, 0)
+			}
+		} else {
+			d.pending = append(d.pending, samples[i*d.channels:(i+n)*d.channels]...)
+		}
+		i += n
+
+		if len(d.pending) == d.frameLen*d.channels {
+			if ev, ok := d.analyze(silent); ok {
+				events = append(events, ev)
+			}
+		}
+	}
+
+	out = d.out
+
+	return
+}
+
+// 分析一个完整的帧并更新状态
+func (d *Detector) analyze(silent bool) (event Event, ok bool) {
+	f := frame{position: d.pendingPos, samples: append([]float32(nil), d.pending...)}
+	d.pending = d.pending[:0]
+
+	speech := d.classify(f.samples, silent)
+
+	if d.active {
+		d.out = append(d.out, f.samples...)
+
+		if speech {
+			d.silenceRun = 0
+		} else if d.silenceRun++; d.silenceRun >= d.hangover {
+			d.active = false
+			d.speechRun = 0
+			event, ok = Event{Type: EventStop, Position: f.position + uint64(d.frameLen)}, true
+		}
+
+		return
+	}
+
+	d.history = append(d.history, f)
+	if limit := d.preRoll + d.onset; len(d.history) > limit {
+		d.history = d.history[len(d.history)-limit:]
+	}
+
+	if !speech {
+		d.speechRun = 0
+		return
+	}
+
+	if d.speechRun++; d.speechRun < d.onset {
+		return
+	}
+
+	// 从语音起始处向前预录
+	start := max(len(d.history)-d.speechRun-d.preRoll, 0)
+	for _, h := range d.history[start:] {
+		d.out = append(d.out, h.samples...)
+	}
+
+	d.active = true
+	d.silenceRun = 0
+	event, ok = Event{Type: EventStart, Position: d.history[start].position}, true
+	d.history = d.history[:0]
+
+	return
+}
+
+// 计算帧特征并判定是否为语音
+func (d *Detector) classify(samples []float32, silent bool) bool {
+	var energy, band float64
+	var crossings int
+
+	for i := 0; i < d.frameLen; i++ {
+		var v float32
+		for c := 0; c < d.channels; c++ {
+			v += samples[i*d.channels+c]
+		}
+		v /= float32(d.channels)
+
+		if (v >= 0) != (d.zeroCrossY >= 0) {
+			crossings++
+		}
+		d.zeroCrossY = v
+
+		y := d.lpState.Process(&d.lowPass, d.hpState.Process(&d.highPass, float64(v)))
+		energy += float64(v) * float64(v)
+		band += y * y
+	}
+
+	f := Features{
+		Energy:    10 * math.Log10(energy/float64(d.frameLen)+1e-12),
+		ZeroCross: float64(crossings) / float64(d.frameLen),
+	}
+	if energy > 0 {
+		f.BandRatio = band / energy
+	}
+
+	if !d.haveFloor {
+		d.noiseFloor = f.Energy
+		d.haveFloor = true
+	}
+
+	f.Speech = !silent &&
+		f.Energy > minEnergy &&
+		f.Energy > d.noiseFloor+d.threshold &&
+		f.BandRatio > 0.3 &&
+		f.ZeroCross < 0.5
+
+	// 噪声基底快速下降、缓慢上升，语音期间几乎不更新
+	switch {
+	case f.Energy < d.noiseFloor:
+		d.noiseFloor += (f.Energy - d.noiseFloor) * 0.2
+	case f.Speech || d.active:
+		d.noiseFloor += (f.Energy - d.noiseFloor) * 0.001
+	default:
+		d.noiseFloor += (f.Energy - d.noiseFloor) * 0.01
+	}
+
+	f.NoiseFloor = d.noiseFloor
+	d.features = f
+
+	return f.Speech
+}
+
+func (d *Detector) frames(duration time.Duration) int {
+	return int((duration + frameDuration/2) / frameDuration)
+}
@@ -0,0 +1,157 @@
+package vban
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+const (
+	HeaderSize     = 28
+	MaxPayloadSize = 1436
+	MaxSamples     = 256
+	MaxChannels    = 256
+	DefaultPort    = 6980
+)
+
+var (
+	ErrInvalidPacket = errors.New("invalid vban packet")
+	ErrSampleRate    = errors.New("unsupported vban sample rate")
+)
+
+// SubProtocol 为 VBAN 子协议。
+type SubProtocol uint8
+
+const (
+	SubProtocolAudio   SubProtocol = 0x00
+	SubProtocolSerial  SubProtocol = 0x20
+	SubProtocolText    SubProtocol = 0x40
+	SubProtocolService SubProtocol = 0x60
+)
+
+// DataType 为音频子协议的采样数据类型。
+type DataType uint8
+
+const (
+	DataTypeByte8   DataType = iota // 8 位无符号整数
+	DataTypeInt16                   // 16 位有符号整数
+	DataTypeInt24                   // 24 位有符号整数
+	DataTypeInt32                   // 32 位有符号整数
+	DataTypeFloat32                 // 32 位浮点
+	DataTypeFloat64                 // 64 位浮点
+	DataType12Bits                  // 保留
+	DataType10Bits                  // 保留
+)
+
+// Codec 为音频子协议的编码方式，本包仅支持 PCM。
+const CodecPCM uint8 = 0x00
+
+// 采样率索引表
+var sampleRates = [...]uint32{
+	6000, 12000, 24000, 48000, 96000, 192000, 384000,
+	8000, 16000, 32000, 64000, 128000, 256000, 512000,
+	11025, 22050, 44100, 88200, 176400, 352800, 705600,
+}
+
+// SampleRateIndex 返回采样率对应的索引。
+func SampleRateIndex(samplesPerSec uint32) (index uint8, ok bool) {
+	for i, rate := range sampleRates {
+		if rate == samplesPerSec {
+			return uint8(i), true
+		}
+	}
+
+	return
+}
+
+// SampleSize 方法返回每个采样的字节数，保留类型返回 0。
+func (t DataType) SampleSize() int {
+	switch t {
+	case DataTypeByte8:
+		return 1
+	case DataTypeInt16:
+		return 2
+	case DataTypeInt24:
+		return 3
+	case DataTypeInt32, DataTypeFloat32:
+		return 4
+	case DataTypeFloat64:
+		return 8
+	}
+
+	return 0
+}
+
+// Header 为 VBAN 包头。
+type Header struct {
+	SubProtocol  SubProtocol
+	SampleRate   uint32 // 音频子协议的采样率
+	Samples      int    // 每包的帧数，1 ~ 256
+	Channels     int    // 声道数，1 ~ 256
+	DataType     DataType
+	Codec        uint8
+	StreamName   string // 最长 16 字节
+	FrameCounter uint32
+}
+
+// Marshal 方法将包头编码到 buf 开头。
+func (h *Header) Marshal(buf []byte) (err error) {
+	if len(buf) < HeaderSize ||
+		h.Samples < 1 || h.Samples > MaxSamples ||
+		h.Channels < 1 || h.Channels > MaxChannels ||
+		len(h.StreamName) > 16 {
+		err = ErrInvalidPacket
+		return
+	}
+
+	index, ok := SampleRateIndex(h.SampleRate)
+	if !ok {
+		err = ErrSampleRate
+		return
+	}
+
+	copy(buf[0:4], "VBAN")
+	buf[4] = uint8(h.SubProtocol)&0xE0 | index
+	buf[5] = uint8(h.Samples - 1)
+	buf[6] = uint8(h.Channels - 1)
+	buf[7] = h.Codec&0xF0 | uint8(h.DataType)&0x07
+
+	clear(buf[8:24])
+	copy(buf[8:24], h.StreamName)
+
+	binary.LittleEndian.PutUint32(buf[24:28], h.FrameCounter)
+
+	return
+}
+
+// Unmarshal 方法解码包头，返回负载。
+func (h *Header) Unmarshal(buf []byte) (payload []byte, err error) {
+	if len(buf) < HeaderSize || string(buf[0:4]) != "VBAN" {
+		err = ErrInvalidPacket
+		return
+	}
+
+	h.SubProtocol = SubProtocol(buf[4] & 0xE0)
+	h.SampleRate = 0
+	if index := int(buf[4] & 0x1F); index < len(sampleRates) {
+		h.SampleRate = sampleRates[index]
+	}
+
+	h.Samples = int(buf[5]) + 1
+	h.Channels = int(buf[6]) + 1
+	h.DataType = DataType(buf[7] & 0x07)
+	h.Codec = buf[7] & 0xF0
+
+	name := buf[8:24]
+	for i, c := range name {
+		if c == 0 {
+			name = name[:i]
+			break
+		}
+	}
+	h.StreamName = string(name)
+
+	h.FrameCounter = binary.LittleEndian.Uint32(buf[24:28])
+	payload = buf[HeaderSize:]
+
+	return
+}
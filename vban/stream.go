@@ -0,0 +1,252 @@
+package vban
+
+import (
+	"errors"
+	"net"
+	"time"
+
+	"github.com/cyberxnomad/wasapi/audioclient"
+	"github.com/cyberxnomad/wasapi/jitter"
+	"github.com/cyberxnomad/wasapi/pcm"
+)
+
+var ErrFormatMismatch = errors.New("vban stream format mismatch")
+
+// 数据类型对应的 (小端) 波形格式
+func (t DataType) waveFormat(samplesPerSec uint32, channels int) audioclient.WAVEFORMATEXTENSIBLE {
+	sampleType := pcm.SampleTypeInt
+	if t == DataTypeFloat32 || t == DataTypeFloat64 {
+		sampleType = pcm.SampleTypeFloat
+	}
+
+	return pcm.NewFormat(sampleType, samplesPerSec, uint16(channels), uint16(8*t.SampleSize()), 0)
+}
+
+// 两种格式的采样编码是否相同
+func sameEncoding(a, b *audioclient.WAVEFORMATEXTENSIBLE) bool {
+	return pcm.SampleTypeOf(a) == pcm.SampleTypeOf(b) && a.Format.BitsPerSample == b.Format.BitsPerSample
+}
+
+// Sender 通过 VBAN 音频子协议发送音频。
+type Sender struct {
+	conn net.Conn
+
+	format      audioclient.WAVEFORMATEXTENSIBLE
+	wire        audioclient.WAVEFORMATEXTENSIBLE
+	header      Header
+	frameSize   int
+	maxFrames   int
+	passthrough bool
+
+	samples []float32
+	buf     []byte
+}
+
+// NewSender 创建 VBAN 发送端，format 为输入数据的格式，dataType 为发送的数据类型。
+func NewSender(conn net.Conn, streamName string, format *audioclient.WAVEFORMATEXTENSIBLE, dataType DataType) (s *Sender, err error) {
+	channels := int(format.Format.Channels)
+
+	if _, ok := SampleRateIndex(format.Format.SamplesPerSec); !ok {
+		err = ErrSampleRate
+		return
+	}
+
+	if dataType.SampleSize() == 0 || channels < 1 || channels > MaxChannels || len(streamName) > 16 {
+		err = ErrInvalidPacket
+		return
+	}
+
+	s = &Sender{
+		conn:   conn,
+		format: *format,
+		wire:   dataType.waveFormat(format.Format.SamplesPerSec, channels),
+		header: Header{
+			SubProtocol: SubProtocolAudio,
+			SampleRate:  format.Format.SamplesPerSec,
+			Channels:    channels,
+			DataType:    dataType,
+			Codec:       CodecPCM,
+			StreamName:  streamName,
+		},
+		frameSize: channels * dataType.SampleSize(),
+	}
+
+	s.maxFrames = min(MaxSamples, MaxPayloadSize/s.frameSize)
+	if s.maxFrames == 0 {
+		s, err = nil, ErrInvalidPacket
+		return
+	}
+
+	s.passthrough = sameEncoding(&s.format, &s.wire)
+	s.buf = make([]byte, HeaderSize+s.maxFrames*s.frameSize)
+
+	return
+}
+
+// FrameCounter 方法返回下一个包的帧计数。
+func (s *Sender) FrameCounter() uint32 {
+	return s.header.FrameCounter
+}
+
+// Send 方法发送音频数据，超过单包容量时拆分为多个包。
+func (s *Sender) Send(data []byte) (err error) {
+	frames := pcm.Frames(&s.format, len(data))
+	blockAlign := int(s.format.Format.BlockAlign)
+	channels := s.header.Channels
+
+	for offset := 0; offset < frames; {
+		n := min(frames-offset, s.maxFrames)
+		chunk := data[offset*blockAlign : (offset+n)*blockAlign]
+		payload := s.buf[HeaderSize : HeaderSize+n*s.frameSize]
+
+		if s.passthrough {
+			copy(payload, chunk)
+		} else {
+			if len(s.samples) < n*channels {
+				s.samples = make([]float32, s.maxFrames*channels)
+			}
+
+			if _, err = pcm.Decode(&s.format, chunk, s.samples[:n*channels]); err != nil {
+				return
+			}
+
+			if _, err = pcm.Encode(&s.wire, s.samples[:n*channels], payload); err != nil {
+				return
+			}
+		}
+
+		s.header.Samples = n
+		if err = s.header.Marshal(s.buf); err != nil {
+			return
+		}
+
+		if _, err = s.conn.Write(s.buf[:HeaderSize+len(payload)]); err != nil {
+			return
+		}
+
+		s.header.FrameCounter++
+		offset += n
+	}
+
+	return
+}
+
+// Receiver 接收 VBAN 音频并转换为呈现流的格式。
+type Receiver struct {
+	conn       net.PacketConn
+	streamName string
+	format     audioclient.WAVEFORMATEXTENSIBLE
+
+	buf     []byte
+	samples []float32
+}
+
+// NewReceiver 创建 VBAN 接收端，streamName 为空时接收任意流。
+//
+// format 为输出数据的格式，其采样率及声道数须与发送端一致。
+func NewReceiver(conn net.PacketConn, streamName string, format *audioclient.WAVEFORMATEXTENSIBLE) *Receiver {
+	return &Receiver{
+		conn:       conn,
+		streamName: streamName,
+		format:     *format,
+		buf:        make([]byte, 2048),
+	}
+}
+
+// ReadPacket 方法读取下一个音频包，data 为转换为输出格式的音频数据。
+//
+// 非音频子协议、其他流名称及不支持的编码的包将被忽略，采样率或声道数不一致时返回 ErrFormatMismatch。
+func (r *Receiver) ReadPacket() (header Header, data []byte, err error) {
+	for {
+		var (
+			n       int
+			payload []byte
+		)
+
+		if n, _, err = r.conn.ReadFrom(r.buf); err != nil {
+			return
+		}
+
+		if payload, err = header.Unmarshal(r.buf[:n]); err != nil {
+			continue
+		}
+
+		if header.SubProtocol != SubProtocolAudio || header.Codec != CodecPCM || header.DataType.SampleSize() == 0 {
+			continue
+		}
+
+		if r.streamName != "" && header.StreamName != r.streamName {
+			continue
+		}
+
+		if header.SampleRate != r.format.Format.SamplesPerSec || header.Channels != int(r.format.Format.Channels) {
+			err = ErrFormatMismatch
+			return
+		}
+
+		if len(payload) < header.Samples*header.Channels*header.DataType.SampleSize() {
+			continue
+		}
+
+		wire := header.DataType.waveFormat(header.SampleRate, header.Channels)
+		count := header.Samples * header.Channels
+		data = make([]byte, header.Samples*int(r.format.Format.BlockAlign))
+
+		if sameEncoding(&wire, &r.format) {
+			copy(data, payload)
+			return
+		}
+
+		if len(r.samples) < count {
+			r.samples = make([]float32, count)
+		}
+
+		if _, err = pcm.Decode(&wire, payload, r.samples[:count]); err != nil {
+			return
+		}
+
+		_, err = pcm.Encode(&r.format, r.samples[:count], data)
+
+		return
+	}
+}
+
+// Run 方法持续接收音频包并写入抖动缓冲区，直到读取出错。
+//
+// VBAN 没有时间戳，这里根据帧计数及上一包的帧数推算时间戳，丢失的包按上一包的帧数计算。
+func (r *Receiver) Run(buffer *jitter.Buffer) (err error) {
+	var (
+		started     bool
+		lastCounter uint32
+		lastTS      uint32
+		lastSamples uint32
+	)
+
+	for {
+		var (
+			header Header
+			data   []byte
+		)
+
+		if header, data, err = r.ReadPacket(); err != nil {
+			return
+		}
+
+		timestamp := uint32(0)
+		if started {
+			delta := int32(header.FrameCounter - lastCounter)
+			timestamp = lastTS + uint32(delta)*lastSamples
+		}
+
+		if !started || int32(header.FrameCounter-lastCounter) > 0 {
+			started = true
+			lastCounter = header.FrameCounter
+			lastTS = timestamp
+			lastSamples = uint32(header.Samples)
+		}
+
+		if err = buffer.Push(uint16(header.FrameCounter), timestamp, data, time.Now()); err != nil {
+			return
+		}
+	}
+}
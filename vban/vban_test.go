@@ -0,0 +1,285 @@
+package vban
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/cyberxnomad/wasapi/audioclient"
+	"github.com/cyberxnomad/wasapi/internal/nettest"
+	"github.com/cyberxnomad/wasapi/pcm"
+)
+
+// 参考包头，按 VBAN 协议规范 (VB-Audio, VBAN_SPECIFICATION) 的字段布局逐字节编写
+var referenceHeaders = []struct {
+	name   string
+	hex    string
+	header Header
+}{
+	{
+		// Voicemeeter 默认输出: 48 kHz, 256 帧, 双声道 16 位
+		name: "Stream1 48k int16",
+		hex:  "5642414e" + "03" + "ff" + "01" + "01" + "53747265616d310000000000000000" + "00" + "2a000000",
+		header: Header{
+			SubProtocol: SubProtocolAudio, SampleRate: 48000, Samples: 256, Channels: 2,
+			DataType: DataTypeInt16, Codec: CodecPCM, StreamName: "Stream1", FrameCounter: 42,
+		},
+	},
+	{
+		name: "44.1k float32 8ch",
+		hex:  "5642414e" + "10" + "7f" + "07" + "04" + "4d6978" + strings.Repeat("00", 13) + "ffffffff",
+		header: Header{
+			SubProtocol: SubProtocolAudio, SampleRate: 44100, Samples: 128, Channels: 8,
+			DataType: DataTypeFloat32, Codec: CodecPCM, StreamName: "Mix", FrameCounter: 0xFFFFFFFF,
+		},
+	},
+	{
+		// 流名称恰好 16 字节，无结尾 NUL
+		name: "full name 8k int24 mono",
+		hex:  "5642414e" + "07" + "4f" + "00" + "02" + hex.EncodeToString([]byte("ABCDEFGHIJKLMNOP")) + "01020304",
+		header: Header{
+			SubProtocol: SubProtocolAudio, SampleRate: 8000, Samples: 80, Channels: 1,
+			DataType: DataTypeInt24, Codec: CodecPCM, StreamName: "ABCDEFGHIJKLMNOP", FrameCounter: 0x04030201,
+		},
+	},
+	{
+		name: "705.6k byte8 max channels",
+		hex:  "5642414e" + "14" + "00" + "ff" + "00" + "7a" + strings.Repeat("00", 15) + "00000000",
+		header: Header{
+			SubProtocol: SubProtocolAudio, SampleRate: 705600, Samples: 1, Channels: 256,
+			DataType: DataTypeByte8, Codec: CodecPCM, StreamName: "z",
+		},
+	},
+}
+
+func TestReferenceHeaders(t *testing.T) {
+	for _, ref := range referenceHeaders {
+		raw, err := hex.DecodeString(ref.hex)
+		if err != nil || len(raw) != HeaderSize {
+			t.Fatalf("%s: bad reference (%d bytes, %v)", ref.name, len(raw), err)
+		}
+
+		var h Header
+		payload, err := h.Unmarshal(append(raw, 0xAA, 0xBB))
+		if err != nil {
+			t.Fatalf("%s: %v", ref.name, err)
+		}
+		if h != ref.header {
+			t.Errorf("%s: Unmarshal = %+v, want %+v", ref.name, h, ref.header)
+		}
+		if !bytes.Equal(payload, []byte{0xAA, 0xBB}) {
+			t.Errorf("%s: payload = % X", ref.name, payload)
+		}
+
+		buf := bytes.Repeat([]byte{0xEE}, HeaderSize)
+		if err = ref.header.Marshal(buf); err != nil {
+			t.Fatalf("%s: %v", ref.name, err)
+		}
+		if !bytes.Equal(buf, raw) {
+			t.Errorf("%s: Marshal = %x, want %x", ref.name, buf, raw)
+		}
+	}
+}
+
+func TestSampleRateIndex(t *testing.T) {
+	// 规范中的 21 个采样率，按索引排列
+	want := []uint32{
+		6000, 12000, 24000, 48000, 96000, 192000, 384000,
+		8000, 16000, 32000, 64000, 128000, 256000, 512000,
+		11025, 22050, 44100, 88200, 176400, 352800, 705600,
+	}
+
+	for i, rate := range want {
+		index, ok := SampleRateIndex(rate)
+		if !ok || int(index) != i {
+			t.Errorf("SampleRateIndex(%d) = %d, %v, want %d", rate, index, ok, i)
+		}
+
+		var h Header
+		raw := make([]byte, HeaderSize)
+		copy(raw, "VBAN")
+		raw[4] = byte(i)
+		if h.Unmarshal(raw); h.SampleRate != rate {
+			t.Errorf("index %d decoded as %d Hz, want %d", i, h.SampleRate, rate)
+		}
+	}
+
+	if _, ok := SampleRateIndex(44000); ok {
+		t.Error("SampleRateIndex(44000) succeeded")
+	}
+
+	// 未定义的索引解码为 0
+	var h Header
+	raw := make([]byte, HeaderSize)
+	copy(raw, "VBAN")
+	raw[4] = 21
+	if h.Unmarshal(raw); h.SampleRate != 0 {
+		t.Errorf("index 21 decoded as %d Hz", h.SampleRate)
+	}
+}
+
+func TestHeaderErrors(t *testing.T) {
+	valid := referenceHeaders[0].header
+
+	for _, h := range []Header{
+		{SampleRate: 48000, Samples: 0, Channels: 1},
+		{SampleRate: 48000, Samples: 257, Channels: 1},
+		{SampleRate: 48000, Samples: 1, Channels: 0},
+		{SampleRate: 48000, Samples: 1, Channels: 257},
+		{SampleRate: 48000, Samples: 1, Channels: 1, StreamName: "ABCDEFGHIJKLMNOPQ"},
+	} {
+		if err := h.Marshal(make([]byte, HeaderSize)); !errors.Is(err, ErrInvalidPacket) {
+			t.Errorf("Marshal(%+v): err = %v", h, err)
+		}
+	}
+
+	if err := valid.Marshal(make([]byte, HeaderSize-1)); !errors.Is(err, ErrInvalidPacket) {
+		t.Errorf("Marshal into short buffer: err = %v", err)
+	}
+
+	h := valid
+	h.SampleRate = 44000
+	if err := h.Marshal(make([]byte, HeaderSize)); !errors.Is(err, ErrSampleRate) {
+		t.Errorf("Marshal 44000 Hz: err = %v", err)
+	}
+
+	if _, err := h.Unmarshal([]byte("VBAX" + strings.Repeat("\x00", 24))); !errors.Is(err, ErrInvalidPacket) {
+		t.Errorf("Unmarshal bad magic: err = %v", err)
+	}
+	if _, err := h.Unmarshal([]byte("VBAN")); !errors.Is(err, ErrInvalidPacket) {
+		t.Errorf("Unmarshal short packet: err = %v", err)
+	}
+}
+
+func TestLoopbackPassthrough(t *testing.T) {
+	send, recv := nettest.Loopback(t)
+	format := pcm.NewFormat(pcm.SampleTypeInt, 48000, 2, 16, audioclient.KSAUDIO_SPEAKER_STEREO)
+
+	sender, err := NewSender(send, "Stream1", &format, DataTypeInt16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	receiver := NewReceiver(recv, "Stream1", &format)
+
+	// 600 帧拆分为 256 + 256 + 88 帧 (1436 字节负载上限为 359 帧，再受 256 帧限制)
+	data := make([]byte, 600*4)
+	for i := 0; i < len(data)/2; i++ {
+		binary.LittleEndian.PutUint16(data[i*2:], uint16(int16(i*37)))
+	}
+
+	if err = sender.Send(data); err != nil {
+		t.Fatal(err)
+	}
+
+	var received []byte
+	for i, frames := range []int{256, 256, 88} {
+		header, chunk, err := receiver.ReadPacket()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if header.Samples != frames || header.FrameCounter != uint32(i) || header.StreamName != "Stream1" ||
+			header.DataType != DataTypeInt16 || header.SampleRate != 48000 || header.Channels != 2 {
+			t.Fatalf("packet %d: %+v", i, header)
+		}
+
+		received = append(received, chunk...)
+	}
+
+	if !bytes.Equal(received, data) {
+		t.Fatal("received audio differs from sent audio")
+	}
+
+	if sender.FrameCounter() != 3 {
+		t.Fatalf("FrameCounter = %d, want 3", sender.FrameCounter())
+	}
+}
+
+func TestLoopbackConversion(t *testing.T) {
+	send, recv := nettest.Loopback(t)
+	input := pcm.NewFormat(pcm.SampleTypeFloat, 44100, 1, 32, audioclient.KSAUDIO_SPEAKER_MONO)
+	output := pcm.NewFormat(pcm.SampleTypeInt, 44100, 1, 32, audioclient.KSAUDIO_SPEAKER_MONO)
+
+	sender, err := NewSender(send, "conv", &input, DataTypeInt24)
+	if err != nil {
+		t.Fatal(err)
+	}
+	receiver := NewReceiver(recv, "", &output)
+
+	samples := make([]float32, 100)
+	for i := range samples {
+		samples[i] = float32(math.Sin(float64(i) / 5))
+	}
+
+	data := make([]byte, len(samples)*4)
+	if _, err = pcm.Encode(&input, samples, data); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = sender.Send(data); err != nil {
+		t.Fatal(err)
+	}
+
+	header, chunk, err := receiver.ReadPacket()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if header.DataType != DataTypeInt24 || header.Samples != len(samples) {
+		t.Fatalf("header = %+v", header)
+	}
+
+	got := make([]float32, len(samples))
+	if _, err = pcm.Decode(&output, chunk, got); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := range samples {
+		if math.Abs(float64(got[i]-samples[i])) > 1.0/(1<<22) {
+			t.Fatalf("sample %d = %v, want %v", i, got[i], samples[i])
+		}
+	}
+}
+
+func TestReceiverFiltering(t *testing.T) {
+	send, recv := nettest.Loopback(t)
+	format := pcm.NewFormat(pcm.SampleTypeInt, 48000, 1, 16, audioclient.KSAUDIO_SPEAKER_MONO)
+	receiver := NewReceiver(recv, "want", &format)
+
+	packet := func(h Header) []byte {
+		buf := make([]byte, HeaderSize+h.Samples*h.Channels*2)
+		if err := h.Marshal(buf); err != nil {
+			t.Fatal(err)
+		}
+		return buf
+	}
+
+	base := Header{SubProtocol: SubProtocolAudio, SampleRate: 48000, Samples: 4, Channels: 1, DataType: DataTypeInt16}
+
+	other := base
+	other.StreamName = "other"
+	text := base
+	text.SubProtocol, text.StreamName = SubProtocolText, "want"
+	good := base
+	good.StreamName, good.FrameCounter = "want", 7
+	mismatch := base
+	mismatch.StreamName, mismatch.Channels = "want", 2
+
+	for _, h := range []Header{other, text, good, mismatch} {
+		if _, err := send.Write(packet(h)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	header, _, err := receiver.ReadPacket()
+	if err != nil || header.FrameCounter != 7 {
+		t.Fatalf("first accepted packet = %+v, %v", header, err)
+	}
+
+	if _, _, err = receiver.ReadPacket(); !errors.Is(err, ErrFormatMismatch) {
+		t.Fatalf("channel mismatch: err = %v", err)
+	}
+}
@@ -0,0 +1,76 @@
+package wav
+
+import (
+	"encoding/binary"
+
+	"github.com/cyberxnomad/wasapi/audioclient"
+)
+
+// UnknownSize 用于长度未知的流，RIFF 及 data 块的长度均填写该值。
+const UnknownSize uint32 = 0xFFFFFFFF
+
+// fmt 块内容，WAVE_FORMAT_EXTENSIBLE 为 40 字节，其余为 16 字节 (PCM) 或 18 字节
+func formatChunk(format *audioclient.WAVEFORMATEXTENSIBLE) (buf []byte) {
+	size := 18
+	switch {
+	case format.Format.FormatTag == audioclient.WAVE_FORMAT_EXTENSIBLE:
+		size = 40
+	case format.Format.FormatTag == audioclient.WAVE_FORMAT_PCM:
+		size = 16
+	}
+
+	buf = make([]byte, size)
+
+	binary.LittleEndian.PutUint16(buf[0:2], format.Format.FormatTag)
+	binary.LittleEndian.PutUint16(buf[2:4], format.Format.Channels)
+	binary.LittleEndian.PutUint32(buf[4:8], format.Format.SamplesPerSec)
+	binary.LittleEndian.PutUint32(buf[8:12], format.Format.AvgBytesPerSec)
+	binary.LittleEndian.PutUint16(buf[12:14], format.Format.BlockAlign)
+	binary.LittleEndian.PutUint16(buf[14:16], format.Format.BitsPerSample)
+
+	if size == 16 {
+		return
+	}
+
+	if size == 18 {
+		binary.LittleEndian.PutUint16(buf[16:18], 0)
+		return
+	}
+
+	binary.LittleEndian.PutUint16(buf[16:18], 22)
+	binary.LittleEndian.PutUint16(buf[18:20], format.Samples)
+	binary.LittleEndian.PutUint32(buf[20:24], format.ChannelMask)
+	binary.LittleEndian.PutUint32(buf[24:28], format.SubFormat.Data1)
+	binary.LittleEndian.PutUint16(buf[28:30], format.SubFormat.Data2)
+	binary.LittleEndian.PutUint16(buf[30:32], format.SubFormat.Data3)
+	copy(buf[32:40], format.SubFormat.Data4[:])
+
+	return
+}
+
+// Header 生成 RIFF/WAVE 文件头 (含 data 块头)，dataSize 为音频数据的字节数，
+// 为 UnknownSize 时表示长度未知的流。
+func Header(format *audioclient.WAVEFORMATEXTENSIBLE, dataSize uint32) (buf []byte) {
+	fmtChunk := formatChunk(format)
+
+	buf = make([]byte, 0, 20+len(fmtChunk)+8)
+	buf = append(buf, "RIFF"...)
+
+	riffSize := UnknownSize
+	if dataSize != UnknownSize {
+		// 包括 data 块长为奇数时的填充字节
+		riffSize = uint32(4+8+len(fmtChunk)+8) + dataSize + dataSize&1
+	}
+
+	buf = binary.LittleEndian.AppendUint32(buf, riffSize)
+	buf = append(buf, "WAVE"...)
+
+	buf = append(buf, "fmt "...)
+	buf = binary.LittleEndian.AppendUint32(buf, uint32(len(fmtChunk)))
+	buf = append(buf, fmtChunk...)
+
+	buf = append(buf, "data"...)
+	buf = binary.LittleEndian.AppendUint32(buf, dataSize)
+
+	return
+}
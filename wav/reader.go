@@ -0,0 +1,213 @@
+package wav
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+
+	"github.com/cyberxnomad/wasapi/audioclient"
+)
+
+var ErrInvalidFile = errors.New("invalid wav file")
+
+// fmt 块的最大长度: WAVEFORMATEX 加上最大的附加数据
+const maxFormatSize = 18 + math.MaxUint16
+
+// Reader 读取 RIFF/WAVE 文件中的音频数据。
+type Reader struct {
+	r         io.Reader
+	format    audioclient.WAVEFORMATEXTENSIBLE
+	dataSize  uint32
+	remaining int64 // 剩余的数据字节数，-1 表示读到文件末尾为止
+
+	seeker io.Seeker
+	left   int64 // 文件头之后剩余的字节数，底层不能定位时为 -1
+}
+
+// NewReader 解析文件头直到 data 块，之后可以通过 Read 读取音频数据。
+func NewReader(r io.Reader) (reader *Reader, err error) {
+	var header [12]byte
+	if _, err = io.ReadFull(r, header[:]); err != nil {
+		return
+	}
+
+	if string(header[0:4]) != "RIFF" || string(header[8:12]) != "WAVE" {
+		err = ErrInvalidFile
+		return
+	}
+
+	reader = &Reader{r: r, left: -1}
+	if reader.seeker, _ = r.(io.Seeker); reader.seeker != nil {
+		if reader.left, err = remaining(reader.seeker); err != nil {
+			return nil, err
+		}
+	}
+
+	haveFormat := false
+
+	for {
+		var chunk [8]byte
+		if _, err = io.ReadFull(r, chunk[:]); err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				err = ErrInvalidFile
+			}
+			return nil, err
+		}
+		reader.consume(8)
+
+		id, size := string(chunk[0:4]), binary.LittleEndian.Uint32(chunk[4:8])
+		// 块长为奇数时有一个填充字节
+		padded := int64(size) + int64(size&1)
+
+		switch id {
+		case "fmt ":
+			if size > maxFormatSize || reader.left >= 0 && padded > reader.left {
+				return nil, ErrInvalidFile
+			}
+
+			buf := make([]byte, padded)
+			if _, err = io.ReadFull(r, buf); err != nil {
+				if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+					err = ErrInvalidFile
+				}
+				return nil, err
+			}
+			reader.consume(padded)
+
+			if err = reader.parseFormat(buf[:size]); err != nil {
+				return nil, err
+			}
+			haveFormat = true
+
+		case "data":
+			if !haveFormat {
+				return nil, ErrInvalidFile
+			}
+
+			reader.dataSize = size
+			reader.remaining = int64(size)
+			switch {
+			case size == UnknownSize:
+				reader.remaining = -1
+			case reader.left >= 0 && reader.remaining > reader.left:
+				// 未正常结束的录音，只读取文件中实际存在的数据
+				reader.remaining = reader.left
+			}
+
+			return reader, nil
+
+		default:
+			if err = reader.skip(padded); err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
+// 跳过 n 字节，超出文件长度时返回 ErrInvalidFile
+func (reader *Reader) skip(n int64) (err error) {
+	if reader.left >= 0 && n > reader.left {
+		return ErrInvalidFile
+	}
+
+	if reader.seeker != nil {
+		_, err = reader.seeker.Seek(n, io.SeekCurrent)
+	} else if _, err = io.CopyN(io.Discard, reader.r, n); errors.Is(err, io.EOF) {
+		err = ErrInvalidFile
+	}
+	if err != nil {
+		return
+	}
+
+	reader.consume(n)
+
+	return
+}
+
+func (reader *Reader) consume(n int64) {
+	if reader.left >= 0 {
+		reader.left -= n
+	}
+}
+
+// 返回当前位置之后剩余的字节数，不改变当前位置
+func remaining(seeker io.Seeker) (n int64, err error) {
+	var pos, end int64
+	if pos, err = seeker.Seek(0, io.SeekCurrent); err != nil {
+		return
+	}
+	if end, err = seeker.Seek(0, io.SeekEnd); err != nil {
+		return
+	}
+	if _, err = seeker.Seek(pos, io.SeekStart); err != nil {
+		return
+	}
+
+	return max(end-pos, 0), nil
+}
+
+func (reader *Reader) parseFormat(buf []byte) (err error) {
+	if len(buf) < 16 {
+		return ErrInvalidFile
+	}
+
+	f := &reader.format
+	f.Format.FormatTag = binary.LittleEndian.Uint16(buf[0:2])
+	f.Format.Channels = binary.LittleEndian.Uint16(buf[2:4])
+	f.Format.SamplesPerSec = binary.LittleEndian.Uint32(buf[4:8])
+	f.Format.AvgBytesPerSec = binary.LittleEndian.Uint32(buf[8:12])
+	f.Format.BlockAlign = binary.LittleEndian.Uint16(buf[12:14])
+	f.Format.BitsPerSample = binary.LittleEndian.Uint16(buf[14:16])
+
+	if len(buf) >= 18 {
+		f.Format.CbSize = binary.LittleEndian.Uint16(buf[16:18])
+	}
+
+	if f.Format.FormatTag == audioclient.WAVE_FORMAT_EXTENSIBLE {
+		if len(buf) < 40 {
+			return ErrInvalidFile
+		}
+
+		f.Samples = binary.LittleEndian.Uint16(buf[18:20])
+		f.ChannelMask = binary.LittleEndian.Uint32(buf[20:24])
+		f.SubFormat.Data1 = binary.LittleEndian.Uint32(buf[24:28])
+		f.SubFormat.Data2 = binary.LittleEndian.Uint16(buf[28:30])
+		f.SubFormat.Data3 = binary.LittleEndian.Uint16(buf[30:32])
+		copy(f.SubFormat.Data4[:], buf[32:40])
+	}
+
+	if f.Format.Channels == 0 || f.Format.BlockAlign == 0 {
+		return ErrInvalidFile
+	}
+
+	return
+}
+
+// Format 方法返回文件的音频格式。
+func (reader *Reader) Format() *audioclient.WAVEFORMATEXTENSIBLE {
+	return &reader.format
+}
+
+// DataSize 方法返回 data 块的长度，长度未知的流返回 UnknownSize。
+func (reader *Reader) DataSize() uint32 {
+	return reader.dataSize
+}
+
+// Read 方法读取音频数据，读完 data 块后返回 io.EOF。
+func (reader *Reader) Read(p []byte) (n int, err error) {
+	if reader.remaining == 0 {
+		return 0, io.EOF
+	}
+
+	if reader.remaining > 0 && int64(len(p)) > reader.remaining {
+		p = p[:reader.remaining]
+	}
+
+	n, err = reader.r.Read(p)
+	if reader.remaining > 0 {
+		reader.remaining -= int64(n)
+	}
+
+	return
+}
@@ -0,0 +1,196 @@
+package wav
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/cyberxnomad/wasapi/audioclient"
+	"github.com/cyberxnomad/wasapi/pcm"
+)
+
+var stereo = pcm.NewFormat(pcm.SampleTypeInt, 48000, 2, 16, audioclient.KSAUDIO_SPEAKER_STEREO)
+
+// 内存中的 io.WriteSeeker
+type file struct {
+	data []byte
+	pos  int64
+}
+
+func (f *file) Write(p []byte) (n int, err error) {
+	if end := f.pos + int64(len(p)); end > int64(len(f.data)) {
+		f.data = append(f.data, make([]byte, end-int64(len(f.data)))...)
+	}
+	n = copy(f.data[f.pos:], p)
+	f.pos += int64(n)
+
+	return
+}
+
+func (f *file) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekCurrent:
+		offset += f.pos
+	case io.SeekEnd:
+		offset += int64(len(f.data))
+	}
+	f.pos = offset
+
+	return offset, nil
+}
+
+// 只实现 io.Reader，用于检查不能定位时的行为
+type stream struct{ io.Reader }
+
+func TestRoundTrip(t *testing.T) {
+	for _, format := range []audioclient.WAVEFORMATEXTENSIBLE{
+		stereo,
+		pcm.NewFormat(pcm.SampleTypeFloat, 44100, 6, 32, audioclient.KSAUDIO_SPEAKER_5POINT1_SURROUND),
+		pcm.NewFormat(pcm.SampleTypeInt, 8000, 1, 8, audioclient.KSAUDIO_SPEAKER_MONO),
+	} {
+		// 8 位单声道时为奇数长度，需要填充字节
+		payload := make([]byte, 3*int(format.Format.BlockAlign))
+		for i := range payload {
+			payload[i] = byte(i * 7)
+		}
+
+		f := &file{}
+		w, err := NewWriter(f, &format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = w.Write(payload); err != nil {
+			t.Fatal(err)
+		}
+		if err = w.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		if want := Header(&format, uint32(len(payload))); !bytes.Equal(f.data[:len(want)], want) {
+			t.Fatalf("header after Close = % X, want % X", f.data[:len(want)], want)
+		}
+		if riff := binary.LittleEndian.Uint32(f.data[4:]); int(riff) != len(f.data)-8 {
+			t.Fatalf("RIFF size %d, file is %d bytes", riff, len(f.data))
+		}
+
+		for _, r := range []io.Reader{bytes.NewReader(f.data), stream{bytes.NewReader(f.data)}} {
+			reader, err := NewReader(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if *reader.Format() != format || reader.DataSize() != uint32(len(payload)) {
+				t.Fatalf("format %+v, data size %d", *reader.Format(), reader.DataSize())
+			}
+
+			// 不读出填充字节
+			got, err := io.ReadAll(reader)
+			if err != nil || !bytes.Equal(got, payload) {
+				t.Fatalf("ReadAll = %d bytes, %v, want %d bytes", len(got), err, len(payload))
+			}
+		}
+	}
+}
+
+func TestWriterOffset(t *testing.T) {
+	// 文件头之前已有其他数据
+	f := &file{data: []byte("prefix")}
+	f.pos = int64(len(f.data))
+
+	w, err := NewWriter(f, &stereo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = w.Write(make([]byte, 40)); err != nil {
+		t.Fatal(err)
+	}
+	if err = w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if string(f.data[:6]) != "prefix" {
+		t.Fatalf("data before the header overwritten: %q", f.data[:6])
+	}
+	if want := Header(&stereo, 40); !bytes.Equal(f.data[6:6+len(want)], want) {
+		t.Fatalf("header = % X, want % X", f.data[6:6+len(want)], want)
+	}
+}
+
+func TestUnknownSize(t *testing.T) {
+	// 不能定位时保留 UnknownSize，读取到文件末尾为止
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, &stereo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write(make([]byte, 100))
+	if err = w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	reader, err := NewReader(stream{&buf})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reader.DataSize() != UnknownSize {
+		t.Fatalf("DataSize = %X, want UnknownSize", reader.DataSize())
+	}
+	if got, err := io.ReadAll(reader); err != nil || len(got) != 100 {
+		t.Fatalf("ReadAll = %d bytes, %v", len(got), err)
+	}
+}
+
+// 在 fmt 块之前插入一个块
+func withChunk(id string, size uint32, body []byte) []byte {
+	header := Header(&stereo, 4)
+
+	data := append([]byte(nil), header[:12]...)
+	data = append(data, id...)
+	data = binary.LittleEndian.AppendUint32(data, size)
+	data = append(data, body...)
+	data = append(data, header[12:]...)
+
+	return append(data, 1, 2, 3, 4)
+}
+
+func TestChunks(t *testing.T) {
+	// 奇数长度的未知块及其填充字节被跳过
+	for _, r := range []func([]byte) io.Reader{
+		func(b []byte) io.Reader { return bytes.NewReader(b) },
+		func(b []byte) io.Reader { return stream{bytes.NewReader(b)} },
+	} {
+		reader, err := NewReader(r(withChunk("LIST", 3, []byte{9, 9, 9, 0})))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, _ := io.ReadAll(reader); !bytes.Equal(got, []byte{1, 2, 3, 4}) {
+			t.Fatalf("data = % X", got)
+		}
+	}
+
+	// 超出文件长度的块，包括长度回绕的 0xFFFFFFFF
+	for _, data := range [][]byte{
+		withChunk("LIST", 1<<30, nil),
+		withChunk("LIST", 0xFFFFFFFF, nil),
+		append([]byte("RIFF\x00\x00\x00\x00WAVEfmt "), 0xFF, 0xFF, 0xFF, 0xFF),
+		append([]byte("RIFF\x00\x00\x00\x00WAVEfmt "), 0x00, 0x00, 0x00, 0x10),
+	} {
+		for _, r := range []io.Reader{bytes.NewReader(data), stream{bytes.NewReader(data)}} {
+			if _, err := NewReader(r); !errors.Is(err, ErrInvalidFile) {
+				t.Errorf("% X...: err = %v, want ErrInvalidFile", data[12:20], err)
+			}
+		}
+	}
+
+	// 未正常结束的录音只读取实际存在的数据
+	data := Header(&stereo, 1000)
+	data = append(data, 1, 2, 3, 4)
+	reader, err := NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := io.ReadAll(reader); err != nil || len(got) != 4 {
+		t.Fatalf("truncated file: ReadAll = %d bytes, %v", len(got), err)
+	}
+}
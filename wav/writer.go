@@ -0,0 +1,93 @@
+package wav
+
+import (
+	"encoding/binary"
+	"io"
+
+	"github.com/cyberxnomad/wasapi/audioclient"
+)
+
+// Writer 写入 RIFF/WAVE 文件。
+//
+// 文件头先以 UnknownSize 写入，底层为 io.WriteSeeker 时 Close 会回写实际长度。
+type Writer struct {
+	w          io.Writer
+	format     audioclient.WAVEFORMATEXTENSIBLE
+	headerSize int
+	written    int64
+	start      int64 // 文件头在底层 io.WriteSeeker 中的偏移，不能定位时为 -1
+}
+
+// NewWriter 写入文件头并返回 Writer。
+func NewWriter(w io.Writer, format *audioclient.WAVEFORMATEXTENSIBLE) (writer *Writer, err error) {
+	// 文件头不一定位于文件开头，例如追加在其他数据之后
+	start := int64(-1)
+	if seeker, ok := w.(io.WriteSeeker); ok {
+		if start, err = seeker.Seek(0, io.SeekCurrent); err != nil {
+			start, err = -1, nil
+		}
+	}
+
+	header := Header(format, UnknownSize)
+	if _, err = w.Write(header); err != nil {
+		return
+	}
+
+	writer = &Writer{
+		w:          w,
+		format:     *format,
+		headerSize: len(header),
+		start:      start,
+	}
+
+	return
+}
+
+// Format 方法返回文件的音频格式。
+func (writer *Writer) Format() *audioclient.WAVEFORMATEXTENSIBLE {
+	return &writer.format
+}
+
+// Write 方法写入音频数据。
+func (writer *Writer) Write(p []byte) (n int, err error) {
+	n, err = writer.w.Write(p)
+	writer.written += int64(n)
+
+	return
+}
+
+// Close 方法在可以定位时回写 RIFF 及 data 块的长度，不关闭底层的 io.Writer。
+func (writer *Writer) Close() (err error) {
+	seeker, ok := writer.w.(io.WriteSeeker)
+	if !ok || writer.start < 0 || writer.written >= int64(UnknownSize)-int64(writer.headerSize) {
+		return
+	}
+
+	// data 块长为奇数时补一个填充字节
+	if writer.written&1 != 0 {
+		if _, err = seeker.Write([]byte{0}); err != nil {
+			return
+		}
+	}
+
+	var buf [4]byte
+	binary.LittleEndian.PutUint32(buf[:], uint32(int64(writer.headerSize)-8+writer.written+writer.written&1))
+	if _, err = seeker.Seek(writer.start+4, io.SeekStart); err != nil {
+		return
+	}
+	if _, err = seeker.Write(buf[:]); err != nil {
+		return
+	}
+
+	binary.LittleEndian.PutUint32(buf[:], uint32(writer.written))
+	if _, err = seeker.Seek(writer.start+int64(writer.headerSize)-4, io.SeekStart); err != nil {
+		return
+	}
+	if _, err = seeker.Write(buf[:]); err != nil {
+		return
+	}
+
+	_, err = seeker.Seek(0, io.SeekEnd)
+
+	return
+}
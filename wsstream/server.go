@@ -0,0 +1,466 @@
+package wsstream
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"sync"
+
+	"github.com/cyberxnomad/wasapi/audioclient"
+	"github.com/cyberxnomad/wasapi/httpstream"
+	"github.com/cyberxnomad/wasapi/pcm"
+)
+
+// Encoding 为二进制帧中采样的编码，均为小端交错排列。
+type Encoding string
+
+const (
+	EncodingFloat32 Encoding = "float32"
+	EncodingPCM16   Encoding = "pcm16"
+)
+
+// 控制消息类型
+const (
+	MessageDevice = "device" // 客户端选择设备
+	MessageFormat = "format" // 客户端选择编码
+	MessageGain   = "gain"   // 客户端设置线性增益
+	MessageMute   = "mute"   // 客户端设置静音
+	MessageInput  = "input"  // 客户端声明回传音频的格式
+	MessageStatus = "status" // 服务端返回当前状态
+	MessageError  = "error"  // 服务端返回错误
+)
+
+// Message 为文本控制通道的 JSON 消息。
+type Message struct {
+	Type       string   `json:"type"`
+	Device     string   `json:"device,omitempty"`
+	Devices    []string `json:"devices,omitempty"`
+	Encoding   Encoding `json:"encoding,omitempty"`
+	Gain       *float64 `json:"gain,omitempty"`
+	Mute       *bool    `json:"mute,omitempty"`
+	SampleRate uint32   `json:"sampleRate,omitempty"`
+	Channels   uint16   `json:"channels,omitempty"`
+	Error      string   `json:"error,omitempty"`
+}
+
+// Server 通过 WebSocket 向浏览器推送捕获的音频，并可将浏览器回传的音频写入呈现流。
+//
+// 二进制帧承载音频，文本帧承载 JSON 控制消息。
+type Server struct {
+	mu sync.Mutex
+
+	devices       map[string]*httpstream.Broadcaster
+	defaultDevice string
+
+	render       io.Writer
+	renderFormat audioclient.WAVEFORMATEXTENSIBLE
+
+	buffer int
+}
+
+// NewServer 创建 WebSocket 服务。
+func NewServer() *Server {
+	return &Server{
+		devices: make(map[string]*httpstream.Broadcaster),
+		buffer:  httpstream.DefaultListenerBuffer,
+	}
+}
+
+// AddDevice 方法添加一个可供选择的捕获设备，第一个添加的设备为默认设备。
+func (s *Server) AddDevice(id string, broadcaster *httpstream.Broadcaster) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.devices[id] = broadcaster
+	if s.defaultDevice == "" {
+		s.defaultDevice = id
+	}
+}
+
+// RemoveDevice 方法移除捕获设备，已连接的客户端不受影响。
+func (s *Server) RemoveDevice(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.devices, id)
+	if s.defaultDevice == id {
+		s.defaultDevice = ""
+		for other := range s.devices {
+			s.defaultDevice = other
+			break
+		}
+	}
+}
+
+// SetRender 方法设置回传音频的目标，w 将收到按 format 编码的数据。
+func (s *Server) SetRender(format *audioclient.WAVEFORMATEXTENSIBLE, w io.Writer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.renderFormat = *format
+	s.render = w
+}
+
+// 查找设备，id 为空时返回默认设备
+func (s *Server) device(id string) (resolved string, broadcaster *httpstream.Broadcaster, ok bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	resolved = id
+	if resolved == "" {
+		resolved = s.defaultDevice
+	}
+
+	broadcaster, ok = s.devices[resolved]
+
+	return
+}
+
+func (s *Server) deviceList() (ids []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id := range s.devices {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	return
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	conn, err := upgrade(w, r)
+	if err != nil {
+		return
+	}
+	defer conn.close(1000)
+
+	sess := &session{
+		server:   s,
+		conn:     conn,
+		control:  make(chan Message, 16),
+		done:     make(chan struct{}),
+		stopped:  make(chan struct{}),
+		encoding: EncodingFloat32,
+		gain:     1,
+	}
+
+	go sess.readLoop()
+	sess.writeLoop()
+}
+
+// 单个客户端的状态
+type session struct {
+	server *Server
+	conn   *wsConn
+
+	control chan Message
+	done    chan struct{} // readLoop 退出时关闭
+	stopped chan struct{} // writeLoop 退出时关闭
+
+	// 由 writeLoop 持有
+	device      string
+	broadcaster *httpstream.Broadcaster
+	encoding    Encoding
+	gain        float32
+	mute        bool
+	samples     []float32 // 解码缓冲
+	encoded     []byte    // 发送缓冲
+
+	// 由 readLoop 持有
+	input    Message
+	hasInput bool
+	inbuf    []float32 // 回传音频的解码缓冲
+	mixed    []float32 // 转换为呈现声道数后的采样
+	rendered []byte    // 按呈现格式编码的数据
+}
+
+func (sess *session) send(msg Message) error {
+	buf, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	return sess.conn.writeMessage(opText, buf)
+}
+
+func (sess *session) sendError(err error) error {
+	return sess.send(Message{Type: MessageError, Error: err.Error()})
+}
+
+func (sess *session) status() Message {
+	gain := float64(sess.gain)
+	mute := sess.mute
+
+	msg := Message{
+		Type:     MessageStatus,
+		Device:   sess.device,
+		Devices:  sess.server.deviceList(),
+		Encoding: sess.encoding,
+		Gain:     &gain,
+		Mute:     &mute,
+	}
+
+	if sess.broadcaster != nil {
+		format := sess.broadcaster.Format()
+		msg.SampleRate = format.Format.SamplesPerSec
+		msg.Channels = format.Format.Channels
+	}
+
+	return msg
+}
+
+// 读取客户端消息，控制消息转交 writeLoop，二进制消息写入呈现流
+func (sess *session) readLoop() {
+	defer close(sess.done)
+
+	for {
+		opcode, payload, err := sess.conn.readMessage()
+		if err != nil {
+			return
+		}
+
+		if opcode == opBinary {
+			if err = sess.handleInput(payload); err != nil {
+				sess.sendError(err)
+			}
+			continue
+		}
+
+		var msg Message
+		if err = json.Unmarshal(payload, &msg); err != nil {
+			sess.sendError(err)
+			continue
+		}
+
+		if msg.Type == MessageInput {
+			sess.input = msg
+			sess.hasInput = true
+			continue
+		}
+
+		select {
+		case sess.control <- msg:
+		case <-sess.stopped:
+			return
+		}
+	}
+}
+
+func (sess *session) writeLoop() {
+	var (
+		ch     <-chan []byte
+		cancel = func() {}
+	)
+	defer close(sess.stopped)
+	defer func() { cancel() }()
+
+	subscribe := func(id string) (err error) {
+		resolved, broadcaster, ok := sess.server.device(id)
+		if !ok {
+			return fmt.Errorf("unknown device %q", id)
+		}
+
+		cancel()
+		ch, cancel = broadcaster.Subscribe(sess.server.buffer)
+		sess.broadcaster = broadcaster
+		sess.device = resolved
+
+		return
+	}
+
+	if err := subscribe(""); err != nil {
+		sess.sendError(err)
+	}
+
+	if sess.send(sess.status()) != nil {
+		return
+	}
+
+	for {
+		select {
+		case <-sess.done:
+			return
+		case msg := <-sess.control:
+			if err := sess.apply(msg, subscribe); err != nil {
+				if sess.sendError(err) != nil {
+					return
+				}
+				continue
+			}
+
+			if sess.send(sess.status()) != nil {
+				return
+			}
+		case packet, ok := <-ch:
+			if !ok {
+				// 过慢被断开或设备已关闭
+				ch = nil
+				sess.broadcaster = nil
+				sess.sendError(fmt.Errorf("device %q stream ended", sess.device))
+				continue
+			}
+
+			out, err := sess.encode(packet)
+			if err != nil {
+				if sess.sendError(err) != nil {
+					return
+				}
+				continue
+			}
+
+			if sess.conn.writeMessage(opBinary, out) != nil {
+				return
+			}
+		}
+	}
+}
+
+func (sess *session) apply(msg Message, subscribe func(string) error) (err error) {
+	switch msg.Type {
+	case MessageDevice:
+		err = subscribe(msg.Device)
+	case MessageFormat:
+		if msg.Encoding != EncodingFloat32 && msg.Encoding != EncodingPCM16 {
+			err = fmt.Errorf("unsupported encoding %q", msg.Encoding)
+			return
+		}
+		sess.encoding = msg.Encoding
+	case MessageGain:
+		if msg.Gain == nil || *msg.Gain < 0 {
+			err = fmt.Errorf("invalid gain")
+			return
+		}
+		sess.gain = float32(*msg.Gain)
+	case MessageMute:
+		if msg.Mute == nil {
+			err = fmt.Errorf("invalid mute")
+			return
+		}
+		sess.mute = *msg.Mute
+	case MessageStatus:
+	default:
+		err = fmt.Errorf("unknown message type %q", msg.Type)
+	}
+
+	return
+}
+
+// 将捕获数据按客户端的编码、增益及静音设置转换，返回的切片在下次调用前有效
+func (sess *session) encode(packet []byte) (out []byte, err error) {
+	format := sess.broadcaster.Format()
+	size := int(format.Format.BitsPerSample / 8)
+	if size == 0 {
+		err = pcm.ErrUnsupportedFormat
+		return
+	}
+
+	sess.samples = grow(sess.samples, len(packet)/size)
+
+	var n int
+	if n, err = pcm.Decode(&format, packet, sess.samples); err != nil {
+		return
+	}
+	samples := sess.samples[:n]
+
+	gain := sess.gain
+	if sess.mute {
+		gain = 0
+	}
+
+	if sess.encoding == EncodingPCM16 {
+		sess.encoded = grow(sess.encoded, 2*n)
+		for i, v := range samples {
+			binary.LittleEndian.PutUint16(sess.encoded[2*i:], uint16(int16(pcm.Quantize(v*gain, 1<<15))))
+		}
+		return sess.encoded, nil
+	}
+
+	sess.encoded = grow(sess.encoded, 4*n)
+	for i, v := range samples {
+		binary.LittleEndian.PutUint32(sess.encoded[4*i:], math.Float32bits(v*gain))
+	}
+
+	return sess.encoded, nil
+}
+
+// 返回长度为 n 的切片，容量足够时复用 buf
+func grow[T any](buf []T, n int) []T {
+	if cap(buf) < n {
+		return make([]T, n)
+	}
+
+	return buf[:n]
+}
+
+// 将浏览器回传的音频转换为呈现格式
+func (sess *session) handleInput(payload []byte) (err error) {
+	sess.server.mu.Lock()
+	render, format := sess.server.render, sess.server.renderFormat
+	sess.server.mu.Unlock()
+
+	if render == nil {
+		return fmt.Errorf("render not available")
+	}
+
+	if !sess.hasInput {
+		return fmt.Errorf("input format not declared")
+	}
+
+	if sess.input.SampleRate != format.Format.SamplesPerSec || sess.input.Channels == 0 {
+		return fmt.Errorf("input must be %d Hz", format.Format.SamplesPerSec)
+	}
+
+	var samples []float32
+	switch sess.input.Encoding {
+	case EncodingPCM16:
+		samples = grow(sess.inbuf, len(payload)/2)
+		for i := range samples {
+			samples[i] = float32(int16(binary.LittleEndian.Uint16(payload[2*i:]))) / (1 << 15)
+		}
+	case EncodingFloat32, "":
+		samples = grow(sess.inbuf, len(payload)/4)
+		for i := range samples {
+			samples[i] = math.Float32frombits(binary.LittleEndian.Uint32(payload[4*i:]))
+		}
+	default:
+		return fmt.Errorf("unsupported encoding %q", sess.input.Encoding)
+	}
+	sess.inbuf = samples
+
+	inChannels := int(sess.input.Channels)
+	outChannels := int(format.Format.Channels)
+	frames := len(samples) / inChannels
+
+	sess.mixed = grow(sess.mixed, frames*outChannels)
+	out := sess.mixed
+	for i := 0; i < frames; i++ {
+		in := samples[i*inChannels : (i+1)*inChannels]
+
+		if outChannels == 1 {
+			var sum float32
+			for _, v := range in {
+				sum += v
+			}
+			out[i] = sum / float32(inChannels)
+			continue
+		}
+
+		for c := 0; c < outChannels; c++ {
+			out[i*outChannels+c] = in[c%inChannels]
+		}
+	}
+
+	sess.rendered = grow(sess.rendered, frames*int(format.Format.BlockAlign))
+	if _, err = pcm.Encode(&format, out, sess.rendered); err != nil {
+		return
+	}
+
+	_, err = render.Write(sess.rendered)
+
+	return
+}
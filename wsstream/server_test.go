@@ -0,0 +1,370 @@
+package wsstream
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"math"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cyberxnomad/wasapi/audioclient"
+	"github.com/cyberxnomad/wasapi/httpstream"
+	"github.com/cyberxnomad/wasapi/pcm"
+)
+
+var (
+	stereo = pcm.NewFormat(pcm.SampleTypeInt, 48000, 2, 16, audioclient.KSAUDIO_SPEAKER_STEREO)
+	mono   = pcm.NewFormat(pcm.SampleTypeInt, 48000, 1, 16, audioclient.KSAUDIO_SPEAKER_MONO)
+)
+
+// RFC 6455 1.3 中的示例
+const (
+	sampleKey    = "dGhlIHNhbXBsZSBub25jZQ=="
+	sampleAccept = "s3pPLMBiTxaQ9kYGzzhZRbK+xOo="
+)
+
+// 测试用的 WebSocket 客户端
+type client struct {
+	t    *testing.T
+	conn net.Conn
+	br   *bufio.Reader
+}
+
+// 发送握手请求，返回响应
+func handshake(t *testing.T, server *httptest.Server, header string) (c *client, resp *http.Response) {
+	t.Helper()
+
+	conn, err := net.Dial("tcp", strings.TrimPrefix(server.URL, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	if _, err = conn.Write([]byte("GET /ws HTTP/1.1\r\nHost: test\r\n" + header + "\r\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	c = &client{t: t, conn: conn, br: bufio.NewReader(conn)}
+	if resp, err = http.ReadResponse(c.br, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	return
+}
+
+func dial(t *testing.T, server *httptest.Server) *client {
+	t.Helper()
+
+	c, resp := handshake(t, server, "Connection: keep-alive, Upgrade\r\nUpgrade: websocket\r\n"+
+		"Sec-WebSocket-Version: 13\r\nSec-WebSocket-Key: "+sampleKey+"\r\n")
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("handshake: %s", resp.Status)
+	}
+
+	return c
+}
+
+// 发送带掩码的帧
+func (c *client) writeFrame(fin bool, opcode byte, payload []byte) {
+	c.t.Helper()
+
+	head := []byte{opcode, 0x80}
+	if fin {
+		head[0] |= 0x80
+	}
+
+	switch n := len(payload); {
+	case n < 126:
+		head[1] |= byte(n)
+	case n <= 0xFFFF:
+		head[1] |= 126
+		head = binary.BigEndian.AppendUint16(head, uint16(n))
+	default:
+		head[1] |= 127
+		head = binary.BigEndian.AppendUint64(head, uint64(n))
+	}
+
+	mask := [4]byte{0x12, 0x34, 0x56, 0x78}
+	head = append(head, mask[:]...)
+	for i, b := range payload {
+		head = append(head, b^mask[i%4])
+	}
+
+	if _, err := c.conn.Write(head); err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+func (c *client) send(msg string) {
+	c.writeFrame(true, opText, []byte(msg))
+}
+
+// 读取服务端的帧，服务端的帧不带掩码
+func (c *client) readFrame() (opcode byte, payload []byte) {
+	c.t.Helper()
+
+	var head [2]byte
+	if _, err := c.br.Read(head[:1]); err != nil {
+		c.t.Fatal(err)
+	}
+	if _, err := c.br.Read(head[1:]); err != nil {
+		c.t.Fatal(err)
+	}
+	if head[0]&0x80 == 0 || head[1]&0x80 != 0 {
+		c.t.Fatalf("frame header % X: want FIN and no mask", head)
+	}
+
+	length := int(head[1] & 0x7F)
+	switch length {
+	case 126:
+		var ext [2]byte
+		c.readFull(ext[:])
+		length = int(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		c.readFull(ext[:])
+		length = int(binary.BigEndian.Uint64(ext[:]))
+	}
+
+	payload = make([]byte, length)
+	c.readFull(payload)
+
+	return head[0] & 0x0F, payload
+}
+
+func (c *client) readFull(buf []byte) {
+	c.t.Helper()
+
+	for n := 0; n < len(buf); {
+		m, err := c.br.Read(buf[n:])
+		if err != nil {
+			c.t.Fatal(err)
+		}
+		n += m
+	}
+}
+
+// 读取一个控制消息
+func (c *client) message() (msg Message) {
+	c.t.Helper()
+
+	opcode, payload := c.readFrame()
+	if opcode != opText {
+		c.t.Fatalf("opcode %X, want text", opcode)
+	}
+	if err := json.Unmarshal(payload, &msg); err != nil {
+		c.t.Fatal(err)
+	}
+
+	return
+}
+
+func TestHandshake(t *testing.T) {
+	server := httptest.NewServer(NewServer())
+	defer server.Close()
+
+	c := dial(t, server)
+	c.conn.Close()
+
+	_, resp := handshake(t, server, "Connection: Upgrade\r\nUpgrade: websocket\r\nSec-WebSocket-Version: 13\r\nSec-WebSocket-Key: "+sampleKey+"\r\n")
+	if got := resp.Header.Get("Sec-WebSocket-Accept"); got != sampleAccept {
+		t.Fatalf("Sec-WebSocket-Accept = %q, want %q", got, sampleAccept)
+	}
+
+	for _, header := range []string{
+		"",
+		"Connection: Upgrade\r\nUpgrade: websocket\r\nSec-WebSocket-Key: " + sampleKey + "\r\n",
+		"Connection: Upgrade\r\nUpgrade: websocket\r\nSec-WebSocket-Version: 13\r\n",
+		"Connection: Upgrade\r\nUpgrade: h2c\r\nSec-WebSocket-Version: 13\r\nSec-WebSocket-Key: " + sampleKey + "\r\n",
+	} {
+		if _, resp = handshake(t, server, header); resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("%q: %s, want 400", header, resp.Status)
+		}
+	}
+}
+
+func TestStream(t *testing.T) {
+	mic := httpstream.NewBroadcaster(&stereo)
+	s := NewServer()
+	s.AddDevice("mic", mic)
+	s.AddDevice("line", httpstream.NewBroadcaster(&mono))
+
+	server := httptest.NewServer(s)
+	defer server.Close()
+
+	c := dial(t, server)
+
+	// 连接后先收到默认设备的状态
+	status := c.message()
+	if status.Type != MessageStatus || status.Device != "mic" || status.SampleRate != 48000 || status.Channels != 2 ||
+		status.Encoding != EncodingFloat32 || *status.Gain != 1 || *status.Mute || strings.Join(status.Devices, ",") != "line,mic" {
+		t.Fatalf("initial status = %+v", status)
+	}
+
+	packet := make([]byte, 8)
+	for i, v := range []int16{16384, -16384, 32767, -32768} {
+		binary.LittleEndian.PutUint16(packet[2*i:], uint16(v))
+	}
+
+	// 按编码、增益及静音设置转换后以二进制帧发送
+	for _, tt := range []struct {
+		control string
+		want    []float64
+	}{
+		{"", []float64{0.5, -0.5, 32767.0 / 32768, -1}},
+		{`{"type":"gain","gain":0.5}`, []float64{0.25, -0.25, 32767.0 / 65536, -0.5}},
+		{`{"type":"format","encoding":"pcm16"}`, []float64{8192, -8192, 16384, -16384}},
+		{`{"type":"gain","gain":4}`, []float64{32767, -32768, 32767, -32768}},
+		{`{"type":"mute","mute":true}`, []float64{0, 0, 0, 0}},
+	} {
+		if tt.control != "" {
+			c.send(tt.control)
+			if msg := c.message(); msg.Type != MessageStatus {
+				t.Fatalf("%s: reply %+v", tt.control, msg)
+			}
+		}
+
+		if _, err := mic.Write(packet); err != nil {
+			t.Fatal(err)
+		}
+
+		opcode, payload := c.readFrame()
+		if opcode != opBinary {
+			t.Fatalf("%s: opcode %X, want binary", tt.control, opcode)
+		}
+
+		for i, want := range tt.want {
+			var got float64
+			if len(payload) == 8 {
+				got = float64(int16(binary.LittleEndian.Uint16(payload[2*i:])))
+			} else {
+				got = float64(math.Float32frombits(binary.LittleEndian.Uint32(payload[4*i:])))
+			}
+			if math.Abs(got-want) > 1e-6 {
+				t.Fatalf("%s: sample %d = %v, want %v", tt.control, i, got, want)
+			}
+		}
+	}
+
+	// 错误的控制消息返回错误，连接保持
+	for _, msg := range []string{`{"type":"bogus"}`, `{"type":"device","device":"none"}`, `{"type":"format","encoding":"mp3"}`, `{"type":"gain","gain":-1}`, `not json`} {
+		c.send(msg)
+		if reply := c.message(); reply.Type != MessageError || reply.Error == "" {
+			t.Fatalf("%s: reply %+v, want error", msg, reply)
+		}
+	}
+
+	// 分片的文本消息，中间插入 ping
+	c.writeFrame(false, opText, []byte(`{"type":"dev`))
+	c.writeFrame(true, opPing, []byte("hi"))
+	if opcode, payload := c.readFrame(); opcode != opPong || string(payload) != "hi" {
+		t.Fatalf("ping reply opcode %X payload %q", opcode, payload)
+	}
+	c.writeFrame(true, opContinuation, []byte(`ice","device":"line"}`))
+	if status = c.message(); status.Device != "line" || status.Channels != 1 {
+		t.Fatalf("status after switching device = %+v", status)
+	}
+
+	// 关闭握手
+	c.writeFrame(true, opClose, binary.BigEndian.AppendUint16(nil, 1000))
+	if opcode, _ := c.readFrame(); opcode != opClose {
+		t.Fatalf("opcode %X, want close", opcode)
+	}
+}
+
+func TestProtocolErrors(t *testing.T) {
+	server := httptest.NewServer(NewServer())
+	defer server.Close()
+
+	// 未带掩码的帧使服务端断开连接
+	c := dial(t, server)
+	c.message()
+	if _, err := c.conn.Write([]byte{0x81, 0x02, 'h', 'i'}); err != nil {
+		t.Fatal(err)
+	}
+	for {
+		if _, err := c.br.ReadByte(); err != nil {
+			break
+		}
+	}
+}
+
+// 线程安全地记录写入的数据
+type recorder struct {
+	mu   sync.Mutex
+	data []byte
+}
+
+func (r *recorder) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.data = append(r.data, p...)
+
+	return len(p), nil
+}
+
+func (r *recorder) bytes() []byte {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]byte(nil), r.data...)
+}
+
+func TestInput(t *testing.T) {
+	render := &recorder{}
+	s := NewServer()
+	s.SetRender(&mono, render)
+
+	server := httptest.NewServer(s)
+	defer server.Close()
+
+	// 没有捕获设备时先收到错误，之后是状态
+	c := dial(t, server)
+	if msg := c.message(); msg.Type != MessageError {
+		t.Fatalf("first message %+v, want error", msg)
+	}
+	c.message()
+
+	// 未声明格式时拒绝
+	c.writeFrame(true, opBinary, make([]byte, 8))
+	if reply := c.message(); reply.Type != MessageError {
+		t.Fatalf("undeclared input: reply %+v", reply)
+	}
+
+	c.send(`{"type":"input","sampleRate":48000,"channels":2,"encoding":"pcm16"}`)
+
+	// 立体声混合为单声道，连续发送以复用缓冲
+	var want []int16
+	for k := 0; k < 3; k++ {
+		frame := make([]byte, 0, 16)
+		for _, v := range [][2]int16{{1000, 3000}, {-2000, -4000}, {int16(k), int16(k)}, {32767, 32767}} {
+			frame = binary.LittleEndian.AppendUint16(frame, uint16(v[0]))
+			frame = binary.LittleEndian.AppendUint16(frame, uint16(v[1]))
+			want = append(want, int16((int32(v[0])+int32(v[1]))/2))
+		}
+		c.writeFrame(true, opBinary, frame)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(render.bytes()) < 2*len(want) && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+
+	data := render.bytes()
+	if len(data) != 2*len(want) {
+		t.Fatalf("render received %d bytes, want %d", len(data), 2*len(want))
+	}
+	for i, w := range want {
+		if got := int16(binary.LittleEndian.Uint16(data[2*i:])); got != w {
+			t.Fatalf("sample %d = %d, want %d", i, got, w)
+		}
+	}
+}
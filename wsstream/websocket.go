@@ -0,0 +1,234 @@
+package wsstream
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// WebSocket 操作码 (RFC 6455 5.2)
+const (
+	opContinuation = 0x0
+	opText         = 0x1
+	opBinary       = 0x2
+	opClose        = 0x8
+	opPing         = 0x9
+	opPong         = 0xA
+)
+
+// 单个消息的最大长度
+const maxMessageSize = 1 << 20
+
+const websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+var errProtocol = errors.New("websocket protocol error")
+
+// 最小化的服务端 WebSocket 连接，不支持扩展
+type wsConn struct {
+	conn net.Conn
+	br   *bufio.Reader
+
+	wmu sync.Mutex
+}
+
+func headerContains(h http.Header, key, value string) bool {
+	for _, v := range h.Values(key) {
+		for _, token := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(token), value) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// 完成握手并接管连接
+func upgrade(w http.ResponseWriter, r *http.Request) (c *wsConn, err error) {
+	key := r.Header.Get("Sec-WebSocket-Key")
+
+	if r.Method != http.MethodGet ||
+		!headerContains(r.Header, "Connection", "upgrade") ||
+		!headerContains(r.Header, "Upgrade", "websocket") ||
+		r.Header.Get("Sec-WebSocket-Version") != "13" || key == "" {
+		http.Error(w, "websocket upgrade required", http.StatusBadRequest)
+		err = errProtocol
+		return
+	}
+
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "websocket not supported", http.StatusInternalServerError)
+		err = errors.New("response writer does not support hijacking")
+		return
+	}
+
+	var (
+		conn net.Conn
+		rw   *bufio.ReadWriter
+	)
+
+	if conn, rw, err = hijacker.Hijack(); err != nil {
+		return
+	}
+
+	sum := sha1.Sum([]byte(key + websocketGUID))
+
+	rw.WriteString("HTTP/1.1 101 Switching Protocols\r\n")
+	rw.WriteString("Upgrade: websocket\r\n")
+	rw.WriteString("Connection: Upgrade\r\n")
+	rw.WriteString("Sec-WebSocket-Accept: " + base64.StdEncoding.EncodeToString(sum[:]) + "\r\n\r\n")
+
+	if err = rw.Flush(); err != nil {
+		conn.Close()
+		return
+	}
+
+	c = &wsConn{conn: conn, br: rw.Reader}
+
+	return
+}
+
+// 读取一个完整的数据消息，自动回应 ping 及 close
+func (c *wsConn) readMessage() (opcode byte, payload []byte, err error) {
+	for {
+		var (
+			fin  bool
+			op   byte
+			data []byte
+		)
+
+		if fin, op, data, err = c.readFrame(); err != nil {
+			return
+		}
+
+		switch op {
+		case opPing:
+			if err = c.writeMessage(opPong, data); err != nil {
+				return
+			}
+			continue
+		case opPong:
+			continue
+		case opClose:
+			c.writeMessage(opClose, data)
+			err = io.EOF
+			return
+		case opContinuation:
+			if opcode == 0 {
+				err = errProtocol
+				return
+			}
+		case opText, opBinary:
+			if opcode != 0 {
+				err = errProtocol
+				return
+			}
+			opcode = op
+		default:
+			err = errProtocol
+			return
+		}
+
+		if len(payload)+len(data) > maxMessageSize {
+			err = errProtocol
+			return
+		}
+
+		payload = append(payload, data...)
+		if fin {
+			return
+		}
+	}
+}
+
+func (c *wsConn) readFrame() (fin bool, opcode byte, payload []byte, err error) {
+	var head [2]byte
+	if _, err = io.ReadFull(c.br, head[:]); err != nil {
+		return
+	}
+
+	fin = head[0]&0x80 != 0
+	opcode = head[0] & 0x0F
+
+	// 客户端发送的帧必须带掩码
+	if head[0]&0x70 != 0 || head[1]&0x80 == 0 {
+		err = errProtocol
+		return
+	}
+
+	length := uint64(head[1] & 0x7F)
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err = io.ReadFull(c.br, ext[:]); err != nil {
+			return
+		}
+		length = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err = io.ReadFull(c.br, ext[:]); err != nil {
+			return
+		}
+		length = binary.BigEndian.Uint64(ext[:])
+	}
+
+	if length > maxMessageSize || (opcode >= opClose && (length > 125 || !fin)) {
+		err = errProtocol
+		return
+	}
+
+	var mask [4]byte
+	if _, err = io.ReadFull(c.br, mask[:]); err != nil {
+		return
+	}
+
+	payload = make([]byte, length)
+	if _, err = io.ReadFull(c.br, payload); err != nil {
+		return
+	}
+
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+
+	return
+}
+
+// 发送一个不分片的消息
+func (c *wsConn) writeMessage(opcode byte, payload []byte) (err error) {
+	header := make([]byte, 2, 10+len(payload))
+	header[0] = 0x80 | opcode
+
+	switch n := len(payload); {
+	case n < 126:
+		header[1] = byte(n)
+	case n <= 0xFFFF:
+		header[1] = 126
+		header = binary.BigEndian.AppendUint16(header, uint16(n))
+	default:
+		header[1] = 127
+		header = binary.BigEndian.AppendUint64(header, uint64(n))
+	}
+
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+
+	_, err = c.conn.Write(append(header, payload...))
+
+	return
+}
+
+// 发送关闭帧并关闭连接
+func (c *wsConn) close(code uint16) (err error) {
+	c.writeMessage(opClose, binary.BigEndian.AppendUint16(nil, code))
+
+	return c.conn.Close()
+}